@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"regexp"
+	"strings"
 	"time"
 )
 
@@ -16,66 +18,295 @@ type Config struct {
 	// Relay configuration
 	Relay RelayConfig `json:"relay"`
 
+	// HLS preview configuration
+	HLS HLSConfig `json:"hls"`
+
 	// Recording configuration
 	Recording RecordingConfig `json:"recording"`
 
+	// Assets (watermark images) configuration
+	Assets AssetsConfig `json:"assets"`
+
 	// Logging configuration
 	Logging LoggingConfig `json:"logging"`
 }
 
-// HTTPConfig contains HTTP server settings
+// HTTPConfig contains HTTP server settings. ReadTimeout bounds reading a whole
+// request, WriteTimeout the time from the end of the request headers to the
+// end of the response, and IdleTimeout how long a keep-alive connection waits
+// for its next request. 0 disables a timeout, which Warnings reports.
+// Long-lived responses (recording events, HLS, downloads) lift their own write
+// deadline, so WriteTimeout only limits ordinary API calls.
 type HTTPConfig struct {
 	Host         string        `json:"host"`
 	Port         string        `json:"port"`
 	ReadTimeout  time.Duration `json:"read_timeout"`
 	WriteTimeout time.Duration `json:"write_timeout"`
 	IdleTimeout  time.Duration `json:"idle_timeout"`
+
+	// Origins allowed to call the JSON API from the browser ("*" for any), empty disables CORS
+	CORSOrigins []string `json:"cors_origins,omitempty"`
+
+	// Recording event (SSE) connections served at once, 0 for unlimited
+	SSEMaxClients int `json:"sse_max_clients"`
 }
 
 // RelayConfig contains relay-specific settings
 type RelayConfig struct {
-	InputTimeout  time.Duration `json:"input_timeout"`
-	OutputTimeout time.Duration `json:"output_timeout"`
-	RTSPServer    RTSPConfig    `json:"rtsp_server"`
+	InputTimeout  time.Duration    `json:"input_timeout"`
+	OutputTimeout time.Duration    `json:"output_timeout"`
+	RTSPServer    RTSPConfig       `json:"rtsp_server"`
+	SlowOutput    SlowOutputConfig `json:"slow_output"`
+
+	// How long an input must publish to the local RTSP server before it is reported Running
+	InputStabilization time.Duration `json:"input_stabilization"`
+
+	// Suspend an input once all its consumers stopped delivering data for this long, 0 never
+	IdleInputTimeout time.Duration `json:"idle_input_timeout"`
+
+	// How long an input stays on its backup source before the primary is retried, 0 only manually
+	FailbackInterval time.Duration `json:"failback_interval"`
+
+	// Purge inputs left without consumers (and outputs) for this long, 0 keeps them listed
+	InputHistoryMaxAge time.Duration `json:"input_history_max_age"`
+
+	// Let a live input with a single output skip the local RTSP relay
+	DirectPassthrough bool `json:"direct_passthrough"`
+
+	// Maximum output relays fed by a single input, 0 for unlimited
+	MaxOutputsPerInput int `json:"max_outputs_per_input"`
+
+	// How many relays are started at once when importing a configuration
+	ImportConcurrency int `json:"import_concurrency"`
+
+	// Default reading of inputs and HLS previews: "low_latency", or "stable" to buffer against network jitter
+	Buffering string `json:"buffering"`
+
+	// Default input profile bundling ffmpeg's input options: "low_latency", "balanced", "robust", or empty for none
+	InputProfile string `json:"input_profile"`
+
+	// What reloading relay_config.json does with relays it no longer lists: "merge" keeps them, "replace" deletes them
+	ReloadMode string `json:"reload_mode"`
+
+	// Relay config fetched over HTTP(S) and imported at startup, empty for none
+	ConfigURL        string            `json:"config_url"`
+	ConfigURLHeaders map[string]string `json:"config_url_headers,omitempty"` // e.g. {"Authorization": "Bearer ..."}
+	ConfigURLTimeout time.Duration     `json:"config_url_timeout"`
+	// Copy of the last fetched config, imported when a fetch fails
+	ConfigURLCache string `json:"config_url_cache"`
+
+	// How many outputs are stopped at once on shutdown, and how long each ffmpeg may take to exit
+	ShutdownConcurrency int           `json:"shutdown_concurrency"`
+	ShutdownStopTimeout time.Duration `json:"shutdown_stop_timeout"`
+
+	// Nice value (-20 to 19) for ffmpeg processes, 0 keeps go-mls's priority
+	ProcessNice int `json:"process_nice"`
+
+	// ffmpeg -threads for output and HLS encodes, 0 lets ffmpeg decide
+	Threads int `json:"threads"`
+
+	// ffmpeg -max_muxing_queue_size of outputs in packets, 0 keeps ffmpeg's default
+	MaxMuxingQueueSize int `json:"max_muxing_queue_size"`
+
+	// CPUs ffmpeg processes are pinned to (Linux), empty for all
+	CPUAffinity []int `json:"cpu_affinity,omitempty"`
+}
+
+// SlowOutputConfig controls detection of output relays that cannot keep up
+// with real time. Policy is one of "log", "degrade" or "drop".
+type SlowOutputConfig struct {
+	Policy   string        `json:"policy"`
+	MinSpeed float64       `json:"min_speed"`
+	Grace    time.Duration `json:"grace"`
 }
 
 // RTSPConfig contains RTSP server settings
 type RTSPConfig struct {
 	Host string `json:"host"`
 	Port int    `json:"port"`
+
+	// Even UDP port for RTP, RTCP on the next one; 0 derives them from Port
+	UDPRTPPort int `json:"udp_rtp_port"`
+
+	// Base interval, jittered and backed off, a start checks the relay stream at when it missed its ready signal
+	ReadyPollInterval time.Duration `json:"ready_poll_interval"`
 }
 
+// HLSConfig contains HLS preview settings. AnalyzeDuration and ProbeSize are
+// passed to ffmpeg as-is (e.g. "500k", "5M"); empty leaves ffmpeg's default.
+// AudioSampleRate and AudioChannels of 0 keep the source's audio format.
+// SegmentPattern names segment files with either a counter ("seg_%08d.ts")
+// or strftime fields ("seg_%Y%m%d-%H%M%S.ts"); SegmentFormat is "mpegts"
+// (.ts segments) or "fmp4" (.m4s segments). After an input fails to start a
+// preview, new attempts are refused for FailedCooldown, doubling with every
+// consecutive failure up to MaxFailedCooldown. Sync adds ffmpeg flags against
+// A/V drift, none by default. MaxHeight downscales taller video to that many
+// lines, 0 keeps the source resolution. MaxViewers caps the viewers of one
+// input's preview, 0 for unlimited.
+type HLSConfig struct {
+	AnalyzeDuration string `json:"analyzeduration"`
+	ProbeSize       string `json:"probesize"`
+	AudioSampleRate int    `json:"audio_sample_rate"`
+	AudioChannels   int    `json:"audio_channels"`
+	SegmentPattern  string `json:"segment_pattern"`
+	SegmentFormat   string `json:"segment_format"`
+	MaxHeight       int    `json:"max_height"`
+	MaxViewers      int    `json:"max_viewers"`
+
+	FailedCooldown    time.Duration `json:"failed_cooldown"`
+	MaxFailedCooldown time.Duration `json:"max_failed_cooldown"`
+
+	Sync HLSSyncConfig `json:"sync"`
+}
+
+// HLSSyncConfig selects ffmpeg A/V sync flags for HLS previews: AudioResample
+// adds -af aresample=async=1, FPSMode sets -fps_mode ("cfr", "vfr",
+// "passthrough" or "auto", empty leaves it out) and CopyTS adds -copyts
+// -start_at_zero
+type HLSSyncConfig struct {
+	AudioResample bool   `json:"audio_resample"`
+	FPSMode       string `json:"fps_mode"`
+	CopyTS        bool   `json:"copyts"`
+}
+
+var (
+	segmentCounterPattern   = regexp.MustCompile(`%0?[0-9]*d`)
+	segmentTimestampPattern = regexp.MustCompile(`%[YmHMSs]`)
+)
+
 // RecordingConfig contains recording-specific settings
 type RecordingConfig struct {
 	Directory string `json:"directory"`
+
+	// Upload finished recordings to an S3 bucket, empty bucket keeps them local only
+	S3 S3Config `json:"s3"`
+
+	// URL POSTed a JSON event whenever a recording stops, empty disables it
+	WebhookURL string `json:"webhook_url"`
+
+	// Total bytes the directory may hold before the oldest recordings are deleted, 0 for unlimited
+	MaxTotalSize int64 `json:"max_total_size"`
+
+	// Recordings that may be active at once, 0 for unlimited
+	MaxConcurrent int `json:"max_concurrent"`
+
+	// Signal asking ffmpeg to finish a stopped recording ("SIGINT" or "SIGTERM"), empty picks one by container
+	StopSignal string `json:"stop_signal"`
+
+	// How long a stopped recording may take to finish its file before ffmpeg is killed
+	FinalizeTimeout time.Duration `json:"finalize_timeout"`
+
+	// How much of an input a highlight buffer keeps, the longest highlight that can be saved
+	HighlightWindow time.Duration `json:"highlight_window"`
+
+	// Start the recordings that were active when the server last stopped again on boot
+	ResumeOnStart bool `json:"resume_on_start"`
+
+	// Directories new recordings are filed under by start time, e.g. "%Y/%m/%d", empty keeps them flat
+	DateLayout string `json:"date_layout"`
+
+	// Font file of burnt-in timestamps, empty for ffmpeg's default font
+	TimestampFont string `json:"timestamp_font"`
+
+	// Corner burnt-in timestamps are drawn in: top-left, top-right, bottom-left or bottom-right
+	TimestampPosition string `json:"timestamp_position"`
+
+	// Font size of burnt-in timestamps, in pixels
+	TimestampFontSize int `json:"timestamp_font_size"`
+
+	// Directory the named pipes of recordings streamed live are created in, empty for one under the temp directory
+	FIFODir string `json:"fifo_dir"`
+}
+
+// S3Config locates an S3 (or S3-compatible) bucket recordings are uploaded to.
+// Empty credentials fall back to AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY.
+// DeleteLocal removes a recording from the recordings directory once uploaded.
+type S3Config struct {
+	Endpoint    string `json:"endpoint"`
+	Region      string `json:"region"`
+	Bucket      string `json:"bucket"`
+	Prefix      string `json:"prefix"`
+	AccessKey   string `json:"access_key,omitempty"`
+	SecretKey   string `json:"secret_key,omitempty"`
+	DeleteLocal bool   `json:"delete_local"`
+}
+
+// AssetsConfig contains settings for static assets used by outputs
+type AssetsConfig struct {
+	// Directory watermark images are loaded from; empty disables watermarks
+	Directory string `json:"directory"`
 }
 
 // LoggingConfig contains logging settings
 type LoggingConfig struct {
 	Level string `json:"level"`
 	File  string `json:"file,omitempty"`
+
+	// Log every HTTP request (method, path, status, duration, size, client)
+	AccessLog bool `json:"access_log"`
+
+	// Go time layout of line timestamps, e.g. "2006-01-02T15:04:05.000Z07:00"; empty for "2006/01/02 15:04:05"
+	TimeFormat string `json:"time_format"`
+
+	// Timestamp lines in UTC instead of local time
+	UTC bool `json:"utc"`
 }
 
 // DefaultConfig returns a configuration with sensible defaults
 func DefaultConfig() *Config {
 	return &Config{
 		HTTP: HTTPConfig{
-			Host:         "0.0.0.0",
-			Port:         "8080",
-			ReadTimeout:  30 * time.Second,
-			WriteTimeout: 30 * time.Second,
-			IdleTimeout:  120 * time.Second,
+			Host:          "0.0.0.0",
+			Port:          "8080",
+			ReadTimeout:   30 * time.Second,
+			WriteTimeout:  30 * time.Second,
+			IdleTimeout:   120 * time.Second,
+			SSEMaxClients: 100,
 		},
 		Relay: RelayConfig{
 			InputTimeout:  30 * time.Second,
 			OutputTimeout: 60 * time.Second,
 			RTSPServer: RTSPConfig{
-				Host: "127.0.0.1",
-				Port: 8554,
+				Host:              "127.0.0.1",
+				Port:              8554,
+				ReadyPollInterval: 100 * time.Millisecond,
+			},
+			SlowOutput: SlowOutputConfig{
+				Policy:   "log",
+				MinSpeed: 0.9,
+				Grace:    30 * time.Second,
 			},
+			InputStabilization: 500 * time.Millisecond,
+			ImportConcurrency:  4,
+			Buffering:          "low_latency",
+			ReloadMode:         "merge",
+			ConfigURLTimeout:   10 * time.Second,
+			ConfigURLCache:     "relay_config.remote.json",
+
+			ShutdownConcurrency: 8,
+			ShutdownStopTimeout: time.Second,
+		},
+		HLS: HLSConfig{
+			AnalyzeDuration: "500k",
+			ProbeSize:       "500k",
+			AudioSampleRate: 44100,
+			AudioChannels:   2,
+			SegmentPattern:  "segment_%05d.ts",
+			SegmentFormat:   "mpegts",
+
+			FailedCooldown:    10 * time.Second,
+			MaxFailedCooldown: 5 * time.Minute,
 		},
 		Recording: RecordingConfig{
-			Directory: "recordings",
+			Directory:       "recordings",
+			FinalizeTimeout: 10 * time.Second,
+			HighlightWindow: 2 * time.Minute,
+
+			TimestampPosition: "top-left",
+			TimestampFontSize: 24,
+		},
+		Assets: AssetsConfig{
+			Directory: "assets",
 		},
 		Logging: LoggingConfig{
 			Level: "info",
@@ -130,6 +361,20 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("HTTP port cannot be empty")
 	}
 
+	for _, origin := range c.HTTP.CORSOrigins {
+		if origin == "" {
+			return fmt.Errorf("CORS origins cannot be empty")
+		}
+	}
+
+	if c.HTTP.ReadTimeout < 0 || c.HTTP.WriteTimeout < 0 || c.HTTP.IdleTimeout < 0 {
+		return fmt.Errorf("HTTP timeouts cannot be negative")
+	}
+
+	if c.HTTP.SSEMaxClients < 0 {
+		return fmt.Errorf("SSE max clients cannot be negative")
+	}
+
 	// Validate relay timeouts
 	if c.Relay.InputTimeout <= 0 {
 		return fmt.Errorf("input timeout must be positive")
@@ -139,19 +384,243 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("output timeout must be greater than input timeout")
 	}
 
+	if c.Relay.InputStabilization < 0 {
+		return fmt.Errorf("input stabilization cannot be negative")
+	}
+
+	if c.Relay.IdleInputTimeout < 0 {
+		return fmt.Errorf("idle input timeout cannot be negative")
+	}
+
+	if c.Relay.FailbackInterval < 0 {
+		return fmt.Errorf("failback interval cannot be negative")
+	}
+
+	if c.Relay.InputHistoryMaxAge < 0 {
+		return fmt.Errorf("input history max age cannot be negative")
+	}
+
+	if c.Relay.MaxOutputsPerInput < 0 {
+		return fmt.Errorf("max outputs per input cannot be negative")
+	}
+
+	if c.Relay.ImportConcurrency <= 0 {
+		return fmt.Errorf("import concurrency must be positive")
+	}
+
+	switch c.Relay.Buffering {
+	case "low_latency", "stable":
+	default:
+		return fmt.Errorf("relay buffering must be one of low_latency, stable")
+	}
+
+	switch c.Relay.InputProfile {
+	case "", "low_latency", "balanced", "robust":
+	default:
+		return fmt.Errorf("relay input profile must be empty or one of low_latency, balanced, robust")
+	}
+
+	switch c.Relay.ReloadMode {
+	case "merge", "replace":
+	default:
+		return fmt.Errorf("relay reload mode must be one of merge, replace")
+	}
+
+	if c.Relay.ShutdownConcurrency <= 0 {
+		return fmt.Errorf("shutdown concurrency must be positive")
+	}
+
+	if c.Relay.ShutdownStopTimeout <= 0 {
+		return fmt.Errorf("shutdown stop timeout must be positive")
+	}
+
+	if c.Relay.ProcessNice < -20 || c.Relay.ProcessNice > 19 {
+		return fmt.Errorf("process nice must be between -20 and 19")
+	}
+
+	if c.Relay.Threads < 0 {
+		return fmt.Errorf("threads cannot be negative")
+	}
+	if c.Relay.MaxMuxingQueueSize < 0 {
+		return fmt.Errorf("max muxing queue size cannot be negative")
+	}
+	for _, cpu := range c.Relay.CPUAffinity {
+		if cpu < 0 {
+			return fmt.Errorf("cpu affinity entries cannot be negative")
+		}
+	}
+	if c.Relay.ConfigURL != "" {
+		if !strings.HasPrefix(c.Relay.ConfigURL, "http://") && !strings.HasPrefix(c.Relay.ConfigURL, "https://") {
+			return fmt.Errorf("relay config URL must be an http(s) URL")
+		}
+		if c.Relay.ConfigURLTimeout <= 0 {
+			return fmt.Errorf("relay config URL timeout must be positive")
+		}
+	}
+
 	// Validate RTSP server configuration
 	if c.Relay.RTSPServer.Port <= 0 || c.Relay.RTSPServer.Port > 65535 {
 		return fmt.Errorf("RTSP server port must be between 1 and 65535")
 	}
+	if p := c.Relay.RTSPServer.UDPRTPPort; p < 0 || p > 65534 || p%2 != 0 {
+		return fmt.Errorf("RTSP server UDP RTP port must be even and between 0 and 65534")
+	}
+	if c.Relay.RTSPServer.ReadyPollInterval <= 0 {
+		return fmt.Errorf("RTSP server ready poll interval must be positive")
+	}
+
+	// Validate slow output policy
+	switch c.Relay.SlowOutput.Policy {
+	case "log", "degrade", "drop":
+	default:
+		return fmt.Errorf("slow output policy must be one of log, degrade, drop")
+	}
+	if c.Relay.SlowOutput.MinSpeed <= 0 {
+		return fmt.Errorf("slow output min speed must be positive")
+	}
+	if c.Relay.SlowOutput.Grace <= 0 {
+		return fmt.Errorf("slow output grace must be positive")
+	}
+
+	// Validate HLS audio format
+	if c.HLS.AudioSampleRate < 0 {
+		return fmt.Errorf("HLS audio sample rate cannot be negative")
+	}
+	if c.HLS.AudioChannels < 0 {
+		return fmt.Errorf("HLS audio channels cannot be negative")
+	}
+	if c.HLS.MaxHeight < 0 {
+		return fmt.Errorf("HLS max height cannot be negative")
+	}
+	if c.HLS.MaxViewers < 0 {
+		return fmt.Errorf("HLS max viewers cannot be negative")
+	}
+
+	// Validate HLS segment naming
+	segmentExt := ""
+	switch c.HLS.SegmentFormat {
+	case "mpegts":
+		segmentExt = ".ts"
+	case "fmp4":
+		segmentExt = ".m4s"
+	default:
+		return fmt.Errorf("HLS segment format must be one of mpegts, fmp4")
+	}
+	if strings.ContainsAny(c.HLS.SegmentPattern, "/\\") {
+		return fmt.Errorf("HLS segment pattern must be a file name")
+	}
+	if !segmentCounterPattern.MatchString(c.HLS.SegmentPattern) && !segmentTimestampPattern.MatchString(c.HLS.SegmentPattern) {
+		return fmt.Errorf("HLS segment pattern must contain a counter (%%05d) or timestamp (%%Y%%m%%d%%H%%M%%S) placeholder")
+	}
+	if !strings.HasSuffix(c.HLS.SegmentPattern, segmentExt) {
+		return fmt.Errorf("HLS segment pattern must end in %s for %s segments", segmentExt, c.HLS.SegmentFormat)
+	}
+
+	// A layout without any time element would stamp every line with the same text
+	if c.Logging.TimeFormat != "" && time.Unix(0, 0).UTC().Format(c.Logging.TimeFormat) == c.Logging.TimeFormat {
+		return fmt.Errorf("logging time format must be a Go time layout such as 2006-01-02T15:04:05Z07:00")
+	}
+
+	switch c.HLS.Sync.FPSMode {
+	case "", "cfr", "vfr", "passthrough", "auto":
+	default:
+		return fmt.Errorf("HLS fps mode must be one of cfr, vfr, passthrough, auto")
+	}
+
+	if c.HLS.FailedCooldown <= 0 {
+		return fmt.Errorf("HLS failed cooldown must be positive")
+	}
+	if c.HLS.MaxFailedCooldown < c.HLS.FailedCooldown {
+		return fmt.Errorf("HLS max failed cooldown must be at least the failed cooldown")
+	}
 
 	// Validate recording directory
 	if c.Recording.Directory == "" {
 		return fmt.Errorf("recording directory cannot be empty")
 	}
+	switch c.Recording.StopSignal {
+	case "", "SIGINT", "SIGTERM":
+	default:
+		return fmt.Errorf("recording stop signal must be SIGINT or SIGTERM")
+	}
+	if c.Recording.FinalizeTimeout <= 0 {
+		return fmt.Errorf("recording finalize timeout must be positive")
+	}
+	if c.Recording.MaxTotalSize < 0 {
+		return fmt.Errorf("recording max total size cannot be negative")
+	}
+	if c.Recording.MaxConcurrent < 0 {
+		return fmt.Errorf("recording max concurrent cannot be negative")
+	}
+	if c.Recording.HighlightWindow < 2*time.Second || c.Recording.HighlightWindow > 30*time.Minute {
+		return fmt.Errorf("recording highlight window must be between 2s and 30m")
+	}
+	if c.Recording.S3.Bucket != "" {
+		if !strings.HasPrefix(c.Recording.S3.Endpoint, "http://") && !strings.HasPrefix(c.Recording.S3.Endpoint, "https://") {
+			return fmt.Errorf("recording S3 endpoint must be an http(s) URL")
+		}
+	} else if c.Recording.S3.DeleteLocal {
+		return fmt.Errorf("recording S3 delete_local requires a bucket")
+	}
+	if c.Recording.WebhookURL != "" && !strings.HasPrefix(c.Recording.WebhookURL, "http://") && !strings.HasPrefix(c.Recording.WebhookURL, "https://") {
+		return fmt.Errorf("recording webhook URL must be an http(s) URL")
+	}
+	if !validDateLayout(c.Recording.DateLayout) {
+		return fmt.Errorf("recording date layout must be a relative path of %%Y, %%m, %%d and %%H fields")
+	}
+	switch c.Recording.TimestampPosition {
+	case "", "top-left", "top-right", "bottom-left", "bottom-right":
+	default:
+		return fmt.Errorf("recording timestamp position must be top-left, top-right, bottom-left or bottom-right")
+	}
+	if c.Recording.TimestampFontSize < 0 || (c.Recording.TimestampFontSize > 0 && (c.Recording.TimestampFontSize < 8 || c.Recording.TimestampFontSize > 200)) {
+		return fmt.Errorf("recording timestamp font size must be between 8 and 200")
+	}
 
 	return nil
 }
 
+// validDateLayout reports whether layout only uses the %Y, %m, %d and %H
+// fields and stays inside the recordings directory
+func validDateLayout(layout string) bool {
+	if layout == "" {
+		return true
+	}
+	if strings.Contains(layout, "\\") || strings.HasPrefix(layout, "/") {
+		return false
+	}
+	for _, elem := range strings.Split(layout, "/") {
+		if elem == "" || elem == "." || elem == ".." {
+			return false
+		}
+	}
+	for i := 0; i < len(layout); i++ {
+		if layout[i] == '%' {
+			if i+1 == len(layout) || !strings.ContainsRune("YmdH", rune(layout[i+1])) {
+				return false
+			}
+			i++
+		}
+	}
+	return true
+}
+
+// Warnings lists settings that are valid but probably unintended, such as a
+// timeout left at 0 by a partial config file
+func (c *Config) Warnings() []string {
+	var warnings []string
+	if c.HTTP.ReadTimeout == 0 {
+		warnings = append(warnings, "http.read_timeout is 0: slow clients can hold connections open indefinitely")
+	}
+	if c.HTTP.WriteTimeout == 0 {
+		warnings = append(warnings, "http.write_timeout is 0: API responses to stalled clients are never cut off")
+	}
+	if c.HTTP.IdleTimeout == 0 {
+		warnings = append(warnings, "http.idle_timeout is 0: idle keep-alive connections fall back to read_timeout")
+	}
+	return warnings
+}
+
 // GetRTSPServerURL returns the full RTSP server URL
 func (c *Config) GetRTSPServerURL() string {
 	return fmt.Sprintf("rtsp://%s:%d", c.Relay.RTSPServer.Host, c.Relay.RTSPServer.Port)
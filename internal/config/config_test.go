@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -129,6 +130,405 @@ func TestConfigValidation(t *testing.T) {
 			shouldError: true,
 			errorMsg:    "RTSP server port must be between 1 and 65535",
 		},
+		{
+			name: "Odd RTSP UDP RTP port",
+			modifyFunc: func(c *Config) {
+				c.Relay.RTSPServer.UDPRTPPort = 8001
+			},
+			shouldError: true,
+			errorMsg:    "RTSP server UDP RTP port must be even and between 0 and 65534",
+		},
+		{
+			name: "Zero RTSP ready poll interval",
+			modifyFunc: func(c *Config) {
+				c.Relay.RTSPServer.ReadyPollInterval = 0
+			},
+			shouldError: true,
+			errorMsg:    "RTSP server ready poll interval must be positive",
+		},
+		{
+			name: "Negative input stabilization",
+			modifyFunc: func(c *Config) {
+				c.Relay.InputStabilization = -time.Second
+			},
+			shouldError: true,
+			errorMsg:    "input stabilization cannot be negative",
+		},
+		{
+			name: "Negative HLS audio sample rate",
+			modifyFunc: func(c *Config) {
+				c.HLS.AudioSampleRate = -1
+			},
+			shouldError: true,
+			errorMsg:    "HLS audio sample rate cannot be negative",
+		},
+		{
+			name: "Negative HLS audio channels",
+			modifyFunc: func(c *Config) {
+				c.HLS.AudioChannels = -1
+			},
+			shouldError: true,
+			errorMsg:    "HLS audio channels cannot be negative",
+		},
+		{
+			name: "Negative max outputs per input",
+			modifyFunc: func(c *Config) {
+				c.Relay.MaxOutputsPerInput = -1
+			},
+			shouldError: true,
+			errorMsg:    "max outputs per input cannot be negative",
+		},
+		{
+			name: "Zero import concurrency",
+			modifyFunc: func(c *Config) {
+				c.Relay.ImportConcurrency = 0
+			},
+			shouldError: true,
+			errorMsg:    "import concurrency must be positive",
+		},
+		{
+			name: "Unknown relay buffering",
+			modifyFunc: func(c *Config) {
+				c.Relay.Buffering = "huge"
+			},
+			shouldError: true,
+			errorMsg:    "relay buffering must be one of low_latency, stable",
+		},
+		{
+			name: "Unknown relay input profile",
+			modifyFunc: func(c *Config) {
+				c.Relay.InputProfile = "fast"
+			},
+			shouldError: true,
+			errorMsg:    "relay input profile must be empty or one of low_latency, balanced, robust",
+		},
+		{
+			name: "Unknown relay reload mode",
+			modifyFunc: func(c *Config) {
+				c.Relay.ReloadMode = "sync"
+			},
+			shouldError: true,
+			errorMsg:    "relay reload mode must be one of merge, replace",
+		},
+		{
+			name: "Zero shutdown concurrency",
+			modifyFunc: func(c *Config) {
+				c.Relay.ShutdownConcurrency = 0
+			},
+			shouldError: true,
+			errorMsg:    "shutdown concurrency must be positive",
+		},
+		{
+			name: "Zero shutdown stop timeout",
+			modifyFunc: func(c *Config) {
+				c.Relay.ShutdownStopTimeout = 0
+			},
+			shouldError: true,
+			errorMsg:    "shutdown stop timeout must be positive",
+		},
+		{
+			name: "Process nice out of range",
+			modifyFunc: func(c *Config) {
+				c.Relay.ProcessNice = 20
+			},
+			shouldError: true,
+			errorMsg:    "process nice must be between -20 and 19",
+		},
+		{
+			name: "Negative threads",
+			modifyFunc: func(c *Config) {
+				c.Relay.Threads = -1
+			},
+			shouldError: true,
+			errorMsg:    "threads cannot be negative",
+		},
+		{
+			name: "Relay config URL not http",
+			modifyFunc: func(c *Config) {
+				c.Relay.ConfigURL = "ftp://config.example.com/relays.json"
+			},
+			shouldError: true,
+			errorMsg:    "relay config URL must be an http(s) URL",
+		},
+		{
+			name: "Relay config URL without timeout",
+			modifyFunc: func(c *Config) {
+				c.Relay.ConfigURL = "https://config.example.com/relays.json"
+				c.Relay.ConfigURLTimeout = 0
+			},
+			shouldError: true,
+			errorMsg:    "relay config URL timeout must be positive",
+		},
+		{
+			name: "Negative max muxing queue size",
+			modifyFunc: func(c *Config) {
+				c.Relay.MaxMuxingQueueSize = -1
+			},
+			shouldError: true,
+			errorMsg:    "max muxing queue size cannot be negative",
+		},
+		{
+			name: "Negative CPU in affinity",
+			modifyFunc: func(c *Config) {
+				c.Relay.CPUAffinity = []int{0, -1}
+			},
+			shouldError: true,
+			errorMsg:    "cpu affinity entries cannot be negative",
+		},
+		{
+			name: "HLS segment pattern without placeholder",
+			modifyFunc: func(c *Config) {
+				c.HLS.SegmentPattern = "segment.ts"
+			},
+			shouldError: true,
+			errorMsg:    "HLS segment pattern must contain a counter (%05d) or timestamp (%Y%m%d%H%M%S) placeholder",
+		},
+		{
+			name: "HLS segment pattern with timestamp",
+			modifyFunc: func(c *Config) {
+				c.HLS.SegmentPattern = "seg_%Y%m%d-%H%M%S.ts"
+			},
+			shouldError: false,
+		},
+		{
+			name: "HLS fmp4 segments need .m4s",
+			modifyFunc: func(c *Config) {
+				c.HLS.SegmentFormat = "fmp4"
+			},
+			shouldError: true,
+			errorMsg:    "HLS segment pattern must end in .m4s for fmp4 segments",
+		},
+		{
+			name: "HLS segment pattern with directory",
+			modifyFunc: func(c *Config) {
+				c.HLS.SegmentPattern = "../segment_%05d.ts"
+			},
+			shouldError: true,
+			errorMsg:    "HLS segment pattern must be a file name",
+		},
+		{
+			name: "Empty CORS origin",
+			modifyFunc: func(c *Config) {
+				c.HTTP.CORSOrigins = []string{"https://ui.example.com", ""}
+			},
+			shouldError: true,
+			errorMsg:    "CORS origins cannot be empty",
+		},
+		{
+			name: "Negative idle input timeout",
+			modifyFunc: func(c *Config) {
+				c.Relay.IdleInputTimeout = -time.Second
+			},
+			shouldError: true,
+			errorMsg:    "idle input timeout cannot be negative",
+		},
+		{
+			name: "Negative HTTP write timeout",
+			modifyFunc: func(c *Config) {
+				c.HTTP.WriteTimeout = -time.Second
+			},
+			shouldError: true,
+			errorMsg:    "HTTP timeouts cannot be negative",
+		},
+		{
+			name: "Zero HTTP write timeout",
+			modifyFunc: func(c *Config) {
+				c.HTTP.WriteTimeout = 0
+			},
+			shouldError: false,
+		},
+		{
+			name: "Negative SSE max clients",
+			modifyFunc: func(c *Config) {
+				c.HTTP.SSEMaxClients = -1
+			},
+			shouldError: true,
+			errorMsg:    "SSE max clients cannot be negative",
+		},
+		{
+			name: "S3 upload without endpoint",
+			modifyFunc: func(c *Config) {
+				c.Recording.S3.Bucket = "media"
+			},
+			shouldError: true,
+			errorMsg:    "recording S3 endpoint must be an http(s) URL",
+		},
+		{
+			name: "S3 delete_local without bucket",
+			modifyFunc: func(c *Config) {
+				c.Recording.S3.DeleteLocal = true
+			},
+			shouldError: true,
+			errorMsg:    "recording S3 delete_local requires a bucket",
+		},
+		{
+			name: "Recording webhook without scheme",
+			modifyFunc: func(c *Config) {
+				c.Recording.WebhookURL = "hooks.example.com/recordings"
+			},
+			shouldError: true,
+			errorMsg:    "recording webhook URL must be an http(s) URL",
+		},
+		{
+			name: "Negative recording quota",
+			modifyFunc: func(c *Config) {
+				c.Recording.MaxTotalSize = -1
+			},
+			shouldError: true,
+			errorMsg:    "recording max total size cannot be negative",
+		},
+		{
+			name: "RFC3339 log time format",
+			modifyFunc: func(c *Config) {
+				c.Logging.TimeFormat = "2006-01-02T15:04:05.000Z07:00"
+				c.Logging.UTC = true
+			},
+			shouldError: false,
+		},
+		{
+			name: "Log time format without layout elements",
+			modifyFunc: func(c *Config) {
+				c.Logging.TimeFormat = "iso8601"
+			},
+			shouldError: true,
+			errorMsg:    "logging time format must be a Go time layout such as 2006-01-02T15:04:05Z07:00",
+		},
+		{
+			name: "HLS max cooldown below base",
+			modifyFunc: func(c *Config) {
+				c.HLS.FailedCooldown = time.Minute
+				c.HLS.MaxFailedCooldown = 30 * time.Second
+			},
+			shouldError: true,
+			errorMsg:    "HLS max failed cooldown must be at least the failed cooldown",
+		},
+		{
+			name: "Negative failback interval",
+			modifyFunc: func(c *Config) {
+				c.Relay.FailbackInterval = -time.Minute
+			},
+			shouldError: true,
+			errorMsg:    "failback interval cannot be negative",
+		},
+		{
+			name: "Negative input history max age",
+			modifyFunc: func(c *Config) {
+				c.Relay.InputHistoryMaxAge = -time.Hour
+			},
+			shouldError: true,
+			errorMsg:    "input history max age cannot be negative",
+		},
+		{
+			name: "Invalid HLS fps mode",
+			modifyFunc: func(c *Config) {
+				c.HLS.Sync.FPSMode = "drop"
+			},
+			shouldError: true,
+			errorMsg:    "HLS fps mode must be one of cfr, vfr, passthrough, auto",
+		},
+		{
+			name: "Invalid recording stop signal",
+			modifyFunc: func(c *Config) {
+				c.Recording.StopSignal = "SIGHUP"
+			},
+			shouldError: true,
+			errorMsg:    "recording stop signal must be SIGINT or SIGTERM",
+		},
+		{
+			name: "Zero recording finalize timeout",
+			modifyFunc: func(c *Config) {
+				c.Recording.FinalizeTimeout = 0
+			},
+			shouldError: true,
+			errorMsg:    "recording finalize timeout must be positive",
+		},
+		{
+			name: "Negative max concurrent recordings",
+			modifyFunc: func(c *Config) {
+				c.Recording.MaxConcurrent = -1
+			},
+			shouldError: true,
+			errorMsg:    "recording max concurrent cannot be negative",
+		},
+		{
+			name: "Highlight window too short",
+			modifyFunc: func(c *Config) {
+				c.Recording.HighlightWindow = time.Second
+			},
+			shouldError: true,
+			errorMsg:    "recording highlight window must be between 2s and 30m",
+		},
+		{
+			name: "Recording date layout leaving the directory",
+			modifyFunc: func(c *Config) {
+				c.Recording.DateLayout = "../%Y/%m"
+			},
+			shouldError: true,
+			errorMsg:    "recording date layout must be a relative path of %Y, %m, %d and %H fields",
+		},
+		{
+			name: "Recording date layout with unknown field",
+			modifyFunc: func(c *Config) {
+				c.Recording.DateLayout = "%Y/%j"
+			},
+			shouldError: true,
+			errorMsg:    "recording date layout must be a relative path of %Y, %m, %d and %H fields",
+		},
+		{
+			name: "Valid recording date layout",
+			modifyFunc: func(c *Config) {
+				c.Recording.DateLayout = "archive/%Y/%m/%d"
+			},
+			shouldError: false,
+		},
+		{
+			name: "Invalid recording timestamp position",
+			modifyFunc: func(c *Config) {
+				c.Recording.TimestampPosition = "center"
+			},
+			shouldError: true,
+			errorMsg:    "recording timestamp position must be top-left, top-right, bottom-left or bottom-right",
+		},
+		{
+			name: "Recording timestamp font size too small",
+			modifyFunc: func(c *Config) {
+				c.Recording.TimestampFontSize = 4
+			},
+			shouldError: true,
+			errorMsg:    "recording timestamp font size must be between 8 and 200",
+		},
+		{
+			name: "Negative HLS max height",
+			modifyFunc: func(c *Config) {
+				c.HLS.MaxHeight = -1
+			},
+			shouldError: true,
+			errorMsg:    "HLS max height cannot be negative",
+		},
+		{
+			name: "Negative HLS max viewers",
+			modifyFunc: func(c *Config) {
+				c.HLS.MaxViewers = -1
+			},
+			shouldError: true,
+			errorMsg:    "HLS max viewers cannot be negative",
+		},
+		{
+			name: "Invalid slow output policy",
+			modifyFunc: func(c *Config) {
+				c.Relay.SlowOutput.Policy = "ignore"
+			},
+			shouldError: true,
+			errorMsg:    "slow output policy must be one of log, degrade, drop",
+		},
+		{
+			name: "Zero slow output grace",
+			modifyFunc: func(c *Config) {
+				c.Relay.SlowOutput.Grace = 0
+			},
+			shouldError: true,
+			errorMsg:    "slow output grace must be positive",
+		},
 		{
 			name: "Empty recording directory",
 			modifyFunc: func(c *Config) {
@@ -161,6 +561,19 @@ func TestConfigValidation(t *testing.T) {
 	}
 }
 
+func TestConfigWarnings(t *testing.T) {
+	config := DefaultConfig()
+	if warnings := config.Warnings(); len(warnings) != 0 {
+		t.Errorf("expected no warnings for defaults, got %v", warnings)
+	}
+
+	config.HTTP.WriteTimeout = 0
+	warnings := config.Warnings()
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "write_timeout") {
+		t.Errorf("expected a write_timeout warning, got %v", warnings)
+	}
+}
+
 func TestGetRTSPServerURL(t *testing.T) {
 	config := DefaultConfig()
 	config.Relay.RTSPServer.Host = "192.168.1.100"
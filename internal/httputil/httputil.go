@@ -4,6 +4,10 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"strings"
+	"time"
+
+	"go-mls/internal/logger"
 )
 
 // MaxRequestSize is the maximum allowed request body size (1MB)
@@ -21,6 +25,64 @@ func WriteError(w http.ResponseWriter, status int, msg string) {
 	WriteJSON(w, status, map[string]string{"error": msg})
 }
 
+// WriteErrorCode writes a JSON error response with a machine-readable code
+func WriteErrorCode(w http.ResponseWriter, status int, code, msg string) {
+	WriteJSON(w, status, map[string]string{"error": msg, "code": code})
+}
+
+// AllowMethods reports whether r uses one of methods. Otherwise it answers
+// 405 Method Not Allowed with an Allow header and the caller should return.
+// GET also admits HEAD.
+func AllowMethods(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, m := range methods {
+		if r.Method == m || (m == http.MethodGet && r.Method == http.MethodHead) {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	WriteError(w, http.StatusMethodNotAllowed, "method not allowed, use "+strings.Join(methods, " or "))
+	return false
+}
+
+// DisableWriteTimeout lifts the server's WriteTimeout for a long-lived
+// response such as an event stream or a large download
+func DisableWriteTimeout(w http.ResponseWriter) {
+	// Writers without deadline support (e.g. in tests) keep the server timeout
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+}
+
+// CORS wraps h so that browsers on one of origins may call it: matching
+// requests get CORS headers and OPTIONS preflights are answered with 204
+// without reaching h. "*" allows any origin. With no origins h is returned
+// unchanged.
+func CORS(origins []string, h http.HandlerFunc) http.HandlerFunc {
+	if len(origins) == 0 {
+		return h
+	}
+	allowed := make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		allowed[origin] = true
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin != "" && (allowed["*"] || allowed[origin]) {
+			if allowed["*"] {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Add("Vary", "Origin")
+			}
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		}
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		h(w, r)
+	}
+}
+
 // DecodeJSON decodes JSON from request body into v with size limit protection
 func DecodeJSON(r *http.Request, v interface{}) error {
 	// Limit request body size to prevent DoS attacks
@@ -31,3 +93,53 @@ func DecodeJSON(r *http.Request, v interface{}) error {
 	decoder.DisallowUnknownFields() // Reject unknown fields for security
 	return decoder.Decode(v)
 }
+
+// accessLogWriter records the status and size of a response for AccessLog
+type accessLogWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *accessLogWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *accessLogWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Flush keeps SSE and other streaming responses working through AccessLog
+func (w *accessLogWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *accessLogWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// AccessLog wraps h and logs one line per request with its method, path,
+// status, duration, response size and remote address
+func AccessLog(l *logger.Logger, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		lw := &accessLogWriter{ResponseWriter: w}
+		h.ServeHTTP(lw, r)
+		status := lw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		l.Info("%s %s %d %v %dB %s", r.Method, r.URL.Path, status, time.Since(start).Round(time.Microsecond), lw.bytes, r.RemoteAddr)
+	})
+}
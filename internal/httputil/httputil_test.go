@@ -1,12 +1,18 @@
 package httputil
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
+	"fmt"
+	"go-mls/internal/logger"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestWriteJSON(t *testing.T) {
@@ -54,6 +60,25 @@ func TestWriteError(t *testing.T) {
 	}
 }
 
+func TestWriteErrorCode(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	WriteErrorCode(w, http.StatusServiceUnavailable, "ffmpeg_not_found", "ffmpeg not found")
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+
+	var result map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Errorf("failed to unmarshal response: %v", err)
+	}
+
+	if result["code"] != "ffmpeg_not_found" || result["error"] != "ffmpeg not found" {
+		t.Errorf("unexpected error response: %v", result)
+	}
+}
+
 func TestDecodeJSON_Success(t *testing.T) {
 	type TestStruct struct {
 		Name  string `json:"name"`
@@ -156,3 +181,180 @@ func TestDecodeJSON_EmptyBody(t *testing.T) {
 		t.Error("expected error for empty body, got nil")
 	}
 }
+
+func TestCORS(t *testing.T) {
+	called := false
+	h := func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusOK)
+	}
+
+	// Preflight from an allowed origin is answered without reaching the handler
+	req := httptest.NewRequest(http.MethodOptions, "/api/relay/start", nil)
+	req.Header.Set("Origin", "https://ui.example.com")
+	w := httptest.NewRecorder()
+	CORS([]string{"https://ui.example.com"}, h)(w, req)
+	if w.Code != http.StatusNoContent || called {
+		t.Errorf("expected 204 without calling the handler, got %d (called %v)", w.Code, called)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://ui.example.com" {
+		t.Errorf("expected the origin to be allowed, got %q", got)
+	}
+	for _, method := range []string{"POST", "DELETE"} {
+		if got := w.Header().Get("Access-Control-Allow-Methods"); !strings.Contains(got, method) {
+			t.Errorf("expected %s in allowed methods, got %q", method, got)
+		}
+	}
+
+	// Other origins get no CORS headers
+	req = httptest.NewRequest(http.MethodPost, "/api/relay/start", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	w = httptest.NewRecorder()
+	CORS([]string{"https://ui.example.com"}, h)(w, req)
+	if !called || w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("expected the request to pass through without CORS headers, got %v", w.Header())
+	}
+
+	// Without configured origins the handler is used as is
+	called = false
+	req = httptest.NewRequest(http.MethodOptions, "/api/relay/start", nil)
+	w = httptest.NewRecorder()
+	CORS(nil, h)(w, req)
+	if !called || w.Code != http.StatusOK {
+		t.Errorf("expected the unwrapped handler to answer, got %d (called %v)", w.Code, called)
+	}
+}
+
+func TestAllowMethods(t *testing.T) {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/relay/start", nil)
+	if AllowMethods(w, req, http.MethodPost) {
+		t.Fatal("expected GET to be rejected for a POST endpoint")
+	}
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status %d, got %d", http.StatusMethodNotAllowed, w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "POST" {
+		t.Errorf("expected Allow header POST, got %q", allow)
+	}
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodDelete, "/api/relay/delete-input", nil)
+	if !AllowMethods(w, req, http.MethodPost, http.MethodDelete) {
+		t.Error("expected DELETE to be allowed")
+	}
+
+	req = httptest.NewRequest(http.MethodHead, "/api/relay/status", nil)
+	if !AllowMethods(w, req, http.MethodGet) {
+		t.Error("expected HEAD to be allowed where GET is")
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	h := AccessLog(logger.NewLoggerWithWriter(&buf), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(http.Flusher); !ok {
+			t.Error("expected the wrapped writer to support flushing")
+		}
+		WriteError(w, http.StatusNotFound, "not here")
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/api/recording/list", nil)
+	req.RemoteAddr = "192.0.2.1:4321"
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	line := buf.String()
+	for _, want := range []string{"GET /api/recording/list 404", fmt.Sprintf("%dB", w.Body.Len()), "192.0.2.1:4321"} {
+		if !strings.Contains(line, want) {
+			t.Errorf("expected access log to contain %q, got %q", want, line)
+		}
+	}
+}
+
+func TestDisableWriteTimeout(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		DisableWriteTimeout(w)
+		time.Sleep(300 * time.Millisecond)
+		w.Write([]byte("late"))
+	}))
+	srv.Config.WriteTimeout = 100 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("expected the response to outlive the write timeout, got %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "late" {
+		t.Errorf("expected body %q, got %q", "late", body)
+	}
+}
+
+func TestUpgradeWebSocket(t *testing.T) {
+	closed := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := UpgradeWebSocket(w, r)
+		if err != nil {
+			return
+		}
+		ws.WriteText("hello")
+		<-ws.Done()
+		close(closed)
+	}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Origin", "http://elsewhere.example")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected status %d for a cross-origin handshake, got %d", http.StatusForbidden, resp.StatusCode)
+	}
+
+	conn, err := net.Dial("tcp", host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	fmt.Fprintf(conn, "GET / HTTP/1.1\r\nHost: %s\r\nOrigin: http://%s\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n"+
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n", host, host)
+	reader := bufio.NewReader(conn)
+	resp, err = http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("expected status %d, got %d", http.StatusSwitchingProtocols, resp.StatusCode)
+	}
+	// The accept value for this key from RFC 6455, section 1.3
+	if accept := resp.Header.Get("Sec-WebSocket-Accept"); accept != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("unexpected Sec-WebSocket-Accept %q", accept)
+	}
+
+	frame := make([]byte, 7)
+	if _, err := io.ReadFull(reader, frame); err != nil {
+		t.Fatal(err)
+	}
+	if frame[0] != 0x81 || frame[1] != 5 || string(frame[2:]) != "hello" {
+		t.Errorf("unexpected text frame %x", frame)
+	}
+
+	// A masked close frame without payload ends the connection
+	conn.Write([]byte{0x88, 0x80, 1, 2, 3, 4})
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected Done to be closed after the client closed")
+	}
+}
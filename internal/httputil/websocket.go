@@ -0,0 +1,228 @@
+package httputil
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A minimal server side of RFC 6455 for pushing text messages to a browser,
+// such as a live ffmpeg log. Messages from the client are read only to answer
+// pings and notice when it goes away; their payloads are discarded.
+
+// websocketGUID is appended to the client key to compute Sec-WebSocket-Accept
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Frame opcodes
+const (
+	wsOpText  = 0x1
+	wsOpClose = 0x8
+	wsOpPing  = 0x9
+	wsOpPong  = 0xA
+)
+
+// wsMaxControlPayload is the largest payload a control frame may have
+const wsMaxControlPayload = 125
+
+// wsWriteTimeout bounds each frame write, so a stalled client cannot block its writer
+const wsWriteTimeout = 10 * time.Second
+
+// WebSocket is an upgraded connection. WriteText and Close are safe for
+// concurrent use.
+type WebSocket struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	writeMu sync.Mutex    // serializes frames on conn
+	done    chan struct{} // closed once the client is gone or Close was called
+	once    sync.Once
+}
+
+// UpgradeWebSocket answers a WebSocket handshake and takes over the
+// connection. On failure it has written an error response. Browsers do not
+// apply CORS to WebSockets, so handshakes from another origin are refused.
+func UpgradeWebSocket(w http.ResponseWriter, r *http.Request) (*WebSocket, error) {
+	if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") {
+		WriteError(w, http.StatusBadRequest, "WebSocket upgrade required")
+		return nil, errors.New("not a websocket handshake")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		WriteError(w, http.StatusUpgradeRequired, "unsupported WebSocket version")
+		return nil, errors.New("unsupported websocket version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		WriteError(w, http.StatusBadRequest, "invalid Sec-WebSocket-Key")
+		return nil, errors.New("invalid websocket key")
+	}
+	if origin := r.Header.Get("Origin"); origin != "" {
+		if u, err := url.Parse(origin); err != nil || !strings.EqualFold(u.Host, r.Host) {
+			WriteError(w, http.StatusForbidden, "cross-origin WebSocket refused")
+			return nil, errors.New("cross-origin websocket")
+		}
+	}
+
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "WebSocket unsupported")
+		return nil, err
+	}
+	// Lift the server's read and write timeouts for the long-lived connection
+	_ = conn.SetDeadline(time.Time{})
+
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n"
+	if _, err := conn.Write([]byte(response)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	ws := &WebSocket{conn: conn, reader: rw.Reader, done: make(chan struct{})}
+	go ws.readLoop()
+	return ws, nil
+}
+
+// headerContains reports whether a comma-separated header has token
+func headerContains(h http.Header, name, token string) bool {
+	for _, value := range h.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Done returns a channel closed once the client has gone away or Close was called
+func (ws *WebSocket) Done() <-chan struct{} {
+	return ws.done
+}
+
+// WriteText sends msg as a text message
+func (ws *WebSocket) WriteText(msg string) error {
+	return ws.writeFrame(wsOpText, []byte(msg))
+}
+
+// Close sends a normal closure and closes the connection
+func (ws *WebSocket) Close() error {
+	_ = ws.writeFrame(wsOpClose, []byte{0x03, 0xE8}) // 1000, normal closure
+	ws.shutdown()
+	return nil
+}
+
+func (ws *WebSocket) shutdown() {
+	ws.once.Do(func() {
+		close(ws.done)
+		ws.conn.Close()
+	})
+}
+
+// writeFrame writes one unfragmented, unmasked frame as servers must
+func (ws *WebSocket) writeFrame(opcode byte, payload []byte) error {
+	select {
+	case <-ws.done:
+		return net.ErrClosed
+	default:
+	}
+	header := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n <= wsMaxControlPayload:
+		header = append(header, byte(n))
+	case n <= 0xFFFF:
+		header = append(header, 126)
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+
+	ws.writeMu.Lock()
+	defer ws.writeMu.Unlock()
+	_ = ws.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	if _, err := ws.conn.Write(append(header, payload...)); err != nil {
+		ws.shutdown()
+		return err
+	}
+	return nil
+}
+
+// readLoop reads client frames until the client closes or the connection
+// fails, answering pings
+func (ws *WebSocket) readLoop() {
+	defer ws.shutdown()
+	for {
+		opcode, payload, err := ws.readFrame()
+		if err != nil {
+			return
+		}
+		switch opcode {
+		case wsOpClose:
+			_ = ws.writeFrame(wsOpClose, payload)
+			return
+		case wsOpPing:
+			if ws.writeFrame(wsOpPong, payload) != nil {
+				return
+			}
+		}
+	}
+}
+
+// readFrame reads one client frame, which must be masked. Data payloads are
+// discarded; control payloads are returned.
+func (ws *WebSocket) readFrame() (byte, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(ws.reader, head[:]); err != nil {
+		return 0, nil, err
+	}
+	opcode := head[0] & 0x0F
+	if head[1]&0x80 == 0 {
+		return 0, nil, errors.New("unmasked client frame")
+	}
+	length := uint64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(ws.reader, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(ws.reader, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(ws.reader, mask[:]); err != nil {
+		return 0, nil, err
+	}
+	if opcode < wsOpClose {
+		// Data frame, not needed
+		_, err := io.CopyN(io.Discard, ws.reader, int64(length))
+		return opcode, nil, err
+	}
+	if length > wsMaxControlPayload {
+		return 0, nil, errors.New("control frame too large")
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(ws.reader, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return opcode, payload, nil
+}
@@ -0,0 +1,130 @@
+// Package lifecycle shuts application components down in dependency order
+package lifecycle
+
+import (
+	"fmt"
+	"go-mls/internal/logger"
+	"sync"
+)
+
+// Manager stops registered components so that every component stops before
+// the components it depends on, e.g. recordings and HLS previews before the
+// input relays they consume
+type Manager struct {
+	mu         sync.Mutex
+	logger     *logger.Logger
+	components []*component // in registration order
+	byName     map[string]*component
+}
+
+type component struct {
+	name      string
+	stop      func()
+	dependsOn []string
+}
+
+// NewManager creates an empty Manager
+func NewManager(l *logger.Logger) *Manager {
+	return &Manager{logger: l, byName: make(map[string]*component)}
+}
+
+// Register adds a component that is stopped by calling stop. dependsOn names
+// components that must still be running while it stops; they may be
+// registered later, Validate checks that they all exist.
+func (m *Manager) Register(name string, stop func(), dependsOn ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.byName[name]; exists {
+		return fmt.Errorf("component %q already registered", name)
+	}
+	c := &component{name: name, stop: stop, dependsOn: dependsOn}
+	m.components = append(m.components, c)
+	m.byName[name] = c
+	return nil
+}
+
+// Validate reports unknown dependencies and dependency cycles
+func (m *Manager) Validate() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, err := m.orderLocked()
+	return err
+}
+
+// ShutdownOrder returns the component names in the order Shutdown stops them
+func (m *Manager) ShutdownOrder() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	order, err := m.orderLocked()
+	names := make([]string, len(order))
+	for i, c := range order {
+		names[i] = c.name
+	}
+	return names, err
+}
+
+// orderLocked sorts components so dependents come before their dependencies.
+// Among components free to stop, the latest registered goes first, so
+// independent components stop in reverse start order. On an unknown
+// dependency or a cycle it still returns every component, falling back to
+// reverse registration order for those it could not place.
+func (m *Manager) orderLocked() ([]*component, error) {
+	var err error
+	dependents := make(map[string]int) // unstopped components depending on each name
+	for _, c := range m.components {
+		for _, dep := range c.dependsOn {
+			if _, ok := m.byName[dep]; !ok {
+				if err == nil {
+					err = fmt.Errorf("component %q depends on unknown component %q", c.name, dep)
+				}
+				continue
+			}
+			dependents[dep]++
+		}
+	}
+
+	order := make([]*component, 0, len(m.components))
+	placed := make(map[string]bool)
+	for len(order) < len(m.components) {
+		var next *component
+		for i := len(m.components) - 1; i >= 0; i-- {
+			c := m.components[i]
+			if !placed[c.name] && dependents[c.name] == 0 {
+				next = c
+				break
+			}
+		}
+		if next == nil {
+			if err == nil {
+				err = fmt.Errorf("dependency cycle among components")
+			}
+			for i := len(m.components) - 1; i >= 0; i-- {
+				if c := m.components[i]; !placed[c.name] {
+					order = append(order, c)
+					placed[c.name] = true
+				}
+			}
+			break
+		}
+		order = append(order, next)
+		placed[next.name] = true
+		for _, dep := range next.dependsOn {
+			dependents[dep]--
+		}
+	}
+	return order, err
+}
+
+// Shutdown stops every component in dependency order
+func (m *Manager) Shutdown() {
+	m.mu.Lock()
+	order, err := m.orderLocked()
+	m.mu.Unlock()
+	if err != nil {
+		m.logger.Error("Lifecycle: %v, stopping the remaining components in reverse start order", err)
+	}
+	for _, c := range order {
+		m.logger.Info("Lifecycle: Stopping %s...", c.name)
+		c.stop()
+	}
+}
@@ -0,0 +1,54 @@
+package lifecycle
+
+import (
+	"go-mls/internal/logger"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestManager_ShutdownOrder(t *testing.T) {
+	m := NewManager(logger.NewLoggerWithWriter(io.Discard))
+	var stopped []string
+	register := func(name string, deps ...string) {
+		if err := m.Register(name, func() { stopped = append(stopped, name) }, deps...); err != nil {
+			t.Fatalf("Register(%s): %v", name, err)
+		}
+	}
+	// Registered out of dependency order on purpose
+	register("http", "hls", "recordings", "relays")
+	register("rtsp")
+	register("hls", "relays")
+	register("relays", "rtsp")
+	register("recordings", "relays")
+
+	if err := m.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	m.Shutdown()
+	want := []string{"http", "recordings", "hls", "relays", "rtsp"}
+	if !reflect.DeepEqual(stopped, want) {
+		t.Errorf("shutdown order = %v, want %v", stopped, want)
+	}
+}
+
+func TestManager_InvalidDependencies(t *testing.T) {
+	m := NewManager(logger.NewLoggerWithWriter(io.Discard))
+	m.Register("a", func() {}, "b")
+	m.Register("b", func() {}, "a")
+	m.Register("c", func() {}, "missing")
+	if err := m.Register("a", func() {}); err == nil {
+		t.Error("duplicate registration accepted")
+	}
+
+	err := m.Validate()
+	if err == nil || !strings.Contains(err.Error(), `unknown component "missing"`) {
+		t.Errorf("Validate error = %v, want unknown dependency", err)
+	}
+	// Everything is still stopped, even with a cycle
+	order, err := m.ShutdownOrder()
+	if err == nil || len(order) != 3 {
+		t.Errorf("ShutdownOrder = %v, %v; want all 3 components and an error", order, err)
+	}
+}
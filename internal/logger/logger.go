@@ -1,10 +1,12 @@
 package logger
 
 import (
+	"fmt"
 	"io"
-	"log"
 	"os"
+	"strings"
 	"sync"
+	"time"
 )
 
 type LogLevel int
@@ -17,67 +19,104 @@ const (
 	FATAL
 )
 
+// DefaultTimeFormat matches the standard library's log.LstdFlags
+const DefaultTimeFormat = "2006/01/02 15:04:05"
+
 type Logger struct {
 	level  LogLevel
-	mu     sync.Mutex
-	logger *log.Logger
+	out    *output // shared with loggers derived by WithPrefix
+	prefix string  // prepended to every message, set by WithPrefix
+}
+
+// output is the destination and timestamp style shared by a logger and its
+// prefixed children; mu serializes whole lines onto w
+type output struct {
+	mu         sync.Mutex
+	w          io.Writer
+	timeFormat string
+	utc        bool
+	buf        []byte
 }
 
 func NewLogger() *Logger {
+	return NewLoggerWithWriter(os.Stderr)
+}
+
+func NewLoggerWithWriter(w io.Writer) *Logger {
 	lvl := INFO
 	if os.Getenv("GO_MLS_DEBUG") == "1" {
 		lvl = DEBUG
 	}
 	return &Logger{
-		level:  lvl,
-		logger: log.New(os.Stderr, "", log.LstdFlags),
+		level: lvl,
+		out:   &output{w: w, timeFormat: DefaultTimeFormat},
 	}
 }
 
-func NewLoggerWithWriter(w io.Writer) *Logger {
-	lvl := INFO
-	if os.Getenv("GO_MLS_DEBUG") == "1" {
-		lvl = DEBUG
+// SetTimeFormat sets the time.Format layout of line timestamps (empty for
+// DefaultTimeFormat) and whether they are in UTC instead of local time. It
+// also applies to loggers derived from l with WithPrefix.
+func (l *Logger) SetTimeFormat(layout string, utc bool) {
+	if layout == "" {
+		layout = DefaultTimeFormat
 	}
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	l.out.timeFormat = layout
+	l.out.utc = utc
+}
+
+// WithPrefix returns a logger writing to the same output at the same level that
+// tags every message with "[prefix] ", e.g. a relay's correlation ID
+func (l *Logger) WithPrefix(prefix string) *Logger {
 	return &Logger{
-		level:  lvl,
-		logger: log.New(w, "", log.LstdFlags),
+		level:  l.level,
+		out:    l.out,
+		prefix: l.prefix + "[" + strings.ReplaceAll(prefix, "%", "%%") + "] ",
+	}
+}
+
+// write formats one line as "<time> [LEVEL] <prefix><msg>\n"
+func (l *Logger) write(level, msg string, args ...interface{}) {
+	now := time.Now()
+	o := l.out
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.utc {
+		now = now.UTC()
+	}
+	o.buf = now.AppendFormat(o.buf[:0], o.timeFormat)
+	o.buf = append(o.buf, " ["+level+"] "...)
+	o.buf = fmt.Appendf(o.buf, l.prefix+msg, args...)
+	if len(o.buf) == 0 || o.buf[len(o.buf)-1] != '\n' {
+		o.buf = append(o.buf, '\n')
 	}
+	o.w.Write(o.buf)
 }
 
 func (l *Logger) Debug(msg string, args ...interface{}) {
 	if l.level <= DEBUG {
-		l.mu.Lock()
-		defer l.mu.Unlock()
-		l.logger.Printf("[DEBUG] "+msg, args...)
+		l.write("DEBUG", msg, args...)
 	}
 }
 func (l *Logger) Info(msg string, args ...interface{}) {
 	if l.level <= INFO {
-		l.mu.Lock()
-		defer l.mu.Unlock()
-		l.logger.Printf("[INFO] "+msg, args...)
+		l.write("INFO", msg, args...)
 	}
 }
 func (l *Logger) Warn(msg string, args ...interface{}) {
 	if l.level <= WARN {
-		l.mu.Lock()
-		defer l.mu.Unlock()
-		l.logger.Printf("[WARN] "+msg, args...)
+		l.write("WARN", msg, args...)
 	}
 }
 func (l *Logger) Error(msg string, args ...interface{}) {
 	if l.level <= ERROR {
-		l.mu.Lock()
-		defer l.mu.Unlock()
-		l.logger.Printf("[ERROR] "+msg, args...)
+		l.write("ERROR", msg, args...)
 	}
 }
 func (l *Logger) Fatal(msg string, args ...interface{}) {
 	if l.level <= FATAL {
-		l.mu.Lock()
-		defer l.mu.Unlock()
-		l.logger.Printf("[FATAL] "+msg, args...)
+		l.write("FATAL", msg, args...)
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,34 @@
+package logger
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogger_TimeFormat(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLoggerWithWriter(&buf)
+	l.Info("default %d", 1)
+	if !regexp.MustCompile(`^\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2} \[INFO\] default 1\n$`).MatchString(buf.String()) {
+		t.Errorf("default line = %q", buf.String())
+	}
+
+	buf.Reset()
+	l.SetTimeFormat("2006-01-02T15:04:05.000Z07:00", true)
+	// Derived loggers share the root's output settings
+	l.WithPrefix("rec:1").Warn("utc")
+	line := buf.String()
+	if !strings.HasSuffix(line, " [WARN] [rec:1] utc\n") {
+		t.Fatalf("prefixed line = %q", line)
+	}
+	ts, err := time.Parse("2006-01-02T15:04:05.000Z07:00", line[:strings.IndexByte(line, ' ')])
+	if err != nil {
+		t.Fatalf("timestamp not in configured format: %v", err)
+	}
+	if _, offset := ts.Zone(); offset != 0 || !strings.Contains(line, "Z ") {
+		t.Errorf("timestamp %q is not UTC", ts)
+	}
+}
@@ -3,8 +3,10 @@ package process
 import (
 	"fmt"
 	"os"
+	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 // ProcUsage holds CPU and memory usage info
@@ -18,6 +20,17 @@ type ProcUsage struct {
 	Cmdline string  `json:"cmdline,omitempty"`
 }
 
+// Available reports whether process metrics can be read on this system. They
+// come from /proc, so they are missing on non-Linux systems and in sandboxes
+// without /proc; usage lookups then always fail. The check runs once.
+var Available = sync.OnceValue(func() bool {
+	if runtime.GOOS != "linux" {
+		return false
+	}
+	_, err := os.ReadFile("/proc/self/stat")
+	return err == nil
+})
+
 // GetSelfUsage returns usage for the current process
 func GetSelfUsage() (*ProcUsage, error) {
 	pid := os.Getpid()
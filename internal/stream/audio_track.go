@@ -0,0 +1,64 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// An audio track selection picks among the audio tracks of a multi-track
+// source (e.g. several languages). By default ffmpeg keeps one video and one
+// audio track, so only the source's default track reaches the local RTSP
+// server. The input relay can select another track by index or language, or
+// publish all of them with "all"; outputs, HLS previews and recordings then
+// pick one of the published tracks by index. Languages can only be selected on
+// the input: the tags do not survive the RTSP hop.
+const allAudioTracks = "all"
+
+// ErrInvalidAudioTrack is returned for an audio track selection that is not
+// an index, or for inputs a language code or "all"
+var ErrInvalidAudioTrack = errors.New("invalid audio track")
+
+// maxAudioTrackIndex bounds track indexes to something a real source can carry
+const maxAudioTrackIndex = 63
+
+// audioTrackLanguage matches ISO 639 language codes as found in stream tags
+var audioTrackLanguage = regexp.MustCompile(`^[a-z]{2,3}$`)
+
+// validateAudioTrack checks an audio track selection: empty, an index among
+// the audio tracks, and for inputs also "all" or a language code
+func validateAudioTrack(track string, input bool) error {
+	if track == "" {
+		return nil
+	}
+	if n, err := strconv.Atoi(track); err == nil {
+		if n < 0 || n > maxAudioTrackIndex {
+			return fmt.Errorf("%w %q: index must be from 0 to %d", ErrInvalidAudioTrack, track, maxAudioTrackIndex)
+		}
+		return nil
+	}
+	if !input {
+		return fmt.Errorf("%w %q: must be an index among the input's audio tracks, select languages on the input", ErrInvalidAudioTrack, track)
+	}
+	if track != allAudioTracks && !audioTrackLanguage.MatchString(track) {
+		return fmt.Errorf("%w %q: must be an index, a language code such as eng, or all", ErrInvalidAudioTrack, track)
+	}
+	return nil
+}
+
+// audioTrackMapArgs returns the -map directives keeping the video of the
+// first input and the selected audio track; none for an empty selection. The
+// video is optional so audio-only sources keep working.
+func audioTrackMapArgs(track string) []string {
+	switch {
+	case track == "":
+		return nil
+	case track == allAudioTracks:
+		return []string{"-map", "0:v?", "-map", "0:a?"}
+	case audioTrackLanguage.MatchString(track):
+		return []string{"-map", "0:v?", "-map", "0:a:m:language:" + track}
+	default:
+		return []string{"-map", "0:v?", "-map", "0:a:" + track}
+	}
+}
@@ -0,0 +1,76 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+)
+
+// BufferingMode trades latency for stability when ffmpeg reads an input. Low
+// latency passes packets on as they arrive, which stutters on jittery remote
+// sources; stable buffers and probes longer to ride out network jitter, at the
+// cost of a few seconds of delay. It applies to the input relay pulling the
+// source and to the input's HLS preview.
+type BufferingMode string
+
+const (
+	BufferingLowLatency BufferingMode = "low_latency" // Default: no buffering beyond ffmpeg's own
+	BufferingStable     BufferingMode = "stable"      // Buffer to smooth out network jitter
+)
+
+// ErrInvalidBuffering is returned for an unknown buffering mode
+var ErrInvalidBuffering = errors.New("invalid buffering mode")
+
+// Buffers of the stable mode, modest so a stalled source cannot pile up memory
+const (
+	stableRTBufSize       = "16M"
+	stableThreadQueueSize = "1024"
+	stableAnalyzeDuration = "2000000" // 2s in microseconds, over a few keyframe intervals
+)
+
+// validateBuffering checks a buffering mode; empty leaves the default
+func validateBuffering(mode BufferingMode) error {
+	switch mode {
+	case "", BufferingLowLatency, BufferingStable:
+		return nil
+	}
+	return fmt.Errorf("%w %q: must be %s or %s", ErrInvalidBuffering, mode, BufferingLowLatency, BufferingStable)
+}
+
+// bufferingArgs returns the ffmpeg input options of mode, placed before -i
+func bufferingArgs(mode BufferingMode) []string {
+	if mode == BufferingStable {
+		return []string{"-thread_queue_size", stableThreadQueueSize, "-rtbufsize", stableRTBufSize}
+	}
+	return nil
+}
+
+// SetBuffering sets the buffering mode of inputs without their own, used by
+// input relays and HLS sessions started afterwards; set before relays start
+func (rm *RelayManager) SetBuffering(mode BufferingMode) error {
+	if err := validateBuffering(mode); err != nil {
+		return err
+	}
+	if mode == "" {
+		mode = BufferingLowLatency
+	}
+	rm.buffering = mode
+	rm.InputRelays.buffering = mode
+	return nil
+}
+
+// SetInputBuffering sets the buffering mode of inputName, used when its input
+// relay or HLS session next starts. Empty restores the default mode.
+func (rm *RelayManager) SetInputBuffering(inputName string, mode BufferingMode) error {
+	if err := validateBuffering(mode); err != nil {
+		return err
+	}
+	rm.configMu.Lock()
+	defer rm.configMu.Unlock()
+
+	config, exists := rm.inputConfigs[inputName]
+	if !exists {
+		return fmt.Errorf("input configuration not found for: %s", inputName)
+	}
+	config.Buffering = mode
+	return nil
+}
@@ -0,0 +1,17 @@
+package stream
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// installFakeFFmpeg puts an ffmpeg running script first in PATH for the rest of the test
+func installFakeFFmpeg(t *testing.T, script string) {
+	t.Helper()
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "ffmpeg"), []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake ffmpeg: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
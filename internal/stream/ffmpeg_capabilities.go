@@ -0,0 +1,179 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ffmpegQueryTimeout bounds a single `ffmpeg -encoders`/`-formats` invocation
+const ffmpegQueryTimeout = 10 * time.Second
+
+// ErrFFmpegNotFound is returned when the ffmpeg binary cannot be found
+var ErrFFmpegNotFound = errors.New("ffmpeg not found; install it or add it to PATH")
+
+// FFmpegEncoder describes an encoder reported by `ffmpeg -encoders`
+type FFmpegEncoder struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// FFmpegEncoders groups the installed encoders by media type
+type FFmpegEncoders struct {
+	Video []FFmpegEncoder `json:"video"`
+	Audio []FFmpegEncoder `json:"audio"`
+}
+
+// FFmpegFormat describes a container format reported by `ffmpeg -formats`
+type FFmpegFormat struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// FFmpegFormats groups the installed formats by direction
+type FFmpegFormats struct {
+	Muxers   []FFmpegFormat `json:"muxers"`
+	Demuxers []FFmpegFormat `json:"demuxers"`
+}
+
+// FFmpegCapabilities detects and caches what the installed ffmpeg supports.
+// Results are only cached once a query succeeds, so installing ffmpeg while
+// go-mls is running is picked up on the next request.
+type FFmpegCapabilities struct {
+	ffmpegPath string // immutable
+
+	// --- Cached results, protected by mu ---
+	encoders *FFmpegEncoders
+	formats  *FFmpegFormats
+	mu       sync.Mutex
+}
+
+// NewFFmpegCapabilities creates a capabilities cache for the given ffmpeg binary
+func NewFFmpegCapabilities(ffmpegPath string) *FFmpegCapabilities {
+	return &FFmpegCapabilities{ffmpegPath: ffmpegPath}
+}
+
+// Available reports whether the ffmpeg binary can be found. It is not cached
+// so installing ffmpeg while go-mls is running is picked up.
+func (c *FFmpegCapabilities) Available() error {
+	if _, err := exec.LookPath(c.ffmpegPath); err != nil {
+		return fmt.Errorf("%w: %v", ErrFFmpegNotFound, err)
+	}
+	return nil
+}
+
+// Encoders returns the video and audio encoders built into ffmpeg
+func (c *FFmpegCapabilities) Encoders() (*FFmpegEncoders, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.encoders != nil {
+		return c.encoders, nil
+	}
+	output, err := c.query("-encoders")
+	if err != nil {
+		return nil, err
+	}
+	c.encoders = parseFFmpegEncoders(output)
+	return c.encoders, nil
+}
+
+// Formats returns the muxers and demuxers built into ffmpeg
+func (c *FFmpegCapabilities) Formats() (*FFmpegFormats, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.formats != nil {
+		return c.formats, nil
+	}
+	output, err := c.query("-formats")
+	if err != nil {
+		return nil, err
+	}
+	c.formats = parseFFmpegFormats(output)
+	return c.formats, nil
+}
+
+// HasEncoder reports whether ffmpeg has an encoder with the given name
+func (c *FFmpegCapabilities) HasEncoder(name string) bool {
+	encoders, err := c.Encoders()
+	if err != nil {
+		return false
+	}
+	for _, list := range [][]FFmpegEncoder{encoders.Video, encoders.Audio} {
+		for _, enc := range list {
+			if enc.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *FFmpegCapabilities) query(args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), ffmpegQueryTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, c.ffmpegPath, append([]string{"-hide_banner"}, args...)...).Output()
+	if err != nil {
+		return "", fmt.Errorf("ffmpeg %s failed: %w", strings.Join(args, " "), err)
+	}
+	return string(out), nil
+}
+
+// ffmpegListEntries walks the table printed by `ffmpeg -encoders`/`-formats`:
+// a legend, a dashed separator whose width matches the flags column, then one
+// " <flags> <name> <description>" line per entry.
+func ffmpegListEntries(output string, fn func(flags, name, description string)) {
+	width := 0
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if width == 0 {
+			if trimmed := strings.TrimSpace(line); trimmed != "" && strings.Trim(trimmed, "-") == "" {
+				width = len(trimmed)
+			}
+			continue
+		}
+		if len(line) < width+2 || line[0] != ' ' {
+			continue
+		}
+		fields := strings.SplitN(strings.TrimSpace(line[1+width:]), " ", 2)
+		if fields[0] == "" {
+			continue
+		}
+		description := ""
+		if len(fields) == 2 {
+			description = strings.TrimSpace(fields[1])
+		}
+		fn(line[1:1+width], fields[0], description)
+	}
+}
+
+func parseFFmpegEncoders(output string) *FFmpegEncoders {
+	encoders := &FFmpegEncoders{Video: []FFmpegEncoder{}, Audio: []FFmpegEncoder{}}
+	ffmpegListEntries(output, func(flags, name, description string) {
+		enc := FFmpegEncoder{Name: name, Description: description}
+		switch flags[0] {
+		case 'V':
+			encoders.Video = append(encoders.Video, enc)
+		case 'A':
+			encoders.Audio = append(encoders.Audio, enc)
+		}
+	})
+	return encoders
+}
+
+func parseFFmpegFormats(output string) *FFmpegFormats {
+	formats := &FFmpegFormats{Muxers: []FFmpegFormat{}, Demuxers: []FFmpegFormat{}}
+	ffmpegListEntries(output, func(flags, name, description string) {
+		format := FFmpegFormat{Name: name, Description: description}
+		if strings.Contains(flags, "D") {
+			formats.Demuxers = append(formats.Demuxers, format)
+		}
+		if strings.Contains(flags, "E") {
+			formats.Muxers = append(formats.Muxers, format)
+		}
+	})
+	return formats
+}
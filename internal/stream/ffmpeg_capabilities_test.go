@@ -0,0 +1,352 @@
+package stream
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-mls/internal/logger"
+)
+
+func TestParseFFmpegEncoders(t *testing.T) {
+	t.Parallel()
+	output := `Encoders:
+ V..... = Video
+ A..... = Audio
+ S..... = Subtitle
+ .F.... = Frame-level multithreading
+ ------
+ V....D libx264              libx264 H.264 / AVC / MPEG-4 AVC / MPEG-4 part 10 (codec h264)
+ V....D h264_nvenc           NVIDIA NVENC H.264 encoder (codec h264)
+ A....D aac                  AAC (Advanced Audio Coding)
+ S..... srt                  SubRip subtitle
+`
+	enc := parseFFmpegEncoders(output)
+	if len(enc.Video) != 2 || enc.Video[0].Name != "libx264" || enc.Video[1].Name != "h264_nvenc" {
+		t.Errorf("unexpected video encoders: %+v", enc.Video)
+	}
+	if len(enc.Audio) != 1 || enc.Audio[0].Name != "aac" || enc.Audio[0].Description != "AAC (Advanced Audio Coding)" {
+		t.Errorf("unexpected audio encoders: %+v", enc.Audio)
+	}
+}
+
+func TestParseFFmpegFormats(t *testing.T) {
+	t.Parallel()
+	output := `File formats:
+ D. = Demuxing supported
+ .E = Muxing supported
+ --
+ D  aac             raw ADTS AAC (Advanced Audio Coding)
+  E flv             FLV (Flash Video)
+ DE mpegts          MPEG-TS (MPEG-2 Transport Stream)
+`
+	formats := parseFFmpegFormats(output)
+	if len(formats.Muxers) != 2 || formats.Muxers[0].Name != "flv" || formats.Muxers[1].Name != "mpegts" {
+		t.Errorf("unexpected muxers: %+v", formats.Muxers)
+	}
+	if len(formats.Demuxers) != 2 || formats.Demuxers[0].Name != "aac" {
+		t.Errorf("unexpected demuxers: %+v", formats.Demuxers)
+	}
+}
+
+func TestOutputRelayArgs_Reconnect(t *testing.T) {
+	t.Parallel()
+	rm := NewRelayManager(logger.NewLoggerWithWriter(&bytes.Buffer{}), t.TempDir())
+	opts := &FFmpegOptions{Reconnect: "10"}
+
+	// Reconnect is done by the relay restarting ffmpeg, never by ffmpeg flags
+	for _, outputURL := range []string{"rtmp://live.example.com/app/key", "http://ingest.example.com/live"} {
+		args := strings.Join(rm.buildOutputRelayArgs("rtsp://127.0.0.1:8554/relay/cam", outputURL, opts), " ")
+		if strings.Contains(args, "-reconnect") {
+			t.Errorf("expected no reconnect flags for %s, got %s", outputURL, args)
+		}
+	}
+	for _, value := range []string{"0", "-5", "soon"} {
+		if err := (&FFmpegOptions{Reconnect: value}).Validate(); err == nil {
+			t.Errorf("expected reconnect %q to be rejected", value)
+		}
+	}
+	if err := opts.Validate(); err != nil {
+		t.Errorf("expected reconnect %q to be valid, got %v", opts.Reconnect, err)
+	}
+}
+
+func TestOutputRelayArgs_KeyframeInterval(t *testing.T) {
+	t.Parallel()
+	rm := NewRelayManager(logger.NewLoggerWithWriter(&bytes.Buffer{}), t.TempDir())
+	localURL := "rtsp://127.0.0.1:8554/relay/cam"
+	outputURL := "rtmp://live.twitch.tv/app/key"
+
+	tests := []struct {
+		name     string
+		interval string
+		fps      string
+		want     string // expected GOP flags, empty for none
+		wantErr  bool
+	}{
+		{name: "unset", fps: "30"},
+		{name: "seconds", interval: "2", fps: "30", want: "-g 60 -keyint_min 60"},
+		{name: "fractional seconds", interval: "0.5", fps: "60", want: "-g 30 -keyint_min 30"},
+		{name: "ntsc rational fps", interval: "1001/1000", fps: "30000/1001", want: "-g 30 -keyint_min 30"},
+		{name: "frames", interval: "48f", want: "-g 48 -keyint_min 48"},
+		{name: "fractional gop", interval: "2", fps: "29.97", wantErr: true},
+		{name: "seconds without framerate", interval: "2", wantErr: true},
+		{name: "zero frames", interval: "0f", fps: "30", wantErr: true},
+		{name: "garbage", interval: "2s", fps: "30", wantErr: true},
+	}
+	for _, tt := range tests {
+		opts := &FFmpegOptions{Framerate: tt.fps, KeyframeInterval: tt.interval}
+		err := opts.Validate()
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected validation error", tt.name)
+			}
+			if _, err := rm.StartRelayWithOptions("rtsp://cam.local/stream", outputURL, "cam", "twitch", opts, ""); err == nil {
+				t.Errorf("%s: expected StartRelayWithOptions to reject the options", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected validation error: %v", tt.name, err)
+			continue
+		}
+		args := strings.Join(rm.buildOutputRelayArgs(localURL, outputURL, opts), " ")
+		if tt.want == "" {
+			if strings.Contains(args, "-g ") {
+				t.Errorf("%s: expected no GOP flags, got %s", tt.name, args)
+			}
+		} else if !strings.Contains(args, tt.want) {
+			t.Errorf("%s: expected %q in %s", tt.name, tt.want, args)
+		}
+	}
+
+	twitch := PlatformPresets["Twitch"].Options
+	if err := twitch.Validate(); err != nil {
+		t.Errorf("Twitch preset has invalid options: %v", err)
+	}
+}
+
+func TestOutputRelayArgs_AudioNormalize(t *testing.T) {
+	t.Parallel()
+	rm := NewRelayManager(logger.NewLoggerWithWriter(&bytes.Buffer{}), t.TempDir())
+	localURL := "rtsp://127.0.0.1:8554/relay/cam"
+	outputURL := "rtmp://live.example.com/app/key"
+
+	opts := &FFmpegOptions{
+		AudioCodec:     "aac",
+		Rotation:       "transpose=1",
+		AudioNormalize: "-16",
+		ExtraArgs:      []string{"-af", "highpass=f=80", "-vf", "hflip", "-preset", "veryfast"},
+	}
+	if err := opts.Validate(); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+	args := rm.buildOutputRelayArgs(localURL, outputURL, opts)
+	joined := strings.Join(args, " ")
+	if !strings.Contains(joined, "-vf transpose=1,hflip") {
+		t.Errorf("expected rotation merged with extra video filters, got %s", joined)
+	}
+	if !strings.Contains(joined, "-af highpass=f=80,loudnorm=I=-16:TP=-1.5:LRA=11") {
+		t.Errorf("expected loudnorm appended to extra audio filters, got %s", joined)
+	}
+	if strings.Count(joined, "-vf ") != 1 || strings.Count(joined, "-af ") != 1 {
+		t.Errorf("expected a single filtergraph per stream type, got %s", joined)
+	}
+	if !strings.Contains(joined, "-preset veryfast -f flv") {
+		t.Errorf("expected remaining extra args before the output format, got %s", joined)
+	}
+
+	for _, bad := range []*FFmpegOptions{
+		{AudioNormalize: "loud"},
+		{AudioNormalize: "-80"},
+		{AudioNormalize: "-16", AudioCodec: "copy"},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("expected validation error for %+v", *bad)
+		}
+	}
+}
+
+func TestOutputRelayArgs_Watermark(t *testing.T) {
+	t.Parallel()
+	assetsDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(assetsDir, "logo.png"), []byte("png"), 0644); err != nil {
+		t.Fatalf("failed to write logo: %v", err)
+	}
+	rm := NewRelayManager(logger.NewLoggerWithWriter(&bytes.Buffer{}), t.TempDir())
+	localURL := "rtsp://127.0.0.1:8554/relay/cam"
+	outputURL := "rtmp://live.example.com/app/key"
+
+	opts := &FFmpegOptions{Watermark: "logo.png", Resolution: "720x1280", Rotation: "transpose=1", WatermarkPosition: "top-left"}
+	if err := rm.ValidateFFmpegOptions(opts); err == nil {
+		t.Error("expected watermark to be rejected without an assets directory")
+	}
+	rm.SetAssetsDir(assetsDir)
+	if err := rm.ValidateFFmpegOptions(opts); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+
+	joined := strings.Join(rm.buildOutputRelayArgs(localURL, outputURL, opts), " ")
+	want := "-i " + localURL + " -i " + filepath.Join(assetsDir, "logo.png")
+	if !strings.Contains(joined, want) {
+		t.Errorf("expected logo as second input, got %s", joined)
+	}
+	if !strings.Contains(joined, "-filter_complex [0:v]transpose=1,scale=720:1280[base];[base][1:v]overlay=10:10[vout] -map [vout] -map 0:a?") {
+		t.Errorf("expected rotation, scale and overlay in one filtergraph, got %s", joined)
+	}
+	if strings.Contains(joined, "-vf ") || strings.Contains(joined, "-s ") {
+		t.Errorf("expected no -vf or -s alongside -filter_complex, got %s", joined)
+	}
+
+	for _, bad := range []*FFmpegOptions{
+		{Watermark: "../logo.png"},
+		{Watermark: "sub/logo.png"},
+		{Watermark: "missing.png"},
+		{Watermark: "logo.png", WatermarkPosition: "middle"},
+		{Watermark: "logo.png", VideoCodec: "copy"},
+	} {
+		if err := rm.ValidateFFmpegOptions(bad); err == nil {
+			t.Errorf("expected validation error for %+v", *bad)
+		}
+	}
+}
+
+func TestOutputRelayArgs_AudioFormat(t *testing.T) {
+	t.Parallel()
+	rm := NewRelayManager(logger.NewLoggerWithWriter(&bytes.Buffer{}), t.TempDir())
+	localURL := "rtsp://127.0.0.1:8554/relay/cam"
+	outputURL := "rtmp://live.example.com/app/key"
+
+	joined := strings.Join(rm.buildOutputRelayArgs(localURL, outputURL, &FFmpegOptions{AudioCodec: "aac"}), " ")
+	if strings.Contains(joined, "-ar ") || strings.Contains(joined, "-ac ") {
+		t.Errorf("expected audio format passthrough by default, got %s", joined)
+	}
+	opts := &FFmpegOptions{AudioCodec: "aac", AudioSampleRate: "48000", AudioChannels: "2"}
+	if err := opts.Validate(); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+	joined = strings.Join(rm.buildOutputRelayArgs(localURL, outputURL, opts), " ")
+	if !strings.Contains(joined, "-c:a aac -ar 48000 -ac 2") {
+		t.Errorf("expected sample rate and channels after the audio codec, got %s", joined)
+	}
+
+	for _, bad := range []*FFmpegOptions{
+		{AudioSampleRate: "48kHz"},
+		{AudioChannels: "0"},
+		{AudioChannels: "1", AudioCodec: "copy"},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("expected validation error for %+v", *bad)
+		}
+	}
+}
+
+func TestOutputRelayArgs_Threads(t *testing.T) {
+	t.Parallel()
+	rm := NewRelayManager(logger.NewLoggerWithWriter(&bytes.Buffer{}), t.TempDir())
+	localURL := "rtsp://127.0.0.1:8554/relay/cam"
+	outputURL := "rtmp://live.example.com/app/key"
+
+	if joined := strings.Join(rm.buildOutputRelayArgs(localURL, outputURL, nil), " "); strings.Contains(joined, "-threads") {
+		t.Errorf("expected ffmpeg to pick threads by default, got %s", joined)
+	}
+
+	rm.SetThreads(2)
+	if joined := strings.Join(rm.buildOutputRelayArgs(localURL, outputURL, nil), " "); !strings.Contains(joined, "-threads 2 -f flv") {
+		t.Errorf("expected global -threads before the muxer, got %s", joined)
+	}
+	opts := &FFmpegOptions{VideoCodec: "libx264", Threads: "4"}
+	if err := opts.Validate(); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+	if joined := strings.Join(rm.buildOutputRelayArgs(localURL, outputURL, opts), " "); !strings.Contains(joined, "-threads 4 -f flv") {
+		t.Errorf("expected per-output -threads to override the global one, got %s", joined)
+	}
+
+	for _, bad := range []string{"-1", "two"} {
+		if err := (&FFmpegOptions{Threads: bad}).Validate(); err == nil {
+			t.Errorf("expected validation error for threads %q", bad)
+		}
+	}
+}
+
+func TestOutputRelayArgs_MaxMuxingQueueSize(t *testing.T) {
+	t.Parallel()
+	rm := NewRelayManager(logger.NewLoggerWithWriter(&bytes.Buffer{}), t.TempDir())
+	localURL := "rtsp://127.0.0.1:8554/relay/cam"
+	outputURL := "rtmp://live.example.com/app/key"
+
+	if joined := strings.Join(rm.buildOutputRelayArgs(localURL, outputURL, nil), " "); strings.Contains(joined, "-max_muxing_queue_size") {
+		t.Errorf("expected ffmpeg's default queue size, got %s", joined)
+	}
+
+	rm.SetMaxMuxingQueueSize(1024)
+	if joined := strings.Join(rm.buildOutputRelayArgs(localURL, outputURL, nil), " "); !strings.Contains(joined, "-max_muxing_queue_size 1024 -f flv") {
+		t.Errorf("expected the global queue size before the muxer, got %s", joined)
+	}
+	opts := &FFmpegOptions{MaxMuxingQueueSize: "4096"}
+	if err := opts.Validate(); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+	if joined := strings.Join(rm.buildOutputRelayArgs(localURL, outputURL, opts), " "); !strings.Contains(joined, "-max_muxing_queue_size 4096 -f flv") {
+		t.Errorf("expected the per-output queue size to override the global one, got %s", joined)
+	}
+	if got := FFmpegOptionsFromMap(ffmpegOptionsMap(opts)).MaxMuxingQueueSize; got != "4096" {
+		t.Errorf("expected the queue size to survive an export, got %q", got)
+	}
+
+	for _, bad := range []string{"0", "-1", "lots"} {
+		if err := (&FFmpegOptions{MaxMuxingQueueSize: bad}).Validate(); err == nil {
+			t.Errorf("expected validation error for max muxing queue size %q", bad)
+		}
+	}
+}
+
+func TestRelayManager_FFmpegNotFound(t *testing.T) {
+	t.Parallel()
+	rm := NewRelayManager(logger.NewLoggerWithWriter(&bytes.Buffer{}), t.TempDir())
+	rm.SetFFmpegCapabilities(NewFFmpegCapabilities(filepath.Join(t.TempDir(), "no-such-ffmpeg")))
+
+	_, err := rm.StartRelayWithOptions("rtsp://camera.example.com/stream", "rtmp://live.example.com/app/key", "cam", "live", nil, "")
+	if !errors.Is(err, ErrFFmpegNotFound) {
+		t.Fatalf("expected ErrFFmpegNotFound, got %v", err)
+	}
+	rm.InputRelays.mu.Lock()
+	defer rm.InputRelays.mu.Unlock()
+	if len(rm.InputRelays.Relays) != 0 {
+		t.Errorf("expected no input relay to be created, got %d", len(rm.InputRelays.Relays))
+	}
+}
+
+func TestRelayManager_CheckOutputReachable(t *testing.T) {
+	t.Parallel()
+	rm := NewRelayManager(logger.NewLoggerWithWriter(&bytes.Buffer{}), t.TempDir())
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	addr := ln.Addr().String()
+	if err := rm.CheckOutputReachable("rtmp://" + addr + "/app/key"); err != nil {
+		t.Errorf("expected listening output to be reachable, got %v", err)
+	}
+	ln.Close()
+	if err := rm.CheckOutputReachable("rtmp://" + addr + "/app/key"); !errors.Is(err, ErrOutputUnreachable) {
+		t.Errorf("expected ErrOutputUnreachable for closed port, got %v", err)
+	}
+
+	dir := t.TempDir()
+	if err := rm.CheckOutputReachable("file://" + filepath.Join(dir, "out.flv")); err != nil {
+		t.Errorf("expected writable directory to pass, got %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("expected probe file to be removed, found %d entries", len(entries))
+	}
+	if err := rm.CheckOutputReachable("file://" + filepath.Join(dir, "missing", "out.flv")); !errors.Is(err, ErrOutputUnreachable) {
+		t.Errorf("expected ErrOutputUnreachable for missing directory, got %v", err)
+	}
+}
@@ -5,14 +5,44 @@ import (
 	"bytes"
 	"context"
 	"io"
+	"os"
 	"os/exec"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
+
+	"golang.org/x/sys/unix"
 )
 
+// processNice is the OS scheduling priority new ffmpeg processes start with
+var processNice atomic.Int32
+
+// SetProcessNice sets the nice value (-20 to 19) applied to every ffmpeg
+// process started afterwards; 0 keeps go-mls's own priority
+func SetProcessNice(nice int) {
+	processNice.Store(int32(nice))
+}
+
+// processCPUs is the CPU set new ffmpeg processes are pinned to, nil for all
+var processCPUs atomic.Pointer[unix.CPUSet]
+
+// SetProcessCPUAffinity pins every ffmpeg process started afterwards to the
+// given CPUs; an empty list lets them run on any CPU
+func SetProcessCPUAffinity(cpus []int) {
+	if len(cpus) == 0 {
+		processCPUs.Store(nil)
+		return
+	}
+	var set unix.CPUSet
+	for _, cpu := range cpus {
+		set.Set(cpu)
+	}
+	processCPUs.Store(&set)
+}
+
 // FFmpegStatus represents the state of an ffmpeg process
 const (
 	FFmpegStarting = iota
@@ -35,6 +65,7 @@ type FFmpegProcess struct {
 	Cancel   context.CancelFunc // Context cancel function (never reassigned)
 	Ctx      context.Context    // Context for cancellation (never reassigned)
 	waitCh   chan error         // Channel for Wait() result (never reassigned)
+	done     chan struct{}      // Closed once the process has exited (never reassigned)
 	waitOnce sync.Once          // Ensures only one Wait() call on Cmd
 
 	// --- Set-once at Start(), then read-only ---
@@ -42,15 +73,22 @@ type FFmpegProcess struct {
 	StartTime   time.Time // Set at Start(), then read-only
 	hasProgress bool      // Whether ffmpeg args include -progress for parsing
 
+	// --- May be changed between construction and Start() ---
+	Nice       int            // nice value applied at Start(), 0 keeps the inherited priority
+	StopSignal syscall.Signal // graceful stop signal sent by Stop, 0 for SIGTERM
+
 	// --- Mutable, protected by mu ---
-	Status      int            // FFmpegStarting, FFmpegRunning, etc. (read/written by multiple goroutines)
-	Wg          sync.WaitGroup // For external goroutine tracking (if used)
-	Speed       float64        // Last parsed speed (e.g., 1.01x)
-	LastSpeed   time.Time      // Last time speed was updated
-	Bitrate     float64        // Last parsed bitrate (kbps)
-	LastBitrate time.Time      // Last time bitrate was updated
-	outputBuf   bytes.Buffer   // Captured stdout/stderr for error reporting
-	mu          sync.Mutex     // Protects Status and all mutable fields above
+	Status      int                      // FFmpegStarting, FFmpegRunning, etc. (read/written by multiple goroutines)
+	Wg          sync.WaitGroup           // For external goroutine tracking (if used)
+	Speed       float64                  // Last parsed speed (e.g., 1.01x)
+	LastSpeed   time.Time                // Last time speed was updated
+	Bitrate     float64                  // Last parsed bitrate (kbps)
+	LastBitrate time.Time                // Last time bitrate was updated
+	DropFrames  int64                    // Last parsed drop_frames counter
+	outputBuf   bytes.Buffer             // Captured stdout/stderr for error reporting
+	subscribers map[chan string]struct{} // Live output subscribers, see SubscribeOutput
+	outputEnded bool                     // Set once the process exited and subscribers were closed
+	mu          sync.Mutex               // Protects Status and all mutable fields above
 }
 
 // NewFFmpegProcess creates a new FFmpegProcess with context and process group
@@ -58,6 +96,8 @@ func NewFFmpegProcess(ctx context.Context, args ...string) (*FFmpegProcess, erro
 	c, cancel := context.WithCancel(ctx)
 	cmd := exec.CommandContext(c, "ffmpeg", args...)
 	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	// Cancelling ctx kills the whole group too, not just ffmpeg itself
+	cmd.Cancel = func() error { return signalGroup(cmd.Process, syscall.SIGKILL) }
 
 	// Check if args contain -progress for progress parsing
 	hasProgress := false
@@ -74,7 +114,9 @@ func NewFFmpegProcess(ctx context.Context, args ...string) (*FFmpegProcess, erro
 		Cancel:      cancel,
 		Ctx:         c,
 		waitCh:      make(chan error, 1),
+		done:        make(chan struct{}),
 		hasProgress: hasProgress,
+		Nice:        int(processNice.Load()),
 	}
 	return proc, nil
 }
@@ -111,6 +153,15 @@ func (p *FFmpegProcess) Start() error {
 	p.PID = p.Cmd.Process.Pid
 	p.Status = FFmpegRunning
 	p.StartTime = time.Now()
+	if p.Nice != 0 {
+		// Best effort: raising priority needs privileges, and where setpriority
+		// is unsupported ffmpeg simply keeps the inherited priority
+		_ = syscall.Setpriority(syscall.PRIO_PROCESS, p.PID, p.Nice)
+	}
+	if cpus := processCPUs.Load(); cpus != nil {
+		// Best effort as well: CPUs outside the allowed set are rejected by the kernel
+		_ = unix.SchedSetaffinity(p.PID, cpus)
+	}
 
 	// Start a goroutine to call Wait() exactly once
 	go func() {
@@ -118,6 +169,8 @@ func (p *FFmpegProcess) Start() error {
 			err := p.Cmd.Wait()
 			p.waitCh <- err
 			close(p.waitCh)
+			close(p.done)
+			p.endSubscribers()
 		})
 	}()
 
@@ -178,6 +231,14 @@ func (p *FFmpegProcess) parseProgress(r io.Reader) {
 				}
 			}
 		}
+		if strings.HasPrefix(line, "drop_frames=") {
+			val := strings.TrimSpace(strings.TrimPrefix(line, "drop_frames="))
+			if dropped, err := strconv.ParseInt(val, 10, 64); err == nil {
+				p.mu.Lock()
+				p.DropFrames = dropped
+				p.mu.Unlock()
+			}
+		}
 		select {
 		case <-p.Ctx.Done():
 			return
@@ -202,6 +263,12 @@ func (p *FFmpegProcess) captureOutput(r io.Reader) {
 			p.mu.Lock()
 			p.outputBuf.WriteString(line)
 			p.outputBuf.WriteString("\n")
+			for ch := range p.subscribers {
+				select {
+				case ch <- line:
+				default: // Slow subscriber, drop the line rather than stall ffmpeg
+				}
+			}
 			p.mu.Unlock()
 		}
 		select {
@@ -231,6 +298,13 @@ func (p *FFmpegProcess) GetBitrate() (float64, time.Time) {
 	return p.Bitrate, p.LastBitrate
 }
 
+// GetDropFrames returns the last parsed drop_frames counter (concurrent-safe)
+func (p *FFmpegProcess) GetDropFrames() int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.DropFrames
+}
+
 // SetStats allows tests or wrappers to inject stats (optional, for extensibility)
 func (p *FFmpegProcess) SetStats(speed, bitrate float64) {
 	p.mu.Lock()
@@ -246,7 +320,24 @@ func (p *FFmpegProcess) Wait() error {
 	return <-p.waitCh
 }
 
-// Stop attempts graceful shutdown, then force kills if needed
+// Done returns a channel that is closed once the process has exited.
+// Unlike Wait, it does not consume the exit error, so monitors can use it freely.
+func (p *FFmpegProcess) Done() <-chan struct{} {
+	return p.done
+}
+
+// signalGroup sends sig to the process group of proc, which leads its own
+// group (Setpgid), so children it spawned are signalled as well. It falls
+// back to proc alone if the group cannot be signalled.
+func signalGroup(proc *os.Process, sig syscall.Signal) error {
+	if err := syscall.Kill(-proc.Pid, sig); err == nil {
+		return nil
+	}
+	return proc.Signal(sig)
+}
+
+// Stop attempts graceful shutdown of the process group, then force kills the
+// group if ffmpeg has not exited within timeout
 func (p *FFmpegProcess) Stop(timeout time.Duration) error {
 	p.mu.Lock()
 	if p.Status != FFmpegRunning || p.Cmd == nil || p.Cmd.Process == nil {
@@ -254,18 +345,25 @@ func (p *FFmpegProcess) Stop(timeout time.Duration) error {
 		return nil
 	}
 	p.mu.Unlock()
-	// Use SIGTERM for graceful shutdown (ffmpeg handles SIGTERM cleanly)
-	err := p.Cmd.Process.Signal(syscall.SIGTERM)
+	// Ask for a graceful shutdown (ffmpeg handles SIGTERM and SIGINT cleanly)
+	sig := p.StopSignal
+	if sig == 0 {
+		sig = syscall.SIGTERM
+	}
+	err := signalGroup(p.Cmd.Process, sig)
 	if err != nil {
 		// Fallback to SIGKILL if SIGTERM fails
-		_ = p.Cmd.Process.Kill()
+		_ = signalGroup(p.Cmd.Process, syscall.SIGKILL)
 	}
-	// Wait for process to exit or timeout
+	// Wait for process to exit or timeout (without consuming the result meant for Wait)
 	select {
 	case <-time.After(timeout):
-		_ = p.Cmd.Process.Kill()
+		_ = signalGroup(p.Cmd.Process, syscall.SIGKILL)
 		return nil
-	case <-p.waitCh:
+	case <-p.done:
+		// Children that ignored SIGTERM would outlive ffmpeg; the group
+		// exists as long as any of them does
+		_ = syscall.Kill(-p.PID, syscall.SIGKILL)
 		return nil
 	}
 }
@@ -294,3 +392,51 @@ func (p *FFmpegProcess) GetLastOutputLines(n int) []string {
 	}
 	return lines[len(lines)-n:]
 }
+
+// subscriberBuffer is how many lines a slow output subscriber may lag behind
+// before lines are dropped for it
+const subscriberBuffer = 256
+
+// SubscribeOutput returns the last backlog lines of captured output and a
+// channel receiving each line captured after them, closed once the process
+// has exited. Call cancel when done listening.
+func (p *FFmpegProcess) SubscribeOutput(backlog int) (lines []string, output <-chan string, cancel func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if text := strings.TrimSpace(p.outputBuf.String()); text != "" && backlog > 0 {
+		lines = strings.Split(text, "\n")
+		if len(lines) > backlog {
+			lines = lines[len(lines)-backlog:]
+		}
+	}
+	ch := make(chan string, subscriberBuffer)
+	if p.outputEnded {
+		close(ch)
+		return lines, ch, func() {}
+	}
+	if p.subscribers == nil {
+		p.subscribers = make(map[chan string]struct{})
+	}
+	p.subscribers[ch] = struct{}{}
+	return lines, ch, func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if _, ok := p.subscribers[ch]; ok {
+			delete(p.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// endSubscribers closes the channels of all output subscribers once the
+// process has exited
+func (p *FFmpegProcess) endSubscribers() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.outputEnded = true
+	for ch := range p.subscribers {
+		close(ch)
+	}
+	p.subscribers = nil
+}
@@ -0,0 +1,102 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// processRunning reports whether pid exists and is not a zombie
+func processRunning(pid int) bool {
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return false
+	}
+	fields := strings.Fields(string(stat[bytes.LastIndexByte(stat, ')')+1:]))
+	return len(fields) > 0 && fields[0] != "Z"
+}
+
+func TestFFmpegProcess_StopKillsProcessGroup(t *testing.T) {
+	if _, err := os.Stat("/proc/self/stat"); err != nil {
+		t.Skip("needs /proc")
+	}
+	tests := []struct {
+		name   string
+		parent string // how the fake ffmpeg treats SIGTERM
+	}{
+		{"ffmpeg ignores SIGTERM", "trap '' TERM\n"},
+		{"only the child ignores SIGTERM", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pidFile := filepath.Join(t.TempDir(), "child.pid")
+			script := "#!/bin/sh\n" + tt.parent +
+				"sh -c \"trap '' TERM; exec sleep 30\" &\n" +
+				"echo $! > " + pidFile + "\nwait\n"
+			installFakeFFmpeg(t, script)
+
+			proc, err := NewFFmpegProcess(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := proc.Start(); err != nil {
+				t.Fatalf("Start failed: %v", err)
+			}
+			var child int
+			deadline := time.Now().Add(5 * time.Second)
+			for child == 0 {
+				if data, err := os.ReadFile(pidFile); err == nil {
+					child, _ = strconv.Atoi(strings.TrimSpace(string(data)))
+				}
+				if time.Now().After(deadline) {
+					t.Fatal("fake ffmpeg did not start its child")
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			proc.Stop(300 * time.Millisecond)
+			select {
+			case <-proc.Done():
+			case <-time.After(2 * time.Second):
+				t.Fatal("ffmpeg survived Stop")
+			}
+			deadline = time.Now().Add(2 * time.Second)
+			for processRunning(child) {
+				if time.Now().After(deadline) {
+					t.Fatalf("child process %d survived Stop", child)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		})
+	}
+}
+
+func TestFFmpegProcess_SubscribeOutput(t *testing.T) {
+	proc := newTestShellProcess(t, "echo first >&2; sleep 0.3; echo second >&2; sleep 0.3")
+	backlog, lines, cancel := proc.SubscribeOutput(10)
+	defer cancel()
+
+	got := backlog
+	for line := range lines {
+		got = append(got, line)
+	}
+	if strings.Join(got, ",") != "first,second" {
+		t.Errorf("expected the backlog and live lines first,second, got %v", got)
+	}
+
+	// After the exit the whole output is backlog and the channel is closed
+	backlog, lines, cancel = proc.SubscribeOutput(1)
+	defer cancel()
+	if strings.Join(backlog, ",") != "second" {
+		t.Errorf("expected a backlog of the last line, got %v", backlog)
+	}
+	if _, ok := <-lines; ok {
+		t.Error("expected a closed channel once the process exited")
+	}
+}
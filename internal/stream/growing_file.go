@@ -0,0 +1,53 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+)
+
+// A file:// input that is still being written, such as a recording in
+// progress elsewhere, ends early when ffmpeg catches up with the writer and
+// takes the momentary end of file for the real one. In growing file mode the
+// input relay instead keeps reading at the end of the file, without seeking,
+// until it stops growing for growingFileTimeout. The container must be
+// readable while incomplete: MPEG-TS, FLV, Matroska or fragmented MP4, not a
+// regular MP4 whose index is only written when it is finished.
+
+// growingFileTimeout is the -rw_timeout, in microseconds, after which a
+// growing file that stopped growing is taken as complete
+const growingFileTimeout = "10000000"
+
+// ErrGrowingFileNotFile is returned when growing file mode is set on an input
+// that is not a file:// source
+var ErrGrowingFileNotFile = errors.New("growing file mode requires a file:// input")
+
+// validateGrowingFile checks that growing file mode may be set on inputURL
+func validateGrowingFile(inputURL string, growing bool) error {
+	if growing && !isFiniteInput(inputURL) {
+		return fmt.Errorf("%w, got %s", ErrGrowingFileNotFile, RedactURL(inputURL))
+	}
+	return nil
+}
+
+// growingFileArgs returns the file protocol options, placed before -i, that
+// follow a file while it is being written
+func growingFileArgs() []string {
+	return []string{"-follow", "1", "-seekable", "0", "-rw_timeout", growingFileTimeout}
+}
+
+// SetInputGrowingFile sets whether the file:// input of inputName is read as
+// a growing file, used when its input relay next starts
+func (rm *RelayManager) SetInputGrowingFile(inputName string, growing bool) error {
+	rm.configMu.Lock()
+	defer rm.configMu.Unlock()
+
+	config, exists := rm.inputConfigs[inputName]
+	if !exists {
+		return fmt.Errorf("input configuration not found for: %s", inputName)
+	}
+	if err := validateGrowingFile(config.InputURL, growing); err != nil {
+		return err
+	}
+	config.GrowingFile = growing
+	return nil
+}
@@ -0,0 +1,373 @@
+package stream
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"go-mls/internal/logger"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A highlight buffer keeps the last few minutes of an input as stream-copied
+// MPEG-TS segments, so "save the last 30 seconds" can be done after the fact.
+// ffmpeg's HLS muxer maintains the ring: it lists the newest segments in a
+// playlist and deletes the ones that fall out of the window.
+const (
+	DefaultHighlightWindow = 2 * time.Minute
+	highlightSegmentTime   = 2 * time.Second
+	highlightPlaylist      = "buffer.m3u8"
+	highlightSaveTimeout   = time.Minute
+)
+
+const highlightConsumer = "highlight"
+
+var (
+	// ErrHighlightNotEnabled is returned when saving a highlight of an input
+	// without a running buffer
+	ErrHighlightNotEnabled = errors.New("highlight buffer not enabled for input")
+	// ErrHighlightEmpty is returned when the buffer has no complete segment yet
+	ErrHighlightEmpty = errors.New("highlight buffer has no segments yet")
+	// ErrInvalidHighlightDuration is returned for a duration outside (0, window]
+	ErrInvalidHighlightDuration = errors.New("invalid highlight duration")
+)
+
+// highlightBuffer is the segmenting ffmpeg of one input
+type highlightBuffer struct {
+	inputName string
+	dir       string
+	proc      *FFmpegProcess
+	startedAt time.Time
+
+	// Saves read segments under RLock; disabling removes dir under Lock
+	dirMu sync.RWMutex
+}
+
+// HighlightBufferStatus describes an enabled buffer for the API
+type HighlightBufferStatus struct {
+	InputName string    `json:"input_name"`
+	Running   bool      `json:"running"`  // false once the segmenting ffmpeg exited
+	Buffered  float64   `json:"buffered"` // seconds of complete segments available
+	StartedAt time.Time `json:"started_at"`
+}
+
+// SavedHighlight is a highlight written to the recordings directory
+type SavedHighlight struct {
+	Filename string  `json:"filename"`
+	Duration float64 `json:"duration"` // seconds, whole segments so may exceed the request slightly
+	FileSize int64   `json:"file_size"`
+}
+
+// highlightSegment is one complete segment listed in the buffer playlist
+type highlightSegment struct {
+	name     string
+	duration float64
+}
+
+// HighlightManager runs the highlight buffers of the inputs they were enabled
+// for and saves highlights from them into the recordings directory
+type HighlightManager struct {
+	mu      sync.Mutex
+	buffers map[string]*highlightBuffer // key: input name, protected by mu
+	window  time.Duration               // protected by mu
+
+	logger       *logger.Logger
+	recDir       string
+	tempDir      string // parent of the highlight_* buffer directories
+	relayManager *RelayManager
+}
+
+func NewHighlightManager(l *logger.Logger, recDir string, rm *RelayManager) *HighlightManager {
+	return &HighlightManager{
+		buffers:      make(map[string]*highlightBuffer),
+		window:       DefaultHighlightWindow,
+		logger:       l,
+		recDir:       recDir,
+		tempDir:      os.TempDir(),
+		relayManager: rm,
+	}
+}
+
+// SetWindow sets how much of an input new buffers keep, which is also the
+// longest highlight that can be saved
+func (m *HighlightManager) SetWindow(window time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.window = window
+}
+
+// Enable starts the highlight buffer of inputName, taking a reference on its
+// input relay. Enabling an input that already has a buffer does nothing.
+func (m *HighlightManager) Enable(ctx context.Context, inputName string) error {
+	if inputName == "" || strings.Contains(inputName, "..") || strings.ContainsAny(inputName, "/\\") {
+		return errors.New("invalid input name")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.buffers[inputName]; exists {
+		return nil
+	}
+	localURL, err := m.relayManager.StartInputRelayForConsumer(ctx, inputName, highlightConsumer)
+	if err != nil {
+		return err
+	}
+	dir, err := os.MkdirTemp(m.tempDir, "highlight_"+inputName+"_")
+	if err != nil {
+		m.relayManager.StopInputRelayForConsumer(inputName, highlightConsumer)
+		return fmt.Errorf("failed to create highlight buffer dir: %w", err)
+	}
+	proc, err := NewFFmpegProcess(context.Background(), highlightBufferArgs(localURL, dir, m.window)...)
+	if err == nil {
+		err = proc.Start()
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		m.relayManager.StopInputRelayForConsumer(inputName, highlightConsumer)
+		return fmt.Errorf("failed to start highlight buffer: %w", err)
+	}
+	m.buffers[inputName] = &highlightBuffer{inputName: inputName, dir: dir, proc: proc, startedAt: time.Now()}
+	m.logger.Info("Highlight buffer enabled for %s (window %v, PID %d)", inputName, m.window, proc.PID)
+	return nil
+}
+
+// highlightBufferArgs returns the ffmpeg args that keep the last window of
+// localURL as segments in dir. One segment more than the window is listed so
+// a full window can be saved while the newest segment is still being written.
+func highlightBufferArgs(localURL, dir string, window time.Duration) []string {
+	listSize := int((window+highlightSegmentTime-1)/highlightSegmentTime) + 1
+	return []string{
+		"-rtsp_transport", "tcp",
+		"-i", localURL,
+		"-c", "copy",
+		"-f", "hls",
+		"-hls_time", strconv.Itoa(int(highlightSegmentTime.Seconds())),
+		"-hls_list_size", strconv.Itoa(listSize),
+		"-hls_flags", "delete_segments",
+		"-hls_segment_filename", filepath.Join(dir, "segment_%06d.ts"),
+		"-y",
+		filepath.Join(dir, highlightPlaylist),
+	}
+}
+
+// Disable stops the highlight buffer of inputName and deletes its segments
+func (m *HighlightManager) Disable(inputName string) error {
+	m.mu.Lock()
+	buf, exists := m.buffers[inputName]
+	delete(m.buffers, inputName)
+	m.mu.Unlock()
+	if !exists {
+		return ErrHighlightNotEnabled
+	}
+	m.stopBuffer(buf)
+	m.logger.Info("Highlight buffer disabled for %s", inputName)
+	return nil
+}
+
+func (m *HighlightManager) stopBuffer(buf *highlightBuffer) {
+	if err := buf.proc.Stop(2 * time.Second); err != nil {
+		m.logger.Warn("Error stopping highlight buffer of %s: %v", buf.inputName, err)
+	}
+	m.relayManager.StopInputRelayForConsumer(buf.inputName, highlightConsumer)
+	buf.dirMu.Lock()
+	os.RemoveAll(buf.dir)
+	buf.dirMu.Unlock()
+}
+
+// Status lists the enabled buffers
+func (m *HighlightManager) Status() []HighlightBufferStatus {
+	m.mu.Lock()
+	buffers := make([]*highlightBuffer, 0, len(m.buffers))
+	for _, buf := range m.buffers {
+		buffers = append(buffers, buf)
+	}
+	m.mu.Unlock()
+
+	statuses := make([]HighlightBufferStatus, 0, len(buffers))
+	for _, buf := range buffers {
+		running := true
+		select {
+		case <-buf.proc.Done():
+			running = false
+		default:
+		}
+		var buffered float64
+		buf.dirMu.RLock()
+		segments, _ := readHighlightPlaylist(filepath.Join(buf.dir, highlightPlaylist))
+		buf.dirMu.RUnlock()
+		for _, seg := range segments {
+			buffered += seg.duration
+		}
+		statuses = append(statuses, HighlightBufferStatus{
+			InputName: buf.inputName,
+			Running:   running,
+			Buffered:  buffered,
+			StartedAt: buf.startedAt,
+		})
+	}
+	return statuses
+}
+
+// SaveHighlight writes the last duration of inputName's buffer to
+// <input>_highlight_<unix time>.mp4 in the recordings directory. Whole
+// segments are used, so the highlight may be up to a segment longer; it is
+// shorter when less has been buffered so far.
+func (m *HighlightManager) SaveHighlight(inputName string, duration time.Duration) (*SavedHighlight, error) {
+	m.mu.Lock()
+	buf, exists := m.buffers[inputName]
+	window := m.window
+	m.mu.Unlock()
+	if !exists {
+		return nil, ErrHighlightNotEnabled
+	}
+	if duration <= 0 || duration > window {
+		return nil, fmt.Errorf("%w: must be more than 0 and at most %v", ErrInvalidHighlightDuration, window)
+	}
+
+	saveDir, err := os.MkdirTemp(m.tempDir, "highlight_save_")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(saveDir)
+	segments, err := buf.snapshot(saveDir, duration)
+	if err != nil {
+		return nil, err
+	}
+
+	var total float64
+	var list strings.Builder
+	for _, seg := range segments {
+		total += seg.duration
+		fmt.Fprintf(&list, "file '%s'\n", seg.name)
+	}
+	listPath := filepath.Join(saveDir, "concat.txt")
+	if err := os.WriteFile(listPath, []byte(list.String()), 0644); err != nil {
+		return nil, err
+	}
+
+	filename := fmt.Sprintf("%s_highlight_%d.mp4", inputName, time.Now().Unix())
+	finalPath := filepath.Join(m.recDir, filename)
+	// Written under another extension so the recordings list skips it until done
+	partPath := finalPath + ".part"
+	ctx, cancel := context.WithTimeout(context.Background(), highlightSaveTimeout)
+	defer cancel()
+	proc, err := NewFFmpegProcess(ctx, "-f", "concat", "-safe", "0", "-i", listPath,
+		"-c", "copy", "-movflags", "+faststart", "-f", "mp4", "-y", partPath)
+	if err == nil {
+		err = proc.Start()
+	}
+	if err == nil {
+		err = proc.Wait()
+		if err != nil {
+			err = fmt.Errorf("%w: %s", err, strings.Join(proc.GetLastOutputLines(3), "; "))
+		}
+	}
+	if err != nil {
+		os.Remove(partPath)
+		m.logger.Error("Failed to save highlight of %s: %v", inputName, err)
+		return nil, fmt.Errorf("failed to save highlight: %w", err)
+	}
+	if err := os.Rename(partPath, finalPath); err != nil {
+		os.Remove(partPath)
+		return nil, err
+	}
+	saved := &SavedHighlight{Filename: filename, Duration: total}
+	if info, err := os.Stat(finalPath); err == nil {
+		saved.FileSize = info.Size()
+	}
+	m.logger.Info("Saved %.1fs highlight of %s to %s", total, inputName, filename)
+	sseBroker.NotifyAll("update")
+	return saved, nil
+}
+
+// snapshot links the newest segments covering duration into saveDir, so
+// ffmpeg deleting them from the ring cannot break the save, and returns them
+// oldest first
+func (buf *highlightBuffer) snapshot(saveDir string, duration time.Duration) ([]highlightSegment, error) {
+	buf.dirMu.RLock()
+	defer buf.dirMu.RUnlock()
+	segments, err := readHighlightPlaylist(filepath.Join(buf.dir, highlightPlaylist))
+	if errors.Is(err, os.ErrNotExist) || (err == nil && len(segments) == 0) {
+		return nil, ErrHighlightEmpty
+	}
+	if err != nil {
+		return nil, err
+	}
+	start := len(segments)
+	var total float64
+	for start > 0 && total < duration.Seconds() {
+		start--
+		total += segments[start].duration
+	}
+	segments = segments[start:]
+	for _, seg := range segments {
+		if err := linkOrCopy(filepath.Join(buf.dir, seg.name), filepath.Join(saveDir, seg.name)); err != nil {
+			return nil, err
+		}
+	}
+	return segments, nil
+}
+
+// readHighlightPlaylist returns the segments listed in an HLS media playlist
+func readHighlightPlaylist(path string) ([]highlightSegment, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var segments []highlightSegment
+	duration := -1.0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "#EXTINF:"):
+			value, _, _ := strings.Cut(strings.TrimPrefix(line, "#EXTINF:"), ",")
+			if d, err := strconv.ParseFloat(value, 64); err == nil {
+				duration = d
+			}
+		case line == "" || strings.HasPrefix(line, "#"):
+		case duration >= 0:
+			segments = append(segments, highlightSegment{name: filepath.Base(line), duration: duration})
+			duration = -1
+		}
+	}
+	return segments, scanner.Err()
+}
+
+// linkOrCopy hard-links src to dst, copying when linking is not possible
+func linkOrCopy(src, dst string) error {
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// Shutdown stops all highlight buffers
+func (m *HighlightManager) Shutdown() {
+	m.mu.Lock()
+	buffers := m.buffers
+	m.buffers = make(map[string]*highlightBuffer)
+	m.mu.Unlock()
+	for _, buf := range buffers {
+		m.stopBuffer(buf)
+	}
+}
@@ -0,0 +1,106 @@
+package stream
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go-mls/internal/logger"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHighlightBufferArgs(t *testing.T) {
+	t.Parallel()
+	args := strings.Join(highlightBufferArgs("rtsp://127.0.0.1:8554/relay/cam", "/tmp/buf", 30*time.Second), " ")
+	// 15 segments cover the window, one more is kept for the one being written
+	for _, want := range []string{"-c copy", "-hls_time 2", "-hls_list_size 16", "-hls_flags delete_segments", "/tmp/buf/buffer.m3u8"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("expected buffer args to contain %q, got %s", want, args)
+		}
+	}
+}
+
+func TestHighlightManager_SaveHighlight(t *testing.T) {
+	// Fake ffmpeg that "concatenates" by copying the concat list to the output
+	script := "#!/bin/sh\nwhile [ \"$1\" != \"-i\" ]; do shift; done\nlist=$2\nfor a; do out=$a; done\ncp \"$list\" \"$out\"\n"
+	installFakeFFmpeg(t, script)
+
+	log := logger.NewLoggerWithWriter(&bytes.Buffer{})
+	recDir := t.TempDir()
+	m := NewHighlightManager(log, recDir, NewRelayManager(log, recDir))
+	m.tempDir = t.TempDir()
+	m.SetWindow(20 * time.Second)
+
+	if _, err := m.SaveHighlight("cam", 10*time.Second); !errors.Is(err, ErrHighlightNotEnabled) {
+		t.Fatalf("expected ErrHighlightNotEnabled, got %v", err)
+	}
+
+	bufDir := t.TempDir()
+	m.buffers["cam"] = &highlightBuffer{inputName: "cam", dir: bufDir, proc: newTestShellProcess(t, "exec sleep 30"), startedAt: time.Now()}
+	if _, err := m.SaveHighlight("cam", 10*time.Second); !errors.Is(err, ErrHighlightEmpty) {
+		t.Fatalf("expected ErrHighlightEmpty before the first segment, got %v", err)
+	}
+
+	var playlist strings.Builder
+	playlist.WriteString("#EXTM3U\n#EXT-X-TARGETDURATION:2\n#EXT-X-MEDIA-SEQUENCE:3\n")
+	for i := 3; i <= 8; i++ {
+		name := fmt.Sprintf("segment_%06d.ts", i)
+		if err := os.WriteFile(filepath.Join(bufDir, name), []byte(name), 0644); err != nil {
+			t.Fatalf("failed to write segment: %v", err)
+		}
+		fmt.Fprintf(&playlist, "#EXTINF:2.000000,\n%s\n", filepath.Join(bufDir, name))
+	}
+	if err := os.WriteFile(filepath.Join(bufDir, highlightPlaylist), []byte(playlist.String()), 0644); err != nil {
+		t.Fatalf("failed to write playlist: %v", err)
+	}
+
+	for _, d := range []time.Duration{0, 21 * time.Second} {
+		if _, err := m.SaveHighlight("cam", d); !errors.Is(err, ErrInvalidHighlightDuration) {
+			t.Errorf("expected ErrInvalidHighlightDuration for %v, got %v", d, err)
+		}
+	}
+
+	// 5s needs the newest three 2s segments, oldest first
+	saved, err := m.SaveHighlight("cam", 5*time.Second)
+	if err != nil {
+		t.Fatalf("SaveHighlight failed: %v", err)
+	}
+	if !strings.HasPrefix(saved.Filename, "cam_highlight_") || saved.Duration != 6 {
+		t.Errorf("expected a 6s cam_highlight_* file, got %+v", saved)
+	}
+	data, err := os.ReadFile(filepath.Join(recDir, saved.Filename))
+	if err != nil {
+		t.Fatalf("expected highlight in recordings dir: %v", err)
+	}
+	want := "file 'segment_000006.ts'\nfile 'segment_000007.ts'\nfile 'segment_000008.ts'\n"
+	if string(data) != want {
+		t.Errorf("expected concat list %q, got %q", want, data)
+	}
+	if saved.FileSize != int64(len(data)) {
+		t.Errorf("expected file size %d, got %d", len(data), saved.FileSize)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(recDir, "*.part")); len(matches) != 0 {
+		t.Errorf("expected no partial files left, got %v", matches)
+	}
+
+	// More than was buffered saves everything there is
+	if saved, err := m.SaveHighlight("cam", 20*time.Second); err != nil || saved.Duration != 12 {
+		t.Errorf("expected the whole 12s buffer, got %+v, %v", saved, err)
+	}
+
+	if status := m.Status(); len(status) != 1 || !status[0].Running || status[0].Buffered != 12 {
+		t.Errorf("expected one running buffer with 12s, got %+v", status)
+	}
+	if err := m.Disable("cam"); err != nil {
+		t.Fatalf("Disable failed: %v", err)
+	}
+	if _, err := os.Stat(bufDir); !os.IsNotExist(err) {
+		t.Errorf("expected buffer dir to be removed, got %v", err)
+	}
+	if err := m.Disable("cam"); !errors.Is(err, ErrHighlightNotEnabled) {
+		t.Errorf("expected ErrHighlightNotEnabled disabling twice, got %v", err)
+	}
+}
@@ -4,17 +4,109 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io"
+	"go-mls/internal/httputil"
 	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
 )
 
+// hlsOwnerMarker is written into every HLS session directory and holds the PID
+// of the go-mls instance that created it, so stale directories left behind by a
+// crashed instance can be told apart from those of a concurrently running one.
+// Directories without it are never reaped: they may belong to other programs,
+// to older builds, or to a session another instance is still setting up.
+const hlsOwnerMarker = ".owner.pid"
+
+// ErrHLSViewerLimit is returned by AddViewer when an input's preview already
+// has as many viewers as allowed, see SetMaxViewers
+var ErrHLSViewerLimit = errors.New("HLS viewer limit reached")
+
+// hlsErrorMaxLen caps the ffmpeg output returned to clients for a failed session.
+const hlsErrorMaxLen = 1024
+
+// Default ffmpeg probe settings for HLS sessions, kept small for fast startup
+const (
+	DefaultHLSAnalyzeDuration = "500k"
+	DefaultHLSProbeSize       = "500k"
+)
+
+// Default HLS preview audio format, widely supported by browser players
+const (
+	DefaultHLSAudioSampleRate = 44100
+	DefaultHLSAudioChannels   = 2
+)
+
+// Default cooldown after an input fails to start a preview; it doubles with
+// every consecutive failure up to the maximum
+const (
+	DefaultHLSFailedCooldown    = 10 * time.Second
+	DefaultHLSMaxFailedCooldown = 5 * time.Minute
+)
+
+// hlsInputFailure counts consecutive failed session starts of one input.
+// Protected by HLSManager.mu.
+type hlsInputFailure struct {
+	failures int
+	until    time.Time // no new session is started before this
+}
+
+// Default HLS segment naming. The counter is wide enough that long-running
+// sessions do not wrap around.
+const (
+	DefaultHLSSegmentPattern = "segment_%05d.ts"
+	DefaultHLSSegmentFormat  = "mpegts"
+)
+
+// HLSSyncOptions are ffmpeg flags against audio/video drift in long HLS
+// sessions; the zero value adds none, keeping the lowest latency. They only
+// touch timestamps and frame timing, so all of them work with the encoder's
+// -tune zerolatency.
+type HLSSyncOptions struct {
+	// -af aresample=async=1: stretches or pads audio to follow its timestamps (the old -async 1)
+	AudioResample bool `json:"audio_resample,omitempty"`
+	// -fps_mode (the old -vsync): "cfr" duplicates or drops frames to a constant rate, "vfr" or "passthrough"
+	FPSMode string `json:"fps_mode,omitempty"`
+	// -copyts -start_at_zero: keeps the source's timestamps instead of regenerating them
+	CopyTS bool `json:"copyts,omitempty"`
+}
+
+// Validate reports an unknown FPSMode
+func (o HLSSyncOptions) Validate() error {
+	switch o.FPSMode {
+	case "", "cfr", "vfr", "passthrough", "auto":
+		return nil
+	}
+	return fmt.Errorf("invalid fps_mode %q, must be one of cfr, vfr, passthrough, auto", o.FPSMode)
+}
+
+// args returns the ffmpeg output options for o
+func (o HLSSyncOptions) args() []string {
+	var args []string
+	if o.CopyTS {
+		args = append(args, "-copyts", "-start_at_zero")
+	}
+	if o.FPSMode != "" {
+		args = append(args, "-fps_mode", o.FPSMode)
+	}
+	if o.AudioResample {
+		args = append(args, "-af", "aresample=async=1")
+	}
+	return args
+}
+
+// hlsStrftimePattern matches strftime fields that make ffmpeg name segments
+// by time instead of by counter
+var hlsStrftimePattern = regexp.MustCompile(`%[YmHMSs]`)
+
 type HLSSession struct {
 	// Immutable fields (set at creation, never change)
 	InputName  string
@@ -26,27 +118,44 @@ type HLSSession struct {
 	ViewerIDs  map[string]time.Time // Track individual viewers with heartbeat
 	LastAccess time.Time            // Last time any viewer accessed this session
 
+	viewerActivity map[string]*hlsViewerActivity // what each viewer last fetched, see SessionStats
+
 	// --- Process management (concurrent-safe via FFmpegProcess) ---
 	Proc *FFmpegProcess // FFmpeg process abstraction (handles concurrency and output capture)
 
-	// --- Readiness flag (protected by ReadyMu) ---
+	// --- Readiness flag and failure reason (protected by ReadyMu) ---
 	Ready   bool
-	ReadyMu sync.RWMutex // Protects Ready flag
+	Err     string       // Truncated ffmpeg output if the session failed to become ready
+	ReadyMu sync.RWMutex // Protects Ready and Err
+
+	settled chan struct{} // Closed once the session is ready or has failed
 }
 
 type HLSManager struct {
 	// --- Mutable fields protected by mu ---
 	sessions         map[string]*HLSSession
-	failedInputs     map[string]time.Time // Track failed input attempts for cooldown
-	notFoundLogTimes map[string]time.Time // Last log time for missing inputName warnings
+	failedInputs     map[string]*hlsInputFailure // Consecutive failed starts per input, for cooldown
+	notFoundLogTimes map[string]time.Time        // Last log time for missing inputName warnings
 
 	// --- Immutable/config fields (set at construction) ---
 	cleanupInterval     time.Duration
 	sessionTimeout      time.Duration
 	ffmpegPath          string
-	relayManager        *RelayManager // Reference to relay manager for consumer management
-	failedCooldown      time.Duration // How long to block repeated attempts
-	notFoundLogInterval time.Duration // Minimum interval between logs per inputName
+	tempDir             string         // Parent directory for hls_* session directories
+	relayManager        *RelayManager  // Reference to relay manager for consumer management
+	failedCooldown      time.Duration  // How long to block attempts after a first failure (protected by mu)
+	maxFailedCooldown   time.Duration  // Cap for the cooldown, which doubles per consecutive failure (protected by mu)
+	notFoundLogInterval time.Duration  // Minimum interval between logs per inputName
+	analyzeDuration     string         // Default ffmpeg -analyzeduration (protected by mu)
+	probeSize           string         // Default ffmpeg -probesize (protected by mu)
+	audioSampleRate     int            // ffmpeg -ar, 0 keeps the source rate (protected by mu)
+	audioChannels       int            // ffmpeg -ac, 0 keeps the source layout (protected by mu)
+	threads             int            // ffmpeg -threads, 0 lets ffmpeg decide (protected by mu)
+	segmentPattern      string         // Segment file name, counter or strftime based (protected by mu)
+	segmentFormat       string         // "mpegts" or "fmp4" (protected by mu)
+	syncOptions         HLSSyncOptions // A/V sync flags unless the input overrides them (protected by mu)
+	maxHeight           int            // Downscale taller video to this height, 0 keeps the source's (protected by mu)
+	maxViewers          int            // Viewers per session, 0 for unlimited (protected by mu)
 
 	// --- Shutdown support ---
 	ctx    context.Context    // Context for cancellation
@@ -62,18 +171,208 @@ func NewHLSManager(ffmpegPath string, cleanupInterval, sessionTimeout time.Durat
 		cleanupInterval:     cleanupInterval,
 		sessionTimeout:      sessionTimeout,
 		ffmpegPath:          ffmpegPath,
+		tempDir:             os.TempDir(),
 		relayManager:        nil, // Will be set later via SetRelayManager
-		failedInputs:        make(map[string]time.Time),
-		failedCooldown:      30 * time.Second, // Default cooldown for failed inputs
+		failedInputs:        make(map[string]*hlsInputFailure),
+		failedCooldown:      DefaultHLSFailedCooldown,
+		maxFailedCooldown:   DefaultHLSMaxFailedCooldown,
 		notFoundLogTimes:    make(map[string]time.Time),
 		notFoundLogInterval: 10 * time.Second, // Log at most once per 10s per inputName
+		analyzeDuration:     DefaultHLSAnalyzeDuration,
+		probeSize:           DefaultHLSProbeSize,
+		audioSampleRate:     DefaultHLSAudioSampleRate,
+		audioChannels:       DefaultHLSAudioChannels,
+		segmentPattern:      DefaultHLSSegmentPattern,
+		segmentFormat:       DefaultHLSSegmentFormat,
 		ctx:                 ctx,
 		cancel:              cancel,
 	}
+	m.cleanupOrphanedSessionDirs()
 	go m.cleanupLoop(ctx)
 	return m
 }
 
+// cleanupOrphanedSessionDirs removes hls_* directories left behind by previous
+// instances that exited without cleaning up. A directory is only reaped when its
+// owner marker names a process that is no longer alive (or this very PID, which
+// can only be a previous run since no sessions exist yet). It returns the
+// removed paths.
+func (m *HLSManager) cleanupOrphanedSessionDirs() []string {
+	baseDir := m.tempDir
+	if baseDir == "" {
+		baseDir = os.TempDir()
+	}
+	entries, err := os.ReadDir(baseDir)
+	if err != nil {
+		return nil
+	}
+	self := os.Getpid()
+	var removed []string
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), "hls_") {
+			continue
+		}
+		dir := filepath.Join(baseDir, entry.Name())
+		data, err := os.ReadFile(filepath.Join(dir, hlsOwnerMarker))
+		if err != nil {
+			continue
+		}
+		pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+		if err != nil || (pid != self && processAlive(pid)) {
+			continue
+		}
+		if err := os.RemoveAll(dir); err == nil {
+			removed = append(removed, dir)
+		}
+	}
+	return removed
+}
+
+// processAlive reports whether a process with the given PID exists
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
+
+// SetProbeOptions sets the default -analyzeduration/-probesize passed to ffmpeg
+// for new HLS sessions. Per-input overrides on the InputConfig take precedence.
+func (m *HLSManager) SetProbeOptions(analyzeDuration, probeSize string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.analyzeDuration = analyzeDuration
+	m.probeSize = probeSize
+}
+
+// SetAudioOptions sets the audio sample rate and channel count of new HLS
+// sessions; 0 keeps the source's value
+func (m *HLSManager) SetAudioOptions(sampleRate, channels int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.audioSampleRate = sampleRate
+	m.audioChannels = channels
+}
+
+// SetThreads sets the ffmpeg -threads of new HLS sessions; 0 lets ffmpeg decide
+func (m *HLSManager) SetThreads(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.threads = n
+}
+
+// SetSegmentOptions sets the segment file name pattern and segment format
+// ("mpegts" or "fmp4") of new HLS sessions. The pattern is validated by the
+// config package.
+func (m *HLSManager) SetSegmentOptions(pattern, format string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.segmentPattern = pattern
+	m.segmentFormat = format
+}
+
+// SetSyncOptions sets the A/V sync flags of new HLS sessions. Per-input
+// options on the InputConfig take precedence.
+func (m *HLSManager) SetSyncOptions(o HLSSyncOptions) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.syncOptions = o
+}
+
+// SetMaxHeight caps the video height of new HLS sessions, downscaling taller
+// sources with their aspect ratio kept; 0 keeps the source resolution.
+// Per-input caps on the InputConfig take precedence.
+func (m *HLSManager) SetMaxHeight(h int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.maxHeight = h
+}
+
+// SetMaxViewers caps how many viewers one input's preview may have; viewers
+// beyond it are refused with ErrHLSViewerLimit while those watching carry on.
+// 0 means unlimited. Per-input caps on the InputConfig take precedence.
+func (m *HLSManager) SetMaxViewers(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.maxViewers = n
+}
+
+// maxViewersLocked returns the viewer cap of inputName's preview. Requires m.mu held.
+func (m *HLSManager) maxViewersLocked(inputName string) int {
+	if m.relayManager != nil {
+		if inputCfg, ok := m.relayManager.GetInputConfig(inputName); ok && inputCfg.HLSMaxViewers != nil {
+			return *inputCfg.HLSMaxViewers
+		}
+	}
+	return m.maxViewers
+}
+
+// hlsScaleArgs returns the ffmpeg filter capping the video at maxHeight lines.
+// Smaller sources are not upscaled; the width stays even for libx264.
+func hlsScaleArgs(maxHeight int) []string {
+	if maxHeight <= 0 {
+		return nil
+	}
+	return []string{"-vf", fmt.Sprintf("scale=-2:'min(%d,ih)'", maxHeight)}
+}
+
+// SetFailedCooldown sets how long starting a preview of an input is refused
+// after it failed, doubling per consecutive failure up to max
+func (m *HLSManager) SetFailedCooldown(base, max time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failedCooldown = base
+	m.maxFailedCooldown = max
+}
+
+// recordFailure starts or extends the cooldown of inputName
+func (m *HLSManager) recordFailure(inputName string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.recordFailureLocked(inputName)
+}
+
+func (m *HLSManager) recordFailureLocked(inputName string) {
+	if m.failedInputs == nil {
+		m.failedInputs = make(map[string]*hlsInputFailure)
+	}
+	failure := m.failedInputs[inputName]
+	if failure == nil {
+		failure = &hlsInputFailure{}
+		m.failedInputs[inputName] = failure
+	}
+	failure.failures++
+	cooldown := m.failedCooldown
+	for i := 1; i < failure.failures && cooldown < m.maxFailedCooldown; i++ {
+		cooldown *= 2
+	}
+	cooldown = min(cooldown, m.maxFailedCooldown)
+	failure.until = time.Now().Add(cooldown)
+	if m.relayManager != nil && m.relayManager.Logger != nil {
+		m.relayManager.Logger.Warn("HLS preview of %s failed %d time(s) in a row, next attempt in %v", inputName, failure.failures, cooldown)
+	}
+}
+
+// stopSessionLocked releases everything a session holds and forgets it
+func (m *HLSManager) stopSessionLocked(name string, sess *HLSSession) {
+	if sess.IsConsumer && m.relayManager != nil {
+		m.relayManager.StopInputRelayForConsumer(sess.InputName, hlsConsumer)
+	}
+	if sess.Proc != nil {
+		sess.Proc.Stop(2 * time.Second)
+	}
+	os.RemoveAll(sess.Dir)
+	delete(m.sessions, name)
+}
+
+// failed reports whether the session gave up becoming ready
+func (sess *HLSSession) failed() bool {
+	sess.ReadyMu.RLock()
+	defer sess.ReadyMu.RUnlock()
+	return sess.Err != ""
+}
+
 // SetRelayManager sets the relay manager reference for consumer management
 func (m *HLSManager) SetRelayManager(rm *RelayManager) {
 	m.mu.Lock()
@@ -84,20 +383,29 @@ func (m *HLSManager) SetRelayManager(rm *RelayManager) {
 // Start or get an HLS session for the given input
 func (m *HLSManager) GetOrStartSession(inputName, localURL string) (*HLSSession, error) {
 	m.mu.Lock()
-	// Check for recent failure
-	if failTime, failed := m.failedInputs[inputName]; failed {
-		if time.Since(failTime) < m.failedCooldown {
-			m.mu.Unlock()
-			if m.relayManager != nil && m.relayManager.Logger != nil {
-				m.relayManager.Logger.Warn("Input %s is in failed cooldown, refusing to start session", inputName)
-			}
-			return nil, errors.New("input unavailable (cooldown)")
-		} else {
-			// Cooldown expired, remove
-			delete(m.failedInputs, inputName)
+	defer m.mu.Unlock()
+	return m.getOrStartSessionLocked(inputName, localURL)
+}
+
+// getOrStartSessionLocked returns the single session for inputName, starting it
+// if needed. Every entry point goes through here with m.mu held, so concurrent
+// viewer-start and direct-playlist requests converge on one ffmpeg per input.
+func (m *HLSManager) getOrStartSessionLocked(inputName, localURL string) (*HLSSession, error) {
+	// A failed session keeps reporting its reason until the cooldown is over,
+	// then the next request gets a fresh attempt
+	if failure, failed := m.failedInputs[inputName]; failed && time.Now().Before(failure.until) {
+		if sess, exists := m.sessions[inputName]; exists {
+			sess.LastAccess = time.Now()
+			return sess, nil
+		}
+		if m.relayManager != nil && m.relayManager.Logger != nil {
+			m.relayManager.Logger.Warn("Input %s is in failed cooldown, refusing to start session", inputName)
 		}
+		return nil, fmt.Errorf("input unavailable (cooldown, retry in %v)", time.Until(failure.until).Round(time.Second))
+	}
+	if sess, exists := m.sessions[inputName]; exists && sess.failed() {
+		m.stopSessionLocked(inputName, sess)
 	}
-	defer m.mu.Unlock()
 
 	if m.relayManager != nil && m.relayManager.Logger != nil {
 		m.relayManager.Logger.Debug("GetOrStartSession: inputName=%s", inputName)
@@ -121,56 +429,127 @@ func (m *HLSManager) GetOrStartSession(inputName, localURL string) (*HLSSession,
 	var actualLocalURL string
 	var err error
 	if m.relayManager != nil {
-		actualLocalURL, err = m.relayManager.StartInputRelayForConsumer(inputName)
+		actualLocalURL, err = m.relayManager.StartInputRelayForConsumer(m.ctx, inputName, hlsConsumer)
 		if err != nil {
+			m.recordFailureLocked(inputName)
 			m.relayManager.Logger.Error("Failed to start input relay for HLS: %v", err)
 			return nil, fmt.Errorf("failed to start input relay for HLS: %w", err)
 		}
-		time.Sleep(1 * time.Second)
-		if _, found := m.relayManager.InputRelays.FindLocalURLByInputName(inputName); !found {
-			m.relayManager.StopInputRelayForConsumer(inputName)
-			m.relayManager.Logger.Error("Input relay failed to start for %s", inputName)
-			return nil, fmt.Errorf("input relay failed to start for %s", inputName)
-		}
 	} else {
 		actualLocalURL = localURL
 	}
 
-	dir, err := os.MkdirTemp("", "hls_"+inputName+"_")
+	dir, err := os.MkdirTemp(m.tempDir, "hls_"+inputName+"_")
 	if err != nil {
 		if m.relayManager != nil {
-			m.relayManager.StopInputRelayForConsumer(inputName)
+			m.relayManager.StopInputRelayForConsumer(inputName, hlsConsumer)
 		}
 		if m.relayManager != nil && m.relayManager.Logger != nil {
 			m.relayManager.Logger.Error("Failed to create temp dir: %v", err)
 		}
 		return nil, fmt.Errorf("failed to create temp dir: %w", err)
 	}
+	// Mark the directory as ours so a later startup can tell whether it is orphaned
+	if err := os.WriteFile(filepath.Join(dir, hlsOwnerMarker), []byte(strconv.Itoa(os.Getpid())), 0644); err != nil {
+		if m.relayManager != nil && m.relayManager.Logger != nil {
+			m.relayManager.Logger.Warn("Failed to write owner marker for HLS dir %s: %v", dir, err)
+		}
+	}
 
 	playlist := filepath.Join(dir, "index.m3u8")
-	segmentPattern := filepath.Join(dir, "segment_%03d.ts")
+	segmentPattern := filepath.Join(dir, m.segmentPattern)
+
+	// Slow-to-start sources may need a longer probe than the defaults
+	analyzeDuration, probeSize := m.analyzeDuration, m.probeSize
+	syncOptions := m.syncOptions
+	maxHeight := m.maxHeight
+	var audioTrack string
+	var profile InputProfileSettings
+	buffering := BufferingLowLatency
+	if m.relayManager != nil {
+		profile = m.relayManager.inputProfileSettings(inputName)
+		if profile.AnalyzeDuration != "" {
+			analyzeDuration = profile.AnalyzeDuration
+		}
+		if profile.ProbeSize != "" {
+			probeSize = profile.ProbeSize
+		}
+		buffering = m.relayManager.buffering
+		inputCfg, ok := m.relayManager.GetInputConfig(inputName)
+		if ok && inputCfg.Buffering != "" {
+			buffering = inputCfg.Buffering
+		}
+		if buffering == BufferingStable && profile.AnalyzeDuration == "" {
+			// A longer look at the stream than the low-latency defaults
+			analyzeDuration = stableAnalyzeDuration
+		}
+		if ok {
+			if inputCfg.AnalyzeDuration != "" {
+				analyzeDuration = inputCfg.AnalyzeDuration
+			}
+			if inputCfg.ProbeSize != "" {
+				probeSize = inputCfg.ProbeSize
+			}
+			if inputCfg.HLSSync != nil {
+				syncOptions = *inputCfg.HLSSync
+			}
+			if inputCfg.HLSMaxHeight != nil {
+				maxHeight = *inputCfg.HLSMaxHeight
+			}
+			audioTrack = inputCfg.HLSAudioTrack
+		}
+	}
 
 	// Build ffmpeg args
-	ffmpegArgs := []string{
-		"-rtsp_transport", "tcp",
-		"-analyzeduration", "500k",
-		"-probesize", "500k",
-		"-fflags", "nobuffer",
-		"-i", actualLocalURL,
+	ffmpegArgs := []string{"-rtsp_transport", "tcp"}
+	if analyzeDuration != "" {
+		ffmpegArgs = append(ffmpegArgs, "-analyzeduration", analyzeDuration)
+	}
+	if probeSize != "" {
+		ffmpegArgs = append(ffmpegArgs, "-probesize", probeSize)
+	}
+	if buffering == BufferingStable {
+		ffmpegArgs = append(ffmpegArgs, bufferingArgs(buffering)...)
+	} else if profile.FFlags == "" {
+		ffmpegArgs = append(ffmpegArgs, "-fflags", "nobuffer")
+	}
+	ffmpegArgs = append(ffmpegArgs, profile.flagArgs(actualLocalURL, false)...)
+	ffmpegArgs = append(ffmpegArgs, "-i", actualLocalURL)
+	ffmpegArgs = append(ffmpegArgs, audioTrackMapArgs(audioTrack)...)
+	ffmpegArgs = append(ffmpegArgs,
 		"-c:v", "libx264",
 		"-preset", "ultrafast",
 		"-tune", "zerolatency",
 		"-c:a", "aac",
-		"-ac", "2",
-		"-ar", "44100",
+	)
+	ffmpegArgs = append(ffmpegArgs, hlsScaleArgs(maxHeight)...)
+	if m.audioChannels > 0 {
+		ffmpegArgs = append(ffmpegArgs, "-ac", strconv.Itoa(m.audioChannels))
+	}
+	if m.audioSampleRate > 0 {
+		ffmpegArgs = append(ffmpegArgs, "-ar", strconv.Itoa(m.audioSampleRate))
+	}
+	if m.threads > 0 {
+		ffmpegArgs = append(ffmpegArgs, "-threads", strconv.Itoa(m.threads))
+	}
+	ffmpegArgs = append(ffmpegArgs, syncOptions.args()...)
+	ffmpegArgs = append(ffmpegArgs,
 		"-f", "hls",
 		"-hls_time", "2",
 		"-hls_list_size", "6",
 		"-hls_flags", "delete_segments+append_list",
+	)
+	if m.segmentFormat == "fmp4" {
+		ffmpegArgs = append(ffmpegArgs, "-hls_segment_type", "fmp4", "-hls_fmp4_init_filename", "init.mp4")
+	}
+	if hlsStrftimePattern.MatchString(m.segmentPattern) {
+		ffmpegArgs = append(ffmpegArgs, "-strftime", "1")
+	}
+	ffmpegArgs = append(ffmpegArgs,
 		"-hls_segment_filename", segmentPattern,
 		"-y",
 		playlist,
-	}
+	)
 
 	procCtx, procCancel := context.WithCancel(context.Background())
 	defer func() {
@@ -182,7 +561,7 @@ func (m *HLSManager) GetOrStartSession(inputName, localURL string) (*HLSSession,
 	if err != nil {
 		os.RemoveAll(dir)
 		if m.relayManager != nil {
-			m.relayManager.StopInputRelayForConsumer(inputName)
+			m.relayManager.StopInputRelayForConsumer(inputName, hlsConsumer)
 		}
 		return nil, fmt.Errorf("failed to create ffmpeg process: %w", err)
 	}
@@ -190,7 +569,7 @@ func (m *HLSManager) GetOrStartSession(inputName, localURL string) (*HLSSession,
 	if err := proc.Start(); err != nil {
 		os.RemoveAll(dir)
 		if m.relayManager != nil {
-			m.relayManager.StopInputRelayForConsumer(inputName)
+			m.relayManager.StopInputRelayForConsumer(inputName, hlsConsumer)
 		}
 		return nil, fmt.Errorf("failed to start ffmpeg: %w", err)
 	}
@@ -205,6 +584,7 @@ func (m *HLSManager) GetOrStartSession(inputName, localURL string) (*HLSSession,
 		LastAccess: time.Now(),
 		Proc:       proc,
 		Ready:      false,
+		settled:    make(chan struct{}),
 	}
 	m.sessions[inputName] = sess
 
@@ -214,8 +594,10 @@ func (m *HLSManager) GetOrStartSession(inputName, localURL string) (*HLSSession,
 
 	// Start a goroutine to monitor ffmpeg startup and set Ready flag
 	go func() {
+		defer close(sess.settled)
 		playlistPath := filepath.Join(sess.Dir, "index.m3u8")
 		ready := false
+		exited := false
 		watcher, err := fsnotify.NewWatcher()
 		if err == nil {
 			defer watcher.Close()
@@ -238,12 +620,16 @@ func (m *HLSManager) GetOrStartSession(inputName, localURL string) (*HLSSession,
 					}
 				case <-timeout:
 					break outer
+				case <-sess.Proc.Done():
+					// ffmpeg is gone; no point waiting for a playlist
+					exited = true
+					break outer
 				case <-time.After(50 * time.Millisecond):
 					// continue
 				}
 			}
 		}
-		if !ready {
+		if !ready && !exited {
 			// Fallback to polling if fsnotify fails or times out
 			for i := 0; i < 50; i++ {
 				fileInfo, err := os.Stat(playlistPath)
@@ -258,24 +644,30 @@ func (m *HLSManager) GetOrStartSession(inputName, localURL string) (*HLSSession,
 			sess.ReadyMu.Lock()
 			sess.Ready = true
 			sess.ReadyMu.Unlock()
+			m.mu.Lock()
+			delete(m.failedInputs, inputName)
+			m.mu.Unlock()
 			if m.relayManager != nil && m.relayManager.Logger != nil {
 				m.relayManager.Logger.Info("HLS session ready for inputName=%s (fsnotify/poll)", inputName)
 			}
 			return
 		}
 		// If we get here, ffmpeg failed to create a usable playlist
+		var lines []string
+		if sess.Proc != nil {
+			lines = sess.Proc.GetLastOutputLines(10)
+		}
 		sess.ReadyMu.Lock()
 		sess.Ready = false
+		sess.Err = hlsFailureReason(lines)
 		sess.ReadyMu.Unlock()
+		m.recordFailure(inputName)
 		if m.relayManager != nil && m.relayManager.Logger != nil {
 			m.relayManager.Logger.Error("HLS session failed to become ready for inputName=%s", inputName)
 			// Log last 10 lines of ffmpeg output for debugging
-			if sess.Proc != nil {
-				lines := sess.Proc.GetLastOutputLines(10)
-				for _, line := range lines {
-					if line != "" {
-						m.relayManager.Logger.Error("ffmpeg output: %s", line)
-					}
+			for _, line := range lines {
+				if line != "" {
+					m.relayManager.Logger.Error("ffmpeg output: %s", line)
 				}
 			}
 		}
@@ -284,16 +676,73 @@ func (m *HLSManager) GetOrStartSession(inputName, localURL string) (*HLSSession,
 	return sess, nil
 }
 
+// hlsFailureReason turns the last ffmpeg output lines of a failed session into
+// a message suitable for API clients, keeping the tail if it is too long.
+func hlsFailureReason(lines []string) string {
+	var kept []string
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			kept = append(kept, line)
+		}
+	}
+	if len(kept) == 0 {
+		return "ffmpeg did not produce a playlist"
+	}
+	reason := strings.Join(kept, "\n")
+	if len(reason) > hlsErrorMaxLen {
+		reason = "..." + reason[len(reason)-hlsErrorMaxLen:]
+	}
+	return reason
+}
+
+// WaitForSession waits until the session for inputName is ready, has failed,
+// or ctx is done. It only returns an error if the session failed to start, in
+// which case the error carries the (truncated) ffmpeg output. A session that is
+// still starting when ctx expires is not an error.
+func (m *HLSManager) WaitForSession(ctx context.Context, inputName string) error {
+	m.mu.Lock()
+	sess, exists := m.sessions[inputName]
+	m.mu.Unlock()
+	if !exists {
+		return fmt.Errorf("HLS session not found for %s", inputName)
+	}
+	if sess.settled == nil {
+		return nil
+	}
+
+	select {
+	case <-sess.settled:
+	case <-ctx.Done():
+		return nil
+	}
+
+	sess.ReadyMu.RLock()
+	defer sess.ReadyMu.RUnlock()
+	if !sess.Ready {
+		return fmt.Errorf("HLS session failed to start: %s", sess.Err)
+	}
+	return nil
+}
+
 // AddViewer adds a new viewer to the session and returns a viewer ID
 func (m *HLSManager) AddViewer(inputName, localURL string) (string, error) {
-	sess, err := m.GetOrStartSession(inputName, localURL)
+	// Start/join the session and register the viewer under one lock so the
+	// cleanup loop cannot remove the session in between
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// A failed session is replaced below, its viewers do not count
+	if sess, exists := m.sessions[inputName]; exists && !sess.failed() {
+		if limit := m.maxViewersLocked(inputName); limit > 0 && len(sess.ViewerIDs) >= limit {
+			return "", fmt.Errorf("%w: input %s has %d viewers", ErrHLSViewerLimit, inputName, len(sess.ViewerIDs))
+		}
+	}
+
+	sess, err := m.getOrStartSessionLocked(inputName, localURL)
 	if err != nil {
 		return "", err
 	}
 
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
 	// Generate unique viewer ID
 	viewerID := fmt.Sprintf("viewer_%d_%s", time.Now().UnixNano(), inputName)
 
@@ -328,6 +777,7 @@ func (m *HLSManager) RemoveViewer(inputName, viewerID string) {
 	if sess, exists := m.sessions[inputName]; exists {
 		if _, viewerExists := sess.ViewerIDs[viewerID]; viewerExists {
 			delete(sess.ViewerIDs, viewerID)
+			delete(sess.viewerActivity, viewerID)
 			if m.relayManager != nil && m.relayManager.Logger != nil {
 				m.relayManager.Logger.Info("Removed viewer %s from inputName=%s", viewerID, inputName)
 			}
@@ -351,7 +801,7 @@ func (m *HLSManager) Shutdown() {
 
 	for _, sess := range sessions {
 		if sess.IsConsumer && m.relayManager != nil {
-			m.relayManager.StopInputRelayForConsumer(sess.InputName)
+			m.relayManager.StopInputRelayForConsumer(sess.InputName, hlsConsumer)
 		}
 		if sess.Proc != nil {
 			err := sess.Proc.Stop(2 * time.Second)
@@ -369,11 +819,38 @@ func (m *HLSManager) Shutdown() {
 	}
 }
 
+// canStartSession reports whether a session for inputName can be started on
+// demand: either the caller supplied a local URL or the input is configured.
+func (m *HLSManager) canStartSession(inputName, localURL string) bool {
+	if localURL != "" {
+		return true
+	}
+	if m.relayManager == nil {
+		return false
+	}
+	_, ok := m.relayManager.GetInputConfig(inputName)
+	return ok
+}
+
+// hlsError answers a failed HLS request. Clients asking for JSON get
+// {"code", "message"}, where code is one of session_not_found, viewer_expired,
+// start_failed, session_failed, not_ready and file_not_found; players and
+// browsers get the message as plain text.
+func hlsError(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		httputil.WriteJSON(w, status, map[string]string{"code": code, "message": message})
+		return
+	}
+	http.Error(w, message, status)
+}
+
 // ServeHLS serves HLS playlist or segment, concurrency-safe and with detailed logging
 func (m *HLSManager) ServeHLS(w http.ResponseWriter, r *http.Request, inputName, file string, localURL string) {
 	if m.relayManager != nil && m.relayManager.Logger != nil {
 		m.relayManager.Logger.Debug("ServeHLS: inputName=%s, file=%s", inputName, file)
 	}
+	// Waiting for a session to become ready must not count against the API write timeout
+	httputil.DisableWriteTimeout(w)
 
 	// --- Stale viewer check ---
 	viewerID := r.URL.Query().Get("viewerID")
@@ -385,18 +862,19 @@ func (m *HLSManager) ServeHLS(w http.ResponseWriter, r *http.Request, inputName,
 			if m.relayManager != nil && m.relayManager.Logger != nil {
 				m.relayManager.Logger.Warn("ServeHLS: inputName=%s not found for viewerID=%s", inputName, viewerID)
 			}
-			http.Error(w, "HLS session not found", http.StatusNotFound)
+			hlsError(w, r, http.StatusNotFound, "session_not_found", "HLS session not found")
 			return
 		}
 		last, ok := sess.ViewerIDs[viewerID]
 		if !ok || time.Since(last) > 30*time.Second {
 			// Remove stale viewer
 			delete(sess.ViewerIDs, viewerID)
+			delete(sess.viewerActivity, viewerID)
 			if m.relayManager != nil && m.relayManager.Logger != nil {
 				m.relayManager.Logger.Warn("Stale or missing viewerID %s for inputName=%s; denying request", viewerID, inputName)
 			}
 			m.mu.Unlock()
-			http.Error(w, "Viewer session expired or invalid", http.StatusGone)
+			hlsError(w, r, http.StatusGone, "viewer_expired", "Viewer session expired or invalid")
 			return
 		}
 		// Update heartbeat
@@ -407,6 +885,18 @@ func (m *HLSManager) ServeHLS(w http.ResponseWriter, r *http.Request, inputName,
 
 	m.mu.Lock()
 	sess, exists := m.sessions[inputName]
+	// A direct playlist request (no viewerID) for a known input starts the same
+	// session an explicit viewer would; viewers and direct requests then share it
+	if !exists && viewerID == "" && strings.HasSuffix(file, ".m3u8") && m.canStartSession(inputName, localURL) {
+		var err error
+		sess, err = m.getOrStartSessionLocked(inputName, localURL)
+		if err != nil {
+			m.mu.Unlock()
+			hlsError(w, r, http.StatusServiceUnavailable, "start_failed", "Failed to start HLS session: "+err.Error())
+			return
+		}
+		exists = true
+	}
 	// --- Rate limit 'inputName not found' log spam ---
 	if !exists {
 		now := time.Now()
@@ -419,7 +909,7 @@ func (m *HLSManager) ServeHLS(w http.ResponseWriter, r *http.Request, inputName,
 			m.notFoundLogTimes[inputName] = now
 		}
 		m.mu.Unlock()
-		http.Error(w, "HLS session not found", http.StatusNotFound)
+		hlsError(w, r, http.StatusNotFound, "session_not_found", "HLS session not found")
 		return
 	}
 	m.mu.Unlock()
@@ -433,12 +923,19 @@ func (m *HLSManager) ServeHLS(w http.ResponseWriter, r *http.Request, inputName,
 	waitCtx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 	for !ready() {
+		sess.ReadyMu.RLock()
+		failure := sess.Err
+		sess.ReadyMu.RUnlock()
+		if failure != "" {
+			hlsError(w, r, http.StatusServiceUnavailable, "session_failed", "HLS session failed to start: "+failure)
+			return
+		}
 		select {
 		case <-waitCtx.Done():
 			if m.relayManager != nil && m.relayManager.Logger != nil {
 				m.relayManager.Logger.Error("HLS session not ready for inputName=%s", inputName)
 			}
-			http.Error(w, "HLS session not ready yet, please try again", http.StatusServiceUnavailable)
+			hlsError(w, r, http.StatusServiceUnavailable, "not_ready", "HLS session not ready yet, please try again")
 			return
 		default:
 			time.Sleep(200 * time.Millisecond)
@@ -470,7 +967,7 @@ func (m *HLSManager) ServeHLS(w http.ResponseWriter, r *http.Request, inputName,
 			if m.relayManager != nil && m.relayManager.Logger != nil {
 				m.relayManager.Logger.Error("HLS playlist not available: %v", statErr)
 			}
-			http.Error(w, "HLS playlist not available: "+statErr.Error(), http.StatusNotFound)
+			hlsError(w, r, http.StatusNotFound, "file_not_found", "HLS playlist not available: "+statErr.Error())
 			return
 		}
 
@@ -505,22 +1002,53 @@ func (m *HLSManager) ServeHLS(w http.ResponseWriter, r *http.Request, inputName,
 		if m.relayManager != nil && m.relayManager.Logger != nil {
 			m.relayManager.Logger.Error("HLS file access error: %s", errMsg)
 		}
-		http.Error(w, errMsg, http.StatusNotFound)
+		hlsError(w, r, http.StatusNotFound, "file_not_found", errMsg)
 		return
 	}
 	defer f.Close()
 
-	if strings.HasSuffix(file, ".m3u8") {
-		w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+	ext := filepath.Ext(file)
+	if contentType, ok := hlsContentTypes[ext]; ok {
+		w.Header().Set("Content-Type", contentType)
+	}
+	switch ext {
+	case ".m3u8":
 		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
-	} else if strings.HasSuffix(file, ".ts") {
-		w.Header().Set("Content-Type", "video/MP2T")
+	case ".mp4", ".ts", ".m4s":
+		// Init and media segments are never rewritten while a session runs
+		w.Header().Set("Cache-Control", "public, max-age=3600, immutable")
+	default:
 		w.Header().Set("Cache-Control", "public, max-age=3600")
 	}
 	if m.relayManager != nil && m.relayManager.Logger != nil {
 		m.relayManager.Logger.Debug("Serving file: %s", path)
 	}
-	io.Copy(w, f)
+	// ServeContent answers Range and conditional requests for byte-range
+	// fetches; the ETag lets caching proxies revalidate a segment of the same
+	// name that a restarted session wrote again
+	var modTime time.Time
+	if info, err := f.Stat(); err == nil {
+		modTime = info.ModTime()
+		w.Header().Set("ETag", fmt.Sprintf(`"%x-%x"`, info.Size(), modTime.UnixNano()))
+	}
+	if viewerID == "" {
+		http.ServeContent(w, r, file, modTime, f)
+		return
+	}
+	cw := &countingResponseWriter{ResponseWriter: w}
+	http.ServeContent(cw, r, file, modTime, f)
+	if cw.status < http.StatusBadRequest {
+		m.recordViewerRequest(inputName, viewerID, file, cw.bytes)
+	}
+}
+
+// hlsContentTypes maps HLS file extensions to their MIME type, for both
+// MPEG-TS and fMP4 (CMAF) segments
+var hlsContentTypes = map[string]string{
+	".m3u8": "application/vnd.apple.mpegurl",
+	".ts":   "video/mp2t",
+	".m4s":  "video/mp4",
+	".mp4":  "video/mp4",
 }
 
 // Enhanced cleanup with viewer heartbeat checking
@@ -542,6 +1070,7 @@ func (m *HLSManager) cleanupLoop(ctx context.Context) {
 				for viewerID, lastHeartbeat := range sess.ViewerIDs {
 					if now.Sub(lastHeartbeat) > 30*time.Second {
 						delete(sess.ViewerIDs, viewerID)
+						delete(sess.viewerActivity, viewerID)
 						if m.relayManager != nil && m.relayManager.Logger != nil {
 							m.relayManager.Logger.Info("Removed stale viewer %s from inputName=%s", viewerID, name)
 						}
@@ -554,12 +1083,7 @@ func (m *HLSManager) cleanupLoop(ctx context.Context) {
 					shouldCleanup = now.Sub(sess.LastAccess) > (m.sessionTimeout * 3)
 				}
 				if shouldCleanup {
-					if sess.IsConsumer && m.relayManager != nil {
-						m.relayManager.StopInputRelayForConsumer(sess.InputName)
-					}
-					sess.Proc.Stop(2 * time.Second)
-					os.RemoveAll(sess.Dir)
-					delete(m.sessions, name)
+					m.stopSessionLocked(name, sess)
 					if m.relayManager != nil && m.relayManager.Logger != nil {
 						m.relayManager.Logger.Info("Cleaned up HLS session for inputName=%s", name)
 					}
@@ -570,6 +1094,18 @@ func (m *HLSManager) cleanupLoop(ctx context.Context) {
 	}
 }
 
+// ActiveInputs returns the names of the inputs with an HLS session, sorted
+func (m *HLSManager) ActiveInputs() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.sessions))
+	for name := range m.sessions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // WriteEndlistToAll writes a final playlist with #EXT-X-ENDLIST for all active HLS sessions.
 func (m *HLSManager) WriteEndlistToAll() {
 	m.mu.Lock()
@@ -2,12 +2,18 @@ package stream
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -81,6 +87,50 @@ func TestServeHLS_PlaylistAndSegment(t *testing.T) {
 	}
 }
 
+func TestServeHLS_JSONErrors(t *testing.T) {
+	t.Parallel()
+	mgr := &HLSManager{
+		sessions:            make(map[string]*HLSSession),
+		relayManager:        &RelayManager{Logger: logger.NewLoggerWithWriter(&bytes.Buffer{})},
+		notFoundLogTimes:    make(map[string]time.Time),
+		notFoundLogInterval: time.Minute,
+	}
+	mgr.sessions["in"] = &HLSSession{InputName: "in", ViewerIDs: map[string]time.Time{}}
+
+	tests := []struct {
+		name, accept, path, inputName string
+		status                        int
+		code                          string
+	}{
+		{"unknown input", "application/json", "/index.m3u8", "missing", http.StatusNotFound, "session_not_found"},
+		{"expired viewer", "application/json, text/plain", "/index.m3u8?viewerID=gone", "in", http.StatusGone, "viewer_expired"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.Header.Set("Accept", tt.accept)
+		mgr.ServeHLS(rec, req, tt.inputName, "index.m3u8", "")
+		var body struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("%s: expected JSON body: %v", tt.name, err)
+		}
+		if rec.Code != tt.status || body.Code != tt.code || body.Message == "" {
+			t.Errorf("%s: expected %d %s with a message, got %d %+v", tt.name, tt.status, tt.code, rec.Code, body)
+		}
+	}
+
+	// Players keep getting plain text
+	rec := httptest.NewRecorder()
+	mgr.ServeHLS(rec, httptest.NewRequest(http.MethodGet, "/index.m3u8", nil), "missing", "index.m3u8", "")
+	if rec.Code != http.StatusNotFound || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain") ||
+		strings.TrimSpace(rec.Body.String()) != "HLS session not found" {
+		t.Errorf("expected plain text 404, got %d %q %q", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+}
+
 func TestServeHLS_NotFoundRateLimit(t *testing.T) {
 	t.Parallel()
 	var buf bytes.Buffer
@@ -128,3 +178,562 @@ func TestServeHLS_NotFoundRateLimit(t *testing.T) {
 		t.Fatal("test took too long, possible deadlock or leak")
 	}
 }
+
+func TestHLSManager_CleanupOrphanedSessionDirs(t *testing.T) {
+	t.Parallel()
+	base := t.TempDir()
+	mgr := &HLSManager{tempDir: base}
+
+	mkdir := func(name, owner string) string {
+		dir := filepath.Join(base, name)
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if owner != "" {
+			if err := os.WriteFile(filepath.Join(dir, hlsOwnerMarker), []byte(owner), 0644); err != nil {
+				t.Fatalf("failed to write marker: %v", err)
+			}
+		}
+		return dir
+	}
+
+	// PID of a process that has already exited
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("failed to run helper process: %v", err)
+	}
+	deadPID := strconv.Itoa(cmd.ProcessState.Pid())
+
+	stale := mkdir("hls_stale_1", deadPID)
+	live := mkdir("hls_live_1", strconv.Itoa(os.Getppid()))
+	// Unmarked directories may belong to another program, however old they are
+	unmarked := mkdir("hls_other_1", "")
+	old := time.Now().Add(-24 * time.Hour)
+	if err := os.Chtimes(unmarked, old, old); err != nil {
+		t.Fatalf("failed to age dir: %v", err)
+	}
+	garbled := mkdir("hls_garbled_1", "not a pid")
+	other := mkdir("not_hls_1", deadPID)
+
+	removed := mgr.cleanupOrphanedSessionDirs()
+	if len(removed) != 1 {
+		t.Errorf("expected 1 removed dir, got %v", removed)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be removed", stale)
+	}
+	for _, dir := range []string{live, unmarked, garbled, other} {
+		if _, err := os.Stat(dir); err != nil {
+			t.Errorf("expected %s to be kept, got %v", dir, err)
+		}
+	}
+}
+
+func TestHLSManager_SessionFailureReported(t *testing.T) {
+	mgr := &HLSManager{
+		sessions:        make(map[string]*HLSSession),
+		cleanupInterval: time.Minute,
+		sessionTimeout:  time.Minute,
+	}
+	inputName := "broken"
+	sess := &HLSSession{
+		InputName: inputName,
+		Dir:       t.TempDir(),
+		ViewerIDs: make(map[string]time.Time),
+		Err:       hlsFailureReason([]string{"", "rtsp://localhost:8554/relay/broken: Connection refused"}),
+		settled:   make(chan struct{}),
+	}
+	close(sess.settled)
+	mgr.sessions[inputName] = sess
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := mgr.WaitForSession(ctx, inputName)
+	if err == nil || !strings.Contains(err.Error(), "Connection refused") {
+		t.Fatalf("expected ffmpeg error from WaitForSession, got %v", err)
+	}
+
+	// The playlist request fails fast with the same reason
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/index.m3u8", nil)
+	mgr.ServeHLS(rec, req, inputName, "index.m3u8", "")
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "Connection refused") {
+		t.Errorf("expected 503 with ffmpeg error, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	// Long output keeps only the tail
+	long := hlsFailureReason([]string{strings.Repeat("x", 2*hlsErrorMaxLen), "last line"})
+	if !strings.HasSuffix(long, "last line") || len(long) > hlsErrorMaxLen+3 {
+		t.Errorf("expected truncated reason ending in last line, got %d bytes", len(long))
+	}
+}
+
+func TestHLSManager_ViewerAndDirectPlaylistShareSession(t *testing.T) {
+	// Fake ffmpeg: write the playlist (last arg) and stay alive like a real encoder
+	script := "#!/bin/sh\nfor last; do :; done\nprintf '#EXTM3U\\n#EXT-X-VERSION:3\\n' > \"$last\"\nexec sleep 30\n"
+	installFakeFFmpeg(t, script)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	mgr := &HLSManager{
+		sessions:            make(map[string]*HLSSession),
+		failedInputs:        make(map[string]*hlsInputFailure),
+		notFoundLogTimes:    make(map[string]time.Time),
+		cleanupInterval:     time.Minute,
+		sessionTimeout:      time.Minute,
+		tempDir:             t.TempDir(),
+		notFoundLogInterval: time.Second,
+		ctx:                 ctx,
+		cancel:              cancel,
+	}
+	defer mgr.Shutdown()
+
+	inputName := "shared"
+	localURL := "rtsp://127.0.0.1:1/relay/shared"
+
+	const n = 5
+	var wg sync.WaitGroup
+	viewerErrs := make(chan error, n)
+	codes := make(chan int, n)
+	for i := 0; i < n; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := mgr.AddViewer(inputName, localURL)
+			viewerErrs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			mgr.ServeHLS(rec, httptest.NewRequest(http.MethodGet, "/index.m3u8", nil), inputName, "index.m3u8", localURL)
+			codes <- rec.Code
+		}()
+	}
+	wg.Wait()
+	close(viewerErrs)
+	close(codes)
+
+	for err := range viewerErrs {
+		if err != nil {
+			t.Errorf("AddViewer failed: %v", err)
+		}
+	}
+	for code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("expected direct playlist request to succeed, got %d", code)
+		}
+	}
+
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+	if len(mgr.sessions) != 1 {
+		t.Fatalf("expected a single session, got %d", len(mgr.sessions))
+	}
+	if got := len(mgr.sessions[inputName].ViewerIDs); got != n {
+		t.Errorf("expected %d viewers on the shared session, got %d", n, got)
+	}
+	dirs, _ := filepath.Glob(filepath.Join(mgr.tempDir, "hls_"+inputName+"_*"))
+	if len(dirs) != 1 {
+		t.Errorf("expected one ffmpeg output dir, got %v", dirs)
+	}
+}
+
+func TestHLSManager_AudioOptions(t *testing.T) {
+	installFakeFFmpeg(t, "#!/bin/sh\nexec sleep 30\n")
+
+	mgr := NewHLSManager("ffmpeg", time.Minute, time.Minute)
+	mgr.tempDir = t.TempDir()
+	defer mgr.Shutdown()
+
+	mgr.SetAudioOptions(48000, 1)
+	sess, err := mgr.GetOrStartSession("mono", "rtsp://127.0.0.1:1/relay/mono")
+	if err != nil {
+		t.Fatalf("GetOrStartSession failed: %v", err)
+	}
+	if args := strings.Join(sess.Proc.Cmd.Args, " "); !strings.Contains(args, "-c:a aac -ac 1 -ar 48000 -f hls") {
+		t.Errorf("expected configured audio format in HLS args, got %s", args)
+	}
+
+	// Zero keeps the source format
+	mgr.SetAudioOptions(0, 0)
+	sess, err = mgr.GetOrStartSession("passthrough", "rtsp://127.0.0.1:1/relay/passthrough")
+	if err != nil {
+		t.Fatalf("GetOrStartSession failed: %v", err)
+	}
+	if args := strings.Join(sess.Proc.Cmd.Args, " "); !strings.Contains(args, "-c:a aac -f hls") {
+		t.Errorf("expected no -ac/-ar in HLS args, got %s", args)
+	}
+
+	mgr.SetThreads(2)
+	sess, err = mgr.GetOrStartSession("threads", "rtsp://127.0.0.1:1/relay/threads")
+	if err != nil {
+		t.Fatalf("GetOrStartSession failed: %v", err)
+	}
+	if args := strings.Join(sess.Proc.Cmd.Args, " "); !strings.Contains(args, "-c:a aac -threads 2 -f hls") {
+		t.Errorf("expected -threads in HLS args, got %s", args)
+	}
+	if args := strings.Join(sess.Proc.Cmd.Args, " "); !strings.Contains(args, "-hls_segment_filename "+filepath.Join(sess.Dir, DefaultHLSSegmentPattern)) || strings.Contains(args, "-strftime") {
+		t.Errorf("expected the default counter segment pattern in HLS args, got %s", args)
+	}
+
+	mgr.SetSegmentOptions("seg_%Y%m%d-%H%M%S.m4s", "fmp4")
+	sess, err = mgr.GetOrStartSession("dvr", "rtsp://127.0.0.1:1/relay/dvr")
+	if err != nil {
+		t.Fatalf("GetOrStartSession failed: %v", err)
+	}
+	args := strings.Join(sess.Proc.Cmd.Args, " ")
+	if !strings.Contains(args, "-hls_segment_type fmp4") || !strings.Contains(args, "-strftime 1 -hls_segment_filename "+filepath.Join(sess.Dir, "seg_%Y%m%d-%H%M%S.m4s")) {
+		t.Errorf("expected timestamped fmp4 segments in HLS args, got %s", args)
+	}
+}
+
+func TestHLSManager_SyncOptions(t *testing.T) {
+	installFakeFFmpeg(t, "#!/bin/sh\nexec sleep 30\n")
+
+	relayMgr := NewRelayManager(logger.NewLoggerWithWriter(io.Discard), t.TempDir())
+	relayMgr.InputRelays.SetStartupStabilization(0)
+	defer relayMgr.StopAllRelays()
+	mgr := NewHLSManager("ffmpeg", time.Minute, time.Minute)
+	mgr.tempDir = t.TempDir()
+	mgr.SetRelayManager(relayMgr)
+	defer mgr.Shutdown()
+
+	for _, name := range []string{"plain", "drifting"} {
+		if err := relayMgr.RegisterInputConfig(name, "rtsp://camera.example.com/"+name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mgr.SetSyncOptions(HLSSyncOptions{AudioResample: true})
+	if err := relayMgr.SetInputHLSSync("drifting", &HLSSyncOptions{FPSMode: "cfr", CopyTS: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := relayMgr.SetInputHLSSync("drifting", &HLSSyncOptions{FPSMode: "drop"}); err == nil {
+		t.Error("expected an invalid fps_mode to be rejected")
+	}
+
+	sess, err := mgr.GetOrStartSession("plain", "")
+	if err != nil {
+		t.Fatalf("GetOrStartSession failed: %v", err)
+	}
+	if args := strings.Join(sess.Proc.Cmd.Args, " "); !strings.Contains(args, "-af aresample=async=1 -f hls") || strings.Contains(args, "-copyts") {
+		t.Errorf("expected the default sync flags in HLS args, got %s", args)
+	}
+	sess, err = mgr.GetOrStartSession("drifting", "")
+	if err != nil {
+		t.Fatalf("GetOrStartSession failed: %v", err)
+	}
+	if args := strings.Join(sess.Proc.Cmd.Args, " "); !strings.Contains(args, "-copyts -start_at_zero -fps_mode cfr -f hls") || strings.Contains(args, "aresample") {
+		t.Errorf("expected the input's sync flags in HLS args, got %s", args)
+	}
+}
+
+func TestHLSManager_MaxHeight(t *testing.T) {
+	installFakeFFmpeg(t, "#!/bin/sh\nexec sleep 30\n")
+
+	relayMgr := NewRelayManager(logger.NewLoggerWithWriter(io.Discard), t.TempDir())
+	relayMgr.InputRelays.SetStartupStabilization(0)
+	defer relayMgr.StopAllRelays()
+	mgr := NewHLSManager("ffmpeg", time.Minute, time.Minute)
+	mgr.tempDir = t.TempDir()
+	mgr.SetRelayManager(relayMgr)
+	defer mgr.Shutdown()
+
+	for _, name := range []string{"capped", "custom", "source"} {
+		if err := relayMgr.RegisterInputConfig(name, "rtsp://camera.example.com/"+name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mgr.SetMaxHeight(720)
+	height := func(h int) *int { return &h }
+	if err := relayMgr.SetInputHLSMaxHeight("custom", height(360)); err != nil {
+		t.Fatal(err)
+	}
+	if err := relayMgr.SetInputHLSMaxHeight("source", height(0)); err != nil {
+		t.Fatal(err)
+	}
+	if err := relayMgr.SetInputHLSMaxHeight("source", height(-1)); err == nil {
+		t.Error("expected a negative max_height to be rejected")
+	}
+
+	tests := []struct {
+		input string
+		want  string // scale filter, empty for none
+	}{
+		{"capped", "scale=-2:'min(720,ih)'"},
+		{"custom", "scale=-2:'min(360,ih)'"},
+		{"source", ""},
+	}
+	for _, tt := range tests {
+		sess, err := mgr.GetOrStartSession(tt.input, "")
+		if err != nil {
+			t.Fatalf("GetOrStartSession(%s) failed: %v", tt.input, err)
+		}
+		args := strings.Join(sess.Proc.Cmd.Args, " ")
+		if tt.want == "" && strings.Contains(args, "-vf") {
+			t.Errorf("%s: expected no scale filter, got %s", tt.input, args)
+		} else if tt.want != "" && !strings.Contains(args, "-vf "+tt.want) {
+			t.Errorf("%s: expected -vf %s, got %s", tt.input, tt.want, args)
+		}
+	}
+}
+
+func TestHLSManager_Buffering(t *testing.T) {
+	installFakeFFmpeg(t, "#!/bin/sh\nexec sleep 30\n")
+
+	relayMgr := NewRelayManager(logger.NewLoggerWithWriter(io.Discard), t.TempDir())
+	relayMgr.InputRelays.SetStartupStabilization(0)
+	defer relayMgr.StopAllRelays()
+	mgr := NewHLSManager("ffmpeg", time.Minute, time.Minute)
+	mgr.tempDir = t.TempDir()
+	mgr.SetRelayManager(relayMgr)
+	defer mgr.Shutdown()
+
+	for _, name := range []string{"local", "jittery", "probed"} {
+		if err := relayMgr.RegisterInputConfig(name, "rtsp://camera.example.com/"+name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"jittery", "probed"} {
+		if err := relayMgr.SetInputBuffering(name, BufferingStable); err != nil {
+			t.Fatal(err)
+		}
+	}
+	relayMgr.SetInputProbeOptions("probed", "5M", "")
+	if err := relayMgr.SetInputBuffering("local", "huge"); !errors.Is(err, ErrInvalidBuffering) {
+		t.Errorf("expected ErrInvalidBuffering for an unknown mode, got %v", err)
+	}
+
+	tests := []struct {
+		input    string
+		nobuffer bool
+		analyze  string
+	}{
+		{"local", true, "500k"},
+		{"jittery", false, stableAnalyzeDuration},
+		{"probed", false, "5M"},
+	}
+	for _, tt := range tests {
+		sess, err := mgr.GetOrStartSession(tt.input, "")
+		if err != nil {
+			t.Fatalf("GetOrStartSession(%s) failed: %v", tt.input, err)
+		}
+		args := strings.Join(sess.Proc.Cmd.Args, " ")
+		if got := strings.Contains(args, "-fflags nobuffer"); got != tt.nobuffer {
+			t.Errorf("%s: expected nobuffer %v, got %s", tt.input, tt.nobuffer, args)
+		}
+		if got := strings.Contains(args, "-rtbufsize "+stableRTBufSize); got == tt.nobuffer {
+			t.Errorf("%s: expected rtbufsize %v, got %s", tt.input, !tt.nobuffer, args)
+		}
+		if !strings.Contains(args, "-analyzeduration "+tt.analyze) {
+			t.Errorf("%s: expected -analyzeduration %s, got %s", tt.input, tt.analyze, args)
+		}
+
+		// The input relay pulling the source buffers likewise
+		relayMgr.InputRelays.mu.Lock()
+		relay := relayMgr.InputRelays.Relays["rtsp://camera.example.com/"+tt.input]
+		relayMgr.InputRelays.mu.Unlock()
+		relay.mu.Lock()
+		inputArgs := strings.Join(relay.Proc.Cmd.Args, " ")
+		relay.mu.Unlock()
+		if got := strings.Contains(inputArgs, "-thread_queue_size "+stableThreadQueueSize); got == tt.nobuffer {
+			t.Errorf("%s: expected input relay thread queue %v, got %s", tt.input, !tt.nobuffer, inputArgs)
+		}
+	}
+}
+
+func TestServeHLS_FMP4ContentTypesAndRanges(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{"init.mp4": "initdata", "segment_00001.m4s": "0123456789"}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mgr := &HLSManager{sessions: map[string]*HLSSession{
+		"in": {InputName: "in", Dir: dir, Ready: true, ViewerIDs: map[string]time.Time{}},
+	}}
+
+	rec := httptest.NewRecorder()
+	mgr.ServeHLS(rec, httptest.NewRequest(http.MethodGet, "/init.mp4", nil), "in", "init.mp4", "")
+	if ct := rec.Header().Get("Content-Type"); ct != "video/mp4" {
+		t.Errorf("init segment Content-Type = %q, want video/mp4", ct)
+	}
+	if cc := rec.Header().Get("Cache-Control"); !strings.Contains(cc, "immutable") {
+		t.Errorf("init segment Cache-Control = %q, want immutable", cc)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/segment_00001.m4s", nil)
+	req.Header.Set("Range", "bytes=2-5")
+	rec = httptest.NewRecorder()
+	mgr.ServeHLS(rec, req, "in", "segment_00001.m4s", "")
+	if rec.Code != http.StatusPartialContent || rec.Body.String() != "2345" {
+		t.Errorf("range request = %d %q, want 206 \"2345\"", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "video/mp4" {
+		t.Errorf("media segment Content-Type = %q, want video/mp4", ct)
+	}
+	if cc := rec.Header().Get("Cache-Control"); !strings.Contains(cc, "immutable") {
+		t.Errorf("media segment Cache-Control = %q, want immutable", cc)
+	}
+
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("media segment served without an ETag")
+	}
+	req = httptest.NewRequest(http.MethodGet, "/segment_00001.m4s", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	mgr.ServeHLS(rec, req, "in", "segment_00001.m4s", "")
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("conditional request with matching ETag = %d, want 304", rec.Code)
+	}
+}
+
+func TestHLSManager_ViewerSegmentTracking(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"index.m3u8", "segment_00001.ts", "segment_00002.ts"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("0123456789"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mgr := &HLSManager{sessions: map[string]*HLSSession{
+		"in": {InputName: "in", Dir: dir, Ready: true, ViewerIDs: map[string]time.Time{"v1": time.Now()}},
+	}}
+	fetch := func(file string) {
+		rec := httptest.NewRecorder()
+		mgr.ServeHLS(rec, httptest.NewRequest(http.MethodGet, "/"+file+"?viewerID=v1", nil), "in", file, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s = %d", file, rec.Code)
+		}
+	}
+	fetch("index.m3u8")
+	fetch("segment_00001.ts")
+	fetch("segment_00002.ts")
+	fetch("index.m3u8")
+	fetch("segment_00002.ts")
+
+	stats := mgr.SessionStats()
+	if len(stats) != 1 || len(stats[0].Viewers) != 1 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	v := stats[0].Viewers[0]
+	if v.LastSegment != "segment_00002.ts" || v.Repeats != 1 || v.BytesServed != 50 || v.Stalled {
+		t.Errorf("viewer stats = %+v", v)
+	}
+
+	// Still requesting, but no new segment for longer than the stall window
+	mgr.mu.Lock()
+	mgr.sessions["in"].viewerActivity["v1"].lastSegmentAt = time.Now().Add(-2 * hlsViewerStallAfter)
+	mgr.mu.Unlock()
+	fetch("segment_00002.ts")
+	if v := mgr.SessionStats()[0].Viewers[0]; !v.Stalled || v.Repeats != 2 {
+		t.Errorf("viewer stats after stall = %+v, want stalled", v)
+	}
+}
+
+func TestHLSManager_MaxViewers(t *testing.T) {
+	relayMgr := NewRelayManager(logger.NewLoggerWithWriter(io.Discard), t.TempDir())
+	for _, name := range []string{"busy", "popular"} {
+		if err := relayMgr.RegisterInputConfig(name, "rtsp://camera.example.com/"+name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mgr := &HLSManager{relayManager: relayMgr, maxViewers: 2, sessions: map[string]*HLSSession{
+		"busy":    {InputName: "busy", Ready: true, ViewerIDs: map[string]time.Time{}},
+		"popular": {InputName: "popular", Ready: true, ViewerIDs: map[string]time.Time{}},
+	}}
+
+	var viewers []string
+	for i := 0; i < 2; i++ {
+		id, err := mgr.AddViewer("busy", "")
+		if err != nil {
+			t.Fatalf("AddViewer %d failed: %v", i, err)
+		}
+		viewers = append(viewers, id)
+	}
+	if _, err := mgr.AddViewer("busy", ""); !errors.Is(err, ErrHLSViewerLimit) {
+		t.Fatalf("expected ErrHLSViewerLimit over the limit, got %v", err)
+	}
+	if n := len(mgr.sessions["busy"].ViewerIDs); n != 2 {
+		t.Errorf("expected the existing 2 viewers to stay, got %d", n)
+	}
+	mgr.RemoveViewer("busy", viewers[0])
+	if _, err := mgr.AddViewer("busy", ""); err != nil {
+		t.Errorf("expected a freed place to be taken, got %v", err)
+	}
+
+	// The per-input cap takes precedence, 0 lifting it
+	limit := func(n int) *int { return &n }
+	if err := relayMgr.SetInputHLSMaxViewers("busy", limit(-1)); err == nil {
+		t.Error("expected a negative max_viewers to be rejected")
+	}
+	if err := relayMgr.SetInputHLSMaxViewers("busy", limit(3)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mgr.AddViewer("busy", ""); err != nil {
+		t.Errorf("expected a third viewer with a per-input cap of 3, got %v", err)
+	}
+	if err := relayMgr.SetInputHLSMaxViewers("popular", limit(0)); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := mgr.AddViewer("popular", ""); err != nil {
+			t.Fatalf("expected no cap with max_viewers 0, got %v", err)
+		}
+	}
+}
+
+func TestHLSManager_FailedCooldownGrowsAndExpires(t *testing.T) {
+	script := "#!/bin/sh\nfor last; do :; done\nprintf '#EXTM3U\\n' > \"$last\"\nexec sleep 30\n"
+	installFakeFFmpeg(t, script)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	mgr := &HLSManager{
+		sessions:         make(map[string]*HLSSession),
+		notFoundLogTimes: make(map[string]time.Time),
+		cleanupInterval:  time.Minute,
+		sessionTimeout:   time.Minute,
+		tempDir:          t.TempDir(),
+		ctx:              ctx,
+		cancel:           cancel,
+	}
+	defer mgr.Shutdown()
+	mgr.SetFailedCooldown(time.Second, 3*time.Second)
+
+	// 1s, 2s, then capped at 3s instead of 4s
+	for i := 0; i < 3; i++ {
+		mgr.recordFailure("cam")
+	}
+	stats := mgr.SessionStats()
+	if len(stats) != 1 || stats[0].Failures != 3 {
+		t.Fatalf("stats = %+v, want cam with 3 failures", stats)
+	}
+	if left := time.Until(stats[0].CooldownUntil); left < 2*time.Second || left > 3*time.Second {
+		t.Errorf("cooldown left = %v, want about 3s", left)
+	}
+	if _, err := mgr.GetOrStartSession("cam", "rtsp://127.0.0.1:1/relay/cam"); err == nil || !strings.Contains(err.Error(), "cooldown") {
+		t.Fatalf("start during cooldown: err = %v", err)
+	}
+
+	// A failed session is kept to report its reason, then replaced after the cooldown
+	failed := &HLSSession{InputName: "cam", Dir: t.TempDir(), ViewerIDs: map[string]time.Time{}, Err: "connection refused"}
+	mgr.mu.Lock()
+	mgr.sessions["cam"] = failed
+	mgr.mu.Unlock()
+	if sess, err := mgr.GetOrStartSession("cam", "rtsp://127.0.0.1:1/relay/cam"); err != nil || sess != failed {
+		t.Fatalf("during cooldown got %p, %v; want the failed session", sess, err)
+	}
+	mgr.mu.Lock()
+	mgr.failedInputs["cam"].until = time.Now()
+	mgr.mu.Unlock()
+	sess, err := mgr.GetOrStartSession("cam", "rtsp://127.0.0.1:1/relay/cam")
+	if err != nil || sess == failed {
+		t.Fatalf("after cooldown got %p, %v; want a new session", sess, err)
+	}
+	if err := mgr.WaitForSession(context.Background(), "cam"); err != nil {
+		t.Fatalf("new session failed: %v", err)
+	}
+	// Becoming ready clears the failure history
+	if stats := mgr.SessionStats(); len(stats) != 1 || stats[0].Failures != 0 || !stats[0].Ready {
+		t.Errorf("stats after recovery = %+v", stats)
+	}
+}
@@ -0,0 +1,164 @@
+package stream
+
+import (
+	"net/http"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+)
+
+// hlsViewerStallAfter is how long a viewer that keeps requesting files may go
+// without fetching a new segment before it is reported as stalled
+const hlsViewerStallAfter = 20 * time.Second
+
+// hlsViewerActivity is what ServeHLS saw one viewer fetch. Protected by HLSManager.mu.
+type hlsViewerActivity struct {
+	firstRequestAt time.Time
+	lastRequestAt  time.Time
+	lastSegment    string
+	lastSegmentAt  time.Time
+	repeats        int // refetches of lastSegment
+	bytesServed    int64
+}
+
+// HLSViewerStats describes one viewer's fetches. A viewer that keeps
+// requesting the playlist or the same segment without advancing is Stalled,
+// which points at the player (or its network) rather than at the server.
+type HLSViewerStats struct {
+	ViewerID      string    `json:"viewer_id"`
+	LastHeartbeat time.Time `json:"last_heartbeat"`
+	LastRequestAt time.Time `json:"last_request_at,omitempty"`
+	LastSegment   string    `json:"last_segment,omitempty"`
+	LastSegmentAt time.Time `json:"last_segment_at,omitempty"`
+	Repeats       int       `json:"repeats"`
+	BytesServed   int64     `json:"bytes_served"`
+	BitrateKbps   float64   `json:"bitrate_kbps"` // average since the viewer's first request
+	Stalled       bool      `json:"stalled"`
+}
+
+// HLSSessionStats describes one HLS preview session and its viewers. Inputs
+// whose previews keep failing are listed with their cooldown, even once their
+// session is gone, so it is clear why a preview does not start.
+type HLSSessionStats struct {
+	InputName     string           `json:"input_name"`
+	Ready         bool             `json:"ready"`
+	Error         string           `json:"error,omitempty"`
+	LastAccess    time.Time        `json:"last_access,omitempty"`
+	Failures      int              `json:"failures,omitempty"` // consecutive failed starts
+	CooldownUntil time.Time        `json:"cooldown_until,omitempty"`
+	Viewers       []HLSViewerStats `json:"viewers"`
+}
+
+// countingResponseWriter records the status and body size of a response
+type countingResponseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *countingResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *countingResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// recordViewerRequest notes that viewerID was successfully served file
+func (m *HLSManager) recordViewerRequest(inputName, viewerID, file string, bytes int64) {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sess, ok := m.sessions[inputName]
+	if !ok {
+		return
+	}
+	if _, ok := sess.ViewerIDs[viewerID]; !ok {
+		return
+	}
+	if sess.viewerActivity == nil {
+		sess.viewerActivity = make(map[string]*hlsViewerActivity)
+	}
+	a := sess.viewerActivity[viewerID]
+	if a == nil {
+		a = &hlsViewerActivity{firstRequestAt: now}
+		sess.viewerActivity[viewerID] = a
+	}
+	a.lastRequestAt = now
+	a.bytesServed += bytes
+	if filepath.Ext(file) == ".m3u8" {
+		return
+	}
+	if file == a.lastSegment {
+		a.repeats++
+		return
+	}
+	a.lastSegment = file
+	a.lastSegmentAt = now
+	a.repeats = 0
+}
+
+// SessionStats reports every HLS session with its viewers' last fetches, and
+// every input in failure cooldown
+func (m *HLSManager) SessionStats() []HLSSessionStats {
+	now := time.Now()
+	m.mu.Lock()
+	sessions := make([]*HLSSession, 0, len(m.sessions))
+	stats := make([]HLSSessionStats, 0, len(m.sessions))
+	for name, sess := range m.sessions {
+		st := HLSSessionStats{InputName: name, LastAccess: sess.LastAccess, Viewers: []HLSViewerStats{}}
+		for viewerID, heartbeat := range sess.ViewerIDs {
+			v := HLSViewerStats{ViewerID: viewerID, LastHeartbeat: heartbeat}
+			if a := sess.viewerActivity[viewerID]; a != nil {
+				v.LastRequestAt = a.lastRequestAt
+				v.LastSegment = a.lastSegment
+				v.LastSegmentAt = a.lastSegmentAt
+				v.Repeats = a.repeats
+				v.BytesServed = a.bytesServed
+				if elapsed := a.lastRequestAt.Sub(a.firstRequestAt).Seconds(); elapsed > 0 {
+					v.BitrateKbps = float64(a.bytesServed) * 8 / 1000 / elapsed
+				}
+				// Still asking for files, but none of them new for a while
+				since := a.lastSegmentAt
+				if since.IsZero() {
+					since = a.firstRequestAt
+				}
+				v.Stalled = now.Sub(a.lastRequestAt) < hlsViewerStallAfter && now.Sub(since) > hlsViewerStallAfter
+			}
+			st.Viewers = append(st.Viewers, v)
+		}
+		sort.Slice(st.Viewers, func(i, j int) bool { return st.Viewers[i].ViewerID < st.Viewers[j].ViewerID })
+		stats = append(stats, st)
+		sessions = append(sessions, sess)
+	}
+	for i, sess := range sessions {
+		sess.ReadyMu.RLock()
+		stats[i].Ready = sess.Ready
+		stats[i].Error = sess.Err
+		sess.ReadyMu.RUnlock()
+	}
+	for name, failure := range m.failedInputs {
+		i := slices.IndexFunc(stats, func(st HLSSessionStats) bool { return st.InputName == name })
+		if i < 0 {
+			stats = append(stats, HLSSessionStats{InputName: name, Viewers: []HLSViewerStats{}})
+			i = len(stats) - 1
+		}
+		stats[i].Failures = failure.failures
+		if now.Before(failure.until) {
+			stats[i].CooldownUntil = failure.until
+		}
+	}
+	m.mu.Unlock()
+
+	sort.Slice(stats, func(i, j int) bool { return stats[i].InputName < stats[j].InputName })
+	return stats
+}
@@ -0,0 +1,138 @@
+package stream
+
+import (
+	"sync"
+	"time"
+)
+
+// maxImportJobs is how many import jobs are kept for their progress to be
+// read; the oldest finished ones are forgotten first
+const maxImportJobs = 16
+
+// ImportResult is the outcome of starting one relay of an import
+type ImportResult struct {
+	InputName  string `json:"input_name"`
+	OutputName string `json:"output_name"`
+	Error      string `json:"error,omitempty"`
+}
+
+// ImportProgress is a snapshot of an import job. Started and Failed count
+// the relays of Total that have been started or have failed so far.
+type ImportProgress struct {
+	ID         string         `json:"id"`
+	Total      int            `json:"total"`
+	Started    int            `json:"started"`
+	Failed     int            `json:"failed"`
+	Results    []ImportResult `json:"results"` // in completion order
+	Done       bool           `json:"done"`
+	Error      string         `json:"error,omitempty"` // the last start error once done
+	CreatedAt  time.Time      `json:"created_at"`
+	FinishedAt time.Time      `json:"finished_at,omitempty"`
+}
+
+// ImportJob is an import running in the background, see StartImport. Its
+// methods do nothing on a nil job, so a synchronous import passes none.
+type ImportJob struct {
+	mu       sync.Mutex
+	progress ImportProgress
+	changed  chan struct{} // closed and replaced on every update
+}
+
+func newImportJob() *ImportJob {
+	return &ImportJob{
+		progress: ImportProgress{ID: newCorrelationID(), Results: []ImportResult{}, CreatedAt: time.Now()},
+		changed:  make(chan struct{}),
+	}
+}
+
+// update applies fn to the progress and wakes everyone waiting for a change
+func (j *ImportJob) update(fn func(p *ImportProgress)) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	fn(&j.progress)
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+func (j *ImportJob) begin(total int) {
+	j.update(func(p *ImportProgress) { p.Total = total })
+}
+
+func (j *ImportJob) record(inputName, outputName string, err error) {
+	j.update(func(p *ImportProgress) {
+		result := ImportResult{InputName: inputName, OutputName: outputName}
+		if err != nil {
+			result.Error = err.Error()
+			p.Failed++
+		} else {
+			p.Started++
+		}
+		p.Results = append(p.Results, result)
+	})
+}
+
+func (j *ImportJob) finish(err error) {
+	j.update(func(p *ImportProgress) {
+		p.Done = true
+		p.FinishedAt = time.Now()
+		if err != nil {
+			p.Error = err.Error()
+		}
+	})
+}
+
+// Snapshot returns a copy of the progress and a channel closed on its next
+// change, which is never closed once the job is done
+func (j *ImportJob) Snapshot() (ImportProgress, <-chan struct{}) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	p := j.progress
+	p.Results = append([]ImportResult(nil), p.Results...)
+	return p, j.changed
+}
+
+// StartImport imports a relay config like ImportConfig, but in the background:
+// the file is read and decoded before it returns, then the relays are started
+// while their progress is reported by the returned job
+func (rm *RelayManager) StartImport(filename string) (*ImportJob, error) {
+	rm.Logger.Debug("StartImport called: filename=%s", filename)
+	configs, err := rm.readImport(filename)
+	if err != nil {
+		return nil, err
+	}
+	job := newImportJob()
+	rm.addImportJob(job)
+	go func() {
+		job.finish(rm.importRelays(configs, filename, job))
+	}()
+	return job, nil
+}
+
+// ImportJob returns the import job with id, if it is still kept
+func (rm *RelayManager) ImportJob(id string) (*ImportJob, bool) {
+	rm.importJobsMu.Lock()
+	defer rm.importJobsMu.Unlock()
+	for _, job := range rm.importJobs {
+		if job.progress.ID == id { // immutable, no need for job.mu
+			return job, true
+		}
+	}
+	return nil, false
+}
+
+// addImportJob keeps job, forgetting the oldest finished jobs beyond maxImportJobs
+func (rm *RelayManager) addImportJob(job *ImportJob) {
+	rm.importJobsMu.Lock()
+	defer rm.importJobsMu.Unlock()
+	rm.importJobs = append(rm.importJobs, job)
+	for i := 0; len(rm.importJobs) > maxImportJobs && i < len(rm.importJobs); {
+		if p, _ := rm.importJobs[i].Snapshot(); p.Done {
+			rm.importJobs = append(rm.importJobs[:i], rm.importJobs[i+1:]...)
+			continue
+		}
+		i++
+	}
+}
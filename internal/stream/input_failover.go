@@ -0,0 +1,117 @@
+package stream
+
+import (
+	"fmt"
+	"time"
+)
+
+// SetFailbackInterval sets how long an input relay stays on its backup source
+// before the primary source is tried again; 0 only fails back through FailBack
+func (irm *InputRelayManager) SetFailbackInterval(d time.Duration) {
+	irm.failbackAfter = d
+}
+
+// stopFailbackTimer cancels a pending return to the primary source; relay.mu must be held
+func (r *InputRelay) stopFailbackTimer() {
+	if r.failback != nil {
+		r.failback.Stop()
+		r.failback = nil
+	}
+}
+
+// failOverLocked restarts the input ffmpeg of relay against the input's backup
+// source after the primary one exited with exitErr. The local RTSP URL and the
+// consumers' references are kept, so consumers only see the publisher change.
+// It reports false, leaving relay untouched, when there is no backup to switch
+// to. relay.mu must be held.
+func (irm *InputRelayManager) failOverLocked(relay *InputRelay, exitErr error) bool {
+	if relay.onBackup || relay.Direct || irm.configLookup == nil {
+		return false
+	}
+	if cfg, _ := irm.configLookup(relay.InputName); cfg.BackupURL == "" {
+		return false
+	}
+	log := irm.logFor(relay)
+	log.Warn("InputRelayManager: primary source %s failed (%v), failing over to its backup", RedactURL(relay.InputURL), exitErr)
+	relay.onBackup = true
+	relay.Proc = nil
+	relay.Status = InputStarting
+	relay.LastError = "primary source failed: " + exitErr.Error()
+	// launchLocked substitutes the backup for the primary URL
+	if err := irm.launchLocked(relay, relay.InputURL); err != nil {
+		relay.Status = InputError
+		relay.LastError = fmt.Sprintf("primary source failed: %v; backup: %v", exitErr, err)
+		log.Error("InputRelayManager: failed to start backup source of %s: %v", RedactURL(relay.InputURL), err)
+	} else {
+		log.Info("InputRelayManager: input %s now reads its backup source (PID %d)", RedactURL(relay.InputURL), relay.Proc.PID)
+	}
+	if irm.failbackAfter > 0 {
+		relay.failback = time.AfterFunc(irm.failbackAfter, func() {
+			if err := irm.failBack(relay); err != nil {
+				log.Warn("InputRelayManager: scheduled failback of %s: %v", RedactURL(relay.InputURL), err)
+			}
+		})
+	}
+	return true
+}
+
+// FailBack switches the input relay for inputURL from its backup source back
+// to the primary one. A primary that still fails makes it fail over again.
+func (irm *InputRelayManager) FailBack(inputURL string) error {
+	irm.mu.Lock()
+	relay, exists := irm.Relays[inputURL]
+	irm.mu.Unlock()
+	if !exists {
+		return fmt.Errorf("input relay not found: %s", RedactURL(inputURL))
+	}
+	return irm.failBack(relay)
+}
+
+// failBack returns relay to its primary source, restarting its ffmpeg if it
+// is in use; an idle or stopped relay picks the primary up on its next start
+func (irm *InputRelayManager) failBack(relay *InputRelay) error {
+	log := irm.logFor(relay)
+	relay.mu.Lock()
+	relay.stopFailbackTimer()
+	if !relay.onBackup {
+		relay.mu.Unlock()
+		return fmt.Errorf("input %s is not on its backup source", relay.InputName)
+	}
+	relay.onBackup = false
+	if relay.RefCount == 0 || relay.Direct || relay.Status == InputIdle || relay.Status == InputStopped || relay.Status == InputCompleted {
+		relay.mu.Unlock()
+		log.Info("InputRelayManager: input %s will use its primary source again on its next start", RedactURL(relay.InputURL))
+		return nil
+	}
+	// Stop the backup publisher first, the local RTSP path takes one at a time
+	proc := relay.Proc
+	relay.Proc = nil
+	relay.Status = InputStarting
+	relay.mu.Unlock()
+
+	log.Info("InputRelayManager: failing input %s back to its primary source", RedactURL(relay.InputURL))
+	if proc != nil {
+		if err := proc.Stop(2 * time.Second); err != nil {
+			log.Warn("InputRelayManager: Error stopping backup ffmpeg process for %s: %v", RedactURL(relay.InputURL), err)
+		}
+	}
+
+	relay.mu.Lock()
+	defer relay.mu.Unlock()
+	if relay.Proc != nil || relay.Status != InputStarting || relay.RefCount == 0 {
+		// Stopped, deleted or restarted meanwhile
+		return nil
+	}
+	resolvedInputURL, err := irm.resolveInputURL(relay.InputURL)
+	if err == nil {
+		err = irm.launchLocked(relay, resolvedInputURL)
+	}
+	if err != nil {
+		relay.Status = InputError
+		relay.LastError = err.Error()
+		return err
+	}
+	relay.LastError = ""
+	log.Info("InputRelayManager: input %s reads its primary source again (PID %d)", RedactURL(relay.InputURL), relay.Proc.PID)
+	return nil
+}
@@ -0,0 +1,101 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+)
+
+// CDNs and protected HLS origins may refuse requests without an auth token or
+// referer. HTTP headers set on an http(s) input are sent by the input ffmpeg
+// with -headers. Only the primary source gets them, never a backup on another
+// host, and their values are left out of logged command lines.
+
+// ErrInvalidInputHeaders is returned for headers that cannot be sent
+var ErrInvalidInputHeaders = errors.New("invalid input headers")
+
+// isHTTPInput reports whether inputURL is read over HTTP(S)
+func isHTTPInput(inputURL string) bool {
+	lower := strings.ToLower(inputURL)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// validHeaderName reports whether name is an HTTP token (RFC 9110)
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if c > '~' || !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("!#$%&'*+-.^_`|~", c)) {
+			return false
+		}
+	}
+	return true
+}
+
+// validateInputHeaders checks that headers may be sent when reading inputURL
+func validateInputHeaders(inputURL string, headers map[string]string) error {
+	if len(headers) == 0 {
+		return nil
+	}
+	if !isHTTPInput(inputURL) {
+		return fmt.Errorf("%w: headers need an http(s) input, got %s", ErrInvalidInputHeaders, RedactURL(inputURL))
+	}
+	for name, value := range headers {
+		if !validHeaderName(name) {
+			return fmt.Errorf("%w: %q is not a valid header name", ErrInvalidInputHeaders, name)
+		}
+		if strings.ContainsAny(value, "\r\n\x00") {
+			return fmt.Errorf("%w: the value of %s has a line break", ErrInvalidInputHeaders, name)
+		}
+	}
+	return nil
+}
+
+// inputHeaderArgs returns the -headers option, placed before -i, sending
+// headers in name order
+func inputHeaderArgs(headers map[string]string) []string {
+	if len(headers) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name + ": " + headers[name] + "\r\n")
+	}
+	return []string{"-headers", b.String()}
+}
+
+// redactHeaders keeps only the names of a -headers value
+func redactHeaders(value string) string {
+	lines := strings.Split(strings.TrimSuffix(value, "\r\n"), "\r\n")
+	for i, line := range lines {
+		if name, _, ok := strings.Cut(line, ":"); ok {
+			lines[i] = name + ": xxxxx"
+		}
+	}
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+// SetInputHeaders sets the HTTP headers sent when reading the http(s) input
+// of inputName, used when its input relay next starts; nil clears them
+func (rm *RelayManager) SetInputHeaders(inputName string, headers map[string]string) error {
+	rm.configMu.Lock()
+	defer rm.configMu.Unlock()
+
+	config, exists := rm.inputConfigs[inputName]
+	if !exists {
+		return fmt.Errorf("input configuration not found for: %s", inputName)
+	}
+	if err := validateInputHeaders(config.InputURL, headers); err != nil {
+		return err
+	}
+	config.HTTPHeaders = maps.Clone(headers)
+	return nil
+}
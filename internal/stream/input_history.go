@@ -0,0 +1,95 @@
+package stream
+
+import "time"
+
+// Input relays stay listed after their last consumer lets go, so their state
+// and last error remain visible. Such an input is "historical" in the status
+// until it is consumed again; PurgeInputHistory drops the historical inputs
+// no output refers to anymore once they have been unused long enough.
+
+// Categories of InputRelayStatusV2
+const (
+	InputCategoryActive     = "active"     // held by at least one consumer
+	InputCategoryHistorical = "historical" // kept listed without consumers
+)
+
+// inputHistoryPurgeInterval is how often SetInputHistoryMaxAge purges
+const inputHistoryPurgeInterval = time.Minute
+
+// categoryLocked returns the category of r and since when it has been
+// historical, zero while active; r.mu must be held
+func (r *InputRelay) categoryLocked() (string, time.Time) {
+	if r.RefCount > 0 {
+		return InputCategoryActive, time.Time{}
+	}
+	return InputCategoryHistorical, r.releasedAt
+}
+
+// purgeReleased removes the relays that have had no consumers since before
+// cutoff and are not kept, returning their names. Their ffmpeg and RTSP
+// stream are already gone with the last consumer.
+func (irm *InputRelayManager) purgeReleased(cutoff time.Time, keep func(inputURL string) bool) []string {
+	irm.mu.Lock()
+	defer irm.mu.Unlock()
+	purged := []string{}
+	for inputURL, relay := range irm.Relays {
+		relay.mu.Lock()
+		stale := relay.RefCount == 0 && relay.Proc == nil && relay.releasedAt.Before(cutoff)
+		relay.mu.Unlock()
+		if !stale || keep(inputURL) {
+			continue
+		}
+		delete(irm.Relays, inputURL)
+		purged = append(purged, relay.InputName)
+		irm.logFor(relay).Info("InputRelayManager: Purged historical input %s [%s]", relay.InputName, RedactURL(inputURL))
+	}
+	return purged
+}
+
+// PurgeInputHistory removes the historical inputs that have been unused for
+// at least olderThan, 0 for all of them, and returns their names. Inputs that
+// outputs still refer to, even stopped or disabled ones, are kept so their
+// outputs can be started again.
+func (rm *RelayManager) PurgeInputHistory(olderThan time.Duration) []string {
+	return rm.InputRelays.purgeReleased(time.Now().Add(-olderThan), func(inputURL string) bool {
+		rm.OutputRelays.mu.Lock()
+		defer rm.OutputRelays.mu.Unlock()
+		for _, out := range rm.OutputRelays.Relays {
+			if out.InputURL == inputURL {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// SetInputHistoryMaxAge purges historical inputs unused for maxAge once a
+// minute, until StopAllRelays; 0 keeps them. Call it once, before relays start.
+func (rm *RelayManager) SetInputHistoryMaxAge(maxAge time.Duration) {
+	if maxAge <= 0 {
+		return
+	}
+	rm.historyMaxAge = maxAge
+	go func() {
+		ticker := time.NewTicker(inputHistoryPurgeInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				rm.PurgeInputHistory(maxAge)
+			case <-rm.historyStop:
+				return
+			}
+		}
+	}()
+}
+
+// InputHistoryMaxAge returns the age SetInputHistoryMaxAge purges at, 0 if none
+func (rm *RelayManager) InputHistoryMaxAge() time.Duration {
+	return rm.historyMaxAge
+}
+
+// stopHistoryPurge ends the purging started by SetInputHistoryMaxAge
+func (rm *RelayManager) stopHistoryPurge() {
+	rm.historyStopOnce.Do(func() { close(rm.historyStop) })
+}
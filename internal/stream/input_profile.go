@@ -0,0 +1,152 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// InputProfile names a bundle of the ffmpeg options that tune how an input is
+// read, from low latency to robustness against bad links. The input relay
+// applies it when pulling the source, and HLS previews and recordings when
+// reading the input from the local RTSP server. Explicit analyzeduration and
+// probesize settings of an input still win over its profile's.
+type InputProfile string
+
+const (
+	InputProfileLowLatency InputProfile = "low_latency" // Start fast and pass packets on at once
+	InputProfileBalanced   InputProfile = "balanced"    // Moderate probing over TCP
+	InputProfileRobust     InputProfile = "robust"      // Probe long and tolerate damaged streams
+)
+
+// ErrInvalidInputProfile is returned for an unknown input profile
+var ErrInvalidInputProfile = errors.New("invalid input profile")
+
+// InputProfileSettings are the ffmpeg input options of an input profile; empty
+// fields leave ffmpeg's defaults
+type InputProfileSettings struct {
+	FFlags          string `json:"fflags,omitempty"`          // -fflags
+	AnalyzeDuration string `json:"analyzeduration,omitempty"` // -analyzeduration, in microseconds
+	ProbeSize       string `json:"probesize,omitempty"`       // -probesize, in bytes
+	RTSPTransport   string `json:"rtsp_transport,omitempty"`  // -rtsp_transport of rtsp:// sources
+	Timeout         string `json:"timeout,omitempty"`         // -rw_timeout of network sources, in microseconds
+}
+
+// inputProfiles defines the input profiles, the only place their options live
+var inputProfiles = map[InputProfile]InputProfileSettings{
+	InputProfileLowLatency: {
+		FFlags:          "nobuffer",
+		AnalyzeDuration: "500000",
+		ProbeSize:       "500000",
+		RTSPTransport:   "udp",
+		Timeout:         "5000000",
+	},
+	InputProfileBalanced: {
+		AnalyzeDuration: "1000000",
+		ProbeSize:       "1000000",
+		RTSPTransport:   "tcp",
+		Timeout:         "10000000",
+	},
+	InputProfileRobust: {
+		FFlags:          "+genpts+discardcorrupt",
+		AnalyzeDuration: "5000000",
+		ProbeSize:       "10000000",
+		RTSPTransport:   "tcp",
+		Timeout:         "30000000",
+	},
+}
+
+// InputProfiles returns the input profiles and their options
+func InputProfiles() map[InputProfile]InputProfileSettings {
+	profiles := make(map[InputProfile]InputProfileSettings, len(inputProfiles))
+	for name, settings := range inputProfiles {
+		profiles[name] = settings
+	}
+	return profiles
+}
+
+// validateInputProfile checks an input profile; empty leaves the default
+func validateInputProfile(profile InputProfile) error {
+	if _, ok := inputProfiles[profile]; ok || profile == "" {
+		return nil
+	}
+	return fmt.Errorf("%w %q: must be %s, %s or %s", ErrInvalidInputProfile, profile, InputProfileLowLatency, InputProfileBalanced, InputProfileRobust)
+}
+
+// probeArgs returns -analyzeduration and -probesize, taking analyzeDuration and
+// probeSize over the profile's, and fallbackAnalyze when neither sets one
+func (s InputProfileSettings) probeArgs(analyzeDuration, probeSize, fallbackAnalyze string) []string {
+	var args []string
+	if analyzeDuration == "" {
+		analyzeDuration = s.AnalyzeDuration
+	}
+	if analyzeDuration == "" {
+		analyzeDuration = fallbackAnalyze
+	}
+	if analyzeDuration != "" {
+		args = append(args, "-analyzeduration", analyzeDuration)
+	}
+	if probeSize == "" {
+		probeSize = s.ProbeSize
+	}
+	if probeSize != "" {
+		args = append(args, "-probesize", probeSize)
+	}
+	return args
+}
+
+// flagArgs returns the profile's other options for reading inputURL, placed
+// before -i. The transport is only set on remote sources, as reads of the
+// local RTSP server choose their own.
+func (s InputProfileSettings) flagArgs(inputURL string, remote bool) []string {
+	var args []string
+	if s.FFlags != "" {
+		args = append(args, "-fflags", s.FFlags)
+	}
+	if remote && s.RTSPTransport != "" && (strings.HasPrefix(inputURL, "rtsp://") || strings.HasPrefix(inputURL, "rtsps://")) {
+		args = append(args, "-rtsp_transport", s.RTSPTransport)
+	}
+	if s.Timeout != "" && strings.Contains(inputURL, "://") {
+		args = append(args, "-rw_timeout", s.Timeout)
+	}
+	return args
+}
+
+// SetDefaultInputProfile sets the input profile of inputs without their own,
+// used by input relays, HLS sessions and recordings started afterwards; set
+// before relays start. Empty keeps the options of no profile.
+func (rm *RelayManager) SetDefaultInputProfile(profile InputProfile) error {
+	if err := validateInputProfile(profile); err != nil {
+		return err
+	}
+	rm.inputProfile = profile
+	rm.InputRelays.inputProfile = profile
+	return nil
+}
+
+// SetInputProfile sets the input profile of inputName, used when its input
+// relay, HLS session or recording next starts. Empty restores the default.
+func (rm *RelayManager) SetInputProfile(inputName string, profile InputProfile) error {
+	if err := validateInputProfile(profile); err != nil {
+		return err
+	}
+	rm.configMu.Lock()
+	defer rm.configMu.Unlock()
+
+	config, exists := rm.inputConfigs[inputName]
+	if !exists {
+		return fmt.Errorf("input configuration not found for: %s", inputName)
+	}
+	config.InputProfile = profile
+	return nil
+}
+
+// inputProfileSettings returns the options of the input profile of inputName,
+// its own or the default
+func (rm *RelayManager) inputProfileSettings(inputName string) InputProfileSettings {
+	profile := rm.inputProfile
+	if cfg, ok := rm.GetInputConfig(inputName); ok && cfg.InputProfile != "" {
+		profile = cfg.InputProfile
+	}
+	return inputProfiles[profile]
+}
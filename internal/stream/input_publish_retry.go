@@ -0,0 +1,55 @@
+package stream
+
+import "time"
+
+// An input ffmpeg that exits this soon after launch most likely could not
+// publish to the local RTSP server, e.g. while the server is still starting,
+// rather than lost its source. It is relaunched a few times before the input
+// fails over or is reported as failed.
+const (
+	publishRetryWindow = 5 * time.Second
+	maxPublishRetries  = 3
+	publishRetryDelay  = 500 * time.Millisecond // multiplied by the attempt number
+)
+
+// retryPublishLocked schedules a relaunch of relay's input ffmpeg after it
+// exited with exitErr early in its life. It reports false, leaving relay
+// untouched, when the process ran past publishRetryWindow or the retries are
+// used up. relay.mu must be held.
+func (irm *InputRelayManager) retryPublishLocked(relay *InputRelay, exitErr error) bool {
+	if relay.Direct || time.Since(relay.launchedAt) > publishRetryWindow {
+		relay.publishRetries = 0
+		return false
+	}
+	if relay.publishRetries >= maxPublishRetries {
+		return false
+	}
+	relay.publishRetries++
+	delay := publishRetryDelay * time.Duration(relay.publishRetries)
+	irm.logFor(relay).Warn("InputRelayManager: input %s exited right after starting (%v), retrying in %v (attempt %d of %d)",
+		RedactURL(relay.InputURL), exitErr, delay, relay.publishRetries, maxPublishRetries)
+	relay.Proc = nil
+	relay.Status = InputStarting
+	relay.LastError = "input exited right after starting: " + exitErr.Error()
+	time.AfterFunc(delay, func() { irm.relaunchAfterRetry(relay) })
+	return true
+}
+
+// relaunchAfterRetry starts relay's input ffmpeg again unless the relay was
+// stopped, deleted or restarted while the retry was pending
+func (irm *InputRelayManager) relaunchAfterRetry(relay *InputRelay) {
+	relay.mu.Lock()
+	defer relay.mu.Unlock()
+	if relay.Proc != nil || relay.Status != InputStarting || relay.RefCount == 0 {
+		return
+	}
+	resolvedInputURL, err := irm.resolveInputURL(relay.InputURL)
+	if err == nil {
+		err = irm.launchLocked(relay, resolvedInputURL)
+	}
+	if err != nil {
+		relay.Status = InputError
+		relay.LastError = err.Error()
+		irm.logFor(relay).Error("InputRelayManager: failed to relaunch input %s: %v", RedactURL(relay.InputURL), err)
+	}
+}
@@ -5,7 +5,7 @@ import (
 	"fmt"
 	"go-mls/internal/logger"
 	"os"
-	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -20,8 +20,18 @@ const (
 	InputRunning
 	InputStopped
 	InputError
+	InputCompleted // A finite (file://) source reached EOF and ffmpeg exited cleanly
+	InputIdle      // Suspended because no consumer was delivering data; references are kept
 )
 
+// DefaultInputStabilization is how long an input must have been publishing to
+// the local RTSP server before it is reported Running.
+const DefaultInputStabilization = 500 * time.Millisecond
+
+// inputCompletionWait bounds how long an exiting output waits for its input
+// relay to report a clean EOF before treating the exit as a failure.
+const inputCompletionWait = 2 * time.Second
+
 // InputRelay represents a single input ffmpeg process and its state.
 //
 // Concurrency notes:
@@ -30,8 +40,10 @@ const (
 // - Mutable fields must be accessed with mu held.
 type InputRelay struct {
 	// --- Immutable after construction ---
-	InputURL  string // never changes
-	InputName string // never changes
+	InputURL  string         // never changes
+	InputName string         // never changes
+	ID        string         // never changes; correlation ID tagging this input's log lines
+	log       *logger.Logger // never changes; prefixed with ID
 
 	// --- Set-once at Start, then read-only ---
 	LocalURL string        // set at Start, then read-only
@@ -41,12 +53,99 @@ type InputRelay struct {
 	Proc      *FFmpegProcess   // may be replaced on restart, protected by mu
 	Status    InputRelayStatus // read/written by multiple goroutines, protected by mu
 	LastError string           // protected by mu
-	RefCount  int              // protected by mu
+	RefCount  int              // protected by mu; total of consumers
+	consumers map[string]int   // protected by mu; references held per consumer label
+	Direct    bool             // protected by mu; the only consumer reads the input URL itself, no ffmpeg/RTSP hop
+	inactive  map[string]bool  // protected by mu; consumers that reported they are not delivering data
+	idleTimer *time.Timer      // protected by mu; pending suspension while all consumers are inactive
+	onBackup  bool             // protected by mu; ffmpeg reads the input's backup source
+	failback  *time.Timer      // protected by mu; pending return to the primary source
+
+	launchedAt     time.Time // protected by mu; when the current input ffmpeg was started
+	releasedAt     time.Time // protected by mu; when the last consumer let go, or the relay was listed without one
+	publishRetries int       // protected by mu; relaunches after early exits, see retryPublishLocked
+
+	RestartCount int // protected by mu; launches of the input ffmpeg after the first
+	ErrorCount   int // protected by mu; failed launches and unintended error exits
 
 	// --- Concurrency primitives ---
 	mu sync.Mutex // protects all mutable fields above
 }
 
+// Consumer labels name who holds a reference on an input relay, so a refcount
+// that does not drop to zero can be traced back to its holders
+const hlsConsumer = "hls"
+
+func outputConsumer(outputURL string) string { return "output:" + outputURL }
+
+func recordingConsumer(id string) string { return "recording:" + id }
+
+// addConsumer takes a reference for consumer; relay.mu must be held
+func (r *InputRelay) addConsumer(consumer string) {
+	if r.consumers == nil {
+		r.consumers = make(map[string]int)
+	}
+	r.consumers[consumer]++
+	r.RefCount++
+	r.releasedAt = time.Time{}
+	// A new consumer counts as active
+	r.stopIdleTimer()
+}
+
+// removeConsumer releases a reference held by consumer and reports whether it
+// held one; relay.mu must be held
+func (r *InputRelay) removeConsumer(consumer string) bool {
+	if r.consumers[consumer] == 0 {
+		return false
+	}
+	if r.consumers[consumer]--; r.consumers[consumer] == 0 {
+		delete(r.consumers, consumer)
+		delete(r.inactive, consumer)
+	}
+	r.RefCount--
+	if r.RefCount == 0 {
+		r.stopIdleTimer()
+		r.releasedAt = time.Now()
+	}
+	return true
+}
+
+// allInactive reports whether the relay has consumers and none of them is
+// delivering data; relay.mu must be held
+func (r *InputRelay) allInactive() bool {
+	if r.RefCount == 0 {
+		return false
+	}
+	for consumer := range r.consumers {
+		if !r.inactive[consumer] {
+			return false
+		}
+	}
+	return true
+}
+
+// stopIdleTimer cancels a pending idle suspension; relay.mu must be held
+func (r *InputRelay) stopIdleTimer() {
+	if r.idleTimer != nil {
+		r.idleTimer.Stop()
+		r.idleTimer = nil
+	}
+}
+
+// consumerList returns the sorted labels of the current reference holders,
+// with a "xN" suffix for labels holding more than one; relay.mu must be held
+func (r *InputRelay) consumerList() []string {
+	list := make([]string, 0, len(r.consumers))
+	for consumer, n := range r.consumers {
+		if n > 1 {
+			consumer = fmt.Sprintf("%s x%d", consumer, n)
+		}
+		list = append(list, consumer)
+	}
+	sort.Strings(list)
+	return list
+}
+
 // InputRelayManager manages all input relays (input URL -> local RTSP server)
 //
 // Concurrency notes:
@@ -58,34 +157,131 @@ type InputRelayManager struct {
 	Logger     *logger.Logger         // immutable
 	recDir     string                 // immutable
 	rtspServer *RTSPServerManager     // set at construction or via SetRTSPServer
+
+	// configLookup returns per-input ffmpeg overrides; set once by RelayManager
+	configLookup func(inputName string) (InputConfig, bool)
+
+	stabilization time.Duration // set before relays are started via SetStartupStabilization
+	idleTimeout   time.Duration // set before relays are started via SetIdleTimeout; 0 never suspends
+	failbackAfter time.Duration // set before relays are started via SetFailbackInterval; 0 fails back manually only
+	buffering     BufferingMode // set before relays are started via RelayManager.SetBuffering; inputs may override
+	inputProfile  InputProfile  // set before relays are started via RelayManager.SetDefaultInputProfile; inputs may override
+
+	// onDirectPromoted is called (in its own goroutine) after a direct input got
+	// a second consumer and now publishes to localURL; set once by RelayManager
+	onDirectPromoted func(inputURL, inputName, localURL string)
 }
 
 func NewInputRelayManager(l *logger.Logger, recDir string) *InputRelayManager {
 	return &InputRelayManager{
-		Relays: make(map[string]*InputRelay),
-		Logger: l,
-		recDir: recDir,
+		Relays:        make(map[string]*InputRelay),
+		Logger:        l,
+		recDir:        recDir,
+		stabilization: DefaultInputStabilization,
+		buffering:     BufferingLowLatency,
 	}
 }
 
-// resolveInputURL checks if the inputURL is a file:// URL and returns the correct path for ffmpeg
+// isFiniteInput reports whether inputURL is a source that ends on its own
+// (a recording played back via file://) rather than a live stream.
+func isFiniteInput(inputURL string) bool {
+	return strings.HasPrefix(inputURL, "file://")
+}
+
+// resolveInputURL checks if the inputURL is a file:// URL and returns the correct path for ffmpeg.
+// testsrc:// URLs are validated and returned unchanged.
 func (irm *InputRelayManager) resolveInputURL(inputURL string) (string, error) {
+	if isTestPatternInput(inputURL) {
+		if _, err := parseTestPattern(inputURL); err != nil {
+			return "", err
+		}
+		return inputURL, nil
+	}
 	if strings.HasPrefix(inputURL, "file://") {
 		relative := strings.TrimPrefix(inputURL, "file://")
-		filePath := filepath.Join(irm.recDir, relative)
+		filePath, err := recordingFilePath(irm.recDir, relative)
+		if err != nil {
+			return "", err
+		}
 		if _, err := os.Stat(filePath); err != nil {
 			return "", err
 		}
-		irm.Logger.Debug("Resolved input URL: %s -> %s", inputURL, filePath)
+		irm.Logger.Debug("Resolved input URL: %s -> %s", RedactURL(inputURL), filePath)
 		return filePath, nil
 	}
 	return inputURL, nil
 }
 
+// buildInputRelayArgs returns the ffmpeg args that pull inputURL and publish it
+// to the local RTSP server. inputURL is passed through untouched so credentials
+// embedded in it reach ffmpeg exactly as configured. Probe overrides from cfg
+// win over those of the input profile, and ffmpeg's defaults are left when
+// neither sets one; the stable buffering mode also probes longer without one.
+// A testsrc:// input, already validated by resolveInputURL, is generated with
+// lavfi instead. A growing file is followed past its current end, see
+// growing_file.go.
+func buildInputRelayArgs(inputURL, localURL string, cfg InputConfig) []string {
+	if isTestPatternInput(inputURL) {
+		if p, err := parseTestPattern(inputURL); err == nil {
+			return append(p.inputArgs(), "-f", "rtsp", "-rtsp_transport", "tcp", "-progress", "pipe:1", localURL)
+		}
+	}
+	args := append([]string{"-re"}, bufferingArgs(cfg.Buffering)...)
+	var stableAnalyze string
+	if cfg.Buffering == BufferingStable {
+		stableAnalyze = stableAnalyzeDuration
+	}
+	profile := inputProfiles[cfg.InputProfile]
+	args = append(args, profile.probeArgs(cfg.AnalyzeDuration, cfg.ProbeSize, stableAnalyze)...)
+	args = append(args, profile.flagArgs(inputURL, true)...)
+	if cfg.GrowingFile {
+		args = append(args, growingFileArgs()...)
+	}
+	if isHTTPInput(inputURL) {
+		args = append(args, inputHeaderArgs(cfg.HTTPHeaders)...)
+	}
+	args = append(args, "-i", inputURL)
+	args = append(args, audioTrackMapArgs(cfg.AudioTrack)...)
+	return append(args, "-c", "copy", "-f", "rtsp", "-rtsp_transport", "tcp", "-progress", "pipe:1", localURL)
+}
+
+// newInputRelay creates a stopped relay with its own correlation ID
+func (irm *InputRelayManager) newInputRelay(inputName, inputURL string, timeout time.Duration) *InputRelay {
+	id := newCorrelationID()
+	return &InputRelay{
+		InputURL:   inputURL,
+		InputName:  inputName,
+		ID:         id,
+		log:        irm.Logger.WithPrefix("in:" + id),
+		Status:     InputStopped,
+		Timeout:    timeout,
+		releasedAt: time.Now(),
+	}
+}
+
+// addIdleInput lists a stopped relay for an input that has no consumers yet,
+// unless the input is already known
+func (irm *InputRelayManager) addIdleInput(inputName, inputURL string, timeout time.Duration) {
+	irm.mu.Lock()
+	defer irm.mu.Unlock()
+	if _, exists := irm.Relays[inputURL]; !exists {
+		irm.Relays[inputURL] = irm.newInputRelay(inputName, inputURL, timeout)
+	}
+}
+
+// logFor returns relay's correlation-ID logger, or the manager's for relays
+// that were not created by newInputRelay
+func (irm *InputRelayManager) logFor(relay *InputRelay) *logger.Logger {
+	if relay.log != nil {
+		return relay.log
+	}
+	return irm.Logger
+}
+
 // StartInputRelay starts the input relay process if not running, returns local RTSP URL
-// Increments reference count for each consumer
-func (irm *InputRelayManager) StartInputRelay(inputName, inputURL, localURL string, timeout time.Duration) (string, error) {
-	irm.Logger.Info("InputRelayManager: StartInputRelay: inputName=%s, inputURL=%s", inputName, inputURL)
+// Takes a reference for consumer, which must release it with StopInputRelay
+func (irm *InputRelayManager) StartInputRelay(inputName, inputURL, localURL string, timeout time.Duration, consumer string) (string, error) {
+	irm.Logger.Info("InputRelayManager: StartInputRelay: inputName=%s, inputURL=%s", inputName, RedactURL(inputURL))
 	// Resolve input URL (handle file://)
 	resolvedInputURL, err := irm.resolveInputURL(inputURL)
 	if err != nil {
@@ -95,92 +291,319 @@ func (irm *InputRelayManager) StartInputRelay(inputName, inputURL, localURL stri
 	irm.mu.Lock()
 	relay, exists := irm.Relays[inputURL]
 	if !exists {
-		relay = &InputRelay{
-			InputURL:  inputURL,
-			InputName: inputName,
-			LocalURL:  localURL,
-			Status:    InputStopped,
-			Timeout:   timeout,
-			RefCount:  0,
-		}
+		relay = irm.newInputRelay(inputName, inputURL, timeout)
+		relay.LocalURL = localURL
 		irm.Relays[inputURL] = relay
 	}
+	log := irm.logFor(relay)
 	relay.mu.Lock()
 	// Increment reference count
-	relay.RefCount++
+	relay.addConsumer(consumer)
 	currentRefCount := relay.RefCount // Capture while holding lock
-	irm.Logger.Debug("InputRelayManager: Incremented refcount for %s to %d", inputURL, currentRefCount)
+	log.Debug("InputRelayManager: %s took a reference on %s (refcount: %d)", consumer, RedactURL(inputURL), currentRefCount)
+	// A second consumer of a direct input needs the RTSP hub after all
+	promoted := relay.Direct
+	if promoted {
+		log.Info("InputRelayManager: input %s gained a second consumer, switching from direct to RTSP relay", RedactURL(inputURL))
+		relay.Direct = false
+		relay.Status = InputStopped
+	}
 	if relay.Status == InputStarting || relay.Status == InputRunning {
 		local := relay.LocalURL
 		relay.mu.Unlock()
 		irm.mu.Unlock()
-		irm.Logger.Debug("InputRelayManager: Reusing existing relay for %s (refcount: %d)", inputURL, currentRefCount)
+		log.Debug("InputRelayManager: Reusing existing relay for %s (refcount: %d)", RedactURL(inputURL), currentRefCount)
 		return local, nil
 	}
 	relay.Status = InputStarting
 	relay.LocalURL = localURL
-	ctx := context.Background() // Use background context for now; can be enhanced for cancellation
-	proc, err := NewFFmpegProcess(ctx, "-re", "-i", resolvedInputURL, "-c", "copy", "-f", "rtsp", "-rtsp_transport", "tcp", "-progress", "pipe:1", localURL)
-	if err != nil {
+	// A fresh start tries the primary source again
+	relay.onBackup = false
+	relay.stopFailbackTimer()
+	relay.publishRetries = 0
+	if err := irm.launchLocked(relay, resolvedInputURL); err != nil {
 		relay.Status = InputError
 		relay.LastError = err.Error()
-		relay.RefCount-- // Decrement on failure
+		relay.removeConsumer(consumer) // Release on failure
+		if promoted {
+			// The direct consumer is unaffected and keeps reading the input itself
+			relay.Direct = true
+			relay.Status = InputRunning
+		}
 		relay.mu.Unlock()
 		irm.mu.Unlock()
-		irm.Logger.Error("Failed to create input relay ffmpeg process: %v", err)
+		log.Error("Failed to start input relay ffmpeg: %v", err)
 		return "", err
 	}
+	// Stay Starting until the stream is actually being published
+	log.Info("InputRelayManager: Started ffmpeg process PID %d for %s -> %s (refcount: %d)", relay.Proc.PID, RedactURL(inputURL), localURL, currentRefCount)
+	local := relay.LocalURL
+	if promoted && irm.onDirectPromoted != nil {
+		go irm.onDirectPromoted(inputURL, inputName, local)
+	}
+	relay.mu.Unlock()
+	irm.mu.Unlock()
+	return local, nil
+}
+
+// withDefaults fills in the manager's buffering and input profile where cfg
+// has none
+func (irm *InputRelayManager) withDefaults(cfg InputConfig) InputConfig {
+	if cfg.Buffering == "" {
+		cfg.Buffering = irm.buffering
+	}
+	if cfg.InputProfile == "" {
+		cfg.InputProfile = irm.inputProfile
+	}
+	return cfg
+}
+
+// launchLocked starts the input ffmpeg for relay, publishing to relay.LocalURL,
+// and its monitor goroutines. resolvedInputURL is replaced by the backup
+// source while relay is failed over. relay.mu must be held.
+func (irm *InputRelayManager) launchLocked(relay *InputRelay, resolvedInputURL string) (err error) {
+	if !relay.launchedAt.IsZero() {
+		relay.RestartCount++
+	}
+	defer func() {
+		if err != nil {
+			relay.ErrorCount++
+		}
+	}()
+	var inputCfg InputConfig
+	if irm.configLookup != nil {
+		inputCfg, _ = irm.configLookup(relay.InputName)
+	}
+	inputCfg = irm.withDefaults(inputCfg)
+	if relay.onBackup && inputCfg.BackupURL == "" {
+		// The backup was removed since failing over
+		relay.onBackup = false
+		relay.stopFailbackTimer()
+	}
+	if relay.onBackup {
+		if !isFiniteInput(inputCfg.BackupURL) {
+			inputCfg.GrowingFile = false // only the primary source is a growing file
+		}
+		inputCfg.HTTPHeaders = nil // its headers are not for another host
+		resolved, err := irm.resolveInputURL(inputCfg.BackupURL)
+		if err != nil {
+			return err
+		}
+		resolvedInputURL = resolved
+	}
+	proc, err := NewFFmpegProcess(context.Background(), buildInputRelayArgs(resolvedInputURL, relay.LocalURL, inputCfg)...)
+	if err != nil {
+		return err
+	}
 	relay.Proc = proc
-	err = proc.Start()
+	relay.launchedAt = time.Now()
+	if err := proc.Start(); err != nil {
+		return err
+	}
+	go irm.RunInputRelay(relay)
+	go irm.awaitInputReady(relay, proc)
+	return nil
+}
+
+// SetIdleTimeout sets how long all consumers of a running input may report
+// that they are not delivering data before the input ffmpeg is suspended;
+// 0 disables suspension
+func (irm *InputRelayManager) SetIdleTimeout(d time.Duration) {
+	irm.idleTimeout = d
+}
+
+// SetConsumerActive records whether consumer is currently delivering the data
+// it reads from the input. Once every consumer of a running input has been
+// inactive for the idle timeout the input ffmpeg is suspended (InputIdle) with
+// its references kept; it is restarted as soon as a consumer becomes active
+// again or a new one takes a reference.
+func (irm *InputRelayManager) SetConsumerActive(inputURL, consumer string, active bool) {
+	irm.mu.Lock()
+	relay, exists := irm.Relays[inputURL]
+	irm.mu.Unlock()
+	if !exists {
+		return
+	}
+	relay.mu.Lock()
+	defer relay.mu.Unlock()
+	if relay.consumers[consumer] == 0 || relay.inactive[consumer] == !active {
+		return
+	}
+	if active {
+		delete(relay.inactive, consumer)
+		relay.stopIdleTimer()
+		if relay.Status == InputIdle {
+			irm.resumeLocked(relay)
+		}
+		return
+	}
+	if relay.inactive == nil {
+		relay.inactive = make(map[string]bool)
+	}
+	relay.inactive[consumer] = true
+	irm.armIdleTimerLocked(relay)
+}
+
+// armIdleTimerLocked schedules suspension of relay if all its consumers are
+// inactive; relay.mu must be held
+func (irm *InputRelayManager) armIdleTimerLocked(relay *InputRelay) {
+	if irm.idleTimeout <= 0 || relay.idleTimer != nil || relay.Status != InputRunning || relay.Direct || !relay.allInactive() {
+		return
+	}
+	irm.logFor(relay).Debug("InputRelayManager: no consumer of %s is delivering data, suspending in %v", RedactURL(relay.InputURL), irm.idleTimeout)
+	relay.idleTimer = time.AfterFunc(irm.idleTimeout, func() { irm.suspendIfIdle(relay) })
+}
+
+// suspendIfIdle stops the input ffmpeg of relay if its consumers are still
+// all inactive, keeping their references
+func (irm *InputRelayManager) suspendIfIdle(relay *InputRelay) {
+	log := irm.logFor(relay)
+	relay.mu.Lock()
+	relay.idleTimer = nil
+	if relay.Status != InputRunning || relay.Direct || !relay.allInactive() {
+		relay.mu.Unlock()
+		return
+	}
+	proc := relay.Proc
+	relay.Proc = nil
+	relay.Status = InputIdle
+	consumers := relay.consumerList()
+	relay.mu.Unlock()
+
+	log.Info("InputRelayManager: suspending idle input %s (consumers: %v)", RedactURL(relay.InputURL), consumers)
+	if proc != nil {
+		if err := proc.Stop(2 * time.Second); err != nil {
+			log.Warn("InputRelayManager: Error stopping idle ffmpeg process for %s: %v", RedactURL(relay.InputURL), err)
+		}
+	}
+}
+
+// resumeLocked restarts the input ffmpeg of a suspended relay; relay.mu must be held
+func (irm *InputRelayManager) resumeLocked(relay *InputRelay) {
+	log := irm.logFor(relay)
+	resolvedInputURL, err := irm.resolveInputURL(relay.InputURL)
+	if err == nil {
+		relay.Status = InputStarting
+		err = irm.launchLocked(relay, resolvedInputURL)
+	}
 	if err != nil {
 		relay.Status = InputError
 		relay.LastError = err.Error()
-		relay.RefCount-- // Decrement on failure
-		relay.mu.Unlock()
-		irm.mu.Unlock()
-		irm.Logger.Error("Failed to start input relay ffmpeg: %v", err)
-		return "", err
+		log.Error("InputRelayManager: failed to resume idle input %s: %v", RedactURL(relay.InputURL), err)
+		return
 	}
+	log.Info("InputRelayManager: resumed idle input %s (PID %d)", RedactURL(relay.InputURL), relay.Proc.PID)
+}
+
+// StartDirectInput registers a single consumer that reads inputURL itself,
+// skipping the input ffmpeg and local RTSP hop. It only succeeds when the input
+// has no other consumers; localURL is kept for switching to the RTSP relay as
+// soon as a second consumer calls StartInputRelay.
+func (irm *InputRelayManager) StartDirectInput(inputName, inputURL, localURL string, timeout time.Duration, consumer string) bool {
+	irm.mu.Lock()
+	defer irm.mu.Unlock()
+	relay, exists := irm.Relays[inputURL]
+	if !exists {
+		relay = irm.newInputRelay(inputName, inputURL, timeout)
+		irm.Relays[inputURL] = relay
+	}
+	relay.mu.Lock()
+	defer relay.mu.Unlock()
+	if relay.RefCount > 0 || relay.Status == InputStarting || relay.Status == InputRunning {
+		return false
+	}
+	relay.addConsumer(consumer)
+	relay.Direct = true
+	relay.LocalURL = localURL
 	relay.Status = InputRunning
-	irm.Logger.Info("InputRelayManager: Started ffmpeg process PID %d for %s -> %s (refcount: %d)", proc.PID, inputURL, localURL, currentRefCount)
-	// Start process wait/monitor goroutine
-	go irm.RunInputRelay(relay)
-	local := relay.LocalURL
-	relay.mu.Unlock()
-	irm.mu.Unlock()
-	return local, nil
+	relay.LastError = ""
+	irm.logFor(relay).Info("InputRelayManager: input %s relayed directly (no RTSP hop)", RedactURL(inputURL))
+	return true
+}
+
+// SetStartupStabilization sets how long a new input must publish to the local
+// RTSP server before it is marked Running
+func (irm *InputRelayManager) SetStartupStabilization(d time.Duration) {
+	irm.stabilization = d
+}
+
+// awaitInputReady flips relay from Starting to Running once its stream is being
+// published on the local RTSP server and has stayed up for the stabilization
+// period. Without an RTSP server only the stabilization period applies. If the
+// stream does not appear within the relay timeout, the relay stays Starting.
+func (irm *InputRelayManager) awaitInputReady(relay *InputRelay, proc *FFmpegProcess) {
+	log := irm.logFor(relay)
+	relayPath := "relay/" + relay.InputName
+	timeout := relay.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	deadline := time.Now().Add(timeout)
+	for irm.rtspServer != nil && !irm.rtspServer.IsStreamPublishing(relayPath) {
+		if time.Now().After(deadline) {
+			log.Warn("InputRelayManager: stream %s not published within %v, input %s still starting", relayPath, timeout, RedactURL(relay.InputURL))
+			return
+		}
+		select {
+		case <-proc.Done():
+			return
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	// Let the publisher settle before reporting it as serving
+	select {
+	case <-proc.Done():
+		return
+	case <-time.After(irm.stabilization):
+	}
+
+	relay.mu.Lock()
+	defer relay.mu.Unlock()
+	if relay.Proc == proc && relay.Status == InputStarting {
+		relay.Status = InputRunning
+		log.Debug("InputRelayManager: input %s is now running", RedactURL(relay.InputURL))
+		irm.armIdleTimerLocked(relay)
+	}
 }
 
-// StopInputRelay decrements reference count and stops the input relay process only when refcount reaches 0
+// StopInputRelay releases consumer's reference and stops the input relay process only when refcount reaches 0
 // This implements a reference counting mechanism to handle multiple consumers (recordings + output relays)
 // Returns true if the relay was actually stopped (refcount reached 0)
-func (irm *InputRelayManager) StopInputRelay(inputURL string) bool {
-	irm.Logger.Info("InputRelayManager: StopInputRelay: inputURL=%s", inputURL)
+func (irm *InputRelayManager) StopInputRelay(inputURL, consumer string) bool {
+	irm.Logger.Info("InputRelayManager: StopInputRelay: inputURL=%s, consumer=%s", RedactURL(inputURL), consumer)
 	irm.mu.Lock()
 	relay, exists := irm.Relays[inputURL]
 	if !exists {
-		irm.Logger.Warn("InputRelayManager: relay for %s not found", inputURL)
+		irm.Logger.Warn("InputRelayManager: relay for %s not found", RedactURL(inputURL))
 		irm.mu.Unlock()
 		return false
 	}
+	log := irm.logFor(relay)
 	relay.mu.Lock()
 	shouldStop := false
 	var proc *FFmpegProcess
-	if relay.RefCount > 0 {
-		relay.RefCount--
+	if relay.removeConsumer(consumer) {
 		currentRefCount := relay.RefCount
-		irm.Logger.Debug("InputRelayManager: Decremented refcount for %s to %d", inputURL, currentRefCount)
+		log.Debug("InputRelayManager: %s released its reference on %s (refcount: %d)", consumer, RedactURL(inputURL), currentRefCount)
+		// The remaining consumers may all be idle
+		irm.armIdleTimerLocked(relay)
 	} else {
-		irm.Logger.Warn("InputRelayManager: refcount for %s is already 0, cannot decrement", inputURL)
+		log.Warn("InputRelayManager: %s holds no reference on %s, cannot release (consumers: %v)", consumer, RedactURL(inputURL), relay.consumerList())
 		relay.mu.Unlock()
 		irm.mu.Unlock()
 		return false
 	}
 	if relay.RefCount == 0 {
 		shouldStop = true
+		relay.stopFailbackTimer()
 		proc = relay.Proc
 		relay.Proc = nil
-		relay.Status = InputStopped
+		relay.Direct = false
+		// Keep Completed visible after the last consumer lets go of a finished file
+		if relay.Status != InputCompleted {
+			relay.Status = InputStopped
+		}
 	}
 	inputName := relay.InputName
 	relay.mu.Unlock()
@@ -189,13 +612,13 @@ func (irm *InputRelayManager) StopInputRelay(inputURL string) bool {
 	if shouldStop && proc != nil {
 		err := proc.Stop(2 * time.Second)
 		if err != nil {
-			irm.Logger.Warn("InputRelayManager: Error stopping ffmpeg process for %s: %v", inputURL, err)
+			log.Warn("InputRelayManager: Error stopping ffmpeg process for %s: %v", RedactURL(inputURL), err)
 		}
 	}
 	// Clean up RTSP stream when input relay is fully stopped
 	if shouldStop && irm.rtspServer != nil && inputName != "" {
 		relayPath := "relay/" + inputName
-		irm.Logger.Debug("InputRelayManager: Cleaning up RTSP stream for stopped input relay: %s", relayPath)
+		log.Debug("InputRelayManager: Cleaning up RTSP stream for stopped input relay: %s", relayPath)
 		irm.rtspServer.RemoveStream(relayPath)
 	}
 	// Do NOT delete relay from map here. Deletion is only performed by explicit user action (DeleteInput).
@@ -206,20 +629,26 @@ func (irm *InputRelayManager) StopInputRelay(inputURL string) bool {
 // ForceStopInputRelay forcefully stops an input relay without regard to reference count
 // This should only be used during shutdown or when there are refcount inconsistencies
 func (irm *InputRelayManager) ForceStopInputRelay(inputURL string) bool {
-	irm.Logger.Warn("InputRelayManager: ForceStopInputRelay: inputURL=%s (ignoring refcount)", inputURL)
+	irm.Logger.Warn("InputRelayManager: ForceStopInputRelay: inputURL=%s (ignoring refcount)", RedactURL(inputURL))
 	irm.mu.Lock()
 	relay, exists := irm.Relays[inputURL]
 	if !exists {
-		irm.Logger.Warn("InputRelayManager: relay for %s not found", inputURL)
+		irm.Logger.Warn("InputRelayManager: relay for %s not found", RedactURL(inputURL))
 		irm.mu.Unlock()
 		return false
 	}
+	log := irm.logFor(relay)
 	relay.mu.Lock()
-	currentRefCount := relay.RefCount
-	irm.Logger.Warn("InputRelayManager: Force stopping relay %s (previous refcount: %d)", inputURL, currentRefCount)
+	log.Warn("InputRelayManager: Force stopping relay %s (previous consumers: %v)", RedactURL(inputURL), relay.consumerList())
 	proc := relay.Proc
 	relay.RefCount = 0
+	relay.releasedAt = time.Now()
+	relay.consumers = nil
+	relay.inactive = nil
+	relay.stopIdleTimer()
+	relay.stopFailbackTimer()
 	relay.Proc = nil
+	relay.Direct = false
 	relay.Status = InputStopped
 	inputName := relay.InputName
 	relay.mu.Unlock()
@@ -228,13 +657,13 @@ func (irm *InputRelayManager) ForceStopInputRelay(inputURL string) bool {
 	if proc != nil {
 		err := proc.Stop(1 * time.Second)
 		if err != nil {
-			irm.Logger.Warn("InputRelayManager: Error force stopping ffmpeg process for %s: %v", inputURL, err)
+			log.Warn("InputRelayManager: Error force stopping ffmpeg process for %s: %v", RedactURL(inputURL), err)
 		}
 	}
 	// Clean up RTSP stream when input relay is fully stopped
 	if irm.rtspServer != nil && inputName != "" {
 		relayPath := "relay/" + inputName
-		irm.Logger.Debug("InputRelayManager: Cleaning up RTSP stream for force-stopped input relay: %s", relayPath)
+		log.Debug("InputRelayManager: Cleaning up RTSP stream for force-stopped input relay: %s", relayPath)
 		irm.rtspServer.RemoveStream(relayPath)
 	}
 	return true
@@ -242,13 +671,14 @@ func (irm *InputRelayManager) ForceStopInputRelay(inputURL string) bool {
 
 // RunInputRelay runs and monitors the input relay process
 func (irm *InputRelayManager) RunInputRelay(relay *InputRelay) {
-	irm.Logger.Info("InputRelayManager: RunInputRelay: running ffmpeg for %s -> %s", relay.InputURL, relay.LocalURL)
+	log := irm.logFor(relay)
+	log.Info("InputRelayManager: RunInputRelay: running ffmpeg for %s -> %s", RedactURL(relay.InputURL), relay.LocalURL)
 	var proc *FFmpegProcess
 	relay.mu.Lock()
 	proc = relay.Proc
 	relay.mu.Unlock()
 	if proc == nil {
-		irm.Logger.Error("InputRelayManager: RunInputRelay: FFmpegProcess is nil for %s", relay.InputURL)
+		log.Error("InputRelayManager: RunInputRelay: FFmpegProcess is nil for %s", RedactURL(relay.InputURL))
 		return
 	}
 	err := proc.Wait()
@@ -257,7 +687,21 @@ func (irm *InputRelayManager) RunInputRelay(relay *InputRelay) {
 	relay.mu.Lock()
 	status := relay.Status
 	inputURL := relay.InputURL
+	if relay.Proc != proc && (relay.Proc != nil || status == InputIdle || status == InputStarting) {
+		// Suspended while idle, switching source, or already replaced by a newer process
+		relay.mu.Unlock()
+		log.Info("Input relay process for %s exited (PID=%d), relay was suspended or restarted", RedactURL(inputURL), proc.PID)
+		return
+	}
 	intentional := relay.RefCount == 0 // If refcount is 0, this was an intentional stop
+	if err != nil && !intentional {
+		relay.ErrorCount++
+	}
+	if err != nil && !intentional && (irm.retryPublishLocked(relay, err) || irm.failOverLocked(relay, err)) {
+		relay.mu.Unlock()
+		log.Error("[ffmpeg output] for %s:\n%s", RedactURL(inputURL), redactOutput(output, inputURL))
+		return
+	}
 	if err != nil {
 		if intentional {
 			relay.Status = InputStopped
@@ -267,25 +711,63 @@ func (irm *InputRelayManager) RunInputRelay(relay *InputRelay) {
 			relay.LastError = err.Error()
 		}
 	}
+	completed := false
 	if err == nil {
 		relay.Status = InputStopped
+		if !intentional && isFiniteInput(inputURL) {
+			relay.Status = InputCompleted
+			completed = true
+		}
 	}
 	relay.Proc = nil
 	relay.mu.Unlock()
 
+	if completed {
+		log.Info("Input relay for %s reached end of file (PID=%d)", RedactURL(inputURL), proc.PID)
+		return
+	}
 	if status == InputStopped {
 		if err != nil {
-			irm.Logger.Info("Input relay for %s stopped (signal: %v)", inputURL, err)
+			log.Info("Input relay for %s stopped (signal: %v)", RedactURL(inputURL), err)
 		} else {
-			irm.Logger.Info("Input relay for %s stopped cleanly", inputURL)
+			log.Info("Input relay for %s stopped cleanly", RedactURL(inputURL))
 		}
 		return
 	}
 	if err != nil {
-		irm.Logger.Error("Input relay process exited with error for %s (PID=%d): %v", inputURL, proc.PID, err)
-		irm.Logger.Error("[ffmpeg output] for %s:\n%s", inputURL, output)
+		log.Error("Input relay process exited with error for %s (PID=%d): %v", RedactURL(inputURL), proc.PID, err)
+		log.Error("[ffmpeg output] for %s:\n%s", RedactURL(inputURL), redactOutput(output, inputURL))
 	} else {
-		irm.Logger.Info("Input relay process for %s completed successfully (PID=%d)", inputURL, proc.PID)
+		log.Info("Input relay process for %s completed successfully (PID=%d)", RedactURL(inputURL), proc.PID)
+	}
+}
+
+// WaitForCompletion reports whether the input relay for inputURL finished a
+// finite source cleanly, waiting up to wait for its ffmpeg exit to be observed.
+// Outputs reading from the relay usually notice the end of stream at about the
+// same time the input process exits, so a short wait avoids misreporting them.
+func (irm *InputRelayManager) WaitForCompletion(inputURL string, wait time.Duration) bool {
+	if !isFiniteInput(inputURL) {
+		return false
+	}
+	irm.mu.Lock()
+	relay, exists := irm.Relays[inputURL]
+	irm.mu.Unlock()
+	if !exists {
+		return false
+	}
+	deadline := time.Now().Add(wait)
+	for {
+		relay.mu.Lock()
+		status := relay.Status
+		relay.mu.Unlock()
+		if status == InputCompleted {
+			return true
+		}
+		if (status != InputRunning && status != InputStarting) || time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(50 * time.Millisecond)
 	}
 }
 
@@ -319,18 +801,21 @@ func (irm *InputRelayManager) FindLocalURLByInputName(inputName string) (string,
 
 // DeleteInput completely removes an input relay and all associated outputs
 func (irm *InputRelayManager) DeleteInput(inputURL string) error {
-	irm.Logger.Info("InputRelayManager: DeleteInput: inputURL=%s", inputURL)
+	irm.Logger.Info("InputRelayManager: DeleteInput: inputURL=%s", RedactURL(inputURL))
 	irm.mu.Lock()
 	relay, exists := irm.Relays[inputURL]
 	if !exists {
-		irm.Logger.Warn("InputRelayManager: relay for %s not found", inputURL)
+		irm.Logger.Warn("InputRelayManager: relay for %s not found", RedactURL(inputURL))
 		irm.mu.Unlock()
-		return fmt.Errorf("input relay not found: %s", inputURL)
+		return fmt.Errorf("input relay not found: %s", RedactURL(inputURL))
 	}
+	log := irm.logFor(relay)
 	relay.mu.Lock()
 	proc := relay.Proc
 	relay.Proc = nil
 	relay.Status = InputStopped
+	relay.onBackup = false
+	relay.stopFailbackTimer()
 	inputName := relay.InputName
 	relay.mu.Unlock()
 	// Remove from map before stopping process
@@ -341,16 +826,16 @@ func (irm *InputRelayManager) DeleteInput(inputURL string) error {
 	if proc != nil {
 		err := proc.Stop(1 * time.Second)
 		if err != nil {
-			irm.Logger.Warn("InputRelayManager: Error deleting ffmpeg process for %s: %v", inputURL, err)
+			log.Warn("InputRelayManager: Error deleting ffmpeg process for %s: %v", RedactURL(inputURL), err)
 		}
 	}
 
 	// Clean up RTSP stream
 	if irm.rtspServer != nil && inputName != "" {
 		relayPath := "relay/" + inputName
-		irm.Logger.Debug("InputRelayManager: Cleaning up RTSP stream for deleted input relay: %s", relayPath)
+		log.Debug("InputRelayManager: Cleaning up RTSP stream for deleted input relay: %s", relayPath)
 		irm.rtspServer.RemoveStream(relayPath)
 	}
-	irm.Logger.Info("InputRelayManager: Input relay %s deleted successfully", inputURL)
+	log.Info("InputRelayManager: Input relay %s deleted successfully", RedactURL(inputURL))
 	return nil
 }
@@ -0,0 +1,110 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidTestPattern is returned for a testsrc:// input URL with an unknown
+// pattern or out-of-range parameters
+var ErrInvalidTestPattern = errors.New("invalid test pattern input")
+
+// testPatternScheme marks a synthetic input that ffmpeg generates itself, e.g.
+// testsrc://smptebars?size=640x360&rate=25&tone=440. It needs no external
+// source, so it can be used to check the RTSP, HLS, recording and output
+// plumbing end to end.
+const testPatternScheme = "testsrc://"
+
+// testPatternSources are the lavfi video sources accepted as the URL host
+var testPatternSources = map[string]bool{
+	"testsrc":     true,
+	"testsrc2":    true,
+	"smptebars":   true,
+	"smptehdbars": true,
+	"rgbtestsrc":  true,
+}
+
+// testPattern is a parsed testsrc:// input URL
+type testPattern struct {
+	source string // lavfi video source
+	width  int
+	height int
+	rate   int // frames per second
+	tone   int // sine frequency in Hz; 0 leaves the stream without audio
+}
+
+// isTestPatternInput reports whether inputURL is a synthetic testsrc:// input
+func isTestPatternInput(inputURL string) bool {
+	return strings.HasPrefix(inputURL, testPatternScheme)
+}
+
+// parseTestPattern validates a testsrc:// URL. The pattern defaults to
+// testsrc, size to 1280x720, rate to 30 fps and tone to 1000 Hz.
+func parseTestPattern(inputURL string) (testPattern, error) {
+	p := testPattern{source: "testsrc", width: 1280, height: 720, rate: 30, tone: 1000}
+	u, err := url.Parse(inputURL)
+	if err != nil {
+		return p, fmt.Errorf("%w: %v", ErrInvalidTestPattern, err)
+	}
+	if u.Host != "" {
+		if !testPatternSources[u.Host] {
+			return p, fmt.Errorf("%w: unknown pattern %q", ErrInvalidTestPattern, u.Host)
+		}
+		p.source = u.Host
+	}
+	if u.Path != "" && u.Path != "/" {
+		return p, fmt.Errorf("%w: unexpected path %q", ErrInvalidTestPattern, u.Path)
+	}
+	query := u.Query()
+	for key := range query {
+		switch key {
+		case "size", "rate", "tone":
+		default:
+			return p, fmt.Errorf("%w: unknown parameter %q", ErrInvalidTestPattern, key)
+		}
+	}
+	if size := query.Get("size"); size != "" {
+		w, h, ok := strings.Cut(size, "x")
+		width, errW := strconv.Atoi(w)
+		height, errH := strconv.Atoi(h)
+		// libx264 with yuv420p needs even dimensions
+		if !ok || errW != nil || errH != nil || width < 16 || height < 16 ||
+			width > 3840 || height > 2160 || width%2 != 0 || height%2 != 0 {
+			return p, fmt.Errorf("%w: size must be WIDTHxHEIGHT with even values from 16x16 to 3840x2160, got %q", ErrInvalidTestPattern, size)
+		}
+		p.width, p.height = width, height
+	}
+	if rate := query.Get("rate"); rate != "" {
+		n, err := strconv.Atoi(rate)
+		if err != nil || n < 1 || n > 60 {
+			return p, fmt.Errorf("%w: rate must be 1-60, got %q", ErrInvalidTestPattern, rate)
+		}
+		p.rate = n
+	}
+	if tone := query.Get("tone"); tone != "" {
+		n, err := strconv.Atoi(tone)
+		if err != nil || (n != 0 && (n < 20 || n > 20000)) {
+			return p, fmt.Errorf("%w: tone must be 0 or 20-20000 Hz, got %q", ErrInvalidTestPattern, tone)
+		}
+		p.tone = n
+	}
+	return p, nil
+}
+
+// inputArgs returns the ffmpeg args that generate the pattern and encode it
+// for publishing; raw lavfi frames cannot be stream-copied like a real input
+func (p testPattern) inputArgs() []string {
+	args := []string{"-re", "-f", "lavfi", "-i", fmt.Sprintf("%s=size=%dx%d:rate=%d", p.source, p.width, p.height, p.rate)}
+	if p.tone > 0 {
+		args = append(args, "-f", "lavfi", "-i", fmt.Sprintf("sine=frequency=%d:sample_rate=48000", p.tone))
+	}
+	args = append(args, "-c:v", "libx264", "-preset", "ultrafast", "-tune", "zerolatency",
+		"-pix_fmt", "yuv420p", "-g", strconv.Itoa(p.rate*2))
+	if p.tone > 0 {
+		args = append(args, "-c:a", "aac", "-b:a", "128k")
+	}
+	return args
+}
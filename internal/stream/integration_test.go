@@ -0,0 +1,83 @@
+package stream
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"go-mls/internal/logger"
+)
+
+// TestIntegration_RelayFullPath runs the whole relay path with a real ffmpeg:
+// testsrc.mp4 is published by an input relay to the local RTSP server and
+// pushed by an output relay to a discarding sink. It is skipped where ffmpeg
+// is not installed.
+func TestIntegration_RelayFullPath(t *testing.T) {
+	if testing.Short() {
+		t.Skip("integration test")
+	}
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		t.Skip("ffmpeg not available")
+	}
+	baseline := runtime.NumGoroutine()
+
+	recDir := t.TempDir()
+	src, err := os.ReadFile(filepath.Join("..", "..", "testdata", "testsrc.mp4"))
+	if err != nil {
+		t.Fatalf("failed to read test source: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(recDir, "testsrc.mp4"), src, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	log := logger.NewLoggerWithWriter(io.Discard)
+	rtspServer := NewRTSPServerManager(log)
+	if err := rtspServer.Start(); err != nil {
+		t.Fatalf("failed to start RTSP server: %v", err)
+	}
+	rm := NewRelayManager(log, recDir)
+	rm.SetRTSPServer(rtspServer)
+
+	// The output format is always flv; the null device stands in for a real server
+	inputURL := "file://testsrc.mp4"
+	if _, err := rm.StartRelayWithOptions(inputURL, os.DevNull, "itest", "sink", nil, ""); err != nil {
+		rm.StopAllRelays()
+		rtspServer.Stop()
+		t.Fatalf("StartRelayWithOptions failed: %v", err)
+	}
+
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		status := rm.StatusV2()
+		if len(status.Relays) == 1 && len(status.Relays[0].Outputs) == 1 {
+			in, out := status.Relays[0].Input, status.Relays[0].Outputs[0]
+			if in.Status == "Running" && in.Speed > 0 && out.Status == "Running" {
+				break
+			}
+		}
+		if time.Now().After(deadline) {
+			rm.StopAllRelays()
+			rtspServer.Stop()
+			t.Fatalf("relay did not reach Running with a nonzero input speed: %+v", status.Relays)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+
+	rm.StopAllRelays()
+	rtspServer.Stop()
+
+	// Process monitors, progress readers and RTSP sessions must all have exited
+	deadline = time.Now().Add(10 * time.Second)
+	for runtime.NumGoroutine() > baseline {
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<20)
+			n := runtime.Stack(buf, true)
+			t.Fatalf("goroutine leak: %d running, %d before the test\n%s", runtime.NumGoroutine(), baseline, buf[:n])
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
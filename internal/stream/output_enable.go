@@ -0,0 +1,60 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+)
+
+// An output can be disabled to keep it configured without running it. Unlike
+// stopping, which only lasts until the next start, a disabled output is
+// exported with "enabled": false, listed but not started on import, and
+// refused by StartRelayWithOptions until it is enabled again.
+
+// ErrOutputDisabled is returned when starting a disabled output
+var ErrOutputDisabled = errors.New("output is disabled")
+
+// ErrOutputNotFound is returned when enabling or disabling an unknown output
+var ErrOutputNotFound = errors.New("output not found")
+
+func (rm *RelayManager) outputEnabled(outputURL string) bool {
+	rm.configMu.RLock()
+	defer rm.configMu.RUnlock()
+	return !rm.disabledOutputs[outputURL]
+}
+
+func (rm *RelayManager) setOutputEnabled(outputURL string, enabled bool) {
+	rm.configMu.Lock()
+	defer rm.configMu.Unlock()
+	if enabled {
+		delete(rm.disabledOutputs, outputURL)
+		return
+	}
+	rm.disabledOutputs[outputURL] = true
+}
+
+// EnableOutput enables a disabled output and starts it with its stored preset
+// and ffmpeg options. Enabling an enabled output just starts it.
+func (rm *RelayManager) EnableOutput(inputURL, outputURL, inputName, outputName string) error {
+	preset, opts, err := rm.GetEndpointConfig(inputURL, outputURL)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrOutputNotFound, outputName)
+	}
+	rm.setOutputEnabled(outputURL, true)
+	rm.Logger.Info("Enabled output %s [%s]", outputName, RedactURL(outputURL))
+	_, err = rm.StartRelayWithOptions(inputURL, outputURL, inputName, outputName, opts, preset)
+	return err
+}
+
+// DisableOutput stops an output and keeps it from being started, by imports
+// too, until EnableOutput. Its configuration is kept.
+func (rm *RelayManager) DisableOutput(inputURL, outputURL, inputName, outputName string) error {
+	rm.OutputRelays.mu.Lock()
+	out, exists := rm.OutputRelays.Relays[outputURL]
+	rm.OutputRelays.mu.Unlock()
+	if !exists || out.InputURL != inputURL {
+		return fmt.Errorf("%w: %s", ErrOutputNotFound, outputName)
+	}
+	rm.setOutputEnabled(outputURL, false)
+	rm.Logger.Info("Disabled output %s [%s]", outputName, RedactURL(outputURL))
+	return rm.StopRelay(inputURL, outputURL, inputName, outputName)
+}
@@ -0,0 +1,154 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+)
+
+// An output can be paused, e.g. for a maintenance window: it is stopped and
+// releases its input, which stops once nothing else reads it, and reports
+// "Paused" until it is resumed with its stored preset and ffmpeg options.
+// Unlike disabling, pausing is not exported and does not keep the output from
+// being started; starting or stopping it explicitly ends the pause.
+
+// ErrOutputNotPaused is returned when resuming an output that is not paused
+var ErrOutputNotPaused = errors.New("output is not paused")
+
+// outputStatusPaused is the status reported for a paused output
+const outputStatusPaused = "Paused"
+
+func (rm *RelayManager) outputPaused(outputURL string) bool {
+	rm.configMu.RLock()
+	defer rm.configMu.RUnlock()
+	return rm.pausedOutputs[outputURL]
+}
+
+func (rm *RelayManager) setOutputPaused(outputURL string, paused bool) {
+	rm.configMu.Lock()
+	defer rm.configMu.Unlock()
+	if !paused {
+		delete(rm.pausedOutputs, outputURL)
+		return
+	}
+	rm.pausedOutputs[outputURL] = true
+}
+
+// outputStatusString is outputRelayStatusString reporting paused outputs as
+// such; out.mu must be held
+func (rm *RelayManager) outputStatusString(out *OutputRelay) string {
+	if out.Status == OutputStopped && rm.outputPaused(out.OutputURL) {
+		return outputStatusPaused
+	}
+	return outputRelayStatusString(out.Status)
+}
+
+// PauseOutput stops a running output and marks it paused until ResumeOutput.
+// Pausing a paused output does nothing.
+func (rm *RelayManager) PauseOutput(inputURL, outputURL, inputName, outputName string) error {
+	rm.OutputRelays.mu.Lock()
+	out, exists := rm.OutputRelays.Relays[outputURL]
+	rm.OutputRelays.mu.Unlock()
+	if !exists || out.InputURL != inputURL {
+		return fmt.Errorf("%w: %s", ErrOutputNotFound, outputName)
+	}
+	if rm.outputPaused(outputURL) {
+		return nil
+	}
+	if err := rm.StopRelay(inputURL, outputURL, inputName, outputName); err != nil {
+		return err
+	}
+	rm.setOutputPaused(outputURL, true)
+	rm.Logger.Info("Paused output %s [%s]", outputName, RedactURL(outputURL))
+	return nil
+}
+
+// ResumeOutput starts a paused output again with its stored preset and ffmpeg
+// options. It stays paused if it fails to start.
+func (rm *RelayManager) ResumeOutput(inputURL, outputURL, inputName, outputName string) error {
+	if !rm.outputPaused(outputURL) {
+		return fmt.Errorf("%w: %s", ErrOutputNotPaused, outputName)
+	}
+	preset, opts, err := rm.GetEndpointConfig(inputURL, outputURL)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrOutputNotFound, outputName)
+	}
+	rm.Logger.Info("Resuming output %s [%s]", outputName, RedactURL(outputURL))
+	_, err = rm.StartRelayWithOptions(inputURL, outputURL, inputName, outputName, opts, preset)
+	return err
+}
+
+// PauseResult lists the outputs PauseAll or ResumeAll acted on, each as
+// "input -> output"
+type PauseResult struct {
+	Outputs []string `json:"outputs"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// pausableOutput is an output PauseAll or ResumeAll acts on
+type pausableOutput struct {
+	inputURL, outputURL, inputName, outputName string
+}
+
+func (o pausableOutput) label() string {
+	return o.inputName + " -> " + o.outputName
+}
+
+// outputsWhere returns the outputs for which match, called with out.mu held, is true
+func (rm *RelayManager) outputsWhere(match func(out *OutputRelay) bool) []pausableOutput {
+	inputNames := make(map[string]string)
+	rm.InputRelays.mu.Lock()
+	for inputURL, relay := range rm.InputRelays.Relays {
+		inputNames[inputURL] = relay.InputName
+	}
+	rm.InputRelays.mu.Unlock()
+
+	var outputs []pausableOutput
+	rm.OutputRelays.mu.Lock()
+	defer rm.OutputRelays.mu.Unlock()
+	for outputURL, out := range rm.OutputRelays.Relays {
+		out.mu.Lock()
+		if match(out) {
+			outputs = append(outputs, pausableOutput{out.InputURL, outputURL, inputNames[out.InputURL], out.OutputName})
+		}
+		out.mu.Unlock()
+	}
+	return outputs
+}
+
+// PauseAll pauses every running, starting or reconnecting output, keeping
+// inputs and configuration. Outputs that are stopped, failed or disabled are
+// left alone.
+func (rm *RelayManager) PauseAll() PauseResult {
+	result := PauseResult{Outputs: []string{}}
+	outputs := rm.outputsWhere(func(out *OutputRelay) bool {
+		return out.Status.active()
+	})
+	for _, o := range outputs {
+		if err := rm.PauseOutput(o.inputURL, o.outputURL, o.inputName, o.outputName); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", o.label(), err))
+			continue
+		}
+		result.Outputs = append(result.Outputs, o.label())
+	}
+	rm.Logger.Info("Paused %d outputs, %d errors", len(result.Outputs), len(result.Errors))
+	return result
+}
+
+// ResumeAll resumes every paused output. One failing to start does not keep
+// the others from resuming; it stays paused and is listed in the errors.
+func (rm *RelayManager) ResumeAll() PauseResult {
+	result := PauseResult{Outputs: []string{}}
+	outputs := rm.outputsWhere(func(out *OutputRelay) bool {
+		return rm.outputPaused(out.OutputURL)
+	})
+	for _, o := range outputs {
+		if err := rm.ResumeOutput(o.inputURL, o.outputURL, o.inputName, o.outputName); err != nil {
+			rm.Logger.Error("Failed to resume output %s: %v", o.label(), err)
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", o.label(), err))
+			continue
+		}
+		result.Outputs = append(result.Outputs, o.label())
+	}
+	rm.Logger.Info("Resumed %d outputs, %d errors", len(result.Outputs), len(result.Errors))
+	return result
+}
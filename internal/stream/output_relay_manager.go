@@ -17,6 +17,31 @@ const (
 	OutputRunning
 	OutputStopped
 	OutputError
+	OutputCompleted    // The output ended because its finite input reached EOF
+	OutputReconnecting // Waiting to restart ffmpeg after an error, see reconnectOutput
+)
+
+// active reports whether an output holds its input: starting, running or
+// waiting to reconnect. Stopping, pausing or restarting all outputs acts on these.
+func (s OutputRelayStatus) active() bool {
+	return s == OutputStarting || s == OutputRunning || s == OutputReconnecting
+}
+
+// SlowOutputPolicy decides what happens to an output relay whose ffmpeg
+// process stays below real-time speed (or stops reporting progress) for longer
+// than the configured grace period.
+type SlowOutputPolicy string
+
+const (
+	SlowOutputLog     SlowOutputPolicy = "log"     // Only log the slow output
+	SlowOutputDegrade SlowOutputPolicy = "degrade" // Log and mark the output degraded in status
+	SlowOutputDrop    SlowOutputPolicy = "drop"    // Stop the output so it cannot hold back the input
+)
+
+// Default slow-output detection settings
+const (
+	DefaultSlowOutputMinSpeed = 0.9
+	DefaultSlowOutputGrace    = 30 * time.Second
 )
 
 // OutputRelay represents a single output ffmpeg process and its state.
@@ -37,15 +62,41 @@ type OutputRelay struct {
 	PlatformPreset string            // set at Start, then read-only
 	FFmpegOptions  map[string]string // set at Start, then read-only
 	FFmpegArgs     []string          // set at Start, then read-only
+	Direct         bool              // set at Start, then read-only; reads InputURL itself instead of LocalURL
+	ID             string            // set at Start, then read-only; correlation ID tagging this run's log lines
+	log            *logger.Logger    // set at Start, then read-only; prefixed with ID
 
 	// --- Mutable, protected by mu ---
 	Proc         *FFmpegProcess    // may be replaced on restart, protected by mu
 	Status       OutputRelayStatus // protected by mu
 	LastError    string            // protected by mu
 	shuttingDown bool              // protected by mu
+	Degraded     bool              // protected by mu; set while the output is persistently slow
+	dropReason   string            // protected by mu; set when the slow-output policy drops the relay
+	RestartCount int               // protected by mu; starts after the first, carried over to the replacing relay
+	ErrorCount   int               // protected by mu; failed starts and error exits, carried over likewise
 
 	// --- Concurrency primitives ---
-	mu sync.Mutex // protects all mutable fields above
+	mu   sync.Mutex    // protects all mutable fields above
+	stop chan struct{} // closed when shuttingDown is set, waking a pending reconnect; nil for listed outputs
+}
+
+// reconnects reports whether the relay restarts its ffmpeg after an error exit
+func (relay *OutputRelay) reconnects() bool {
+	value := relay.FFmpegOptions["reconnect"]
+	if value == "" {
+		return false
+	}
+	_, err := parseReconnect(value)
+	return err == nil
+}
+
+// shutDownLocked marks the relay as stopped on purpose. Requires relay.mu held.
+func (relay *OutputRelay) shutDownLocked() {
+	if !relay.shuttingDown && relay.stop != nil {
+		close(relay.stop)
+	}
+	relay.shuttingDown = true
 }
 
 // OutputRelayConfig contains the configuration for starting an output relay
@@ -58,6 +109,7 @@ type OutputRelayConfig struct {
 	PlatformPreset string
 	FFmpegOptions  map[string]string
 	FFmpegArgs     []string
+	Direct         bool // FFmpegArgs read InputURL directly, bypassing the local RTSP relay
 }
 
 // OutputRelayManager manages all output relays
@@ -65,21 +117,61 @@ type OutputRelayConfig struct {
 //
 // Concurrency notes:
 // - All accesses to Relays map must hold mu.
-// - Logger and FailureCallback are set at construction and never changed.
+// - Logger, FailureCallback and InputCompleted are set at construction and never changed.
 type OutputRelayManager struct {
 	Relays          map[string]*OutputRelay          // key: output URL, protected by mu
 	mu              sync.Mutex                       // protects Relays
 	Logger          *logger.Logger                   // immutable
 	FailureCallback func(inputURL, outputURL string) // immutable after set
+
+	// InputCompleted reports whether the input behind inputURL finished a
+	// finite source, making an output exit expected (immutable after set)
+	InputCompleted func(inputURL string) bool
+
+	// OutputActive is told when an output stops delivering data (slow beyond
+	// the grace period) and when it recovers (immutable after set)
+	OutputActive func(inputURL, outputURL string, active bool)
+
+	// Slow-output detection, set before relays are started
+	slowPolicy   SlowOutputPolicy
+	slowMinSpeed float64
+	slowGrace    time.Duration
 }
 
 func NewOutputRelayManager(l *logger.Logger) *OutputRelayManager {
 	return &OutputRelayManager{
-		Relays: make(map[string]*OutputRelay),
-		Logger: l,
+		Relays:       make(map[string]*OutputRelay),
+		Logger:       l,
+		slowPolicy:   SlowOutputLog,
+		slowMinSpeed: DefaultSlowOutputMinSpeed,
+		slowGrace:    DefaultSlowOutputGrace,
 	}
 }
 
+// SetSlowOutputPolicy configures how persistently slow outputs are handled.
+// Zero values keep the current settings.
+func (orm *OutputRelayManager) SetSlowOutputPolicy(policy SlowOutputPolicy, minSpeed float64, grace time.Duration) {
+	if policy != "" {
+		orm.slowPolicy = policy
+	}
+	if minSpeed > 0 {
+		orm.slowMinSpeed = minSpeed
+	}
+	if grace > 0 {
+		orm.slowGrace = grace
+	}
+	orm.Logger.Debug("OutputRelayManager: slow output policy=%s, min speed=%.2f, grace=%v", orm.slowPolicy, orm.slowMinSpeed, orm.slowGrace)
+}
+
+// logFor returns relay's correlation-ID logger, or the manager's for relays
+// that were not created by StartOutputRelay
+func (orm *OutputRelayManager) logFor(relay *OutputRelay) *logger.Logger {
+	if relay.log != nil {
+		return relay.log
+	}
+	return orm.Logger
+}
+
 // SetFailureCallback sets the callback function to be called when an output relay fails
 func (orm *OutputRelayManager) SetFailureCallback(callback func(inputURL, outputURL string)) {
 	orm.FailureCallback = callback
@@ -87,16 +179,26 @@ func (orm *OutputRelayManager) SetFailureCallback(callback func(inputURL, output
 
 // StartOutputRelay starts an output ffmpeg process from local RTSP to output URL
 func (orm *OutputRelayManager) StartOutputRelay(config OutputRelayConfig) error {
-	orm.Logger.Info("OutputRelayManager: StartOutputRelay: inputURL=%s, localURL=%s, outputURL=%s", config.InputURL, config.LocalURL, config.OutputURL)
+	orm.Logger.Info("OutputRelayManager: StartOutputRelay: inputURL=%s, localURL=%s, outputURL=%s", RedactURL(config.InputURL), config.LocalURL, config.OutputURL)
 	orm.mu.Lock()
 	relay, exists := orm.Relays[config.OutputURL]
-	if exists && relay.Status == OutputRunning {
-		orm.Logger.Warn("Output relay already running for %s -> %s", config.LocalURL, config.OutputURL)
+	running := false
+	restarts, failures := 0, 0
+	if exists {
+		relay.mu.Lock()
+		running = relay.Status == OutputRunning
+		restarts, failures = relay.RestartCount, relay.ErrorCount
+		if relay.ID != "" {
+			restarts++ // started before, not only listed
+		}
+		relay.mu.Unlock()
+	}
+	if running {
+		orm.Logger.Warn("Output relay already running for %s -> %s", config.LocalURL, RedactURL(config.OutputURL))
 		orm.mu.Unlock()
 		return nil
 	}
-	ctx := context.Background() // Use background context for now; can be enhanced for cancellation
-	proc, err := NewFFmpegProcess(ctx, append(config.FFmpegArgs, "-progress", "pipe:1")...)
+	proc, err := newOutputProcess(config.FFmpegArgs, config.FFmpegOptions)
 	if err != nil {
 		orm.mu.Unlock()
 		orm.Logger.Error("Failed to create output relay ffmpeg process: %v", err)
@@ -113,37 +215,178 @@ func (orm *OutputRelayManager) StartOutputRelay(config OutputRelayConfig) error
 		PlatformPreset: config.PlatformPreset,
 		FFmpegOptions:  config.FFmpegOptions,
 		FFmpegArgs:     config.FFmpegArgs,
+		Direct:         config.Direct,
+		ID:             newCorrelationID(),
+		RestartCount:   restarts,
+		ErrorCount:     failures,
+		stop:           make(chan struct{}),
 	}
+	relay.log = orm.Logger.WithPrefix("out:" + relay.ID)
 	orm.Relays[config.OutputURL] = relay
 	orm.mu.Unlock()
 	// Start ffmpeg process
 	err = proc.Start()
 	if err != nil {
-		orm.mu.Lock()
+		relay.mu.Lock()
 		relay.Status = OutputError
 		relay.LastError = err.Error()
-		orm.mu.Unlock()
-		orm.Logger.Error("Failed to start output relay ffmpeg: %v", err)
+		relay.ErrorCount++
+		relay.mu.Unlock()
+		relay.log.Error("Failed to start output relay ffmpeg: %v", err)
 		return err
 	}
-	orm.Logger.Info("OutputRelayManager: Started ffmpeg process PID %d for %s -> %s", proc.PID, config.LocalURL, config.OutputURL)
-	// Start process wait/monitor goroutine
+	relay.log.Info("OutputRelayManager: Started ffmpeg process PID %d for %s -> %s", proc.PID, config.LocalURL, RedactURL(config.OutputURL))
+	// Start process wait/monitor goroutines
 	go orm.RunOutputRelay(relay)
+	go orm.monitorOutputSpeed(relay, proc)
 	return nil
 }
 
+// newOutputProcess creates the ffmpeg process of an output from its args,
+// reporting progress on stdout and with the output's nice value applied
+func newOutputProcess(args []string, options map[string]string) (*FFmpegProcess, error) {
+	ctx := context.Background() // Use background context for now; can be enhanced for cancellation
+	proc, err := NewFFmpegProcess(ctx, append(args, "-progress", "pipe:1")...)
+	if err != nil {
+		return nil, err
+	}
+	if value := options["nice"]; value != "" {
+		if nice, err := parseNice(value); err == nil {
+			proc.Nice = nice
+		}
+	}
+	return proc, nil
+}
+
+// addStoppedOutput lists a stopped output relay without starting it, unless
+// the output is already known
+func (orm *OutputRelayManager) addStoppedOutput(config OutputRelayConfig) {
+	orm.mu.Lock()
+	defer orm.mu.Unlock()
+	if _, exists := orm.Relays[config.OutputURL]; exists {
+		return
+	}
+	orm.Relays[config.OutputURL] = &OutputRelay{
+		OutputURL:      config.OutputURL,
+		OutputName:     config.OutputName,
+		InputURL:       config.InputURL,
+		Status:         OutputStopped,
+		PlatformPreset: config.PlatformPreset,
+		FFmpegOptions:  config.FFmpegOptions,
+	}
+}
+
+// monitorOutputSpeed watches the progress reported by an output ffmpeg process
+// and applies the slow-output policy when it stays below the minimum speed (or
+// stops reporting progress, which happens when writes to the output block) for
+// longer than the grace period. It exits when the process does.
+func (orm *OutputRelayManager) monitorOutputSpeed(relay *OutputRelay, proc *FFmpegProcess) {
+	log := orm.logFor(relay)
+	interval := 5 * time.Second
+	if orm.slowGrace/2 < interval {
+		interval = orm.slowGrace / 2
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var slowSince time.Time
+	reportedIdle := false
+	for {
+		select {
+		case <-proc.Done():
+			return
+		case now := <-ticker.C:
+			speed, lastSpeed := proc.GetSpeed()
+			var slow bool
+			if lastSpeed.IsZero() {
+				// No progress yet: only treat it as a stall once the grace period has passed
+				slow = now.Sub(proc.StartTime) > orm.slowGrace
+			} else {
+				slow = speed < orm.slowMinSpeed || now.Sub(lastSpeed) > orm.slowGrace
+			}
+			if !slow {
+				if !slowSince.IsZero() {
+					log.Info("OutputRelayManager: Output %s recovered (speed %.2fx)", RedactURL(relay.OutputURL), speed)
+					slowSince = time.Time{}
+					relay.mu.Lock()
+					relay.Degraded = false
+					relay.mu.Unlock()
+					if reportedIdle && orm.OutputActive != nil {
+						orm.OutputActive(relay.InputURL, relay.OutputURL, true)
+					}
+					reportedIdle = false
+				}
+				continue
+			}
+			if slowSince.IsZero() {
+				slowSince = now
+				continue
+			}
+			if now.Sub(slowSince) < orm.slowGrace {
+				continue
+			}
+			if !reportedIdle && orm.OutputActive != nil {
+				orm.OutputActive(relay.InputURL, relay.OutputURL, false)
+				reportedIdle = true
+			}
+
+			relay.mu.Lock()
+			if relay.Proc != proc || relay.shuttingDown {
+				relay.mu.Unlock()
+				return
+			}
+			alreadyDegraded := relay.Degraded
+			if orm.slowPolicy == SlowOutputDegrade || orm.slowPolicy == SlowOutputDrop {
+				relay.Degraded = true
+			}
+			if orm.slowPolicy == SlowOutputDrop {
+				relay.dropReason = fmt.Sprintf("dropped: output below %.2fx for %v (speed %.2fx, %d frames dropped)", orm.slowMinSpeed, now.Sub(slowSince).Round(time.Second), speed, proc.GetDropFrames())
+			}
+			relay.mu.Unlock()
+
+			switch orm.slowPolicy {
+			case SlowOutputDrop:
+				log.Error("OutputRelayManager: Dropping stalled output %s (speed %.2fx, slow for %v)", RedactURL(relay.OutputURL), speed, now.Sub(slowSince).Round(time.Second))
+				// The monitor in RunOutputRelay sees a non-graceful exit and releases the input
+				if err := proc.Stop(2 * time.Second); err != nil {
+					log.Warn("OutputRelayManager: Error stopping stalled output %s: %v", RedactURL(relay.OutputURL), err)
+				}
+				return
+			default:
+				if !alreadyDegraded {
+					log.Warn("OutputRelayManager: Output %s persistently slow (speed %.2fx for %v, %d frames dropped)", RedactURL(relay.OutputURL), speed, now.Sub(slowSince).Round(time.Second), proc.GetDropFrames())
+				}
+				if orm.slowPolicy == SlowOutputLog {
+					// Re-arm so the warning repeats once per grace period
+					slowSince = now
+				}
+			}
+		}
+	}
+}
+
+// outputStopTimeout is how long a stopped output's ffmpeg may take to exit
+// before it is killed
+const outputStopTimeout = 2 * time.Second
+
 // StopOutputRelay stops an output ffmpeg process
 func (orm *OutputRelayManager) StopOutputRelay(outputURL string) {
-	orm.Logger.Info("OutputRelayManager: StopOutputRelay: outputURL=%s", outputURL)
+	orm.stopOutputRelay(outputURL, outputStopTimeout)
+}
+
+// stopOutputRelay is StopOutputRelay killing ffmpeg after timeout
+func (orm *OutputRelayManager) stopOutputRelay(outputURL string, timeout time.Duration) {
+	orm.Logger.Info("OutputRelayManager: StopOutputRelay: outputURL=%s", RedactURL(outputURL))
 	orm.mu.Lock()
 	relay, exists := orm.Relays[outputURL]
 	if !exists {
-		orm.Logger.Warn("OutputRelayManager: relay for %s not found", outputURL)
+		orm.Logger.Warn("OutputRelayManager: relay for %s not found", RedactURL(outputURL))
 		orm.mu.Unlock()
 		return
 	}
+	log := orm.logFor(relay)
 	relay.mu.Lock()
-	relay.shuttingDown = true
+	relay.shutDownLocked()
 	proc := relay.Proc
 	relay.Proc = nil
 	relay.Status = OutputStopped
@@ -154,87 +397,218 @@ func (orm *OutputRelayManager) StopOutputRelay(outputURL string) {
 
 	// Stop the process outside of any locks
 	if proc != nil {
-		err := proc.Stop(2 * time.Second)
+		err := proc.Stop(timeout)
 		if err != nil {
-			orm.Logger.Warn("OutputRelayManager: Error stopping ffmpeg process for %s: %v", outputURL, err)
+			log.Warn("OutputRelayManager: Error stopping ffmpeg process for %s: %v", RedactURL(outputURL), err)
 		}
 	}
 	// Only call failure callback if this is NOT a graceful shutdown
 	if !shuttingDown && orm.FailureCallback != nil {
-		orm.Logger.Debug("OutputRelayManager: Calling failure callback for failed output inputURL=%s, outputURL=%s", inputURL, outputURL)
+		log.Debug("OutputRelayManager: Calling failure callback for failed output inputURL=%s, outputURL=%s", RedactURL(inputURL), outputURL)
 		orm.FailureCallback(inputURL, outputURL)
 	} else if shuttingDown {
-		orm.Logger.Debug("OutputRelayManager: Graceful shutdown for %s, not calling failure callback", outputURL)
+		log.Debug("OutputRelayManager: Graceful shutdown for %s, not calling failure callback", outputURL)
 	}
 }
 
-// RunOutputRelay runs and monitors the output relay process
+// RunOutputRelay runs and monitors the output relay process, reconnecting
+// it after an error exit when its reconnect option is set
 func (orm *OutputRelayManager) RunOutputRelay(relay *OutputRelay) {
-	orm.Logger.Info("OutputRelayManager: RunOutputRelay: running ffmpeg for %s -> %s", relay.LocalURL, relay.OutputURL)
+	log := orm.logFor(relay)
+	log.Info("OutputRelayManager: RunOutputRelay: running ffmpeg for %s -> %s", relay.LocalURL, RedactURL(relay.OutputURL))
 	var proc *FFmpegProcess
 	relay.mu.Lock()
 	proc = relay.Proc
 	relay.mu.Unlock()
 	if proc == nil {
-		orm.Logger.Error("OutputRelayManager: RunOutputRelay: FFmpegProcess is nil for %s", relay.OutputURL)
+		log.Error("OutputRelayManager: RunOutputRelay: FFmpegProcess is nil for %s", RedactURL(relay.OutputURL))
 		return
 	}
-	err := proc.Wait()
+	attempt := 0
+	for {
+		err := proc.Wait()
 
-	relay.mu.Lock()
-	status := relay.Status
-	shuttingDown := relay.shuttingDown
-	inputURL := relay.InputURL
-	outputURL := relay.OutputURL
-	if err != nil {
-		if shuttingDown {
-			relay.Status = OutputStopped
+		relay.mu.Lock()
+		shuttingDown := relay.shuttingDown
+		inputURL := relay.InputURL
+		relay.mu.Unlock()
+		// Losing the stream of a file that simply ended is not a failure
+		completed := !shuttingDown && orm.InputCompleted != nil && orm.InputCompleted(inputURL)
+
+		relay.mu.Lock()
+		status := relay.Status
+		shuttingDown = relay.shuttingDown
+		outputURL := relay.OutputURL
+		if completed && !shuttingDown {
+			relay.Status = OutputCompleted
 			relay.LastError = ""
-		} else {
-			relay.Status = OutputError
-			relay.LastError = err.Error()
+			relay.Proc = nil
+			relay.mu.Unlock()
+			log.Info("Output relay for %s completed: input %s reached end of file", RedactURL(outputURL), RedactURL(inputURL))
+			// Release the input relay reference just like a failed output would
+			if orm.FailureCallback != nil {
+				orm.FailureCallback(inputURL, outputURL)
+			}
+			return
 		}
+		if err != nil {
+			if shuttingDown {
+				relay.Status = OutputStopped
+				relay.LastError = ""
+			} else {
+				relay.Status = OutputError
+				relay.LastError = err.Error()
+				relay.ErrorCount++
+				if relay.dropReason != "" {
+					relay.LastError = relay.dropReason
+				} else if relay.reconnects() {
+					// Marked before unlocking so a concurrent stop-all finds it
+					relay.Status = OutputReconnecting
+				}
+			}
+		}
+		if err == nil {
+			relay.Status = OutputStopped
+		}
+		relay.Proc = nil
+		relay.mu.Unlock()
+
+		if status == OutputStopped {
+			if err != nil {
+				log.Info("Output relay for %s stopped (signal: %v)", RedactURL(outputURL), err)
+			} else {
+				log.Info("Output relay for %s stopped cleanly", RedactURL(outputURL))
+			}
+			return
+		}
+		if err == nil {
+			log.Info("Output relay process for %s completed successfully", RedactURL(outputURL))
+			return
+		}
+		log.Error("Output relay process exited with error for %s: %v", RedactURL(outputURL), err)
+		if shuttingDown {
+			log.Debug("Output relay exited with error during graceful shutdown for %s, skipping failure callback", RedactURL(outputURL))
+			return
+		}
+		// The output keeps its input relay reference while it reconnects
+		if proc = orm.reconnectOutput(relay, time.Since(proc.StartTime), &attempt); proc != nil {
+			continue
+		}
+		relay.mu.Lock()
+		shuttingDown = relay.shuttingDown
+		relay.mu.Unlock()
+		if shuttingDown {
+			// Stopped while waiting, whoever stopped it releases the input
+			log.Debug("Output relay %s stopped while reconnecting, skipping failure callback", RedactURL(outputURL))
+			return
+		}
+		if orm.FailureCallback != nil {
+			log.Debug("OutputRelayManager: Calling failure callback for inputURL=%s, outputURL=%s", RedactURL(inputURL), RedactURL(outputURL))
+			orm.FailureCallback(inputURL, outputURL)
+		}
+		return
 	}
-	if err == nil {
-		relay.Status = OutputStopped
-	}
-	relay.Proc = nil
+}
+
+// outputReconnectBaseDelay is the wait before the first reconnect of an
+// output, doubling with every further attempt; a variable so tests can shorten it
+var outputReconnectBaseDelay = time.Second
+
+// outputReconnectResetAfter is how long a reconnected output must run for its
+// next failure to start over at the base delay
+const outputReconnectResetAfter = time.Minute
+
+// reconnectOutput restarts the ffmpeg of an output that exited with an error
+// when its reconnect option is set, waiting a doubling delay before each
+// attempt for as long as the delay stays within the option's seconds. ran is
+// how long the failed process ran and attempt counts the reconnects since the
+// output last ran steadily. It returns the started process, or nil when the
+// output is given up, was dropped as too slow, or is stopped or replaced
+// meanwhile.
+func (orm *OutputRelayManager) reconnectOutput(relay *OutputRelay, ran time.Duration, attempt *int) *FFmpegProcess {
+	relay.mu.Lock()
+	reconnects := relay.dropReason == "" && relay.reconnects()
 	relay.mu.Unlock()
+	if !reconnects {
+		return nil
+	}
+	maxDelay, _ := parseReconnect(relay.FFmpegOptions["reconnect"])
+	log := orm.logFor(relay)
+	if ran >= outputReconnectResetAfter {
+		*attempt = 0
+	}
+	for {
+		delay := outputReconnectBaseDelay << *attempt
+		relay.mu.Lock()
+		if relay.shuttingDown {
+			relay.mu.Unlock()
+			return nil
+		}
+		if delay > maxDelay {
+			relay.Status = OutputError
+			relay.mu.Unlock()
+			log.Warn("OutputRelayManager: Giving up reconnecting %s after %d attempts", RedactURL(relay.OutputURL), *attempt)
+			return nil
+		}
+		relay.Status = OutputReconnecting
+		relay.mu.Unlock()
+		*attempt++
+		log.Info("OutputRelayManager: Reconnecting %s in %v (attempt %d)", RedactURL(relay.OutputURL), delay, *attempt)
+		select {
+		case <-relay.stop:
+			return nil
+		case <-time.After(delay):
+		}
 
-	if status == OutputStopped {
+		orm.mu.Lock()
+		current := orm.Relays[relay.OutputURL]
+		orm.mu.Unlock()
+		if current != relay {
+			return nil
+		}
+		proc, err := newOutputProcess(relay.FFmpegArgs, relay.FFmpegOptions)
 		if err != nil {
-			orm.Logger.Info("Output relay for %s stopped (signal: %v)", outputURL, err)
-		} else {
-			orm.Logger.Info("Output relay for %s stopped cleanly", outputURL)
+			log.Error("Failed to create output relay ffmpeg process: %v", err)
+			return nil
 		}
-		return
-	}
-	if err != nil {
-		orm.Logger.Error("Output relay process exited with error for %s: %v", outputURL, err)
-		if !shuttingDown && orm.FailureCallback != nil {
-			orm.Logger.Debug("OutputRelayManager: Calling failure callback for inputURL=%s, outputURL=%s", inputURL, outputURL)
-			orm.FailureCallback(inputURL, outputURL)
-			return
-		} else {
-			orm.Logger.Debug("Output relay exited with error during graceful shutdown for %s, skipping failure callback", outputURL)
+		// Started under the lock so a concurrent stop either finds the process or ends the reconnect
+		relay.mu.Lock()
+		if relay.shuttingDown {
+			relay.mu.Unlock()
+			return nil
 		}
-	} else {
-		orm.Logger.Info("Output relay process for %s completed successfully", outputURL)
+		if err := proc.Start(); err != nil {
+			relay.LastError = err.Error()
+			relay.ErrorCount++
+			relay.mu.Unlock()
+			log.Error("Failed to restart output relay ffmpeg: %v", err)
+			continue
+		}
+		relay.Proc = proc
+		relay.Status = OutputRunning
+		relay.LastError = ""
+		relay.Degraded = false
+		relay.RestartCount++
+		relay.mu.Unlock()
+		log.Info("OutputRelayManager: Reconnected %s with ffmpeg PID %d", RedactURL(relay.OutputURL), proc.PID)
+		go orm.monitorOutputSpeed(relay, proc)
+		return proc
 	}
 }
 
 // DeleteOutput completely removes an output relay
 func (orm *OutputRelayManager) DeleteOutput(outputURL string) error {
-	orm.Logger.Info("OutputRelayManager: DeleteOutput: outputURL=%s", outputURL)
+	orm.Logger.Info("OutputRelayManager: DeleteOutput: outputURL=%s", RedactURL(outputURL))
 	orm.mu.Lock()
 	relay, exists := orm.Relays[outputURL]
 	if !exists {
-		orm.Logger.Warn("OutputRelayManager: relay for %s not found", outputURL)
+		orm.Logger.Warn("OutputRelayManager: relay for %s not found", RedactURL(outputURL))
 		orm.mu.Unlock()
-		return fmt.Errorf("output relay not found: %s", outputURL)
+		return fmt.Errorf("output relay not found: %s", RedactURL(outputURL))
 	}
+	log := orm.logFor(relay)
 	relay.mu.Lock()
-	relay.shuttingDown = true
+	relay.shutDownLocked()
 	proc := relay.Proc
 	relay.Proc = nil
 	relay.Status = OutputStopped
@@ -248,15 +622,15 @@ func (orm *OutputRelayManager) DeleteOutput(outputURL string) error {
 	if proc != nil {
 		err := proc.Stop(1 * time.Second)
 		if err != nil {
-			orm.Logger.Warn("OutputRelayManager: Error deleting ffmpeg process for %s: %v", outputURL, err)
+			log.Warn("OutputRelayManager: Error deleting ffmpeg process for %s: %v", RedactURL(outputURL), err)
 		}
 	}
 
 	// Always call failure callback for deleted outputs to decrement input relay refcount
 	if orm.FailureCallback != nil {
-		orm.Logger.Debug("OutputRelayManager: Calling failure callback for deleted output inputURL=%s, outputURL=%s", inputURL, outputURL)
+		log.Debug("OutputRelayManager: Calling failure callback for deleted output inputURL=%s, outputURL=%s", RedactURL(inputURL), outputURL)
 		orm.FailureCallback(inputURL, outputURL)
 	}
-	orm.Logger.Info("OutputRelayManager: Output relay %s deleted successfully", outputURL)
+	log.Info("OutputRelayManager: Output relay %s deleted successfully", RedactURL(outputURL))
 	return nil
 }
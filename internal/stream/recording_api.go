@@ -2,23 +2,38 @@ package stream
 
 import (
 	"context"
+	"errors"
 	"go-mls/internal/httputil"
 	"net/http"
+	"time"
 )
 
 // Recording API Handlers
 func ApiStartRecording(rm *RecordingManager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodPost) {
+			return
+		}
 		var req struct {
-			Name   string `json:"name"`
-			Source string `json:"source"`
+			Name    string `json:"name"`
+			Source  string `json:"source"`
+			Profile string `json:"profile"` // quality profile, empty for a stream copy
+			// Index of the input's published audio tracks, empty for the default
+			AudioTrack string `json:"audio_track"`
+			// Burn the wall-clock time into the video
+			Timestamp bool `json:"timestamp"`
+			// Also write a re-encoded proxy of a stream copy, {} for the defaults
+			Proxy *RecordingProxy `json:"proxy"`
+			// Also stream the recording live to a named pipe
+			FIFO bool `json:"fifo"`
 		}
 		if err := httputil.DecodeJSON(r, &req); err != nil {
 			httputil.WriteError(w, http.StatusBadRequest, "Invalid request")
 			return
 		}
-		if req.Name == "" || req.Source == "" {
-			httputil.WriteError(w, http.StatusBadRequest, "Name and source required")
+		// Without a source the input relayed under name is recorded
+		if req.Name == "" {
+			httputil.WriteError(w, http.StatusBadRequest, "Name required")
 			return
 		}
 		// Additional validation to prevent "undefined" values
@@ -27,7 +42,23 @@ func ApiStartRecording(rm *RecordingManager) http.HandlerFunc {
 			return
 		}
 		// Diagnostic logging to trace handler execution
-		err := rm.StartRecording(context.Background(), req.Name, req.Source)
+		err := rm.StartRecordingWithOptions(context.Background(), req.Name, req.Source, req.Profile, RecordingOptions{AudioTrack: req.AudioTrack, Timestamp: req.Timestamp, Proxy: req.Proxy, FIFO: req.FIFO})
+		if errors.Is(err, ErrUnknownRecordingProfile) || errors.Is(err, ErrInvalidAudioTrack) || errors.Is(err, ErrInvalidRecordingProxy) || errors.Is(err, ErrRecordingFIFOUnsupported) {
+			httputil.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, ErrUnknownInput) {
+			httputil.WriteError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if errors.Is(err, ErrRecordingLimitReached) || errors.Is(err, ErrRecordingFIFOInUse) {
+			httputil.WriteError(w, http.StatusConflict, err.Error())
+			return
+		}
+		if errors.Is(err, ErrRecordingStorageUnavailable) {
+			httputil.WriteError(w, http.StatusServiceUnavailable, err.Error())
+			return
+		}
 		if err != nil {
 			httputil.WriteError(w, http.StatusInternalServerError, err.Error())
 			return
@@ -38,16 +69,21 @@ func ApiStartRecording(rm *RecordingManager) http.HandlerFunc {
 
 func ApiStopRecording(rm *RecordingManager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodPost) {
+			return
+		}
 		var req struct {
-			Name   string `json:"name"`
-			Source string `json:"source"`
+			Name    string `json:"name"`
+			Source  string `json:"source"`
+			Profile string `json:"profile"` // quality profile, empty for a stream copy
 		}
 		if err := httputil.DecodeJSON(r, &req); err != nil {
 			httputil.WriteError(w, http.StatusBadRequest, "Invalid request")
 			return
 		}
-		if req.Name == "" || req.Source == "" {
-			httputil.WriteError(w, http.StatusBadRequest, "Name and source required")
+		// Without a source the input relayed under name is recorded
+		if req.Name == "" {
+			httputil.WriteError(w, http.StatusBadRequest, "Name required")
 			return
 		}
 		// Additional validation to prevent "undefined" values
@@ -55,8 +91,12 @@ func ApiStopRecording(rm *RecordingManager) http.HandlerFunc {
 			httputil.WriteError(w, http.StatusBadRequest, "Invalid name or source: cannot be 'undefined'")
 			return
 		}
-		if err := rm.StopRecording(req.Name, req.Source); err != nil {
-			httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		if err := rm.StopRecording(req.Name, req.Source, req.Profile); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, ErrUnknownInput) {
+				status = http.StatusNotFound
+			}
+			httputil.WriteError(w, status, err.Error())
 			return
 		}
 		httputil.WriteJSON(w, http.StatusOK, map[string]string{"status": "recording stopped"})
@@ -65,13 +105,60 @@ func ApiStopRecording(rm *RecordingManager) http.HandlerFunc {
 
 func ApiListRecordings(rm *RecordingManager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		recs := rm.ListRecordings()
+		if !httputil.AllowMethods(w, r, http.MethodGet) {
+			return
+		}
+		query := r.URL.Query()
+		filter := RecordingFilter{Name: query.Get("name")}
+		var err error
+		if filter.From, err = parseRecordingTime(query.Get("from"), false); err != nil {
+			httputil.WriteError(w, http.StatusBadRequest, "Invalid from time")
+			return
+		}
+		if filter.To, err = parseRecordingTime(query.Get("to"), true); err != nil {
+			httputil.WriteError(w, http.StatusBadRequest, "Invalid to time")
+			return
+		}
+		recs := rm.ListRecordingsFiltered(filter)
 		httputil.WriteJSON(w, http.StatusOK, recs)
 	}
 }
 
+// parseRecordingTime parses a list filter bound given as RFC 3339 or as a
+// local date (2006-01-02). A date used as the upper bound covers the whole
+// day. An empty value is the zero time, i.e. unbounded.
+func parseRecordingTime(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	day, err := time.ParseInLocation(time.DateOnly, value, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		return day.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+	}
+	return day, nil
+}
+
+// ApiRecordingStats reports active recordings against the concurrent limit
+func ApiRecordingStats(rm *RecordingManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodGet) {
+			return
+		}
+		httputil.WriteJSON(w, http.StatusOK, rm.Stats())
+	}
+}
+
 func ApiDeleteRecording(rm *RecordingManager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodPost, http.MethodDelete) {
+			return
+		}
 		var req struct {
 			Filename string `json:"filename"`
 		}
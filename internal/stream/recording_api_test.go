@@ -1,13 +1,17 @@
 package stream
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"go-mls/internal/logger"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"testing"
 	"time"
@@ -59,19 +63,19 @@ func TestApiStartRecording(t *testing.T) {
 			name:           "Missing name",
 			requestBody:    `{"source": "rtsp://example.com/stream"}`,
 			expectedStatus: http.StatusBadRequest,
-			shouldContain:  "Name and source required",
+			shouldContain:  "Name required",
 		},
 		{
-			name:           "Missing source",
-			requestBody:    `{"name": "test"}`,
-			expectedStatus: http.StatusBadRequest,
-			shouldContain:  "Name and source required",
+			name:           "Missing source of unknown input",
+			requestBody:    `{"name": "unknown"}`,
+			expectedStatus: http.StatusNotFound,
+			shouldContain:  "unknown input",
 		},
 		{
 			name:           "Empty name",
 			requestBody:    `{"name": "", "source": "rtsp://example.com/stream"}`,
 			expectedStatus: http.StatusBadRequest,
-			shouldContain:  "Name and source required",
+			shouldContain:  "Name required",
 		},
 		{
 			name:           "Undefined name",
@@ -180,13 +184,13 @@ func TestApiStopRecording(t *testing.T) {
 			name:           "Missing name",
 			requestBody:    `{"source": "rtsp://example.com/stream"}`,
 			expectedStatus: http.StatusBadRequest,
-			shouldContain:  "Name and source required",
+			shouldContain:  "Name required",
 		},
 		{
-			name:           "Missing source",
-			requestBody:    `{"name": "test"}`,
-			expectedStatus: http.StatusBadRequest,
-			shouldContain:  "Name and source required",
+			name:           "Missing source of unknown input",
+			requestBody:    `{"name": "unknown"}`,
+			expectedStatus: http.StatusNotFound,
+			shouldContain:  "unknown input",
 		},
 		{
 			name:           "Undefined values",
@@ -278,6 +282,76 @@ func TestApiListRecordings(t *testing.T) {
 	}
 }
 
+func TestApiListRecordings_Filters(t *testing.T) {
+	tempDir := t.TempDir()
+	log := logger.NewLoggerWithWriter(io.Discard)
+	relayMgr := NewRelayManager(log, tempDir)
+	rm := NewRecordingManager(log, tempDir, relayMgr)
+	defer rm.Shutdown()
+
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 12, 0, 0, 0, time.Local) }
+	// Two recordings the manager knows about, two found only on disk
+	rm.mu.Lock()
+	rm.recordings["front"] = &Recording{Name: "FrontDoor", Filename: "FrontDoor_1.mp4", FilePath: filepath.Join(tempDir, "FrontDoor_1.mp4"), StartedAt: day(1)}
+	rm.recordings["back"] = &Recording{Name: "backyard", Filename: "backyard_1.mp4", FilePath: filepath.Join(tempDir, "backyard_1.mp4"), StartedAt: day(10)}
+	rm.mu.Unlock()
+	for name, started := range map[string]time.Time{
+		"FrontDoor_1.mp4": day(20), // its mtime must not override the known start time
+		"backyard_1.mp4":  day(20),
+		"frontgate_2.mp4": day(5),
+		"garage_3.mp4":    day(15),
+	} {
+		path := filepath.Join(tempDir, name)
+		if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, started, started); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	list := func(query string) []string {
+		t.Helper()
+		w := httptest.NewRecorder()
+		ApiListRecordings(rm)(w, httptest.NewRequest("GET", "/api/recording/list?"+query, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("list %q: status %d: %s", query, w.Code, w.Body.String())
+		}
+		var recs []Recording
+		if err := json.Unmarshal(w.Body.Bytes(), &recs); err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, r := range recs {
+			names = append(names, r.Name)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"FrontDoor", "backyard", "frontgate", "garage"}},
+		{"name=front", []string{"FrontDoor", "frontgate"}},
+		{"from=2026-03-05&to=2026-03-10", []string{"backyard", "frontgate"}},
+		{"from=" + url.QueryEscape(day(10).Add(time.Hour).Format(time.RFC3339)), []string{"garage"}},
+		{"name=front&to=2026-03-01", []string{"FrontDoor"}},
+	}
+	for _, tt := range tests {
+		if got := list(tt.query); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("list %q = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	ApiListRecordings(rm)(w, httptest.NewRequest("GET", "/api/recording/list?from=yesterday", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid from time, got %d", w.Code)
+	}
+}
+
 func TestApiDeleteRecording(t *testing.T) {
 	// Setup test environment
 	tempDir := t.TempDir()
@@ -447,3 +521,704 @@ func TestApiHandlers_ContentType(t *testing.T) {
 		})
 	}
 }
+
+func TestRecordingManager_MultipleProfilesOfOneSource(t *testing.T) {
+	installFakeFFmpeg(t, "#!/bin/sh\nexec sleep 30\n")
+
+	tempDir := t.TempDir()
+	log := logger.NewLoggerWithWriter(io.Discard)
+	relayMgr := NewRelayManager(log, tempDir)
+	rm := NewRecordingManager(log, tempDir, relayMgr)
+	defer rm.Shutdown()
+
+	source := "rtsp://camera.example.com/stream"
+	for _, profile := range []string{"", "proxy"} {
+		if err := rm.StartRecording(context.Background(), "cam", source, profile); err != nil {
+			t.Fatalf("StartRecording(%q) failed: %v", profile, err)
+		}
+	}
+	if err := rm.StartRecording(context.Background(), "cam", source, "proxy"); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("expected duplicate proxy recording to be rejected, got %v", err)
+	}
+	if err := rm.StartRecording(context.Background(), "cam", source, "8k"); !errors.Is(err, ErrUnknownRecordingProfile) {
+		t.Errorf("expected ErrUnknownRecordingProfile, got %v", err)
+	}
+
+	active := map[string]*Recording{}
+	for _, rec := range rm.ListRecordings() {
+		if rec.Active {
+			active[rec.Profile] = rec
+		}
+	}
+	if len(active) != 2 || active[""] == nil || active["proxy"] == nil {
+		t.Fatalf("expected copy and proxy recordings to be active, got %v", active)
+	}
+	if active[""].Filename == active["proxy"].Filename {
+		t.Errorf("expected distinct files, both are %s", active[""].Filename)
+	}
+
+	relayMgr.InputRelays.mu.Lock()
+	input := relayMgr.InputRelays.Relays[source]
+	relayMgr.InputRelays.mu.Unlock()
+	input.mu.Lock()
+	refCount := input.RefCount
+	input.mu.Unlock()
+	if refCount != 2 {
+		t.Errorf("expected both recordings to share the input relay (refcount 2), got %d", refCount)
+	}
+
+	if err := rm.StopRecording("cam", source, "proxy"); err != nil {
+		t.Fatalf("StopRecording(proxy) failed: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		states := map[string]bool{}
+		for _, rec := range rm.ListRecordings() {
+			if rec.Filename == active[""].Filename || rec.Filename == active["proxy"].Filename {
+				states[rec.Profile] = rec.Active
+			}
+		}
+		if !states["proxy"] {
+			if !states[""] {
+				t.Error("expected the copy recording to keep running after stopping the proxy")
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("proxy recording did not stop")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestRecordingManager_RecordRelayedInputByName(t *testing.T) {
+	installFakeFFmpeg(t, "#!/bin/sh\nexec sleep 30\n")
+
+	tempDir := t.TempDir()
+	log := logger.NewLoggerWithWriter(io.Discard)
+	relayMgr := NewRelayManager(log, tempDir)
+	rm := NewRecordingManager(log, tempDir, relayMgr)
+	defer rm.Shutdown()
+
+	source := "rtsp://camera.example.com/stream"
+	if _, err := relayMgr.InputRelays.StartInputRelay("cam", source, GetRTSPServerURL()+"/relay/cam", time.Second, "test"); err != nil {
+		t.Fatalf("StartInputRelay failed: %v", err)
+	}
+	defer relayMgr.InputRelays.StopInputRelay(source, "test")
+
+	if err := rm.StartRecording(context.Background(), "cam", "", ""); err != nil {
+		t.Fatalf("StartRecording by name failed: %v", err)
+	}
+	if err := rm.StartRecording(context.Background(), "unknown", "", ""); !errors.Is(err, ErrUnknownInput) {
+		t.Errorf("expected ErrUnknownInput, got %v", err)
+	}
+	recs := rm.ListRecordings()
+	if len(recs) != 1 || recs[0].Source != source || !recs[0].Active {
+		t.Fatalf("expected one active recording of %s, got %+v", source, recs)
+	}
+
+	input := relayMgr.InputRelays.Relays[source]
+	input.mu.Lock()
+	refCount := input.RefCount
+	input.mu.Unlock()
+	if refCount != 2 {
+		t.Errorf("expected the recording to share the running input relay (refcount 2), got %d", refCount)
+	}
+
+	if err := rm.StopRecording("cam", "", ""); err != nil {
+		t.Errorf("StopRecording by name failed: %v", err)
+	}
+}
+
+func TestRecordingManager_MaxConcurrentRecordings(t *testing.T) {
+	installFakeFFmpeg(t, "#!/bin/sh\nexec sleep 30\n")
+
+	tempDir := t.TempDir()
+	log := logger.NewLoggerWithWriter(io.Discard)
+	relayMgr := NewRelayManager(log, tempDir)
+	rm := NewRecordingManager(log, tempDir, relayMgr)
+	defer rm.Shutdown()
+	rm.SetMaxConcurrentRecordings(2)
+
+	for _, name := range []string{"cam1", "cam2"} {
+		if err := rm.StartRecording(context.Background(), name, "rtsp://camera.example.com/"+name, ""); err != nil {
+			t.Fatalf("StartRecording(%s) failed: %v", name, err)
+		}
+	}
+	err := rm.StartRecording(context.Background(), "cam3", "rtsp://camera.example.com/cam3", "")
+	if !errors.Is(err, ErrRecordingLimitReached) {
+		t.Fatalf("expected ErrRecordingLimitReached for the third recording, got %v", err)
+	}
+	if stats := rm.Stats(); stats != (RecordingStats{Active: 2, MaxConcurrent: 2}) {
+		t.Errorf("Stats() = %+v, want 2 of 2 active", stats)
+	}
+
+	body := strings.NewReader(`{"name": "cam3", "source": "rtsp://camera.example.com/cam3"}`)
+	w := httptest.NewRecorder()
+	ApiStartRecording(rm)(w, httptest.NewRequest(http.MethodPost, "/api/recording/start", body))
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected 409 from the API at the limit, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Stopping one frees its slot
+	if err := rm.StopRecording("cam1", "rtsp://camera.example.com/cam1", ""); err != nil {
+		t.Fatalf("StopRecording failed: %v", err)
+	}
+	deadline := time.Now().Add(3 * time.Second)
+	for rm.Stats().Active > 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("stopped recording still counted as active")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err := rm.StartRecording(context.Background(), "cam3", "rtsp://camera.example.com/cam3", ""); err != nil {
+		t.Errorf("StartRecording after a stop failed: %v", err)
+	}
+}
+
+func TestRecordingManager_FinalizeSignalAndTimeout(t *testing.T) {
+	// The fake ffmpeg notes the signal it finished a recording on; proxy
+	// recordings hang and ignore every graceful signal
+	script := `#!/bin/sh
+for a; do last=$a; done
+case "$last" in
+*.proxy.mp4) trap '' INT TERM ;;
+*.mp4) trap 'echo INT > "$last.sig"; exit 0' INT; trap 'echo TERM > "$last.sig"; exit 0' TERM ;;
+esac
+sleep 30 &
+wait
+`
+	installFakeFFmpeg(t, script)
+
+	tempDir := t.TempDir()
+	log := logger.NewLoggerWithWriter(io.Discard)
+	relayMgr := NewRelayManager(log, tempDir)
+	rm := NewRecordingManager(log, tempDir, relayMgr)
+	defer rm.Shutdown()
+	if err := rm.SetFinalizeOptions("", 300*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := rm.SetFinalizeOptions("SIGHUP", 0); err == nil {
+		t.Error("expected an unsupported stop signal to be rejected")
+	}
+
+	source := "rtsp://camera.example.com/stream"
+	record := func(profile string) *Recording {
+		t.Helper()
+		if err := rm.StartRecording(context.Background(), "cam", source, profile); err != nil {
+			t.Fatalf("StartRecording(%q) failed: %v", profile, err)
+		}
+		var rec *Recording
+		for _, r := range rm.ListRecordings() {
+			if r.Active && r.Profile == profile {
+				rec = r
+			}
+		}
+		time.Sleep(100 * time.Millisecond) // let the fake install its traps
+		if err := rm.StopRecording("cam", source, profile); err != nil {
+			t.Fatalf("StopRecording(%q) failed: %v", profile, err)
+		}
+		deadline := time.Now().Add(3 * time.Second)
+		for {
+			stopped := true
+			for _, r := range rm.ListRecordings() {
+				if r.Filename == rec.Filename && r.Active {
+					stopped = false
+				}
+			}
+			if stopped {
+				return rec
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("recording %q was not stopped", profile)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+
+	// mp4 needs SIGINT to get its moov atom
+	rec := record("")
+	if sig, _ := os.ReadFile(filepath.Join(tempDir, rec.Filename+".sig")); strings.TrimSpace(string(sig)) != "INT" {
+		t.Errorf("expected the mp4 recording to be finalized with SIGINT, got %q", sig)
+	}
+	// A hung ffmpeg is killed after the finalize timeout
+	record("proxy")
+
+	rm.SetFinalizeOptions("SIGTERM", 0)
+	rec = record("")
+	if sig, _ := os.ReadFile(filepath.Join(tempDir, rec.Filename+".sig")); strings.TrimSpace(string(sig)) != "TERM" {
+		t.Errorf("expected the configured SIGTERM, got %q", sig)
+	}
+}
+
+func TestRecordingManager_PrunesOldestOverQuota(t *testing.T) {
+	dir := t.TempDir()
+	rm := NewRecordingManager(logger.NewLogger(), dir, nil)
+	defer rm.Shutdown()
+
+	base := time.Now().Add(-time.Hour)
+	for i, name := range []string{"live_1.mp4", "old_2.mp4", "mid_3.mp4", "new_4.mp4"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, make([]byte, 100), 0644); err != nil {
+			t.Fatal(err)
+		}
+		mtime := base.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The oldest file is still being recorded and must survive
+	rm.mu.Lock()
+	rm.recordings["live"] = &Recording{Name: "live", Filename: "live_1.mp4", FilePath: filepath.Join(dir, "live_1.mp4"), Active: true}
+	rm.mu.Unlock()
+
+	rm.SetMaxTotalSize(250)
+	rm.pruneRecordings()
+
+	for name, want := range map[string]bool{"live_1.mp4": true, "old_2.mp4": false, "mid_3.mp4": false, "new_4.mp4": true} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if exists := err == nil; exists != want {
+			t.Errorf("%s exists = %v, want %v", name, exists, want)
+		}
+	}
+}
+
+func TestRecordingManager_DateLayout(t *testing.T) {
+	dir := t.TempDir()
+	rm := NewRecordingManager(logger.NewLogger(), dir, nil)
+	defer rm.Shutdown()
+
+	for _, layout := range []string{"../%Y", "/%Y/%m", "%Y/%j", "%Y//%m", "%Y\\%m"} {
+		if err := rm.SetDateLayout(layout); !errors.Is(err, ErrInvalidDateLayout) {
+			t.Errorf("SetDateLayout(%q) = %v, want ErrInvalidDateLayout", layout, err)
+		}
+	}
+	if err := rm.SetDateLayout("%Y/%m/%d"); err != nil {
+		t.Fatalf("SetDateLayout: %v", err)
+	}
+	started := time.Date(2024, 6, 1, 12, 0, 0, 0, time.Local)
+	if got := expandDateLayout(rm.dateLayout, started); got != "2024/06/01" {
+		t.Fatalf("expandDateLayout = %q, want 2024/06/01", got)
+	}
+
+	// A recording filed under its date, next to one from before the layout was set
+	nested := "2024/06/01/cam_1717236000.mp4"
+	if err := os.MkdirAll(filepath.Join(dir, "2024", "06", "01"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{nested, "flat_1717236000.mp4"} {
+		if err := os.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), []byte("video"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names := make(map[string]string)
+	for _, rec := range rm.ListRecordings() {
+		names[rec.Filename] = rec.Name
+	}
+	if names[nested] != "cam" || names["flat_1717236000.mp4"] != "flat" {
+		t.Fatalf("listed %v, want both recordings with their names", names)
+	}
+
+	download := ApiDownloadRecording(rm)
+	for filename, want := range map[string]int{
+		nested: http.StatusOK,
+		"../" + filepath.Base(dir) + "/flat_1717236000.mp4": http.StatusBadRequest,
+		"2024/../flat_1717236000.mp4":                       http.StatusBadRequest,
+		"/etc/passwd.mp4":                                   http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		download(w, httptest.NewRequest(http.MethodGet, "/api/recordings/download?filename="+url.QueryEscape(filename), nil))
+		if w.Code != want {
+			t.Errorf("download %q: status %d, want %d", filename, w.Code, want)
+		}
+		if want == http.StatusOK && w.Header().Get("Content-Disposition") != "attachment; filename=cam_1717236000.mp4" {
+			t.Errorf("Content-Disposition = %q", w.Header().Get("Content-Disposition"))
+		}
+	}
+
+	if err := rm.DeleteRecordingByFilename("2024/../../outside.mp4"); !errors.Is(err, ErrInvalidRecordingPath) {
+		t.Errorf("delete outside the directory = %v, want ErrInvalidRecordingPath", err)
+	}
+	if err := rm.DeleteRecordingByFilename(nested); err != nil {
+		t.Fatalf("DeleteRecordingByFilename: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "2024")); !os.IsNotExist(err) {
+		t.Errorf("empty date directories were not removed: %v", err)
+	}
+}
+
+func TestRecordingManager_RestoreState(t *testing.T) {
+	installFakeFFmpeg(t, "#!/bin/sh\nexec sleep 30\n")
+
+	dir := t.TempDir()
+	log := logger.NewLoggerWithWriter(io.Discard)
+	readState := func() recordingState {
+		t.Helper()
+		path := filepath.Join(dir, recordingStateFile)
+		if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+			t.Fatalf("expected a private state file, got %v, %v", info, err)
+		}
+		var state recordingState
+		data, _ := os.ReadFile(path)
+		if err := json.Unmarshal(data, &state); err != nil {
+			t.Fatalf("invalid state file: %v", err)
+		}
+		return state
+	}
+	waitActive := func(rm *RecordingManager, want int) []*Recording {
+		t.Helper()
+		deadline := time.Now().Add(3 * time.Second)
+		for {
+			var active []*Recording
+			for _, r := range rm.ListRecordings() {
+				if r.Active && r.FilePath != "" {
+					active = append(active, r)
+				}
+			}
+			if len(active) == want {
+				return active
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("expected %d active recordings, got %d", want, len(active))
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+
+	// First run: a preview-only input, one recording left running and one
+	// stopped through the API
+	relayMgr := NewRelayManager(log, dir)
+	rm := NewRecordingManager(log, dir, relayMgr)
+	if err := relayMgr.RegisterInputConfig("preview", "rtsp://camera.example.com/preview"); err != nil {
+		t.Fatal(err)
+	}
+	if err := relayMgr.SetInputHLSAudioTrack("preview", "1"); err != nil {
+		t.Fatal(err)
+	}
+	rm.SetPreviewInputs(func() []string { return []string{"preview"} })
+	if err := rm.StartRecordingWithOptions(context.Background(), "cam1", "rtsp://camera.example.com/cam1", "", RecordingOptions{AudioTrack: "1"}); err != nil {
+		t.Fatalf("StartRecording(cam1) failed: %v", err)
+	}
+	if err := rm.StartRecording(context.Background(), "cam2", "rtsp://camera.example.com/cam2", ""); err != nil {
+		t.Fatalf("StartRecording(cam2) failed: %v", err)
+	}
+	if err := rm.StopRecording("cam2", "rtsp://camera.example.com/cam2", ""); err != nil {
+		t.Fatalf("StopRecording failed: %v", err)
+	}
+	waitActive(rm, 1)
+	rm.Shutdown()
+
+	state := readState()
+	if len(state.Recordings) != 1 || state.Recordings[0].Name != "cam1" || state.Recordings[0].AudioTrack != "1" {
+		t.Fatalf("expected only cam1 to be saved as active, got %+v", state.Recordings)
+	}
+	if len(state.Inputs) != 2 || state.Inputs[0].InputName != "cam1" || state.Inputs[1].InputName != "preview" {
+		t.Fatalf("expected inputs cam1 and preview, got %+v", state.Inputs)
+	}
+
+	// Second run resumes cam1 and restores the inputs with their settings
+	relayMgr = NewRelayManager(log, dir)
+	rm = NewRecordingManager(log, dir, relayMgr)
+	resumed, err := rm.RestoreState(context.Background(), true)
+	if err != nil || resumed != 1 {
+		t.Fatalf("RestoreState = %d, %v, want 1 resumed", resumed, err)
+	}
+	if cfg, ok := relayMgr.GetInputConfig("preview"); !ok || cfg.HLSAudioTrack != "1" {
+		t.Errorf("expected preview input restored with its HLS audio track, got %+v, %v", cfg, ok)
+	}
+	if active := waitActive(rm, 1); active[0].Name != "cam1" || active[0].AudioTrack != "1" {
+		t.Errorf("expected cam1 resumed with its audio track, got %+v", active[0])
+	}
+	rm.Shutdown()
+
+	// Third run without resuming forgets the recording but keeps the inputs
+	relayMgr = NewRelayManager(log, dir)
+	rm = NewRecordingManager(log, dir, relayMgr)
+	defer rm.Shutdown()
+	if resumed, err := rm.RestoreState(context.Background(), false); err != nil || resumed != 0 {
+		t.Fatalf("RestoreState = %d, %v, want none resumed", resumed, err)
+	}
+	if _, ok := relayMgr.GetInputConfig("cam1"); !ok {
+		t.Error("expected cam1 input to be restored")
+	}
+	waitActive(rm, 0)
+	if state := readState(); len(state.Recordings) != 0 || len(state.Inputs) != 2 {
+		t.Errorf("expected the recordings forgotten and the inputs kept, got %+v", state)
+	}
+}
+
+func TestRecordingManager_StorageUnavailable(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "recordings")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	rm := NewRecordingManager(logger.NewLogger(), dir, nil)
+	defer rm.Shutdown()
+
+	if ok, reason := rm.Healthy(); !ok {
+		t.Fatalf("Healthy = false (%s), want true", reason)
+	}
+
+	// The directory disappears, like an unmounted share
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	err := rm.StartRecording(context.Background(), "cam", "rtsp://localhost/cam", "")
+	if !errors.Is(err, ErrRecordingStorageUnavailable) {
+		t.Fatalf("StartRecording = %v, want ErrRecordingStorageUnavailable", err)
+	}
+	if ok, reason := rm.Healthy(); ok || reason == "" {
+		t.Errorf("Healthy = %v, %q, want false with a reason", ok, reason)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for rm.watcherRunning.Load() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if rm.watcherRunning.Load() {
+		t.Fatal("watcher still running after its directory was removed")
+	}
+
+	// It comes back
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	rm.superviseStorage()
+	if ok, reason := rm.Healthy(); !ok {
+		t.Errorf("Healthy = false (%s) after the directory came back", reason)
+	}
+	if !rm.watcherRunning.Load() {
+		t.Error("watcher not re-established after the directory came back")
+	}
+}
+
+func TestRecordingManager_TimestampOverlay(t *testing.T) {
+	rm := NewRecordingManager(logger.NewLogger(), t.TempDir(), nil)
+	defer rm.Shutdown()
+
+	font := filepath.Join(t.TempDir(), "mono.ttf")
+	if err := os.WriteFile(font, []byte("font"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := rm.SetTimestampOptions(font, "bottom-right", 32); err != nil {
+		t.Fatalf("SetTimestampOptions failed: %v", err)
+	}
+	drawtext := "drawtext=fontfile=" + font + ":text='%{localtime}':fontsize=32:fontcolor=white:box=1:boxcolor=black@0.5:boxborderw=5:x=w-tw-10:y=h-th-10"
+	if got := rm.timestamp.filter(); got != drawtext {
+		t.Errorf("filter = %s, want %s", got, drawtext)
+	}
+
+	// A stream copy re-encodes the video, a transcoding profile chains after scaling
+	copyArgs := strings.Join(rm.timestamp.withTimestamp(recordingProfiles[""]), " ")
+	if want := "-c:v libx264 -preset veryfast -crf 20 -c:a copy -vf " + drawtext; copyArgs != want {
+		t.Errorf("copy args = %s, want %s", copyArgs, want)
+	}
+	proxyArgs := strings.Join(rm.timestamp.withTimestamp(recordingProfiles["proxy"]), " ")
+	if !strings.Contains(proxyArgs, "-vf scale=-2:360,"+drawtext+" ") {
+		t.Errorf("proxy args = %s, want the timestamp after scaling", proxyArgs)
+	}
+	if !strings.Contains(strings.Join(recordingProfiles["proxy"], " "), "-vf scale=-2:360 ") {
+		t.Errorf("withTimestamp modified the proxy profile: %v", recordingProfiles["proxy"])
+	}
+
+	for _, tt := range []struct {
+		font, position string
+		size           int
+	}{
+		{filepath.Join(t.TempDir(), "missing.ttf"), "", 0},
+		{t.TempDir(), "", 0},
+		{"/fonts/a:b.ttf", "", 0},
+		{"", "center", 0},
+		{"", "", 500},
+	} {
+		if err := rm.SetTimestampOptions(tt.font, tt.position, tt.size); !errors.Is(err, ErrInvalidTimestampOptions) {
+			t.Errorf("SetTimestampOptions(%q, %q, %d) = %v, want ErrInvalidTimestampOptions", tt.font, tt.position, tt.size, err)
+		}
+	}
+}
+
+func TestRecordingManager_Proxy(t *testing.T) {
+	// The fake ffmpeg writes every .mp4 output, but no proxy when asked to
+	// fail it, and notes the args of the recording process
+	script := `#!/bin/sh
+for a; do
+	case "$a" in
+	*.proxy.mp4) [ -n "$FAKE_PROXY_FAIL" ] || echo data > "$a" ;;
+	*.mp4) echo data > "$a"; echo "$@" > "$a.args" ;;
+	esac
+done
+trap 'exit 0' INT TERM
+sleep 30 &
+wait
+`
+	installFakeFFmpeg(t, script)
+
+	tempDir := t.TempDir()
+	log := logger.NewLoggerWithWriter(io.Discard)
+	relayMgr := NewRelayManager(log, tempDir)
+	rm := NewRecordingManager(log, tempDir, relayMgr)
+	defer rm.Shutdown()
+
+	source := "rtsp://camera.example.com/stream"
+	if err := rm.StartRecordingWithOptions(context.Background(), "cam", source, "720p", RecordingOptions{Proxy: &RecordingProxy{}}); !errors.Is(err, ErrInvalidRecordingProxy) {
+		t.Errorf("expected a proxy of a transcoding profile to be refused, got %v", err)
+	}
+	if err := rm.StartRecordingWithOptions(context.Background(), "cam", source, "", RecordingOptions{Proxy: &RecordingProxy{VideoBitrate: "fast"}}); !errors.Is(err, ErrInvalidRecordingProxy) {
+		t.Errorf("expected an invalid proxy bitrate to be refused, got %v", err)
+	}
+
+	// record runs a recording with a proxy and returns its listed archive and proxy
+	record := func() (archive, proxy *Recording) {
+		t.Helper()
+		if err := rm.StartRecordingWithOptions(context.Background(), "cam", source, "", RecordingOptions{Proxy: &RecordingProxy{Height: 240}}); err != nil {
+			t.Fatalf("StartRecordingWithOptions failed: %v", err)
+		}
+		var filename string
+		for _, r := range rm.ListRecordings() {
+			if r.Active && r.Profile == "" {
+				filename = r.Filename
+			}
+		}
+		deadline := time.Now().Add(3 * time.Second)
+		for {
+			if _, err := os.Stat(filepath.Join(tempDir, filename+".args")); err == nil {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("the recording ffmpeg did not start")
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		if err := rm.StopRecording("cam", source, ""); err != nil {
+			t.Fatalf("StopRecording failed: %v", err)
+		}
+		for deadline = time.Now().Add(3 * time.Second); ; time.Sleep(20 * time.Millisecond) {
+			archive, proxy = nil, nil
+			for _, r := range rm.ListRecordings() {
+				switch {
+				case r.Filename == filename:
+					archive = r
+				case r.Filename == proxyFilename(filename):
+					proxy = r
+				}
+			}
+			if archive != nil && !archive.Active {
+				return archive, proxy
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("the recording was not stopped")
+			}
+		}
+	}
+
+	archive, proxy := record()
+	args, _ := os.ReadFile(filepath.Join(tempDir, archive.Filename+".args"))
+	if !strings.Contains(string(args), " -c copy "+archive.FilePath+" ") || !strings.Contains(string(args), "-vf scale=-2:240 -b:v 800k -c:a aac -b:a 96k ") {
+		t.Errorf("expected a copy and a 240p proxy output, got %s", args)
+	}
+	if archive.ProxyFilename != proxyFilename(archive.Filename) || archive.ProxyError != "" {
+		t.Errorf("expected the archive to name its proxy, got %+v", archive)
+	}
+	if proxy == nil || proxy.Profile != "proxy" || proxy.Name != "cam" || proxy.FileSize == 0 {
+		t.Errorf("expected the proxy to be listed as a recording, got %+v", proxy)
+	}
+
+	// A failed proxy leaves the archive
+	t.Setenv("FAKE_PROXY_FAIL", "1")
+	time.Sleep(1100 * time.Millisecond) // a new second, so a new filename
+	archive, proxy = record()
+	if archive.FileSize == 0 || archive.ProxyError == "" || archive.ProxyFilename != "" {
+		t.Errorf("expected the archive with the proxy failure, got %+v", archive)
+	}
+	if proxy != nil {
+		t.Errorf("expected no failed proxy to be listed, got %+v", proxy)
+	}
+}
+
+func TestSSEBroker_MaxClients(t *testing.T) {
+	b := newSSEBroker()
+	b.maxClients = 1
+	srv := httptest.NewServer(http.HandlerFunc(b.serve))
+	defer srv.Close()
+	defer b.Shutdown()
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 for the first client, got %d", resp.StatusCode)
+	}
+
+	resp2, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp2.Body.Close()
+	if resp2.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 over the limit, got %d", resp2.StatusCode)
+	}
+
+	b.mu.Lock()
+	b.maxClients = 0
+	b.mu.Unlock()
+	if err := b.AddClient(make(chan string, 1)); err != nil {
+		t.Errorf("expected no limit with 0, got %v", err)
+	}
+}
+
+func TestSSEBroker_EvictsSlowClient(t *testing.T) {
+	recv := func(ch chan string) (string, bool) {
+		t.Helper()
+		select {
+		case msg, ok := <-ch:
+			return msg, ok
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting on an SSE client channel")
+			return "", false
+		}
+	}
+	b := newSSEBroker()
+	b.stall = 100 * time.Millisecond
+	defer b.Shutdown()
+	slow, fast := make(chan string, 1), make(chan string, 1)
+	b.AddClient(slow)
+	b.AddClient(fast)
+
+	// A burst of updates coalesces into the pending one instead of evicting
+	for i := 0; i < 100; i++ {
+		b.NotifyAll("update")
+	}
+	recv(fast)
+	b.mu.Lock()
+	n := len(b.clients)
+	b.mu.Unlock()
+	if n != 2 {
+		t.Fatalf("expected a burst to keep both clients, got %d", n)
+	}
+
+	// An update left pending for longer than the stall timeout evicts
+	time.Sleep(150 * time.Millisecond)
+	b.NotifyAll("update")
+	recv(fast)
+	if _, ok := recv(slow); !ok {
+		t.Fatal("expected the slow client's buffered update before it is closed")
+	}
+	if _, ok := recv(slow); ok {
+		t.Fatal("expected the slow client to be disconnected")
+	}
+	b.NotifyAll("update")
+	if msg, _ := recv(fast); msg != "update" {
+		t.Errorf("expected the fast client to keep receiving, got %q", msg)
+	}
+	b.mu.Lock()
+	n = len(b.clients)
+	b.mu.Unlock()
+	if n != 1 {
+		t.Errorf("expected 1 client left, got %d", n)
+	}
+	// Removing an evicted client must not close its channel again
+	b.RemoveClient(slow)
+}
@@ -5,21 +5,28 @@ import (
 	"io"
 	"net/http"
 	"os"
-	"path/filepath"
+	"path"
 	"strings"
 )
 
 // ApiDownloadRecording serves a recording file for download with security checks
 func ApiDownloadRecording(rm *RecordingManager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodGet) {
+			return
+		}
+		// Large recordings take longer to send than the API write timeout
+		httputil.DisableWriteTimeout(w)
 		filename := r.URL.Query().Get("filename")
 		if filename == "" {
 			httputil.WriteError(w, http.StatusBadRequest, "Missing filename")
 			return
 		}
 
-		// Security: Validate filename to prevent path traversal attacks
-		if strings.Contains(filename, "..") || strings.Contains(filename, "/") || strings.Contains(filename, "\\") {
+		// Security: the filename may name date directories but never leave
+		// the recordings directory
+		cleanPath, err := recordingFilePath(rm.dir, filename)
+		if err != nil {
 			httputil.WriteError(w, http.StatusBadRequest, "Invalid filename")
 			return
 		}
@@ -30,22 +37,12 @@ func ApiDownloadRecording(rm *RecordingManager) http.HandlerFunc {
 			return
 		}
 
-		// Resolve and clean the file path
-		filePath := filepath.Join(rm.dir, filename)
-		cleanPath := filepath.Clean(filePath)
-
-		// Additional security: Ensure the resolved path is still within the recordings directory
-		if !strings.HasPrefix(cleanPath, rm.dir) {
-			httputil.WriteError(w, http.StatusForbidden, "Access denied")
-			return
-		}
-
 		if _, err := os.Stat(cleanPath); err != nil {
 			httputil.WriteError(w, http.StatusNotFound, "File not found")
 			return
 		}
 
-		w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+		w.Header().Set("Content-Disposition", "attachment; filename="+path.Base(filename))
 		w.Header().Set("Content-Type", "video/mp4")
 
 		f, err := os.Open(cleanPath)
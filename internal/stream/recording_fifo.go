@@ -0,0 +1,174 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go-mls/internal/logger"
+)
+
+// A recording can also be streamed live to a named pipe (FIFO), so an external
+// tool can process it while it is being written. The recording ffmpeg writes
+// the file and an MPEG-TS copy through the tee muxer; the copy goes over an
+// inherited pipe to a pump that forwards it to the FIFO. The pump, not ffmpeg,
+// opens the FIFO, so the recording never blocks waiting for a reader and a
+// reader may attach, go away and come back at any time: without one, or while
+// it falls behind, the stream is dropped rather than held up. The FIFO is
+// created when the recording starts and removed when it ends. Unlike a
+// RecordingSink, which receives finished files, the FIFO sees the recording
+// live. Named pipes need a Unix system, see recording_fifo_unix.go.
+
+// DefaultFIFODir is where recording FIFOs are created unless SetFIFODir sets
+// another directory
+var DefaultFIFODir = filepath.Join(os.TempDir(), "go-mls-fifo")
+
+// ErrRecordingFIFOUnsupported is returned for a FIFO on a system without named pipes
+var ErrRecordingFIFOUnsupported = errors.New("recording FIFOs need a Unix system")
+
+// ErrRecordingFIFOInUse is returned when another active recording streams to the same FIFO
+var ErrRecordingFIFOInUse = errors.New("recording FIFO in use")
+
+// errNoFIFOReader and errFIFOFull are returned by fifoFile's platform code
+var (
+	errNoFIFOReader = errors.New("no FIFO reader")
+	errFIFOFull     = errors.New("FIFO full")
+)
+
+const (
+	// fifoReopenInterval is how often the pump looks for a reader while it has none
+	fifoReopenInterval = 500 * time.Millisecond
+	fifoBufferSize     = 64 * 1024
+)
+
+// SetFIFODir sets the directory recording FIFOs are created in, creating it;
+// empty restores DefaultFIFODir
+func (rm *RecordingManager) SetFIFODir(dir string) error {
+	if dir == "" {
+		dir = DefaultFIFODir
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create FIFO directory: %w", err)
+	}
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	rm.fifoDir = dir
+	return nil
+}
+
+// fifoName names the FIFO of a recording: after the input, and its profile
+// if any, so a reader knows where to find it before the recording starts
+func fifoName(name, profile string) string {
+	if profile != "" {
+		return name + "." + profile + ".ts"
+	}
+	return name + ".ts"
+}
+
+// fifoPathLocked returns the path of the FIFO called fifoName, failing if an
+// active recording already uses it. Caller must hold rm.mu.
+func (rm *RecordingManager) fifoPathLocked(fifoName string) (string, error) {
+	dir := rm.fifoDir
+	if dir == "" {
+		dir = DefaultFIFODir
+	}
+	path, err := recordingFilePath(dir, fifoName)
+	if err != nil {
+		return "", err
+	}
+	for _, r := range rm.recordings {
+		if r.Active && r.FIFOPath == path {
+			return "", fmt.Errorf("%w: %s", ErrRecordingFIFOInUse, fifoName)
+		}
+	}
+	return path, nil
+}
+
+// teeEscape escapes the characters the tee muxer treats specially in an
+// output filename
+func teeEscape(path string) string {
+	return strings.NewReplacer(`\`, `\\`, `|`, `\|`, `[`, `\[`, `]`, `\]`).Replace(path)
+}
+
+// fifoTeeArgs returns the output args writing filePath and, ignoring any
+// failure of it, an MPEG-TS copy to the inherited file descriptor 3. The tee
+// muxer selects no streams itself, so all are mapped unless mapped already.
+func fifoTeeArgs(filePath string, mapped bool) []string {
+	args := []string{"-f", "tee"}
+	if !mapped {
+		args = append(args, "-map", "0:v?", "-map", "0:a?")
+	}
+	format := strings.TrimPrefix(filepath.Ext(filePath), ".")
+	return append(args, "[f="+format+"]"+teeEscape(filePath)+"|[f=mpegts:onfail=ignore]pipe:3")
+}
+
+// fifoPump forwards what ffmpeg writes to src into the FIFO at path
+type fifoPump struct {
+	path string
+	src  io.ReadCloser
+	log  *logger.Logger
+}
+
+// startFIFOPump creates the FIFO at path and returns the write end of the
+// pipe ffmpeg is to inherit, whose data a started pump forwards to the FIFO
+// until the write end is closed by ffmpeg and the caller, which closes its
+// copy once ffmpeg started or failed to
+func startFIFOPump(path string, log *logger.Logger) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create FIFO directory: %w", err)
+	}
+	if err := makeFIFO(path); err != nil {
+		return nil, err
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	p := &fifoPump{path: path, src: r, log: log}
+	go p.run()
+	return w, nil
+}
+
+// run copies until ffmpeg closes its end of the pipe, then removes the FIFO
+func (p *fifoPump) run() {
+	defer os.Remove(p.path)
+	defer p.src.Close()
+
+	var out fifoFile
+	attached := false
+	var lastOpen time.Time
+	buf := make([]byte, fifoBufferSize)
+	for {
+		n, err := p.src.Read(buf)
+		if n > 0 {
+			if !attached && time.Since(lastOpen) >= fifoReopenInterval {
+				lastOpen = time.Now()
+				if f, openErr := openFIFO(p.path); openErr == nil {
+					out, attached = f, true
+					p.log.Info("Reader attached to recording FIFO %s", p.path)
+				} else if !errors.Is(openErr, errNoFIFOReader) {
+					p.log.Warn("Cannot open recording FIFO %s: %v", p.path, openErr)
+				}
+			}
+			if attached {
+				// A reader that falls behind loses data rather than stalling the recording
+				if _, writeErr := out.write(buf[:n]); writeErr != nil && !errors.Is(writeErr, errFIFOFull) {
+					p.log.Info("Reader of recording FIFO %s went away: %v", p.path, writeErr)
+					out.close()
+					attached = false
+				}
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	if attached {
+		out.close()
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !unix
+
+package stream
+
+const fifoSupported = false
+
+func makeFIFO(path string) error {
+	return ErrRecordingFIFOUnsupported
+}
+
+type fifoFile int
+
+func openFIFO(path string) (fifoFile, error) {
+	return -1, ErrRecordingFIFOUnsupported
+}
+
+func (f fifoFile) write(b []byte) (int, error) {
+	return 0, ErrRecordingFIFOUnsupported
+}
+
+func (f fifoFile) close() {}
@@ -0,0 +1,118 @@
+//go:build unix
+
+package stream
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-mls/internal/logger"
+)
+
+func TestFIFOTeeArgs(t *testing.T) {
+	got := strings.Join(fifoTeeArgs("/rec/a|b[1].mp4", false), " ")
+	want := `-f tee -map 0:v? -map 0:a? [f=mp4]/rec/a\|b\[1\].mp4|[f=mpegts:onfail=ignore]pipe:3`
+	if got != want {
+		t.Errorf("fifoTeeArgs = %q, want %q", got, want)
+	}
+	if got := strings.Join(fifoTeeArgs("/rec/a.mp4", true), " "); strings.Contains(got, "-map") {
+		t.Errorf("expected no maps when already mapped, got %q", got)
+	}
+}
+
+func TestRecordingManager_FIFO(t *testing.T) {
+	// The fake ffmpeg writes the file of the tee output, notes its args and
+	// keeps writing chunks to fd 3 until stopped
+	script := `#!/bin/sh
+for a; do
+	case "$a" in
+	*"|[f=mpegts"*) f="${a#*]}"; f="${f%%|*}"; echo data > "$f"; echo "$@" > "$f.args" ;;
+	esac
+done
+trap 'exit 0' INT TERM
+while :; do echo chunk >&3; sleep 0.05; done
+`
+	installFakeFFmpeg(t, script)
+
+	tempDir := t.TempDir()
+	fifoDir := t.TempDir()
+	log := logger.NewLoggerWithWriter(io.Discard)
+	relayMgr := NewRelayManager(log, tempDir)
+	rm := NewRecordingManager(log, tempDir, relayMgr)
+	defer rm.Shutdown()
+	if err := rm.SetFIFODir(fifoDir); err != nil {
+		t.Fatalf("SetFIFODir failed: %v", err)
+	}
+
+	source := "rtsp://camera.example.com/stream"
+	if err := rm.StartRecordingWithOptions(context.Background(), "cam", source, "", RecordingOptions{FIFO: true}); err != nil {
+		t.Fatalf("StartRecordingWithOptions failed: %v", err)
+	}
+	if err := rm.StartRecordingWithOptions(context.Background(), "cam", "rtsp://other.example.com/stream", "", RecordingOptions{FIFO: true}); !errors.Is(err, ErrRecordingFIFOInUse) {
+		t.Errorf("expected a second recording on the same FIFO to be refused, got %v", err)
+	}
+	var rec *Recording
+	for _, r := range rm.ListRecordings() {
+		if r.Active {
+			rec = r
+		}
+	}
+	if rec == nil || rec.FIFOName != "cam.ts" {
+		t.Fatalf("expected the recording to list its FIFO, got %+v", rec)
+	}
+	fifoPath := filepath.Join(fifoDir, "cam.ts")
+
+	// read attaches a reader and returns the first line it gets
+	read := func() string {
+		t.Helper()
+		lines := make(chan string, 1)
+		go func() {
+			f, err := os.Open(fifoPath)
+			if err != nil {
+				lines <- err.Error()
+				return
+			}
+			defer f.Close()
+			line, _ := bufio.NewReader(f).ReadString('\n')
+			lines <- line
+		}()
+		select {
+		case line := <-lines:
+			return line
+		case <-time.After(3 * time.Second):
+			t.Fatal("nothing was written to the FIFO")
+			return ""
+		}
+	}
+	if line := read(); line != "chunk\n" {
+		t.Errorf("expected a chunk from the FIFO, got %q", line)
+	}
+	// The pump notices the reader left and waits for the next one
+	if line := read(); line != "chunk\n" {
+		t.Errorf("expected a chunk after reattaching, got %q", line)
+	}
+
+	args, _ := os.ReadFile(rec.FilePath + ".args")
+	if !strings.Contains(string(args), "-c copy -f tee -map 0:v? -map 0:a? [f=mp4]"+rec.FilePath+"|[f=mpegts:onfail=ignore]pipe:3") {
+		t.Errorf("expected a tee output of the file and fd 3, got %s", args)
+	}
+
+	if err := rm.StopRecording("cam", source, ""); err != nil {
+		t.Fatalf("StopRecording failed: %v", err)
+	}
+	for deadline := time.Now().Add(3 * time.Second); ; time.Sleep(20 * time.Millisecond) {
+		if _, err := os.Lstat(fifoPath); os.IsNotExist(err) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the FIFO to be removed after the recording stopped")
+		}
+	}
+}
@@ -0,0 +1,63 @@
+//go:build unix
+
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// fifoSupported reports whether recordings can be streamed to FIFOs
+const fifoSupported = true
+
+// makeFIFO creates a FIFO at path readable and writable by the owner only,
+// replacing a FIFO left behind by an earlier run but no other file
+func makeFIFO(path string) error {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeNamedPipe == 0 {
+			return fmt.Errorf("cannot create recording FIFO %s: a file of that name exists", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+	if err := syscall.Mkfifo(path, 0600); err != nil {
+		return fmt.Errorf("cannot create recording FIFO %s: %w", path, err)
+	}
+	return nil
+}
+
+// fifoFile is the write end of a FIFO, in non-blocking mode
+type fifoFile int
+
+// openFIFO opens the FIFO at path for writing without blocking, failing with
+// errNoFIFOReader while no reader has it open
+func openFIFO(path string) (fifoFile, error) {
+	fd, err := syscall.Open(path, syscall.O_WRONLY|syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0)
+	if errors.Is(err, syscall.ENXIO) {
+		return -1, errNoFIFOReader
+	}
+	if err != nil {
+		return -1, err
+	}
+	return fifoFile(fd), nil
+}
+
+// write writes b, failing with errFIFOFull when the reader has not caught up
+// and errNoFIFOReader when it closed the FIFO
+func (f fifoFile) write(b []byte) (int, error) {
+	n, err := syscall.Write(int(f), b)
+	switch {
+	case errors.Is(err, syscall.EAGAIN):
+		return n, errFIFOFull
+	case errors.Is(err, syscall.EPIPE):
+		return n, errNoFIFOReader
+	}
+	return n, err
+}
+
+func (f fifoFile) close() {
+	syscall.Close(int(f))
+}
@@ -0,0 +1,55 @@
+package stream
+
+import (
+	"fmt"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// DefaultRecordingFinalizeTimeout is how long a stopped recording's ffmpeg may
+// take to write its trailer (the mp4 moov atom) before it is killed
+const DefaultRecordingFinalizeTimeout = 10 * time.Second
+
+// recordingKillWait bounds the wait for an ffmpeg that was killed after
+// missing the finalize timeout, so a process stuck in the kernel cannot hold
+// up shutdown
+const recordingKillWait = 5 * time.Second
+
+// recordingStopSignals are the graceful stop signals a recording may use
+var recordingStopSignals = map[string]syscall.Signal{
+	"SIGINT":  syscall.SIGINT,
+	"SIGTERM": syscall.SIGTERM,
+}
+
+// SetFinalizeOptions sets the signal that asks a recording's ffmpeg to finish
+// its file ("SIGINT" or "SIGTERM", empty picks one by container) and how long
+// it may take before being killed. Call it before recordings start.
+func (rm *RecordingManager) SetFinalizeOptions(signal string, timeout time.Duration) error {
+	sig, ok := recordingStopSignals[signal]
+	if signal != "" && !ok {
+		return fmt.Errorf("unsupported recording stop signal %q", signal)
+	}
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	rm.stopSignal = sig
+	if timeout > 0 {
+		rm.finalizeTimeout = timeout
+	}
+	return nil
+}
+
+// stopSignalFor returns the graceful stop signal for a recording written to
+// filename. mp4 and mov only become playable once ffmpeg writes the moov atom
+// on exit, which it reliably does on SIGINT, as when quitting ffmpeg with
+// Ctrl-C. rm.mu must be held.
+func (rm *RecordingManager) stopSignalFor(filename string) syscall.Signal {
+	if rm.stopSignal != 0 {
+		return rm.stopSignal
+	}
+	switch filepath.Ext(filename) {
+	case ".mp4", ".mov":
+		return syscall.SIGINT
+	}
+	return syscall.SIGTERM
+}
@@ -0,0 +1,103 @@
+package stream
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"time"
+)
+
+// Why a recording stopped, reported in RecordingCompletedEvent.StopReason
+const (
+	RecordingCompleted = "completed" // ffmpeg ended on its own, e.g. the input finished
+	RecordingFailed    = "error"     // ffmpeg exited with an error
+	RecordingStopped   = "stopped"   // stopped through the API
+	RecordingShutdown  = "shutdown"  // stopped because go-mls shut down
+)
+
+// RecordingCompletedEvent is the webhook payload sent when a recording stops
+type RecordingCompletedEvent struct {
+	Event      string  `json:"event"` // always "recording.completed"
+	Name       string  `json:"name"`
+	Source     string  `json:"source"` // credentials redacted
+	Profile    string  `json:"profile,omitempty"`
+	Filename   string  `json:"filename"`
+	Size       int64   `json:"size"`
+	Duration   float64 `json:"duration"` // seconds
+	StopReason string  `json:"stop_reason"`
+	SHA256     string  `json:"sha256,omitempty"` // empty if the file could not be read
+}
+
+// SetWebhook makes every recording that stops from now on be reported to n.
+// A nil notifier disables the webhook.
+func (rm *RecordingManager) SetWebhook(n *WebhookNotifier) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	rm.webhook = n
+}
+
+// finishedLocked starts the background work on a recording that just
+// stopped: the completion webhook, then the upload to the sink. It never
+// blocks, so the recording's monitor goroutine finishes straight away.
+// Caller must hold rm.mu.
+func (rm *RecordingManager) finishedLocked(key string, r *Recording, reason string) {
+	upload := rm.sink != nil && r.FilePath != "" && r.FileSize > 0
+	if rm.webhook == nil && !upload {
+		return
+	}
+	if rm.ctx.Err() != nil {
+		if upload {
+			r.UploadStatus = UploadFailed
+			r.UploadError = "shutting down"
+		}
+		return
+	}
+	if upload {
+		r.UploadStatus = UploadPending
+		r.UploadError = ""
+	}
+
+	webhook, sink, deleteLocal := rm.webhook, rm.sink, rm.deleteAfterUpload
+	event := RecordingCompletedEvent{
+		Event:      "recording.completed",
+		Name:       r.Name,
+		Source:     RedactURL(r.Source),
+		Profile:    r.Profile,
+		Filename:   r.Filename,
+		Size:       r.FileSize,
+		Duration:   r.StoppedAt.Sub(r.StartedAt).Round(time.Millisecond).Seconds(),
+		StopReason: reason,
+	}
+	filePath := r.FilePath
+	rm.finishWg.Add(1)
+	go func() {
+		defer rm.finishWg.Done()
+		// Hash before uploading, which may delete the local copy
+		if webhook != nil {
+			if sum, err := fileSHA256(filePath); err == nil {
+				event.SHA256 = sum
+			} else {
+				rm.Logger.Warn("Could not hash recording %s: %v", event.Filename, err)
+			}
+			webhook.Notify(event)
+		}
+		if upload {
+			rm.uploadRecording(key, sink, deleteLocal)
+		}
+	}()
+}
+
+// fileSHA256 returns the hex SHA-256 of the file at path
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
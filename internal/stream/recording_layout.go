@@ -0,0 +1,151 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// A date layout files recordings in nested directories by their start time,
+// e.g. "%Y/%m/%d" puts one started on 1 June 2024 under 2024/06/01/ in the
+// recordings directory. A recording's Filename is then its slash-separated
+// path relative to the recordings directory, such as 2024/06/01/cam_1717200000.mp4;
+// without a layout it is the bare file name as before. Listing, download,
+// deletion and pruning walk the whole tree.
+
+// ErrInvalidDateLayout is returned for a date layout with unknown fields or
+// path elements that could leave the recordings directory
+var ErrInvalidDateLayout = errors.New("invalid recording date layout")
+
+// ErrInvalidRecordingPath is returned for a recording filename that is not a
+// clean relative path inside the recordings directory
+var ErrInvalidRecordingPath = errors.New("invalid recording path")
+
+// dateLayoutFields maps the supported strftime fields to Go time layouts
+var dateLayoutFields = map[byte]string{
+	'Y': "2006",
+	'm': "01",
+	'd': "02",
+	'H': "15",
+}
+
+// validateDateLayout checks a date layout: %Y, %m, %d and %H fields and
+// literal directory names, separated by slashes. Empty keeps recordings flat.
+func validateDateLayout(layout string) error {
+	if layout == "" {
+		return nil
+	}
+	for i := 0; i < len(layout); i++ {
+		if layout[i] != '%' {
+			continue
+		}
+		if i+1 == len(layout) || dateLayoutFields[layout[i+1]] == "" {
+			return fmt.Errorf("%w %q: only %%Y, %%m, %%d and %%H are supported", ErrInvalidDateLayout, layout)
+		}
+		i++
+	}
+	if _, err := cleanRecordingPath(layout); err != nil {
+		return fmt.Errorf("%w %q: must be a relative path without . or .. elements", ErrInvalidDateLayout, layout)
+	}
+	return nil
+}
+
+// expandDateLayout returns the directory of layout for t, empty for no layout
+func expandDateLayout(layout string, t time.Time) string {
+	var b strings.Builder
+	for i := 0; i < len(layout); i++ {
+		if layout[i] == '%' && i+1 < len(layout) {
+			if goLayout := dateLayoutFields[layout[i+1]]; goLayout != "" {
+				b.WriteString(t.Format(goLayout))
+				i++
+				continue
+			}
+		}
+		b.WriteByte(layout[i])
+	}
+	return strings.Trim(b.String(), "/")
+}
+
+// SetDateLayout sets the date layout new recordings are filed under, see
+// validateDateLayout; existing recordings stay where they are
+func (rm *RecordingManager) SetDateLayout(layout string) error {
+	if err := validateDateLayout(layout); err != nil {
+		return err
+	}
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	rm.dateLayout = strings.Trim(layout, "/")
+	return nil
+}
+
+// cleanRecordingPath checks that name is a clean slash-separated relative
+// path: no absolute path, backslash, empty, . or .. element
+func cleanRecordingPath(name string) (string, error) {
+	if name == "" || strings.Contains(name, "\\") || path.IsAbs(name) {
+		return "", ErrInvalidRecordingPath
+	}
+	for _, elem := range strings.Split(name, "/") {
+		if elem == "" || elem == "." || elem == ".." {
+			return "", ErrInvalidRecordingPath
+		}
+	}
+	return name, nil
+}
+
+// recordingFilePath resolves a recording filename, possibly with date
+// directories, to its path under dir, refusing anything that would leave dir
+func recordingFilePath(dir, name string) (string, error) {
+	name, err := cleanRecordingPath(name)
+	if err != nil {
+		return "", err
+	}
+	full := filepath.Join(dir, filepath.FromSlash(name))
+	if rel, err := filepath.Rel(dir, full); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", ErrInvalidRecordingPath
+	}
+	return full, nil
+}
+
+// walkRecordings calls fn with the slash-separated relative name and entry of
+// every .mp4 file in the recordings directory tree. Hidden directories and
+// symlinked ones are not entered.
+func (rm *RecordingManager) walkRecordings(fn func(name string, d fs.DirEntry)) error {
+	return filepath.WalkDir(rm.dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == rm.dir {
+				return err
+			}
+			return nil // a directory vanished or is unreadable, skip it
+		}
+		if d.IsDir() {
+			if p != rm.dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(d.Name()) != ".mp4" {
+			return nil
+		}
+		rel, err := filepath.Rel(rm.dir, p)
+		if err != nil {
+			return nil
+		}
+		fn(filepath.ToSlash(rel), d)
+		return nil
+	})
+}
+
+// removeEmptyDateDirs removes the directories of a deleted recording that are
+// left empty, up to but not including the recordings directory
+func (rm *RecordingManager) removeEmptyDateDirs(filePath string) {
+	for dir := filepath.Dir(filePath); dir != rm.dir && strings.HasPrefix(dir, rm.dir+string(filepath.Separator)); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			return // not empty, or already gone
+		}
+	}
+}
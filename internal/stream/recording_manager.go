@@ -2,12 +2,18 @@ package stream
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"go-mls/internal/httputil"
 	"go-mls/internal/logger"
+	"io/fs"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 	"unsafe"
 
@@ -17,16 +23,56 @@ import (
 // Recording represents a recording session or file
 type Recording struct {
 	// --- Fields exposed to API/JSON ---
-	Name      string    `json:"name"`
-	Source    string    `json:"source"`
-	Filename  string    `json:"filename"`
-	FileSize  int64     `json:"file_size"`
-	StartedAt time.Time `json:"started_at"`
-	StoppedAt time.Time `json:"stopped_at,omitempty"`
-	Active    bool      `json:"active"`
+	ID         string    `json:"id,omitempty"` // correlation ID tagging this recording's log lines
+	Name       string    `json:"name"`
+	Source     string    `json:"source"`
+	Profile    string    `json:"profile,omitempty"`     // quality profile, empty for a stream copy
+	AudioTrack string    `json:"audio_track,omitempty"` // index of the input's audio tracks recorded, empty for the default
+	Timestamp  bool      `json:"timestamp,omitempty"`   // wall-clock time burnt into the video, see SetTimestampOptions
+	Filename   string    `json:"filename"`
+	FileSize   int64     `json:"file_size"`
+	StartedAt  time.Time `json:"started_at"`
+	StoppedAt  time.Time `json:"stopped_at,omitempty"`
+	Active     bool      `json:"active"`
+
+	// Proxy written alongside by the same ffmpeg, see RecordingProxy
+	Proxy         *RecordingProxy `json:"proxy,omitempty"`
+	ProxyFilename string          `json:"proxy_filename,omitempty"`
+	ProxyError    string          `json:"proxy_error,omitempty"` // why the proxy was dropped, the archive is unaffected
+
+	// Named pipe the recording is streamed to live, see recording_fifo.go
+	FIFOName string `json:"fifo_name,omitempty"`
+
+	// Upload to the configured RecordingSink, empty when recordings stay local
+	UploadStatus   string `json:"upload_status,omitempty"`
+	UploadError    string `json:"upload_error,omitempty"`
+	RemoteLocation string `json:"remote_location,omitempty"`
 
 	// --- Internal fields (not exposed to API) ---
-	FilePath string `json:"-"` // Full filesystem path - security sensitive
+	FilePath      string `json:"-"` // Full filesystem path - security sensitive
+	ProxyFilePath string `json:"-"`
+	FIFOPath      string `json:"-"`
+}
+
+// ErrUnknownRecordingProfile is returned when a recording asks for a quality
+// profile that is not in recordingProfiles
+var ErrUnknownRecordingProfile = errors.New("unknown recording profile")
+
+// ErrRecordingLimitReached is returned when as many recordings as allowed by
+// SetMaxConcurrentRecordings are already active
+var ErrRecordingLimitReached = errors.New("concurrent recording limit reached")
+
+// ErrUnknownInput is returned when a recording gives no source and the relay
+// manager knows no input of that name
+var ErrUnknownInput = errors.New("unknown input")
+
+// recordingProfiles maps a recording quality profile to its ffmpeg output
+// args. The empty profile copies the input untouched; the others re-encode, so
+// an archive and a small proxy can be recorded from one input at once.
+var recordingProfiles = map[string][]string{
+	"":      {"-c", "copy"},
+	"720p":  {"-c:v", "libx264", "-preset", "veryfast", "-vf", "scale=-2:720", "-b:v", "3000k", "-c:a", "aac", "-b:a", "128k"},
+	"proxy": {"-c:v", "libx264", "-preset", "veryfast", "-vf", "scale=-2:360", "-b:v", "800k", "-c:a", "aac", "-b:a", "96k"},
 }
 
 // RecordingManager manages active and completed recordings
@@ -38,15 +84,48 @@ type RecordingManager struct {
 	processes  map[string]*FFmpegProcess // Now uses FFmpegProcess abstraction
 	dones      map[string]chan struct{}  // done channel for each recording
 
+	shuttingDown bool // set by Shutdown so stops are reported as such
+
+	// --- Work on finished recordings (webhook, upload), see finishedLocked ---
+	sink              RecordingSink
+	deleteAfterUpload bool
+	webhook           *WebhookNotifier
+	finishWg          sync.WaitGroup
+
+	// --- Stopping, see SetFinalizeOptions ---
+	stopSignal      syscall.Signal // 0 picks one by container
+	finalizeTimeout time.Duration
+
+	// --- Disk quota, see pruneRecordings ---
+	maxTotalSize int64 // bytes, 0 for unlimited
+	pruneMu      sync.Mutex
+
+	maxConcurrent int // active recordings allowed at once, 0 for unlimited
+
+	dateLayout string // directories new recordings are filed under, see SetDateLayout
+
+	timestamp timestampOptions // overlay of RecordingOptions.Timestamp, see SetTimestampOptions
+
+	fifoDir string // where RecordingOptions.FIFO pipes are created, see SetFIFODir
+
+	previewInputs  func() []string // inputs with live HLS previews, see SetPreviewInputs
+	restoredInputs []string        // inputs registered by RestoreState, kept saved while they exist
+
 	// --- Immutable/config fields (set at construction) ---
 	Logger   *logger.Logger // Logger
 	dir      string         // Recordings directory
 	RelayMgr *RelayManager  // Reference to RelayManager for local relay
 
 	// --- Shutdown support ---
-	ctx       context.Context
-	cancel    context.CancelFunc
-	watcherWg sync.WaitGroup
+	ctx         context.Context
+	cancel      context.CancelFunc
+	watcherWg   sync.WaitGroup
+	recordingWg sync.WaitGroup // recording monitor goroutines
+
+	// --- Recordings directory health, see checkStorage ---
+	storageErr     error // protected by storageMu, nil while writable
+	storageMu      sync.Mutex
+	watcherRunning atomic.Bool
 }
 
 // NewRecordingManager creates a RecordingManager and ensures the directory exists
@@ -65,34 +144,150 @@ func NewRecordingManager(l *logger.Logger, dir string, relayMgr *RelayManager) *
 		RelayMgr:   relayMgr,
 		ctx:        ctx,
 		cancel:     cancel,
+
+		finalizeTimeout: DefaultRecordingFinalizeTimeout,
 	}
 
 	// Start the directory watcher with proper shutdown support
+	rm.startWatcher()
+	rm.checkStorage()
 	rm.watcherWg.Add(1)
-	go rm.watchRecordingsDir()
+	go rm.monitorStorage()
 
 	return rm
 }
 
-// StartRecording starts recording a source to a file using ffmpeg, using local relay URL
+// SetMaxConcurrentRecordings caps how many recordings may be active at once;
+// 0 means unlimited
+func (rm *RecordingManager) SetMaxConcurrentRecordings(max int) {
+	rm.mu.Lock()
+	rm.maxConcurrent = max
+	rm.mu.Unlock()
+}
+
+// RecordingStats is the number of active recordings and the limit on them
+type RecordingStats struct {
+	Active        int `json:"active"`
+	MaxConcurrent int `json:"max_concurrent"` // 0 for unlimited
+}
+
+// Stats reports the active recordings against the concurrent recording limit
+func (rm *RecordingManager) Stats() RecordingStats {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	return RecordingStats{Active: rm.activeCountLocked(), MaxConcurrent: rm.maxConcurrent}
+}
+
+// activeCountLocked counts active recordings, including ones still starting.
+// Requires rm.mu held.
+func (rm *RecordingManager) activeCountLocked() int {
+	active := 0
+	for _, rec := range rm.recordings {
+		if rec.Active {
+			active++
+		}
+	}
+	return active
+}
+
+// StartRecording starts recording a source to a file using ffmpeg, using local relay URL.
+// profile selects the quality (see recordingProfiles); recordings of one source
+// with different profiles run side by side and share the input relay.
 // This function implements a two-phase recording start to prevent race conditions:
-// 1. First, create a placeholder recording entry to reserve the name+source combination
+// 1. First, create a placeholder recording entry to reserve the name+source+profile combination
 // 2. Then start the actual recording process
-func (rm *RecordingManager) StartRecording(ctx context.Context, name, sourceURL string) error {
-	rm.Logger.Info("StartRecording called: name=%s, source=%s", name, sourceURL)
+func (rm *RecordingManager) StartRecording(ctx context.Context, name, sourceURL, profile string) error {
+	return rm.StartRecordingWithOptions(ctx, name, sourceURL, profile, RecordingOptions{})
+}
+
+// RecordingOptions are optional settings of a recording
+type RecordingOptions struct {
+	// Index of the input's published audio tracks to record, empty for the default
+	AudioTrack string
+	// Burn the wall-clock time into the video, re-encoding it
+	Timestamp bool
+	// Also write a re-encoded proxy, only with the stream copy profile
+	Proxy *RecordingProxy
+	// Also stream the recording live to a named pipe, see recording_fifo.go
+	FIFO bool
+}
+
+// StartRecordingWithOptions is StartRecording with optional settings
+func (rm *RecordingManager) StartRecordingWithOptions(ctx context.Context, name, sourceURL, profile string, opts RecordingOptions) error {
+	if err := validateAudioTrack(opts.AudioTrack, false); err != nil {
+		return err
+	}
+	if opts.Proxy != nil {
+		if profile != "" {
+			return fmt.Errorf("%w: a proxy is only written alongside a stream copy, not with profile %q", ErrInvalidRecordingProxy, profile)
+		}
+		if err := opts.Proxy.validate(); err != nil {
+			return err
+		}
+	}
+	if opts.FIFO && !fifoSupported {
+		return ErrRecordingFIFOUnsupported
+	}
+	if sourceURL == "" {
+		var err error
+		if sourceURL, err = rm.inputSource(name); err != nil {
+			return err
+		}
+	}
+	rm.Logger.Info("StartRecording called: name=%s, source=%s, profile=%s", name, RedactURL(sourceURL), profile)
+	profileArgs, ok := recordingProfiles[profile]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownRecordingProfile, profile)
+	}
+	// Fail fast with a clear error rather than deep in ffmpeg
+	if err := rm.checkStorage(); err != nil {
+		return fmt.Errorf("%w: %v", ErrRecordingStorageUnavailable, err)
+	}
+	var proxyError string
+	if opts.Proxy != nil {
+		if proxyError = rm.proxyUnencodable(); proxyError != "" {
+			rm.Logger.Warn("Recording %s without its proxy: %s", name, proxyError)
+		}
+	}
 
 	// Phase 1: Check for duplicates and create placeholder
-	// Create a deterministic key for the recording based on name and source
+	// Create a deterministic key for the recording based on name, source and profile
 	recordingKey := fmt.Sprintf("%s_%s", name, sourceURL)
+	fileSuffix := ".mp4"
+	if profile != "" {
+		recordingKey += "_" + profile
+		// Keeps "<name>_<timestamp>" parseable for recordings found on disk
+		fileSuffix = "." + profile + ".mp4"
+	}
 
 	rm.mu.Lock()
-	// Check for existing active recordings by name and source
-	// This prevents multiple recordings with the same name+source combination
+	if rm.shuttingDown {
+		rm.mu.Unlock()
+		return fmt.Errorf("recording manager is shutting down")
+	}
+	// Check for existing active recordings by name, source and profile
+	// This prevents multiple recordings with the same combination
 	for _, rec := range rm.recordings {
-		if rec.Name == name && rec.Source == sourceURL && rec.Active {
+		if rec.Name == name && rec.Source == sourceURL && rec.Profile == profile && rec.Active {
 			rm.mu.Unlock()
-			rm.Logger.Warn("Active recording for name %s and source %s already exists", name, sourceURL)
-			return fmt.Errorf("active recording for name %s and source %s already exists", name, sourceURL)
+			rm.Logger.Warn("Active recording for name %s and source %s already exists", name, RedactURL(sourceURL))
+			return fmt.Errorf("active recording for name %s and source %s already exists", name, RedactURL(sourceURL))
+		}
+	}
+
+	if rm.maxConcurrent > 0 {
+		if active := rm.activeCountLocked(); active >= rm.maxConcurrent {
+			rm.mu.Unlock()
+			rm.Logger.Warn("Refusing recording %s: %d of %d recordings already active", name, active, rm.maxConcurrent)
+			return fmt.Errorf("%w: %d of %d recordings active", ErrRecordingLimitReached, active, rm.maxConcurrent)
+		}
+	}
+	var fifoPath string
+	if opts.FIFO {
+		var err error
+		if fifoPath, err = rm.fifoPathLocked(fifoName(name, profile)); err != nil {
+			rm.mu.Unlock()
+			return err
 		}
 	}
 
@@ -102,11 +297,20 @@ func (rm *RecordingManager) StartRecording(ctx context.Context, name, sourceURL
 	timestamp := currentTime.Unix()
 	uniqueKey := fmt.Sprintf("%s_%d", recordingKey, timestamp)
 	placeholderRec := &Recording{
-		Name:      name,
-		Source:    sourceURL,
-		StartedAt: currentTime,
-		Active:    true, // Mark as active immediately to block other attempts
+		ID:         newCorrelationID(),
+		Name:       name,
+		Source:     sourceURL,
+		Profile:    profile,
+		AudioTrack: opts.AudioTrack,
+		Timestamp:  opts.Timestamp,
+		Proxy:      opts.Proxy,
+		ProxyError: proxyError,
+		FIFOPath:   fifoPath,
+		StartedAt:  currentTime,
+		Active:     true, // Mark as active immediately to block other attempts
 	}
+	log := rm.Logger.WithPrefix("rec:" + placeholderRec.ID)
+	consumer := recordingConsumer(placeholderRec.ID)
 	rm.recordings[uniqueKey] = placeholderRec
 	rm.mu.Unlock()
 
@@ -116,9 +320,9 @@ func (rm *RecordingManager) StartRecording(ctx context.Context, name, sourceURL
 	relayPath := fmt.Sprintf("relay/%s", name)
 	localRelayURL := fmt.Sprintf("rtsp://127.0.0.1:8554/%s", relayPath) // or use GetRTSPServerURL if available
 	// Use the configured timeout from the relay manager
-	_, err := rm.RelayMgr.InputRelays.StartInputRelay(name, sourceURL, localRelayURL, rm.RelayMgr.GetInputTimeout())
+	_, err := rm.RelayMgr.InputRelays.StartInputRelay(name, sourceURL, localRelayURL, rm.RelayMgr.GetInputTimeout(), consumer)
 	if err != nil {
-		rm.Logger.Error("Failed to start input relay for recording: %v", err)
+		log.Error("Failed to start input relay for recording: %v", err)
 		// Clean up the placeholder recording entry on failure
 		rm.mu.Lock()
 		delete(rm.recordings, uniqueKey)
@@ -129,15 +333,15 @@ func (rm *RecordingManager) StartRecording(ctx context.Context, name, sourceURL
 	// Wait for the RTSP stream to become ready before starting recording ffmpeg
 	rtspServer := rm.RelayMgr.GetRTSPServer()
 	if rtspServer != nil {
-		rm.Logger.Info("Waiting for RTSP stream to become ready for recording: %s", relayPath)
+		log.Info("Waiting for RTSP stream to become ready for recording: %s", relayPath)
 		err = rtspServer.WaitForStreamReady(relayPath, 30*time.Second)
 		if err != nil {
-			rm.Logger.Error("Failed to wait for RTSP stream to become ready for recording %s: %v", name, err)
-			rm.Logger.Debug("Stream readiness check failed for %s, checking if stream exists...", relayPath)
+			log.Error("Failed to wait for RTSP stream to become ready for recording %s: %v", name, err)
+			log.Debug("Stream readiness check failed for %s, checking if stream exists...", relayPath)
 			if rtspServer.IsStreamReady(relayPath) {
-				rm.Logger.Warn("Stream %s appears ready but wait failed, continuing anyway", relayPath)
+				log.Warn("Stream %s appears ready but wait failed, continuing anyway", relayPath)
 			} else {
-				rm.RelayMgr.InputRelays.StopInputRelay(sourceURL)
+				rm.RelayMgr.InputRelays.StopInputRelay(sourceURL, consumer)
 				// Clean up the placeholder recording entry
 				rm.mu.Lock()
 				delete(rm.recordings, uniqueKey)
@@ -145,48 +349,111 @@ func (rm *RecordingManager) StartRecording(ctx context.Context, name, sourceURL
 				return fmt.Errorf("RTSP stream not ready for recording: %v", err)
 			}
 		}
-		rm.Logger.Info("RTSP stream is ready for recording: %s", relayPath)
+		log.Info("RTSP stream is ready for recording: %s", relayPath)
 	}
 
 	rm.mu.Lock()
 	defer rm.mu.Unlock()
 
-	filePath := fmt.Sprintf("%s/%s_%d.mp4", rm.dir, name, timestamp)
-	rm.Logger.Debug("Starting ffmpeg for recording: %s", filePath)
-	ffmpegArgs := []string{"-y", "-i", localRelayURL, "-c", "copy", filePath}
+	filename := fmt.Sprintf("%s_%d%s", name, timestamp, fileSuffix)
+	if dateDir := expandDateLayout(rm.dateLayout, currentTime); dateDir != "" {
+		filename = dateDir + "/" + filename
+	}
+	filePath, err := recordingFilePath(rm.dir, filename)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(filePath), 0755)
+	}
+	if err != nil {
+		log.Error("Failed to create directory for recording %s: %v", filename, err)
+		rm.RelayMgr.InputRelays.StopInputRelay(sourceURL, consumer)
+		// Clean up the placeholder recording entry
+		delete(rm.recordings, uniqueKey)
+		return fmt.Errorf("failed to create recording directory: %w", err)
+	}
+	log.Debug("Starting ffmpeg for recording: %s", filePath)
+	// Read the relay with the input's profile, probing as configured for the input
+	inputCfg, _ := rm.RelayMgr.GetInputConfig(name)
+	inputProfile := rm.RelayMgr.inputProfileSettings(name)
+	ffmpegArgs := append([]string{"-y"}, inputProfile.probeArgs(inputCfg.AnalyzeDuration, inputCfg.ProbeSize, "")...)
+	ffmpegArgs = append(ffmpegArgs, inputProfile.flagArgs(localRelayURL, false)...)
+	ffmpegArgs = append(ffmpegArgs, "-i", localRelayURL)
+	ffmpegArgs = append(ffmpegArgs, audioTrackMapArgs(opts.AudioTrack)...)
+	var overlay *timestampOptions
+	if opts.Timestamp {
+		overlay = &rm.timestamp
+		profileArgs = rm.timestamp.withTimestamp(profileArgs)
+	}
+	ffmpegArgs = append(ffmpegArgs, profileArgs...)
+	if fifoPath != "" {
+		ffmpegArgs = append(ffmpegArgs, fifoTeeArgs(filePath, opts.AudioTrack != "")...)
+	} else {
+		ffmpegArgs = append(ffmpegArgs, filePath)
+	}
+	var proxyPath string
+	if opts.Proxy != nil && proxyError == "" {
+		// A second output of the same ffmpeg; stream maps apply per output
+		proxyPath = filepath.Join(filepath.Dir(filePath), filepath.Base(proxyFilename(filename)))
+		ffmpegArgs = append(ffmpegArgs, audioTrackMapArgs(opts.AudioTrack)...)
+		ffmpegArgs = append(ffmpegArgs, opts.Proxy.outputArgs(overlay)...)
+		ffmpegArgs = append(ffmpegArgs, proxyPath)
+	}
 	procCtx, procCancel := context.WithCancel(context.Background())
 	defer func() {
 		if procCancel != nil {
 			procCancel()
 		}
 	}()
-	rm.Logger.Debug("StartRecording: creating FFmpegProcess, args=%v", ffmpegArgs)
+	log.Debug("StartRecording: creating FFmpegProcess, args=%v", ffmpegArgs)
 	proc, err := NewFFmpegProcess(procCtx, ffmpegArgs...)
 	if err != nil {
-		rm.Logger.Error("Failed to create ffmpeg process: %v", err)
-		rm.RelayMgr.InputRelays.StopInputRelay(sourceURL)
+		log.Error("Failed to create ffmpeg process: %v", err)
+		rm.RelayMgr.InputRelays.StopInputRelay(sourceURL, consumer)
 		// Clean up the placeholder recording entry
 		delete(rm.recordings, uniqueKey)
 		return err
 	}
 
+	proc.StopSignal = rm.stopSignalFor(filename)
+	finalizeTimeout := rm.finalizeTimeout
+	if fifoPath != "" {
+		fifoWriter, err := startFIFOPump(fifoPath, log)
+		if err != nil {
+			log.Error("Failed to create recording FIFO: %v", err)
+			rm.RelayMgr.InputRelays.StopInputRelay(sourceURL, consumer)
+			delete(rm.recordings, uniqueKey)
+			return err
+		}
+		// ffmpeg's fd 3; the pump removes the FIFO once ffmpeg has exited
+		proc.Cmd.ExtraFiles = []*os.File{fifoWriter}
+		defer fifoWriter.Close()
+	}
 	if err := proc.Start(); err != nil {
-		rm.Logger.Error("Failed to start ffmpeg: %v", err)
-		rm.RelayMgr.InputRelays.StopInputRelay(sourceURL)
+		log.Error("Failed to start ffmpeg: %v", err)
+		rm.RelayMgr.InputRelays.StopInputRelay(sourceURL, consumer)
 		// Clean up the placeholder recording entry
 		delete(rm.recordings, uniqueKey)
 		return err
 	}
 	procCancel = nil // Ownership transferred to process
-	rm.Logger.Info("RecordingManager: Started ffmpeg process PID %d for recording %s", proc.PID, filePath)
+	log.Info("RecordingManager: Started ffmpeg process PID %d for recording %s", proc.PID, filePath)
 	// Update the placeholder recording with actual file information
 	placeholderRec.FilePath = filePath
-	placeholderRec.Filename = fmt.Sprintf("%s_%d.mp4", name, timestamp)
+	placeholderRec.Filename = filename
+	if proxyPath != "" {
+		placeholderRec.ProxyFilePath = proxyPath
+		placeholderRec.ProxyFilename = proxyFilename(filename)
+	}
+	if fifoPath != "" {
+		placeholderRec.FIFOName = fifoName(name, profile)
+	}
 	rm.processes[uniqueKey] = proc
 	done := make(chan struct{})
 	rm.dones[uniqueKey] = done
+	rm.saveStateLocked()
+	rm.recordingWg.Add(1)
 	go func(key string, done chan struct{}) {
-		defer rm.RelayMgr.InputRelays.StopInputRelay(sourceURL)
+		defer rm.recordingWg.Done()
+		defer rm.RelayMgr.InputRelays.StopInputRelay(sourceURL, consumer)
 		cmdDone := make(chan error, 1)
 		go func() {
 			cmdDone <- proc.Wait()
@@ -201,10 +468,17 @@ func (rm *RecordingManager) StartRecording(ctx context.Context, name, sourceURL
 				filePath = r.FilePath
 				if info, statErr := os.Stat(r.FilePath); statErr == nil {
 					r.FileSize = info.Size()
-					rm.Logger.Debug("Updated file size for finished recording %s: %d bytes", name, r.FileSize)
+					log.Debug("Updated file size for finished recording %s: %d bytes", name, r.FileSize)
 				} else {
-					rm.Logger.Warn("Could not get file size for finished recording %s: %v", name, statErr)
+					log.Warn("Could not get file size for finished recording %s: %v", name, statErr)
+				}
+				reason := RecordingCompleted
+				if err != nil {
+					reason = RecordingFailed
 				}
+				rm.finishProxyLocked(r, proc)
+				rm.finishedLocked(key, r, reason)
+				rm.saveStateLocked()
 			} else {
 				filePath = "(unknown)"
 			}
@@ -212,30 +486,46 @@ func (rm *RecordingManager) StartRecording(ctx context.Context, name, sourceURL
 			sseBroker.NotifyAll("update")
 			if err != nil {
 				ffmpegOutput := proc.GetOutput()
-				rm.Logger.Error("ffmpeg exited with error for %s (%s): %v\nOutput:\n%s", name, filePath, err, ffmpegOutput)
+				log.Error("ffmpeg exited with error for %s (%s): %v\nOutput:\n%s", name, filePath, err, ffmpegOutput)
 			} else {
-				rm.Logger.Info("Recording finished for %s (%s)", name, filePath)
+				log.Info("Recording finished for %s (%s)", name, filePath)
 			}
 		case <-done:
-			rm.Logger.Debug("StartRecording: recording goroutine done channel closed for key=%s", key)
+			log.Debug("StartRecording: recording goroutine done channel closed for key=%s", key)
 			if proc.Cmd.Process != nil {
 				pid := proc.Cmd.Process.Pid
-				rm.Logger.Info("RecordingManager: Gracefully terminating ffmpeg process PID %d for recording %s", pid, name)
-				err := proc.Stop(2 * time.Second)
+				log.Info("RecordingManager: Gracefully terminating ffmpeg process PID %d for recording %s (%v)", pid, name, proc.StopSignal)
+				// Killed if it does not finalize the file in time
+				err := proc.Stop(finalizeTimeout)
 				if err != nil {
-					rm.Logger.Warn("Failed to stop ffmpeg process PID %d: %v", pid, err)
+					log.Warn("Failed to stop ffmpeg process PID %d: %v", pid, err)
 				}
 			}
-			<-cmdDone
+			select {
+			case <-cmdDone:
+			case <-time.After(recordingKillWait):
+				log.Error("RecordingManager: ffmpeg process PID %d for recording %s did not exit after being killed, giving up on it", proc.PID, name)
+			}
 			rm.mu.Lock()
 			if r, ok := rm.recordings[key]; ok {
 				r.Active = false
 				r.StoppedAt = time.Now()
 				if info, statErr := os.Stat(r.FilePath); statErr == nil {
 					r.FileSize = info.Size()
-					rm.Logger.Debug("Updated file size for stopped recording %s: %d bytes", name, r.FileSize)
+					log.Debug("Updated file size for stopped recording %s: %d bytes", name, r.FileSize)
 				} else {
-					rm.Logger.Warn("Could not get file size for stopped recording %s: %v", name, statErr)
+					log.Warn("Could not get file size for stopped recording %s: %v", name, statErr)
+				}
+				reason := RecordingStopped
+				if rm.shuttingDown {
+					reason = RecordingShutdown
+				}
+				rm.finishProxyLocked(r, proc)
+				rm.finishedLocked(key, r, reason)
+				if !rm.shuttingDown {
+					// Stopped through the API; recordings stopped by the
+					// shutdown stay listed so they can be resumed
+					rm.saveStateLocked()
 				}
 			}
 			rm.mu.Unlock()
@@ -246,20 +536,39 @@ func (rm *RecordingManager) StartRecording(ctx context.Context, name, sourceURL
 		delete(rm.processes, key)
 		delete(rm.dones, key)
 		rm.mu.Unlock()
+		rm.pruneRecordings()
 	}(uniqueKey, done)
 	sseBroker.NotifyAll("update")
 	return nil
 }
 
-// StopRecording stops the latest active recording for a given name+source
-func (rm *RecordingManager) StopRecording(name string, source string) error {
-	rm.Logger.Info("StopRecording called: name=%s, source=%s", name, source)
+// inputSource returns the source URL of the relay input called name, so a
+// recording of an input that is already relayed can be asked for by name and
+// share its input relay
+func (rm *RecordingManager) inputSource(name string) (string, error) {
+	if rm.RelayMgr != nil {
+		if inputURL, ok := rm.RelayMgr.GetInputURLByName(name); ok {
+			return inputURL, nil
+		}
+	}
+	return "", fmt.Errorf("%w %q, a source is required", ErrUnknownInput, name)
+}
+
+// StopRecording stops the latest active recording for a given name+source+profile
+func (rm *RecordingManager) StopRecording(name, source, profile string) error {
+	if source == "" {
+		var err error
+		if source, err = rm.inputSource(name); err != nil {
+			return err
+		}
+	}
+	rm.Logger.Info("StopRecording called: name=%s, source=%s, profile=%s", name, RedactURL(source), profile)
 	rm.mu.Lock()
-	// Find the latest active recording for this name+source
+	// Find the latest active recording for this name+source+profile
 	var latestKey string
 	var latestTime int64
 	for key, rec := range rm.recordings {
-		if rec.Name == name && rec.Source == source && rec.Active {
+		if rec.Name == name && rec.Source == source && rec.Profile == profile && rec.Active {
 			started := rec.StartedAt.Unix()
 			if latestKey == "" || started > latestTime {
 				latestKey = key
@@ -269,8 +578,8 @@ func (rm *RecordingManager) StopRecording(name string, source string) error {
 	}
 	if latestKey == "" {
 		rm.mu.Unlock()
-		rm.Logger.Warn("No active recording with name %s and source %s", name, source)
-		return fmt.Errorf("no active recording with name %s and source %s", name, source)
+		rm.Logger.Warn("No active recording with name %s and source %s", name, RedactURL(source))
+		return fmt.Errorf("no active recording with name %s and source %s", name, RedactURL(source))
 	}
 	done, ok := rm.dones[latestKey]
 	if !ok {
@@ -300,10 +609,10 @@ func (rm *RecordingManager) StopAllRecordings() {
 	rm.Logger.Info("RecordingManager: Stopping all active recordings...")
 
 	rm.mu.Lock()
-	activeRecordings := make([]struct{ name, source string }, 0)
+	activeRecordings := make([]struct{ name, source, profile string }, 0)
 	for _, recording := range rm.recordings {
 		if recording.Active {
-			activeRecordings = append(activeRecordings, struct{ name, source string }{recording.Name, recording.Source})
+			activeRecordings = append(activeRecordings, struct{ name, source, profile string }{recording.Name, recording.Source, recording.Profile})
 		}
 	}
 	// Release lock before calling StopRecording to avoid deadlock
@@ -317,7 +626,7 @@ func (rm *RecordingManager) StopAllRecordings() {
 	// Stop each active recording
 	for _, rec := range activeRecordings {
 		rm.Logger.Info("RecordingManager: Stopping recording %s", rec.name)
-		if err := rm.StopRecording(rec.name, rec.source); err != nil {
+		if err := rm.StopRecording(rec.name, rec.source, rec.profile); err != nil {
 			rm.Logger.Debug("RecordingManager: Stop recording %s result: %v", rec.name, err)
 		}
 	}
@@ -329,38 +638,101 @@ func (rm *RecordingManager) StopAllRecordings() {
 func (rm *RecordingManager) Shutdown() {
 	rm.Logger.Info("RecordingManager: Shutting down...")
 
-	// Stop all active recordings first
+	rm.mu.Lock()
+	rm.saveStateLocked()
+	rm.shuttingDown = true
+	rm.mu.Unlock()
+
+	// Stop all active recordings first and wait until their files are final,
+	// so completion webhooks still see them
 	rm.StopAllRecordings()
+	rm.recordingWg.Wait()
 
 	// Shutdown SSE broker to close all active SSE connections
 	rm.Logger.Debug("RecordingManager: Shutting down SSE broker...")
 	sseBroker.Shutdown()
 
-	// Cancel the context to signal the directory watcher to stop
+	// Cancel the context to signal the directory watcher and uploads to stop;
+	// under mu so no finished-recording work is started once finishWg is waited on
+	rm.mu.Lock()
 	rm.cancel()
+	rm.mu.Unlock()
 
-	// Wait for the directory watcher to exit
 	rm.watcherWg.Wait()
+	rm.finishWg.Wait()
 
 	rm.Logger.Info("RecordingManager: Shutdown complete")
 }
 
+// RecordingFilter selects recordings by name and start time. Zero fields
+// match every recording.
+type RecordingFilter struct {
+	Name string    // case-insensitive substring of the recording name
+	From time.Time // started at or after
+	To   time.Time // started at or before
+}
+
+// matches reports whether a recording named name that started at startedAt
+// passes the filter
+func (f RecordingFilter) matches(name string, startedAt time.Time) bool {
+	if f.Name != "" && !strings.Contains(strings.ToLower(name), strings.ToLower(f.Name)) {
+		return false
+	}
+	if !f.From.IsZero() && startedAt.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && startedAt.After(f.To) {
+		return false
+	}
+	return true
+}
+
 // ListRecordings returns all recordings
 func (rm *RecordingManager) ListRecordings() []*Recording {
+	return rm.ListRecordingsFiltered(RecordingFilter{})
+}
+
+// ListRecordingsFiltered returns the recordings passing filter. Files found
+// only on disk are matched on the name parsed from their filename and on their
+// modification time, which stands in for their start time.
+func (rm *RecordingManager) ListRecordingsFiltered(filter RecordingFilter) []*Recording {
 	rm.mu.Lock()
 	recs := make([]*Recording, 0, len(rm.recordings))
 	fileSet := make(map[string]struct{})
 	for _, r := range rm.recordings {
+		if r.Filename != "" {
+			fileSet[r.Filename] = struct{}{}
+		}
+		if r.ProxyFilename != "" {
+			fileSet[r.ProxyFilename] = struct{}{}
+		}
+		if !filter.matches(r.Name, r.StartedAt) {
+			continue
+		}
 		// Create a copy of the recording to avoid race conditions
 		recCopy := &Recording{
-			Name:      r.Name,
-			Source:    r.Source,
-			FilePath:  r.FilePath,
-			Filename:  r.Filename,
-			FileSize:  r.FileSize,
-			StartedAt: r.StartedAt,
-			StoppedAt: r.StoppedAt,
-			Active:    r.Active,
+			ID:         r.ID,
+			Name:       r.Name,
+			Source:     r.Source,
+			Profile:    r.Profile,
+			AudioTrack: r.AudioTrack,
+			Timestamp:  r.Timestamp,
+			FilePath:   r.FilePath,
+			Filename:   r.Filename,
+			FileSize:   r.FileSize,
+			StartedAt:  r.StartedAt,
+			StoppedAt:  r.StoppedAt,
+			Active:     r.Active,
+
+			Proxy:         r.Proxy,
+			ProxyFilename: r.ProxyFilename,
+			ProxyError:    r.ProxyError,
+
+			FIFOName: r.FIFOName,
+
+			UploadStatus:   r.UploadStatus,
+			UploadError:    r.UploadError,
+			RemoteLocation: r.RemoteLocation,
 		}
 
 		// For active/in-process, update file size from disk
@@ -375,56 +747,67 @@ func (rm *RecordingManager) ListRecordings() []*Recording {
 			}
 		}
 		recs = append(recs, recCopy)
-		if recCopy.Filename != "" {
-			fileSet[recCopy.Filename] = struct{}{}
+		// The proxy is listed on its own while its file exists
+		if r.ProxyFilePath != "" {
+			if info, err := os.Stat(r.ProxyFilePath); err == nil {
+				recs = append(recs, &Recording{
+					Name:      r.Name,
+					Source:    r.Source,
+					Profile:   "proxy",
+					Timestamp: r.Timestamp,
+					FilePath:  r.ProxyFilePath,
+					Filename:  r.ProxyFilename,
+					FileSize:  info.Size(),
+					StartedAt: r.StartedAt,
+					StoppedAt: r.StoppedAt,
+					Active:    r.Active,
+				})
+			}
 		}
 	}
 	rm.mu.Unlock()
 
-	// Scan disk for .mp4 files in recordings dir
-	files, err := os.ReadDir(rm.dir)
-	if err == nil {
-		for _, f := range files {
-			if f.IsDir() || filepath.Ext(f.Name()) != ".mp4" {
-				continue
-			}
-			if _, exists := fileSet[f.Name()]; exists {
-				continue // skip duplicate
-			}
-			filePath := filepath.Join(rm.dir, f.Name())
-			// Try to extract name from filename: <name>_<timestamp>.mp4
-			base := f.Name()[:len(f.Name())-4] // strip .mp4
-			sep := -1
-			for i := len(base) - 1; i >= 0; i-- {
-				if base[i] == '_' {
-					sep = i
-					break
-				}
-			}
-			var name string
-			if sep > 0 {
-				name = base[:sep]
-			} else {
-				name = base
-			}
-			info, err := f.Info()
-			started := time.Time{}
-			var size int64
-			if err == nil {
-				started = info.ModTime()
-				size = info.Size()
+	// Scan the recordings dir, date directories included, for .mp4 files
+	_ = rm.walkRecordings(func(filename string, f fs.DirEntry) {
+		if _, exists := fileSet[filename]; exists {
+			return // skip duplicate
+		}
+		filePath := filepath.Join(rm.dir, filepath.FromSlash(filename))
+		// Try to extract name from filename: <name>_<timestamp>.mp4
+		base := f.Name()[:len(f.Name())-4] // strip .mp4
+		sep := -1
+		for i := len(base) - 1; i >= 0; i-- {
+			if base[i] == '_' {
+				sep = i
+				break
 			}
-			recs = append(recs, &Recording{
-				Name:      name,
-				Source:    "",
-				FilePath:  filePath,
-				Filename:  f.Name(),
-				FileSize:  size,
-				StartedAt: started,
-				Active:    false,
-			})
 		}
-	}
+		var name string
+		if sep > 0 {
+			name = base[:sep]
+		} else {
+			name = base
+		}
+		info, err := f.Info()
+		started := time.Time{}
+		var size int64
+		if err == nil {
+			started = info.ModTime()
+			size = info.Size()
+		}
+		if !filter.matches(name, started) {
+			return
+		}
+		recs = append(recs, &Recording{
+			Name:      name,
+			Source:    "",
+			FilePath:  filePath,
+			Filename:  filename,
+			FileSize:  size,
+			StartedAt: started,
+			Active:    false,
+		})
+	})
 	return recs
 }
 
@@ -446,6 +829,7 @@ func (rm *RecordingManager) DeleteRecording(key string) error {
 			rm.Logger.Error("Failed to delete file %s: %v", filePath, err)
 			return err
 		}
+		rm.removeEmptyDateDirs(filePath)
 
 		rm.mu.Lock()
 		delete(rm.recordings, key)
@@ -456,13 +840,15 @@ func (rm *RecordingManager) DeleteRecording(key string) error {
 	}
 	rm.mu.Unlock()
 	// Fallback: try to delete by filename for on-disk-only recordings
-	filename := key + ".mp4"
-	filePath := filepath.Join(rm.dir, filename)
+	filePath, err := recordingFilePath(rm.dir, key+".mp4")
+	if err != nil {
+		rm.Logger.Warn("Refusing to delete recording %q: %v", key, err)
+		return err
+	}
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
 		// Try single-underscore variant if double-underscore does not exist
 		if idx := lastUnderscore(key); idx > 0 && key[idx-1] == '_' {
-			altFilename := key[:idx-1] + key[idx:] + ".mp4"
-			altFilePath := filepath.Join(rm.dir, altFilename)
+			altFilePath := filepath.Join(filepath.Dir(filePath), filepath.Base(key[:idx-1]+key[idx:]+".mp4"))
 			if _, err2 := os.Stat(altFilePath); err2 == nil {
 				filePath = altFilePath
 			}
@@ -472,6 +858,7 @@ func (rm *RecordingManager) DeleteRecording(key string) error {
 		rm.Logger.Error("Failed to delete file %s: %v", filePath, err)
 		return err
 	}
+	rm.removeEmptyDateDirs(filePath)
 	rm.Logger.Info("Deleted on-disk-only recording %s", filePath)
 	sseBroker.NotifyAll("update")
 	return nil
@@ -480,11 +867,16 @@ func (rm *RecordingManager) DeleteRecording(key string) error {
 // DeleteRecordingByFilename deletes a recording file by filename and removes from map if present
 func (rm *RecordingManager) DeleteRecordingByFilename(filename string) error {
 	rm.Logger.Info("DeleteRecordingByFilename called: filename=%s", filename)
-	filePath := filepath.Join(rm.dir, filename)
+	filePath, err := recordingFilePath(rm.dir, filename)
+	if err != nil {
+		rm.Logger.Warn("Refusing to delete recording %q: %v", filename, err)
+		return err
+	}
 	if err := os.Remove(filePath); err != nil {
 		rm.Logger.Error("Failed to delete file %s: %v", filePath, err)
 		return err
 	}
+	rm.removeEmptyDateDirs(filePath)
 	rm.mu.Lock()
 	for key, rec := range rm.recordings {
 		if rec.Filename == filename {
@@ -511,41 +903,79 @@ func lastUnderscore(s string) int {
 
 // SSEBroker manages Server-Sent Events clients for real-time UI updates
 // This implements a fan-out pattern to broadcast updates to multiple browser clients
-var sseBroker = &SSEBroker{
-	clients:  make(map[chan string]struct{}),
-	shutdown: make(chan struct{}),
-}
+var sseBroker = newSSEBroker()
+
+// sseStallTimeout is how long an update may wait for a client to take it
+// before the client is disconnected
+const sseStallTimeout = 30 * time.Second
+
+// sseWriteTimeout bounds each write to an SSE client, so a client that stopped
+// reading cannot hold its connection open after being disconnected
+const sseWriteTimeout = 10 * time.Second
+
+// ErrTooManySSEClients is returned when the SSE client limit is reached
+var ErrTooManySSEClients = errors.New("too many SSE clients")
 
 // SSEBroker handles real-time communication with web browser clients
 // It maintains a registry of active client connections and broadcasts updates
 type SSEBroker struct {
-	clients  map[chan string]struct{} // Map of active client channels
-	mu       sync.Mutex               // Protects concurrent access to clients map
-	shutdown chan struct{}            // Signals when broker should shut down
-	once     sync.Once                // Ensures shutdown only happens once safely
+	clients    map[chan string]time.Time // Active client channels and when each was last sent an update
+	maxClients int                       // 0 for unlimited, set from the config via SetSSEMaxClients
+	stall      time.Duration             // sseStallTimeout, shortened by tests
+	mu         sync.Mutex                // Protects concurrent access to clients and maxClients
+	shutdown   chan struct{}             // Signals when broker should shut down
+	once       sync.Once                 // Ensures shutdown only happens once safely
+}
+
+func newSSEBroker() *SSEBroker {
+	return &SSEBroker{
+		clients:  make(map[chan string]time.Time),
+		stall:    sseStallTimeout,
+		shutdown: make(chan struct{}),
+	}
+}
+
+// SetSSEMaxClients caps how many SSE clients are connected at once, further
+// connections being refused with 503; 0 means unlimited
+func SetSSEMaxClients(max int) {
+	sseBroker.mu.Lock()
+	defer sseBroker.mu.Unlock()
+	sseBroker.maxClients = max
 }
 
 // NotifyAll broadcasts a message to all connected SSE clients
-// Uses non-blocking sends to prevent slow clients from blocking the broadcast
+// Uses non-blocking sends to prevent slow clients from blocking the broadcast.
+// All updates are alike, so a client whose channel is full already has one
+// pending and the message is dropped. Only when that pending update has
+// waited longer than sseStallTimeout is the client disconnected, by closing
+// its channel, rather than being skipped forever.
 func (b *SSEBroker) NotifyAll(msg string) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
-	for ch := range b.clients {
+	now := time.Now()
+	for ch, sent := range b.clients {
 		select {
 		case ch <- msg:
-			// Message sent successfully
+			b.clients[ch] = now
 		default:
-			// Client channel is full/blocked - skip to prevent blocking
-			// This prevents slow clients from affecting other clients
+			if now.Sub(sent) > b.stall {
+				close(ch)
+				delete(b.clients, ch)
+			}
 		}
 	}
 }
 
-// AddClient registers a new SSE client channel for receiving updates
-func (b *SSEBroker) AddClient(ch chan string) {
+// AddClient registers a new SSE client channel for receiving updates,
+// failing with ErrTooManySSEClients when the limit is reached
+func (b *SSEBroker) AddClient(ch chan string) error {
 	b.mu.Lock()
-	b.clients[ch] = struct{}{}
-	b.mu.Unlock()
+	defer b.mu.Unlock()
+	if b.maxClients > 0 && len(b.clients) >= b.maxClients {
+		return fmt.Errorf("%w: %d connected", ErrTooManySSEClients, len(b.clients))
+	}
+	b.clients[ch] = time.Now()
+	return nil
 }
 
 // RemoveClient unregisters an SSE client channel
@@ -567,48 +997,74 @@ func (b *SSEBroker) Shutdown() {
 			close(ch)
 		}
 		// Clear clients map to prevent memory leaks
-		b.clients = make(map[chan string]struct{})
+		b.clients = make(map[chan string]time.Time)
 	})
 }
 
 // SSE handler
 func ApiRecordingsSSE() http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "text/event-stream")
-		w.Header().Set("Cache-Control", "no-cache")
-		w.Header().Set("Connection", "keep-alive")
-		ch := make(chan string, 1)
-		sseBroker.AddClient(ch)
-		defer sseBroker.RemoveClient(ch)
-		flusher, ok := w.(http.Flusher)
-		if !ok {
-			http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
-			return
-		}
-		w.WriteHeader(http.StatusOK)
-		for {
-			select {
-			case msg, ok := <-ch:
-				if !ok {
-					// Channel was closed, exit gracefully
-					return
-				}
-				w.Write([]byte("data: " + msg + "\n\n"))
-				flusher.Flush()
-			case <-r.Context().Done():
+	return sseBroker.serve
+}
+
+// serve streams the broker's updates to one client until it disconnects, is
+// disconnected for falling behind or the broker shuts down
+func (b *SSEBroker) serve(w http.ResponseWriter, r *http.Request) {
+	if !httputil.AllowMethods(w, r, http.MethodGet) {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	ch := make(chan string, 1)
+	if err := b.AddClient(ch); err != nil {
+		httputil.WriteError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	defer b.RemoveClient(ch)
+	// Each write gets its own deadline instead of the server's write timeout
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Now().Add(sseWriteTimeout))
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	// Send the headers now rather than with the first update
+	flusher.Flush()
+	for {
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				// Channel was closed, exit gracefully
 				return
-			case <-sseBroker.shutdown:
+			}
+			// Writers without deadline support (e.g. in tests) keep the server timeout
+			_ = rc.SetWriteDeadline(time.Now().Add(sseWriteTimeout))
+			if _, err := w.Write([]byte("data: " + msg + "\n\n")); err != nil {
 				return
 			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		case <-b.shutdown:
+			return
 		}
 	}
 }
 
+// hiddenEventName reports whether the NUL-padded name of an inotify event is
+// a hidden file
+func hiddenEventName(name []byte) bool {
+	return len(name) > 0 && name[0] == '.'
+}
+
 // watchRecordingsDir watches for changes in the recordings directory and notifies via SSE
 // This function uses inotify (Linux) to efficiently monitor filesystem events.
 // It runs in its own goroutine and handles proper shutdown via context cancellation.
 func (rm *RecordingManager) watchRecordingsDir() {
 	defer rm.watcherWg.Done()
+	defer rm.watcherRunning.Store(false)
 	rm.Logger.Debug("RecordingManager: Starting directory watcher for %s", rm.dir)
 
 	// Initialize inotify file descriptor for filesystem event monitoring
@@ -678,8 +1134,16 @@ func (rm *RecordingManager) watchRecordingsDir() {
 				raw := (*unix.InotifyEvent)(unsafe.Pointer(&eventData[offset]))
 				mask := raw.Mask
 
-				// Check if this is a relevant file system event
-				if mask&(unix.IN_CREATE|unix.IN_MODIFY|unix.IN_DELETE|unix.IN_MOVED_FROM|unix.IN_MOVED_TO|unix.IN_CLOSE_WRITE) != 0 {
+				// The directory was deleted or unmounted, so the watch is gone;
+				// monitorStorage starts a new watcher once it is back
+				if mask&unix.IN_IGNORED != 0 {
+					rm.Logger.Warn("RecordingManager: Lost the watch on %s, recordings list updates paused", rm.dir)
+					return
+				}
+
+				// Check if this is a relevant file system event, ignoring
+				// hidden files such as the storage probe and the state file
+				if mask&(unix.IN_CREATE|unix.IN_MODIFY|unix.IN_DELETE|unix.IN_MOVED_FROM|unix.IN_MOVED_TO|unix.IN_CLOSE_WRITE) != 0 && !hiddenEventName(eventData[offset+unix.SizeofInotifyEvent:offset+unix.SizeofInotifyEvent+raw.Len]) {
 					// Notify all SSE clients that the recordings list should be updated
 					sseBroker.NotifyAll("update")
 				}
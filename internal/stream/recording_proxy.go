@@ -0,0 +1,113 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A stream copy recording can write a small re-encoded proxy alongside the
+// archive from the same ffmpeg, which reads and demuxes the input once rather
+// than twice as two recordings would. The proxy is listed as a recording of
+// its own, named like the archive with a ".proxy.mp4" suffix. Should the proxy
+// come out missing or empty, the archive is kept and the failure is reported
+// in the archive's proxy_error.
+
+// Proxy defaults, those of the "proxy" recording profile
+const (
+	DefaultProxyHeight       = 360
+	DefaultProxyVideoBitrate = "800k"
+	DefaultProxyAudioBitrate = "96k"
+)
+
+// proxySuffix replaces ".mp4" in the archive's filename to name its proxy
+const proxySuffix = ".proxy.mp4"
+
+// proxyBitrate matches a bitrate such as "800k" or "2M"
+var proxyBitrate = regexp.MustCompile(`^[1-9][0-9]*[kM]?$`)
+
+// ErrInvalidRecordingProxy is returned for unusable proxy settings
+var ErrInvalidRecordingProxy = errors.New("invalid recording proxy")
+
+// RecordingProxy describes the proxy written alongside a recording; zero
+// fields take the defaults
+type RecordingProxy struct {
+	Height       int    `json:"height,omitempty"`        // video height, the width follows the aspect ratio
+	VideoBitrate string `json:"video_bitrate,omitempty"` // e.g. "800k"
+	AudioBitrate string `json:"audio_bitrate,omitempty"` // e.g. "96k"
+}
+
+// validate checks the proxy settings
+func (p *RecordingProxy) validate() error {
+	if p.Height != 0 && (p.Height < 144 || p.Height > 2160 || p.Height%2 != 0) {
+		return fmt.Errorf("%w: height %d must be even and between 144 and 2160", ErrInvalidRecordingProxy, p.Height)
+	}
+	for _, bitrate := range []string{p.VideoBitrate, p.AudioBitrate} {
+		if bitrate != "" && !proxyBitrate.MatchString(bitrate) {
+			return fmt.Errorf("%w: bitrate %q must be a number with an optional k or M suffix", ErrInvalidRecordingProxy, bitrate)
+		}
+	}
+	return nil
+}
+
+// outputArgs returns the ffmpeg output args of the proxy, drawing the
+// timestamp after scaling when timestamp is set
+func (p *RecordingProxy) outputArgs(timestamp *timestampOptions) []string {
+	height, videoBitrate, audioBitrate := p.Height, p.VideoBitrate, p.AudioBitrate
+	if height == 0 {
+		height = DefaultProxyHeight
+	}
+	if videoBitrate == "" {
+		videoBitrate = DefaultProxyVideoBitrate
+	}
+	if audioBitrate == "" {
+		audioBitrate = DefaultProxyAudioBitrate
+	}
+	filter := "scale=-2:" + strconv.Itoa(height)
+	if timestamp != nil {
+		filter += "," + timestamp.filter()
+	}
+	return []string{"-c:v", "libx264", "-preset", "veryfast", "-vf", filter, "-b:v", videoBitrate, "-c:a", "aac", "-b:a", audioBitrate}
+}
+
+// proxyFilename names the proxy of the archive filename
+func proxyFilename(filename string) string {
+	return strings.TrimSuffix(filename, ".mp4") + proxySuffix
+}
+
+// proxyUnencodable reports why ffmpeg cannot encode a proxy, empty if it can or
+// its encoders are unknown
+func (rm *RecordingManager) proxyUnencodable() string {
+	if rm.RelayMgr == nil || rm.RelayMgr.ffmpegCaps == nil {
+		return ""
+	}
+	for _, encoder := range []string{"libx264", "aac"} {
+		if !rm.RelayMgr.ffmpegCaps.HasEncoder(encoder) {
+			return "ffmpeg has no " + encoder + " encoder"
+		}
+	}
+	return ""
+}
+
+// finishProxyLocked checks the proxy of a finished recording, dropping it
+// with the reason in ProxyError if it is missing or empty. Caller must hold rm.mu.
+func (rm *RecordingManager) finishProxyLocked(r *Recording, proc *FFmpegProcess) {
+	if r.ProxyFilePath == "" {
+		return
+	}
+	if info, err := os.Stat(r.ProxyFilePath); err == nil && info.Size() > 0 {
+		return
+	}
+	reason := "proxy file is missing or empty"
+	if lines := proc.GetLastOutputLines(3); len(lines) > 0 {
+		reason += ": " + strings.Join(lines, "; ")
+	}
+	rm.Logger.Warn("Proxy of recording %s failed, keeping the archive: %s", r.Filename, reason)
+	os.Remove(r.ProxyFilePath)
+	r.ProxyError = reason
+	r.ProxyFilename = ""
+	r.ProxyFilePath = ""
+}
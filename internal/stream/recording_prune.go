@@ -0,0 +1,99 @@
+package stream
+
+import (
+	"io/fs"
+	"sort"
+	"time"
+)
+
+// recordingPruneInterval is how often the recordings directory is checked
+// against the quota besides after every finished recording
+const recordingPruneInterval = time.Minute
+
+// SetMaxTotalSize caps the total size of the recordings directory at quota
+// bytes; beyond it the oldest finished recordings are deleted. 0 disables the
+// quota. Call it once, before recordings start.
+func (rm *RecordingManager) SetMaxTotalSize(quota int64) {
+	rm.mu.Lock()
+	rm.maxTotalSize = quota
+	rm.mu.Unlock()
+	if quota <= 0 {
+		return
+	}
+	rm.watcherWg.Add(1)
+	go func() {
+		defer rm.watcherWg.Done()
+		ticker := time.NewTicker(recordingPruneInterval)
+		defer ticker.Stop()
+		rm.pruneRecordings()
+		for {
+			select {
+			case <-ticker.C:
+				rm.pruneRecordings()
+			case <-rm.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// pruneRecordings deletes the oldest recordings until the directory fits the
+// quota. Active recordings and those still being uploaded count towards the
+// total but are never deleted.
+func (rm *RecordingManager) pruneRecordings() {
+	rm.pruneMu.Lock()
+	defer rm.pruneMu.Unlock()
+
+	rm.mu.Lock()
+	quota := rm.maxTotalSize
+	busy := make(map[string]bool)
+	for _, r := range rm.recordings {
+		if r.Active || r.UploadStatus == UploadPending || r.UploadStatus == UploadUploading {
+			busy[r.Filename] = true
+		}
+	}
+	rm.mu.Unlock()
+	if quota <= 0 {
+		return
+	}
+
+	type candidate struct {
+		name    string
+		size    int64
+		modTime time.Time
+	}
+	var total int64
+	var candidates []candidate
+	err := rm.walkRecordings(func(name string, e fs.DirEntry) {
+		info, err := e.Info()
+		if err != nil {
+			return
+		}
+		total += info.Size()
+		if !busy[name] {
+			candidates = append(candidates, candidate{name, info.Size(), info.ModTime()})
+		}
+	})
+	if err != nil {
+		rm.Logger.Warn("RecordingManager: Cannot scan %s for quota: %v", rm.dir, err)
+		return
+	}
+	if total <= quota {
+		return
+	}
+
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].modTime.Before(candidates[j].modTime) })
+	for _, c := range candidates {
+		if total <= quota {
+			break
+		}
+		if err := rm.DeleteRecordingByFilename(c.name); err != nil {
+			continue
+		}
+		total -= c.size
+		rm.Logger.Info("RecordingManager: Pruned %s (%d bytes) to stay within the %d byte quota", c.name, c.size, quota)
+	}
+	if total > quota {
+		rm.Logger.Warn("RecordingManager: Recordings still use %d bytes, over the %d byte quota, after pruning", total, quota)
+	}
+}
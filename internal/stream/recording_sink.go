@@ -0,0 +1,100 @@
+package stream
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// RecordingSink stores finished recordings somewhere other than the local
+// recordings directory. Upload may be called again for the same key after a
+// failure and should pick up where the previous attempt stopped if it can.
+type RecordingSink interface {
+	// Name identifies the sink in logs, e.g. "s3"
+	Name() string
+	// Upload copies the file at path to the sink under key and returns its remote location
+	Upload(ctx context.Context, key, path string) (string, error)
+}
+
+// Upload states reported in Recording.UploadStatus
+const (
+	UploadPending   = "pending"
+	UploadUploading = "uploading"
+	UploadUploaded  = "uploaded"
+	UploadFailed    = "failed"
+)
+
+const (
+	recordingUploadAttempts = 5
+	recordingUploadBackoff  = 2 * time.Second
+)
+
+// SetSink makes every recording that finishes from now on be uploaded to
+// sink. With deleteLocal the local file is removed once its upload succeeded.
+// A nil sink keeps recordings local only, which is the default.
+func (rm *RecordingManager) SetSink(sink RecordingSink, deleteLocal bool) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	rm.sink = sink
+	rm.deleteAfterUpload = deleteLocal
+}
+
+// uploadRecording uploads one recording, retrying with exponential backoff
+// until it succeeds, runs out of attempts or the manager shuts down
+func (rm *RecordingManager) uploadRecording(key string, sink RecordingSink, deleteLocal bool) {
+	rm.mu.Lock()
+	r, ok := rm.recordings[key]
+	if !ok {
+		rm.mu.Unlock()
+		return
+	}
+	filePath, filename := r.FilePath, r.Filename
+	log := rm.Logger.WithPrefix("rec:" + r.ID)
+	r.UploadStatus = UploadUploading
+	rm.mu.Unlock()
+	sseBroker.NotifyAll("update")
+
+	var location string
+	var err error
+	backoff := recordingUploadBackoff
+	for attempt := 1; attempt <= recordingUploadAttempts; attempt++ {
+		location, err = sink.Upload(rm.ctx, filename, filePath)
+		if err == nil || rm.ctx.Err() != nil {
+			break
+		}
+		log.Warn("Upload of %s to %s failed (attempt %d/%d): %v", filename, sink.Name(), attempt, recordingUploadAttempts, err)
+		if attempt == recordingUploadAttempts {
+			break
+		}
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-rm.ctx.Done():
+		}
+	}
+
+	rm.mu.Lock()
+	if r, ok := rm.recordings[key]; ok {
+		if err != nil {
+			r.UploadStatus = UploadFailed
+			r.UploadError = err.Error()
+		} else {
+			r.UploadStatus = UploadUploaded
+			r.UploadError = ""
+			r.RemoteLocation = location
+		}
+	}
+	rm.mu.Unlock()
+	sseBroker.NotifyAll("update")
+
+	if err != nil {
+		log.Error("Giving up uploading %s to %s: %v", filename, sink.Name(), err)
+		return
+	}
+	log.Info("Uploaded %s to %s", filename, location)
+	if deleteLocal {
+		if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+			log.Warn("Could not remove uploaded recording %s: %v", filePath, err)
+		}
+	}
+}
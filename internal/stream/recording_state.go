@@ -0,0 +1,187 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// recordingStateFile in the recordings directory lists the active recordings
+// and the inputs that had live recordings or HLS previews, so they can be
+// restored after a restart, see RestoreState. Restored inputs stay listed
+// until they are deleted. It is rewritten whenever a
+// recording starts or ends and once more on shutdown, whose stops leave the
+// recordings listed. Recordings that ended on their own or were stopped through
+// the API are dropped, so they are never resumed.
+const recordingStateFile = ".active_state.json"
+
+const recordingStateVersion = 1
+
+type recordingState struct {
+	Version    int               `json:"version"`
+	Inputs     []InputConfig     `json:"inputs"`
+	Recordings []activeRecording `json:"recordings"`
+}
+
+// activeRecording is what is needed to start a recording again
+type activeRecording struct {
+	Name       string          `json:"name"`
+	Source     string          `json:"source"`
+	Profile    string          `json:"profile,omitempty"`
+	AudioTrack string          `json:"audio_track,omitempty"`
+	Timestamp  bool            `json:"timestamp,omitempty"`
+	Proxy      *RecordingProxy `json:"proxy,omitempty"`
+	FIFO       bool            `json:"fifo,omitempty"`
+	StartedAt  time.Time       `json:"started_at"`
+}
+
+// SetPreviewInputs makes the saved state include the inputs f reports as
+// having live HLS previews, typically HLSManager.ActiveInputs
+func (rm *RecordingManager) SetPreviewInputs(f func() []string) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	rm.previewInputs = f
+}
+
+// saveStateLocked writes the state file. Failures are logged, they never
+// affect the recordings themselves. Caller must hold rm.mu.
+func (rm *RecordingManager) saveStateLocked() {
+	state := recordingState{Version: recordingStateVersion, Inputs: []InputConfig{}, Recordings: []activeRecording{}}
+	inputs := make(map[string]InputConfig)
+	addInput := func(name, source string) {
+		if _, seen := inputs[name]; seen {
+			return
+		}
+		if rm.RelayMgr != nil {
+			if cfg, ok := rm.RelayMgr.GetInputConfig(name); ok && (source == "" || cfg.InputURL == source) {
+				inputs[name] = cfg
+				return
+			}
+		}
+		if source != "" {
+			inputs[name] = InputConfig{InputURL: source, InputName: name}
+		}
+	}
+	for _, rec := range rm.recordings {
+		if !rec.Active || rec.FilePath == "" {
+			continue // stopped, or still starting
+		}
+		state.Recordings = append(state.Recordings, activeRecording{
+			Name:       rec.Name,
+			Source:     rec.Source,
+			Profile:    rec.Profile,
+			AudioTrack: rec.AudioTrack,
+			Timestamp:  rec.Timestamp,
+			Proxy:      rec.Proxy,
+			FIFO:       rec.FIFOPath != "",
+			StartedAt:  rec.StartedAt,
+		})
+		addInput(rec.Name, rec.Source)
+	}
+	if rm.previewInputs != nil {
+		for _, name := range rm.previewInputs() {
+			addInput(name, "")
+		}
+	}
+	for _, name := range rm.restoredInputs {
+		addInput(name, "")
+	}
+	for _, cfg := range inputs {
+		state.Inputs = append(state.Inputs, cfg)
+	}
+	sort.Slice(state.Inputs, func(i, j int) bool { return state.Inputs[i].InputName < state.Inputs[j].InputName })
+	sort.Slice(state.Recordings, func(i, j int) bool { return state.Recordings[i].StartedAt.Before(state.Recordings[j].StartedAt) })
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		rm.Logger.Warn("Could not encode recording state: %v", err)
+		return
+	}
+	// Source URLs may carry credentials; written aside and renamed so a crash
+	// never leaves a truncated file
+	path := filepath.Join(rm.dir, recordingStateFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		rm.Logger.Warn("Could not save recording state: %v", err)
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		rm.Logger.Warn("Could not save recording state: %v", err)
+	}
+}
+
+// RestoreState reads the state saved before the last shutdown or crash. The
+// inputs listed in it are registered again with their settings, so inputs that
+// only served previews or recordings are known again. With resume the
+// recordings that were active are started again, each as a new file; the
+// returned count is of the recordings being resumed. A missing state file is
+// not an error.
+func (rm *RecordingManager) RestoreState(ctx context.Context, resume bool) (int, error) {
+	data, err := os.ReadFile(filepath.Join(rm.dir, recordingStateFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	var state recordingState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return 0, err
+	}
+
+	var restored []string
+	for _, cfg := range state.Inputs {
+		if rm.RelayMgr == nil {
+			break
+		}
+		if err := rm.RelayMgr.RegisterInputConfig(cfg.InputName, cfg.InputURL); err != nil {
+			rm.Logger.Error("Could not restore input %s: %v", cfg.InputName, err)
+			continue
+		}
+		rm.RelayMgr.setInputConfig(cfg)
+		rm.RelayMgr.InputRelays.addIdleInput(cfg.InputName, cfg.InputURL, rm.RelayMgr.GetInputTimeout())
+		restored = append(restored, cfg.InputName)
+	}
+	rm.mu.Lock()
+	rm.restoredInputs = restored
+	rm.mu.Unlock()
+
+	if !resume || len(state.Recordings) == 0 {
+		if len(state.Recordings) > 0 {
+			rm.Logger.Info("%d recording(s) were active before the restart and are not resumed", len(state.Recordings))
+		}
+		// Forget them so a later restart with resuming enabled does not pick them up
+		rm.mu.Lock()
+		rm.saveStateLocked()
+		rm.mu.Unlock()
+		return 0, nil
+	}
+
+	var wg sync.WaitGroup
+	for _, rec := range state.Recordings {
+		wg.Add(1)
+		go func(rec activeRecording) {
+			defer wg.Done()
+			rm.Logger.Info("Resuming recording %s (active since %s)", rec.Name, rec.StartedAt.Format(time.RFC3339))
+			if err := rm.StartRecordingWithOptions(ctx, rec.Name, rec.Source, rec.Profile, RecordingOptions{AudioTrack: rec.AudioTrack, Timestamp: rec.Timestamp, Proxy: rec.Proxy, FIFO: rec.FIFO}); err != nil {
+				rm.Logger.Error("Could not resume recording %s: %v", rec.Name, err)
+			}
+		}(rec)
+	}
+	go func() {
+		// Drops the recordings that could not be resumed
+		wg.Wait()
+		rm.mu.Lock()
+		defer rm.mu.Unlock()
+		if !rm.shuttingDown {
+			rm.saveStateLocked()
+		}
+	}()
+	return len(state.Recordings), nil
+}
@@ -0,0 +1,107 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrRecordingStorageUnavailable is returned when starting a recording while
+// the recordings directory cannot be written, e.g. an unmounted NAS share
+var ErrRecordingStorageUnavailable = errors.New("recordings directory is not writable")
+
+// storageCheckInterval is how often the recordings directory is probed
+const storageCheckInterval = 30 * time.Second
+
+// storageProbeFile is written and removed again to probe the directory
+const storageProbeFile = ".write_check"
+
+// checkStorage probes whether the recordings directory can be written and
+// records the outcome for Healthy, logging when it changes
+func (rm *RecordingManager) checkStorage() error {
+	err := probeWritable(rm.dir)
+
+	rm.storageMu.Lock()
+	wasHealthy := rm.storageErr == nil
+	rm.storageErr = err
+	rm.storageMu.Unlock()
+
+	switch {
+	case err != nil && wasHealthy:
+		rm.Logger.Warn("RecordingManager: Recordings directory %s became unwritable, new recordings will be refused: %v", rm.dir, err)
+	case err == nil && !wasHealthy:
+		rm.Logger.Info("RecordingManager: Recordings directory %s is writable again", rm.dir)
+	}
+	return err
+}
+
+// probeWritable creates and removes a file in dir
+func probeWritable(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	path := filepath.Join(dir, storageProbeFile)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write([]byte("ok"))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if removeErr := os.Remove(path); err == nil {
+		err = removeErr
+	}
+	return err
+}
+
+// Healthy reports whether the recordings directory was writable when last
+// probed, and if not why
+func (rm *RecordingManager) Healthy() (bool, string) {
+	rm.storageMu.Lock()
+	defer rm.storageMu.Unlock()
+	if rm.storageErr != nil {
+		return false, rm.storageErr.Error()
+	}
+	return true, ""
+}
+
+// monitorStorage probes the recordings directory every storageCheckInterval
+// until shutdown, re-establishing the directory watcher once the directory
+// is back if it had stopped, e.g. because the share was unmounted
+func (rm *RecordingManager) monitorStorage() {
+	defer rm.watcherWg.Done()
+	ticker := time.NewTicker(storageCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-rm.ctx.Done():
+			return
+		case <-ticker.C:
+			rm.superviseStorage()
+		}
+	}
+}
+
+// superviseStorage is one round of monitorStorage
+func (rm *RecordingManager) superviseStorage() {
+	if rm.checkStorage() == nil && !rm.watcherRunning.Load() {
+		rm.Logger.Info("RecordingManager: Re-establishing the watcher of %s", rm.dir)
+		rm.startWatcher()
+	}
+}
+
+// startWatcher starts watchRecordingsDir. It is only called from the
+// constructor and from monitorStorage, which is itself counted in watcherWg,
+// so Shutdown cannot be waiting on a zero count meanwhile.
+func (rm *RecordingManager) startWatcher() {
+	rm.watcherRunning.Store(true)
+	rm.watcherWg.Add(1)
+	go rm.watchRecordingsDir()
+}
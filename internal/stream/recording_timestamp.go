@@ -0,0 +1,133 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// A recording can have the wall-clock time burnt into its video with ffmpeg's
+// drawtext filter, e.g. for evidence or monitoring footage. Drawing needs the
+// video re-encoded: a stream copy recording becomes an H.264 one with its audio
+// still copied, and the transcoding profiles draw after scaling.
+
+// Timestamp overlay defaults
+const (
+	DefaultTimestampPosition = "top-left"
+	DefaultTimestampFontSize = 24
+)
+
+// timestampPositions maps a corner to the drawtext coordinates placing the
+// text there, 10 pixels from the edges
+var timestampPositions = map[string]string{
+	"top-left":     "x=10:y=10",
+	"top-right":    "x=w-tw-10:y=10",
+	"bottom-left":  "x=10:y=h-th-10",
+	"bottom-right": "x=w-tw-10:y=h-th-10",
+}
+
+// timestampVideoArgs re-encode the video of a stream copy recording
+var timestampVideoArgs = []string{"-c:v", "libx264", "-preset", "veryfast", "-crf", "20", "-c:a", "copy"}
+
+// ErrInvalidTimestampOptions is returned for an unusable timestamp font,
+// position or size
+var ErrInvalidTimestampOptions = errors.New("invalid timestamp options")
+
+// timestampOptions are the font and placement of timestamp overlays
+type timestampOptions struct {
+	fontFile string // empty for ffmpeg's default font
+	position string
+	fontSize int
+}
+
+// SetTimestampOptions sets the font file, corner and font size of timestamp
+// overlays; empty values and 0 keep the defaults. The font must be a readable
+// file whose path needs no escaping in a filtergraph.
+func (rm *RecordingManager) SetTimestampOptions(fontFile, position string, fontSize int) error {
+	if position == "" {
+		position = DefaultTimestampPosition
+	}
+	if fontSize == 0 {
+		fontSize = DefaultTimestampFontSize
+	}
+	if _, ok := timestampPositions[position]; !ok {
+		return fmt.Errorf("%w: position %q must be top-left, top-right, bottom-left or bottom-right", ErrInvalidTimestampOptions, position)
+	}
+	if fontSize < 8 || fontSize > 200 {
+		return fmt.Errorf("%w: font size %d must be between 8 and 200", ErrInvalidTimestampOptions, fontSize)
+	}
+	if err := validateTimestampFont(fontFile); err != nil {
+		return err
+	}
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	rm.timestamp = timestampOptions{fontFile: fontFile, position: position, fontSize: fontSize}
+	return nil
+}
+
+// validateTimestampFont checks that fontFile, if set, is a readable regular
+// file that can be passed to drawtext unquoted
+func validateTimestampFont(fontFile string) error {
+	if fontFile == "" {
+		return nil
+	}
+	if strings.ContainsAny(fontFile, `'\:,;[]=`) {
+		return fmt.Errorf("%w: font path %q contains characters special to ffmpeg filters", ErrInvalidTimestampOptions, fontFile)
+	}
+	f, err := os.Open(fontFile)
+	if err != nil {
+		return fmt.Errorf("%w: font %v", ErrInvalidTimestampOptions, err)
+	}
+	defer f.Close()
+	if info, err := f.Stat(); err != nil || !info.Mode().IsRegular() {
+		return fmt.Errorf("%w: font %s is not a regular file", ErrInvalidTimestampOptions, fontFile)
+	}
+	return nil
+}
+
+// filter returns the drawtext filter drawing the local time as
+// "YYYY-MM-DD HH:MM:SS" on a translucent box
+func (t timestampOptions) filter() string {
+	position, size := t.position, t.fontSize
+	if position == "" {
+		position = DefaultTimestampPosition
+	}
+	if size == 0 {
+		size = DefaultTimestampFontSize
+	}
+	var b strings.Builder
+	b.WriteString("drawtext=")
+	if t.fontFile != "" {
+		b.WriteString("fontfile=" + t.fontFile + ":")
+	}
+	b.WriteString("text='%{localtime}':fontsize=" + strconv.Itoa(size))
+	b.WriteString(":fontcolor=white:box=1:boxcolor=black@0.5:boxborderw=5:")
+	b.WriteString(timestampPositions[position])
+	return b.String()
+}
+
+// withTimestamp returns the output args of a recording profile drawing the
+// timestamp too, chained after the profile's own video filter if it has one
+func (t timestampOptions) withTimestamp(profileArgs []string) []string {
+	args := make([]string, 0, len(profileArgs)+len(timestampVideoArgs)+2)
+	filtered := false
+	for i := 0; i < len(profileArgs); i++ {
+		switch {
+		case profileArgs[i] == "-c" && i+1 < len(profileArgs) && profileArgs[i+1] == "copy":
+			args = append(args, timestampVideoArgs...)
+			i++
+		case profileArgs[i] == "-vf" && i+1 < len(profileArgs):
+			args = append(args, "-vf", profileArgs[i+1]+","+t.filter())
+			filtered = true
+			i++
+		default:
+			args = append(args, profileArgs[i])
+		}
+	}
+	if !filtered {
+		args = append(args, "-vf", t.filter())
+	}
+	return args
+}
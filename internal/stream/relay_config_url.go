@@ -0,0 +1,145 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Instances sharing one relay config can fetch it over HTTP(S) at startup
+// instead of each having its own file. A fetched config is validated before
+// anything is applied and then kept in a local cache file, which is imported
+// instead when the next fetch fails, e.g. while the config server is down.
+
+// maxRemoteConfigSize bounds a fetched relay config
+const maxRemoteConfigSize = 10 << 20
+
+// ErrInvalidRelayConfig is returned for a relay config that fails validation
+var ErrInvalidRelayConfig = errors.New("invalid relay config")
+
+// RemoteConfig locates a relay config served over HTTP(S)
+type RemoteConfig struct {
+	URL       string
+	Headers   map[string]string // sent with the request, e.g. Authorization
+	Timeout   time.Duration     // of the whole fetch
+	CacheFile string            // copy of the last good config, empty for none
+}
+
+// validateExport checks that every input and output of an import can be
+// identified, so a truncated or mistaken config is refused as a whole
+func validateExport(inputs []exportInput) error {
+	names := make(map[string]bool)
+	outputs := make(map[string]bool)
+	for i, in := range inputs {
+		if in.Input.InputName == "" || in.Input.InputURL == "" {
+			return fmt.Errorf("%w: input %d needs a name and a URL", ErrInvalidRelayConfig, i+1)
+		}
+		if names[in.Input.InputName] {
+			return fmt.Errorf("%w: input %s is listed twice", ErrInvalidRelayConfig, in.Input.InputName)
+		}
+		names[in.Input.InputName] = true
+		for _, out := range in.Outputs {
+			if out.OutputURL == "" || out.OutputName == "" {
+				return fmt.Errorf("%w: an output of input %s needs a name and a URL", ErrInvalidRelayConfig, in.Input.InputName)
+			}
+			if outputs[out.OutputURL] {
+				return fmt.Errorf("%w: output %s is listed twice", ErrInvalidRelayConfig, out.OutputName)
+			}
+			outputs[out.OutputURL] = true
+		}
+	}
+	return nil
+}
+
+// fetchRemoteConfig downloads and validates the config at remote.URL
+func fetchRemoteConfig(ctx context.Context, remote RemoteConfig) ([]byte, []exportInput, error) {
+	ctx, cancel := context.WithTimeout(ctx, remote.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, remote.URL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	for name, value := range remote.Headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("server answered %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteConfigSize+1))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(data) > maxRemoteConfigSize {
+		return nil, nil, fmt.Errorf("config larger than %d bytes", maxRemoteConfigSize)
+	}
+	configs, err := parseExport(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidRelayConfig, err)
+	}
+	if err := validateExport(configs); err != nil {
+		return nil, nil, err
+	}
+	return data, configs, nil
+}
+
+// writeCacheFile replaces filename with data in one step, readable only by
+// the owner since configs carry stream keys
+func writeCacheFile(filename string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filename), ".relay_config-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+// StartImportURL fetches the relay config at remote.URL and starts importing
+// it as an import job. Should the fetch or validation fail, the cached copy
+// from an earlier fetch is imported instead; without one the error is returned.
+func (rm *RelayManager) StartImportURL(ctx context.Context, remote RemoteConfig) (*ImportJob, error) {
+	source := RedactURL(remote.URL)
+	data, configs, err := fetchRemoteConfig(ctx, remote)
+	switch {
+	case err == nil && remote.CacheFile != "":
+		if err := writeCacheFile(remote.CacheFile, data); err != nil {
+			rm.Logger.Warn("Failed to cache relay config from %s in %s: %v", source, remote.CacheFile, err)
+		}
+	case err != nil && remote.CacheFile == "":
+		rm.Logger.Error("Failed to fetch relay config from %s: %v", source, err)
+		return nil, err
+	case err != nil:
+		rm.Logger.Warn("Failed to fetch relay config from %s, using the cached copy %s: %v", source, remote.CacheFile, err)
+		if configs, err = rm.readImport(remote.CacheFile); err != nil {
+			return nil, err
+		}
+		if err := validateExport(configs); err != nil {
+			rm.Logger.Error("Cached relay config %s: %v", remote.CacheFile, err)
+			return nil, err
+		}
+		source = remote.CacheFile
+	}
+
+	job := newImportJob()
+	rm.addImportJob(job)
+	go func() {
+		job.finish(rm.importRelays(configs, source, job))
+	}()
+	return job, nil
+}
@@ -0,0 +1,193 @@
+package stream
+
+import (
+	"fmt"
+	"maps"
+)
+
+// RelayPlan is what StartRelayWithOptions would do for a relay, as worked out
+// by ExplainRelayStart. URLs and command lines are redacted.
+type RelayPlan struct {
+	InputName      string            `json:"input_name"`
+	OutputName     string            `json:"output_name"`
+	InputURL       string            `json:"input_url"`
+	OutputURL      string            `json:"output_url"`
+	PlatformPreset string            `json:"platform_preset,omitempty"`
+	FFmpegOptions  map[string]string `json:"ffmpeg_options,omitempty"`
+	RTSPPath       string            `json:"rtsp_path"`
+	LocalURL       string            `json:"local_url"`
+	// Direct is set when the output would read the input itself, without the RTSP hop
+	Direct bool `json:"direct"`
+	// InputReused is set when the input ffmpeg is already running and would be shared
+	InputReused bool         `json:"input_reused"`
+	Input       *InputConfig `json:"input_config,omitempty"`
+	InputArgs   []string     `json:"input_ffmpeg_args,omitempty"` // nil when no input ffmpeg would be started
+	OutputArgs  []string     `json:"output_ffmpeg_args"`
+	Warnings    []string     `json:"warnings"`
+}
+
+// ExplainRelayStart resolves a relay start like StartRelayWithOptions without
+// starting anything or storing any configuration. overrides, which may be nil,
+// holds per-input settings to apply on top of the stored ones as the start API
+// would. Options, preset, overrides and loops are validated as for a start and
+// returned as errors; conditions that depend on the current state, such as a
+// missing ffmpeg or a full output cap, are listed as warnings in the plan.
+func (rm *RelayManager) ExplainRelayStart(inputURL, outputURL, inputName, outputName string, opts *FFmpegOptions, preset string, overrides *InputConfig) (*RelayPlan, error) {
+	if err := rm.validateRelayStart(inputURL, outputURL, inputName, outputName, opts, preset); err != nil {
+		return nil, err
+	}
+	inputCfg, err := rm.plannedInputConfig(inputName, inputURL, overrides)
+	if err != nil {
+		return nil, err
+	}
+
+	relayPath, localRelayURL := relayLocalURL(inputName)
+	plan := &RelayPlan{
+		InputName:      inputName,
+		OutputName:     outputName,
+		InputURL:       RedactURL(inputURL),
+		OutputURL:      RedactURL(outputURL),
+		PlatformPreset: preset,
+		FFmpegOptions:  ffmpegOptionsMap(opts),
+		RTSPPath:       relayPath,
+		LocalURL:       localRelayURL,
+		Warnings:       []string{},
+	}
+	warn := func(format string, args ...interface{}) {
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf(format, args...))
+	}
+
+	if rm.ffmpegCaps != nil {
+		if err := rm.ffmpegCaps.Available(); err != nil {
+			warn("%v", err)
+		}
+	}
+	if _, err := rm.checkInputName(inputName, inputURL); err != nil {
+		warn("%v", err)
+	}
+	if err := rm.checkOutputLimit(inputURL, outputURL, inputName); err != nil {
+		warn("%v", err)
+	}
+	// Presets are applied by the client; the relay only runs explicit options
+	switch {
+	case opts == nil && preset == "":
+		warn("no preset or ffmpeg options: the output copies the input streams")
+	case opts == nil:
+		warn("preset %s is only recorded without ffmpeg options: the output copies the input streams", preset)
+	case preset != "":
+		warn("the ffmpeg options are used as given, preset %s does not change them", preset)
+	}
+
+	if rm.outputRunning(outputURL) {
+		warn("output %s is already running and would be left as it is", outputName)
+	}
+	status, direct := rm.InputRelays.inputState(inputURL)
+	live := status == InputStarting || status == InputRunning
+	plan.Direct = rm.directPassthrough && !isFiniteInput(inputURL) && !isTestPatternInput(inputURL) &&
+		!inputCfg.needsInputRelay() && !live
+	plan.InputReused = live && !direct
+	if direct && live {
+		warn("input %s is relayed directly and would switch to the RTSP relay for a second consumer", inputName)
+	}
+
+	if plan.Direct {
+		plan.OutputArgs = RedactArgs(rm.buildOutputRelayArgs(inputURL, outputURL, opts))
+	} else {
+		plan.OutputArgs = RedactArgs(rm.buildOutputRelayArgs(localRelayURL, outputURL, opts))
+	}
+	if !plan.Direct && !plan.InputReused {
+		inputCfg = rm.InputRelays.withDefaults(inputCfg)
+		resolved, err := rm.InputRelays.resolveInputURL(inputURL)
+		if err != nil {
+			warn("input %s cannot be read: %v", inputName, err)
+			resolved = inputURL
+		}
+		plan.InputArgs = RedactArgs(buildInputRelayArgs(resolved, localRelayURL, inputCfg))
+	}
+	for name := range inputCfg.HTTPHeaders {
+		inputCfg.HTTPHeaders[name] = "xxxxx"
+	}
+	inputCfg.InputURL = RedactURL(inputCfg.InputURL)
+	inputCfg.BackupURL = RedactURL(inputCfg.BackupURL)
+	plan.Input = &inputCfg
+	return plan, nil
+}
+
+// plannedInputConfig returns the configuration the input relay of inputName
+// would start with: the stored one, unless it is for another URL, with the
+// non-empty fields of overrides applied and validated
+func (rm *RelayManager) plannedInputConfig(inputName, inputURL string, overrides *InputConfig) (InputConfig, error) {
+	cfg := InputConfig{InputURL: inputURL, InputName: inputName}
+	if stored, ok := rm.GetInputConfig(inputName); ok && stored.InputURL == inputURL {
+		cfg = stored
+		cfg.HTTPHeaders = maps.Clone(stored.HTTPHeaders)
+	}
+	if overrides == nil {
+		return cfg, nil
+	}
+	if overrides.AnalyzeDuration != "" || overrides.ProbeSize != "" {
+		cfg.AnalyzeDuration = overrides.AnalyzeDuration
+		cfg.ProbeSize = overrides.ProbeSize
+	}
+	if overrides.BackupURL != "" {
+		cfg.BackupURL = overrides.BackupURL
+	}
+	if overrides.AudioTrack != "" {
+		if err := validateAudioTrack(overrides.AudioTrack, true); err != nil {
+			return cfg, err
+		}
+		cfg.AudioTrack = overrides.AudioTrack
+	}
+	if overrides.Buffering != "" {
+		if err := validateBuffering(overrides.Buffering); err != nil {
+			return cfg, err
+		}
+		cfg.Buffering = overrides.Buffering
+	}
+	if overrides.InputProfile != "" {
+		if err := validateInputProfile(overrides.InputProfile); err != nil {
+			return cfg, err
+		}
+		cfg.InputProfile = overrides.InputProfile
+	}
+	if overrides.GrowingFile {
+		if err := validateGrowingFile(inputURL, true); err != nil {
+			return cfg, err
+		}
+		cfg.GrowingFile = true
+	}
+	if len(overrides.HTTPHeaders) > 0 {
+		if err := validateInputHeaders(inputURL, overrides.HTTPHeaders); err != nil {
+			return cfg, err
+		}
+		cfg.HTTPHeaders = maps.Clone(overrides.HTTPHeaders)
+	}
+	return cfg, nil
+}
+
+// outputRunning reports whether the output relay of outputURL is running
+func (rm *RelayManager) outputRunning(outputURL string) bool {
+	rm.OutputRelays.mu.Lock()
+	defer rm.OutputRelays.mu.Unlock()
+	out, exists := rm.OutputRelays.Relays[outputURL]
+	if !exists {
+		return false
+	}
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	return out.Status == OutputRunning
+}
+
+// inputState returns the status of the input relay of inputURL and whether
+// it is relayed directly
+func (irm *InputRelayManager) inputState(inputURL string) (InputRelayStatus, bool) {
+	irm.mu.Lock()
+	defer irm.mu.Unlock()
+	relay, exists := irm.Relays[inputURL]
+	if !exists {
+		return InputStopped, false
+	}
+	relay.mu.Lock()
+	defer relay.mu.Unlock()
+	return relay.Status, relay.Direct
+}
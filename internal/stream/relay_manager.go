@@ -1,9 +1,21 @@
 package stream
 
 import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math/big"
+	"net"
+	"net/url"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -11,10 +23,75 @@ import (
 	"go-mls/internal/process"
 )
 
+// ErrInputNameConflict is returned when an input name is already used for a
+// different input URL that still has outputs or consumers
+var ErrInputNameConflict = errors.New("input name already in use for a different URL")
+
+// ErrOutputLimitReached is returned when an input already has the maximum
+// number of outputs allowed by SetMaxOutputsPerInput
+var ErrOutputLimitReached = errors.New("output limit reached for input")
+
+// ErrRelayLoop is returned when an output would feed back into go-mls itself
+var ErrRelayLoop = errors.New("output would create a relay loop")
+
+// ErrUnknownPreset is returned when a relay names a platform preset that does
+// not exist and gives no ffmpeg options of its own
+var ErrUnknownPreset = errors.New("unknown platform preset")
+
+// ErrOutputUnreachable is returned by CheckOutputReachable when the output
+// cannot be connected to or written
+var ErrOutputUnreachable = errors.New("output unreachable")
+
+// outputProbeTimeout bounds the TCP dial made by CheckOutputReachable
+const outputProbeTimeout = 3 * time.Second
+
 // InputConfig stores persistent input configuration
 type InputConfig struct {
 	InputURL  string `json:"input_url"`
 	InputName string `json:"input_name"`
+
+	// Per-input ffmpeg probe overrides for slow-to-start sources (empty = default)
+	AnalyzeDuration string `json:"analyzeduration,omitempty"`
+	ProbeSize       string `json:"probesize,omitempty"`
+
+	// Source the input relay fails over to when ffmpeg exits with an error (empty = none)
+	BackupURL string `json:"backup_url,omitempty"`
+
+	// A/V sync flags of the input's HLS preview, nil for the HLS defaults
+	HLSSync *HLSSyncOptions `json:"hls_sync,omitempty"`
+
+	// Video height cap of the input's HLS preview (0 = source), nil for the HLS default
+	HLSMaxHeight *int `json:"hls_max_height,omitempty"`
+
+	// Viewer cap of the input's HLS preview (0 = unlimited), nil for the HLS default
+	HLSMaxViewers *int `json:"hls_max_viewers,omitempty"`
+
+	// Audio tracks of a multi-track source the input relay publishes: an index,
+	// a language code or "all" (empty = ffmpeg's default track)
+	AudioTrack string `json:"audio_track,omitempty"`
+
+	// Index of the published audio track the HLS preview plays (empty = default)
+	HLSAudioTrack string `json:"hls_audio_track,omitempty"`
+
+	// Latency/stability trade-off of reading the source and of the HLS preview (empty = default)
+	Buffering BufferingMode `json:"buffering,omitempty"`
+
+	// Bundle of ffmpeg options reading the input, see InputProfile (empty = default)
+	InputProfile InputProfile `json:"input_profile,omitempty"`
+
+	// Read a file:// input that is still being written, see growing_file.go
+	GrowingFile bool `json:"growing_file,omitempty"`
+
+	// HTTP headers sent when reading an http(s) input, see input_headers.go
+	HTTPHeaders map[string]string `json:"http_headers,omitempty"`
+}
+
+// needsInputRelay reports whether the input has options the input ffmpeg
+// applies when reading the source, which a direct output would lose: HTTP
+// headers, probe sizes, buffering, an input profile or an audio track
+func (c InputConfig) needsInputRelay() bool {
+	return len(c.HTTPHeaders) > 0 || c.AnalyzeDuration != "" || c.ProbeSize != "" ||
+		c.Buffering != "" || c.InputProfile != "" || c.AudioTrack != ""
 }
 
 // RelayManager manages all relays (per input URL)
@@ -22,40 +99,109 @@ type RelayManager struct {
 	InputRelays  *InputRelayManager
 	OutputRelays *OutputRelayManager
 	Logger       *logger.Logger
-	rtspServer   *RTSPServerManager // RTSP server for local relays
-	recDir       string             // Directory for playing recordings from
+	rtspServer   *RTSPServerManager  // RTSP server for local relays
+	recDir       string              // Directory for playing recordings from
+	assetsDir    string              // Directory holding watermark images, empty disables watermarks
+	ffmpegCaps   *FFmpegCapabilities // Detected ffmpeg features, nil if unknown
 
 	// Configuration registry for persistent input mappings
-	inputConfigs map[string]*InputConfig // inputName -> InputConfig
-	configMu     sync.RWMutex            // Protects inputConfigs
+	inputConfigs     map[string]*InputConfig // inputName -> InputConfig
+	outputPriorities map[string]int          // outputURL -> import start priority
+	disabledOutputs  map[string]bool         // outputURL -> true while disabled, see DisableOutput
+	pausedOutputs    map[string]bool         // outputURL -> true while paused, see PauseOutput
+	configMu         sync.RWMutex            // Protects inputConfigs, outputPriorities, disabledOutputs and pausedOutputs
+
+	// Background imports, see StartImport
+	importJobs   []*ImportJob
+	importJobsMu sync.Mutex
 
 	// Configurable timeouts
 	inputTimeout  time.Duration
 	outputTimeout time.Duration
 
+	// Skip the local RTSP hop for inputs with a single output (set before relays start)
+	directPassthrough bool
+
+	// Maximum outputs per input, 0 for unlimited (set before relays start)
+	maxOutputsPerInput int
+
+	// Maximum relays ImportConfig starts at once
+	importConcurrency int
+
+	// Buffering mode of inputs without their own (set before relays start)
+	buffering BufferingMode
+
+	// Input profile of inputs without their own, empty for none (set before relays start)
+	inputProfile InputProfile
+
+	// Outputs StopAllRelays stops at once, and how long each ffmpeg may take to exit
+	shutdownConcurrency int
+	shutdownStopTimeout time.Duration
+
+	// Default ffmpeg -threads for outputs, 0 lets ffmpeg decide (set before relays start)
+	threads int
+
+	// Default -max_muxing_queue_size of outputs, 0 keeps ffmpeg's (set before relays start)
+	maxMuxingQueueSize int
+
 	// Mutex map for serializing concurrent starts of the same input URL
 	startMutexes   map[string]*sync.Mutex
 	startMutexesMu sync.Mutex
+
+	// Stage of the start in progress per input URL, see relay_stage.go
+	startStages   map[string]RelayStage
+	startStagesMu sync.Mutex
+
+	// Purging of historical inputs, see input_history.go
+	historyMaxAge   time.Duration // set before relays are started via SetInputHistoryMaxAge
+	historyStop     chan struct{}
+	historyStopOnce sync.Once
 }
 
 func NewRelayManager(l *logger.Logger, recDir string) *RelayManager {
 	irm := NewInputRelayManager(l, recDir)
 	orm := NewOutputRelayManager(l)
 	rm := &RelayManager{
-		InputRelays:   irm,
-		OutputRelays:  orm,
-		Logger:        l,
-		recDir:        recDir,
-		inputConfigs:  make(map[string]*InputConfig),
-		inputTimeout:  30 * time.Second, // Default values, can be overridden
-		outputTimeout: 60 * time.Second,
-		startMutexes:  make(map[string]*sync.Mutex),
+		InputRelays:       irm,
+		OutputRelays:      orm,
+		Logger:            l,
+		recDir:            recDir,
+		inputConfigs:      make(map[string]*InputConfig),
+		outputPriorities:  make(map[string]int),
+		disabledOutputs:   make(map[string]bool),
+		pausedOutputs:     make(map[string]bool),
+		inputTimeout:      30 * time.Second, // Default values, can be overridden
+		outputTimeout:     60 * time.Second,
+		importConcurrency: DefaultImportConcurrency,
+		buffering:         BufferingLowLatency,
+
+		shutdownConcurrency: DefaultShutdownConcurrency,
+		shutdownStopTimeout: DefaultShutdownStopTimeout,
+		startMutexes:        make(map[string]*sync.Mutex),
+		startStages:         make(map[string]RelayStage),
+		historyStop:         make(chan struct{}),
+	}
+
+	// Let input relays pick up per-input ffmpeg overrides
+	irm.configLookup = rm.GetInputConfig
+
+	// Move direct outputs onto the RTSP relay once their input gains a second consumer
+	irm.onDirectPromoted = rm.rehomeDirectOutputs
+
+	// Outputs of a finished file:// input end normally rather than failing
+	orm.InputCompleted = func(inputURL string) bool {
+		return irm.WaitForCompletion(inputURL, inputCompletionWait)
+	}
+
+	// Stalled outputs count as idle consumers of their input
+	orm.OutputActive = func(inputURL, outputURL string, active bool) {
+		irm.SetConsumerActive(inputURL, outputConsumer(outputURL), active)
 	}
 
 	// Set up failure callback for output relays to clean up input relay refcount
 	orm.SetFailureCallback(func(inputURL, outputURL string) {
 		l.Debug("Output relay failure callback: cleaning up input relay refcount for inputURL=%s", inputURL)
-		irm.StopInputRelay(inputURL) // RTSP cleanup is handled internally
+		irm.StopInputRelay(inputURL, outputConsumer(outputURL)) // RTSP cleanup is handled internally
 	})
 
 	return rm
@@ -81,7 +227,160 @@ type FFmpegOptions struct {
 	Framerate  string // e.g. "30"
 	Bitrate    string // e.g. "2500k"
 	Rotation   string // e.g. "transpose=1" for 90deg
+	Reconnect  string // restart a failed output for up to this many seconds of backoff, e.g. "10"; empty disables
 	ExtraArgs  []string
+
+	KeyframeInterval string // GOP in seconds ("2", needs Framerate) or frames ("60f")
+	AudioNormalize   string // loudnorm integrated loudness target in LUFS, e.g. "-16"; empty disables
+	AudioSampleRate  string // e.g. "48000"; empty keeps the source rate
+	AudioChannels    string // e.g. "2" for stereo, "1" to downmix to mono; empty keeps the source layout
+
+	Watermark         string // image file name in the assets directory, e.g. "logo.png"
+	WatermarkPosition string // top-left, top-right, bottom-left, bottom-right (default) or center
+
+	Nice    string // per-output nice value (-20 to 19) overriding the global one; empty keeps it
+	Threads string // per-output ffmpeg -threads overriding the global one; empty keeps it
+
+	MaxMuxingQueueSize string // per-output -max_muxing_queue_size in packets overriding the global one; empty keeps it
+
+	AudioTrack string // index of the input's published audio tracks to send, e.g. "1"; empty keeps the default
+}
+
+// watermarkOverlays maps watermark positions to overlay filter coordinates
+var watermarkOverlays = map[string]string{
+	"top-left":     "10:10",
+	"top-right":    "W-w-10:10",
+	"bottom-left":  "10:H-h-10",
+	"bottom-right": "W-w-10:H-h-10",
+	"center":       "(W-w)/2:(H-h)/2",
+}
+
+// loudnorm accepts integrated loudness targets in this range (LUFS)
+const (
+	minLoudnessTarget = -70.0
+	maxLoudnessTarget = -5.0
+)
+
+// Validate checks option combinations that ffmpeg would otherwise reject or
+// silently round
+func (o *FFmpegOptions) Validate() error {
+	if _, err := o.keyframeIntervalFrames(); err != nil {
+		return err
+	}
+	if o.AudioNormalize != "" {
+		target, err := strconv.ParseFloat(o.AudioNormalize, 64)
+		if err != nil || target < minLoudnessTarget || target > maxLoudnessTarget {
+			return fmt.Errorf("invalid audio normalization target %q: must be between %g and %g LUFS", o.AudioNormalize, minLoudnessTarget, maxLoudnessTarget)
+		}
+		if o.AudioCodec == "copy" {
+			return fmt.Errorf("audio normalization requires re-encoding audio, it cannot be combined with audio codec copy")
+		}
+	}
+	for _, field := range []struct{ name, value string }{
+		{"audio sample rate", o.AudioSampleRate},
+		{"audio channels", o.AudioChannels},
+	} {
+		name, value := field.name, field.value
+		if value == "" {
+			continue
+		}
+		if n, err := strconv.Atoi(value); err != nil || n <= 0 {
+			return fmt.Errorf("invalid %s %q: must be a positive integer", name, value)
+		}
+		if o.AudioCodec == "copy" {
+			return fmt.Errorf("changing the %s requires re-encoding audio, it cannot be combined with audio codec copy", name)
+		}
+	}
+	if o.Nice != "" {
+		if _, err := parseNice(o.Nice); err != nil {
+			return err
+		}
+	}
+	if o.Threads != "" {
+		if n, err := strconv.Atoi(o.Threads); err != nil || n < 0 {
+			return fmt.Errorf("invalid threads %q: must be a non-negative integer (0 lets ffmpeg decide)", o.Threads)
+		}
+	}
+	if o.Reconnect != "" {
+		if _, err := parseReconnect(o.Reconnect); err != nil {
+			return err
+		}
+	}
+	if o.MaxMuxingQueueSize != "" {
+		if n, err := strconv.Atoi(o.MaxMuxingQueueSize); err != nil || n <= 0 {
+			return fmt.Errorf("invalid max muxing queue size %q: must be a positive number of packets", o.MaxMuxingQueueSize)
+		}
+	}
+	if err := validateAudioTrack(o.AudioTrack, false); err != nil {
+		return err
+	}
+	if o.Watermark != "" {
+		if _, ok := watermarkOverlays[o.watermarkPosition()]; !ok {
+			return fmt.Errorf("invalid watermark position %q", o.WatermarkPosition)
+		}
+		if o.VideoCodec == "copy" {
+			return fmt.Errorf("a watermark requires re-encoding video, it cannot be combined with video codec copy")
+		}
+	}
+	return nil
+}
+
+// parseNice parses a nice value, which the OS limits to -20..19
+func parseNice(value string) (int, error) {
+	nice, err := strconv.Atoi(value)
+	if err != nil || nice < -20 || nice > 19 {
+		return 0, fmt.Errorf("invalid nice value %q: must be an integer from -20 to 19", value)
+	}
+	return nice, nil
+}
+
+// parseReconnect parses the longest delay between reconnect attempts, in seconds
+func parseReconnect(value string) (time.Duration, error) {
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds <= 0 {
+		return 0, fmt.Errorf("invalid reconnect %q: must be a positive number of seconds", value)
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+func (o *FFmpegOptions) watermarkPosition() string {
+	if o.WatermarkPosition == "" {
+		return "bottom-right"
+	}
+	return o.WatermarkPosition
+}
+
+// keyframeIntervalFrames converts KeyframeInterval to a GOP size in frames.
+// It returns 0 when no interval is set. A seconds value must be a whole
+// number of frames at the configured framerate (e.g. 2s at 29.97 fps is not).
+func (o *FFmpegOptions) keyframeIntervalFrames() (int, error) {
+	interval := strings.TrimSpace(o.KeyframeInterval)
+	if interval == "" {
+		return 0, nil
+	}
+	if frames, ok := strings.CutSuffix(interval, "f"); ok {
+		n, err := strconv.Atoi(frames)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid keyframe interval %q: frame count must be a positive integer", o.KeyframeInterval)
+		}
+		return n, nil
+	}
+	seconds, ok := new(big.Rat).SetString(interval)
+	if !ok || seconds.Sign() <= 0 {
+		return 0, fmt.Errorf("invalid keyframe interval %q: must be seconds (e.g. 2) or frames (e.g. 60f)", o.KeyframeInterval)
+	}
+	if o.Framerate == "" {
+		return 0, fmt.Errorf("keyframe interval %q in seconds requires a framerate", o.KeyframeInterval)
+	}
+	fps, ok := new(big.Rat).SetString(strings.TrimSpace(o.Framerate))
+	if !ok || fps.Sign() <= 0 {
+		return 0, fmt.Errorf("invalid framerate %q", o.Framerate)
+	}
+	frames := new(big.Rat).Mul(seconds, fps)
+	if !frames.IsInt() || !frames.Num().IsInt64() {
+		return 0, fmt.Errorf("keyframe interval %ss at %s fps is %s frames; it must be a whole number of frames", interval, o.Framerate, frames.FloatString(2))
+	}
+	return int(frames.Num().Int64()), nil
 }
 
 // PlatformPreset defines a set of FFmpeg options for a platform
@@ -95,11 +394,23 @@ var PlatformPresets = map[string]PlatformPreset{
 	"YouTube": {
 		Name: "YouTube",
 		Options: FFmpegOptions{
-			VideoCodec: "libx264",
-			AudioCodec: "aac",
-			Resolution: "1920x1080",
-			Framerate:  "30",
-			Bitrate:    "4500k",
+			VideoCodec:       "libx264",
+			AudioCodec:       "aac",
+			Resolution:       "1920x1080",
+			Framerate:        "30",
+			Bitrate:          "4500k",
+			KeyframeInterval: "2",
+		},
+	},
+	"Twitch": {
+		Name: "Twitch",
+		Options: FFmpegOptions{
+			VideoCodec:       "libx264",
+			AudioCodec:       "aac",
+			Resolution:       "1920x1080",
+			Framerate:        "30",
+			Bitrate:          "6000k",
+			KeyframeInterval: "2",
 		},
 	},
 	"Instagram": {
@@ -126,39 +437,98 @@ var PlatformPresets = map[string]PlatformPreset{
 	},
 }
 
+// ValidatePreset checks that name is a known platform preset; empty is
+// allowed. The error lists the valid names.
+func ValidatePreset(name string) error {
+	if name == "" {
+		return nil
+	}
+	if _, ok := PlatformPresets[name]; ok {
+		return nil
+	}
+	names := make([]string, 0, len(PlatformPresets))
+	for known := range PlatformPresets {
+		names = append(names, known)
+	}
+	sort.Strings(names)
+	return fmt.Errorf("%w %q, valid presets: %s", ErrUnknownPreset, name, strings.Join(names, ", "))
+}
+
+// DefaultImportConcurrency is how many relays ImportConfig starts at once
+const DefaultImportConcurrency = 4
+
+// DefaultShutdownConcurrency is how many outputs StopAllRelays stops at once
+const DefaultShutdownConcurrency = 8
+
+// DefaultShutdownStopTimeout is how long StopAllRelays lets an output's ffmpeg
+// exit before killing it; shorter than for a normal stop to bound shutdown
+const DefaultShutdownStopTimeout = time.Second
+
 // StartRelay starts a relay for an input/output URL and stores names
-// StartRelayWithOptions starts a relay with advanced ffmpeg options and/or platform preset
-func (rm *RelayManager) StartRelayWithOptions(inputURL, outputURL, inputName, outputName string, opts *FFmpegOptions, preset string) error {
-	rm.Logger.Debug("StartRelayWithOptions called: input=%s, output=%s, input_name=%s, output_name=%s, preset=%s", inputURL, outputURL, inputName, outputName, preset)
+// StartRelayWithOptions starts a relay with advanced ffmpeg options and/or platform preset.
+// It returns the output ffmpeg's arguments, unredacted; see RedactArgs.
+func (rm *RelayManager) StartRelayWithOptions(inputURL, outputURL, inputName, outputName string, opts *FFmpegOptions, preset string) ([]string, error) {
+	rm.Logger.Debug("StartRelayWithOptions called: input=%s, output=%s, input_name=%s, output_name=%s, preset=%s", RedactURL(inputURL), outputURL, inputName, outputName, preset)
 
-	// Register input configuration for future HLS access
-	rm.RegisterInputConfig(inputName, inputURL)
+	if err := rm.validateRelayStart(inputURL, outputURL, inputName, outputName, opts, preset); err != nil {
+		return nil, err
+	}
+	// Fail early instead of with an exec error from deep inside the relay managers
+	if rm.ffmpegCaps != nil {
+		if err := rm.ffmpegCaps.Available(); err != nil {
+			rm.Logger.Error("Cannot start relay %s -> %s: %v", inputName, outputName, err)
+			return nil, err
+		}
+	}
+
+	// Register input configuration for future HLS access; a name may only refer to one URL
+	if err := rm.RegisterInputConfig(inputName, inputURL); err != nil {
+		rm.Logger.Error("Cannot start relay %s -> %s: %v", inputName, outputName, err)
+		return nil, err
+	}
 
 	// Get mutex for this input URL to serialize concurrent starts
 	startMutex := rm.getStartMutex(inputURL)
 	startMutex.Lock()
 	defer startMutex.Unlock()
 
-	// Compose local RTSP relay path and URL
-	relayPath := fmt.Sprintf("relay/%s", inputName)
-	localRelayURL := fmt.Sprintf("%s/%s", GetRTSPServerURL(), relayPath)
+	// Checked under the start mutex so concurrent starts cannot exceed the cap
+	if err := rm.checkOutputLimit(inputURL, outputURL, inputName); err != nil {
+		rm.Logger.Error("Cannot start relay %s -> %s: %v", inputName, outputName, err)
+		return nil, err
+	}
+
+	relayPath, localRelayURL := relayLocalURL(inputName)
+
+	defer rm.clearStartStage(inputURL)
+
+	// A lone output of a live input can read it directly, without the RTSP hop.
+	// Test patterns are generated by the input ffmpeg, so always go through it.
+	// So are inputs with options only the input ffmpeg applies.
+	if rm.directPassthrough && !isFiniteInput(inputURL) && !isTestPatternInput(inputURL) && !rm.inputNeedsRelay(inputName) &&
+		rm.InputRelays.StartDirectInput(inputName, inputURL, localRelayURL, rm.inputTimeout, outputConsumer(outputURL)) {
+		rm.setStartStage(inputURL, StageStartingOutput)
+		return rm.startDirectOutput(inputURL, outputURL, inputName, outputName, localRelayURL, opts, preset)
+	}
 
 	// Start or get the input relay
-	_, err := rm.InputRelays.StartInputRelay(inputName, inputURL, localRelayURL, rm.inputTimeout)
+	rm.setStartStage(inputURL, StageStartingInput)
+	_, err := rm.InputRelays.StartInputRelay(inputName, inputURL, localRelayURL, rm.inputTimeout, outputConsumer(outputURL))
 	if err != nil {
 		rm.Logger.Error("Failed to start input relay for output: %v", err)
-		return err
+		return nil, err
 	}
 
 	// Wait for the RTSP stream to become ready before starting output ffmpeg
 	if rm.rtspServer != nil {
+		rm.setStartStage(inputURL, StageWaitingRTSP)
 		rm.Logger.Info("Waiting for RTSP stream to become ready: %s", relayPath)
 		err = rm.rtspServer.WaitForStreamReady(relayPath, 30*time.Second)
 		if err != nil {
 			rm.Logger.Error("Failed to wait for RTSP stream to become ready for %s: %v", inputName, err)
 			if !rm.rtspServer.IsStreamReady(relayPath) {
-				rm.InputRelays.StopInputRelay(inputURL)
-				return fmt.Errorf("RTSP stream not ready: %v", err)
+				rm.InputRelays.StopInputRelay(inputURL, outputConsumer(outputURL))
+				return nil, fmt.Errorf("RTSP stream not ready: %v", err)
 			}
 			rm.Logger.Warn("Stream %s appears ready but wait failed, continuing anyway", relayPath)
 		} else {
@@ -167,15 +537,312 @@ func (rm *RelayManager) StartRelayWithOptions(inputURL, outputURL, inputName, ou
 	}
 
 	// Build ffmpeg args for output relay
+	rm.setStartStage(inputURL, StageStartingOutput)
+	args := rm.buildOutputRelayArgs(localRelayURL, outputURL, opts)
+
+	config := OutputRelayConfig{
+		OutputURL:      outputURL,
+		OutputName:     outputName,
+		InputURL:       inputURL,
+		LocalURL:       localRelayURL,
+		Timeout:        rm.outputTimeout,
+		PlatformPreset: preset,
+		FFmpegOptions:  ffmpegOptionsMap(opts),
+		FFmpegArgs:     args,
+	}
+	err = rm.OutputRelays.StartOutputRelay(config)
+	if err != nil {
+		rm.Logger.Error("Failed to start output relay: %v", err)
+		rm.InputRelays.StopInputRelay(inputURL, outputConsumer(outputURL))
+		return nil, err
+	}
+
+	rm.setOutputPaused(outputURL, false)
+	rm.Logger.Info("Started relay: %s [%s] -> %s [%s]", inputName, RedactURL(inputURL), outputName, outputURL)
+	return args, nil
+}
+
+// validateRelayStart checks the options, preset and output of a relay to be
+// started, without side effects
+func (rm *RelayManager) validateRelayStart(inputURL, outputURL, inputName, outputName string, opts *FFmpegOptions, preset string) error {
+	if err := rm.ValidateFFmpegOptions(opts); err != nil {
+		return err
+	}
+	// Explicit options configure the relay themselves, whatever the preset is called
+	if opts == nil {
+		if err := ValidatePreset(preset); err != nil {
+			return err
+		}
+	}
+	if err := rm.checkRelayLoop(inputURL, outputURL); err != nil {
+		rm.Logger.Error("Rejected relay %s -> %s: %v", inputName, outputName, err)
+		return err
+	}
+	if !rm.outputEnabled(outputURL) {
+		rm.Logger.Warn("Not starting disabled output %s [%s]", outputName, RedactURL(outputURL))
+		return fmt.Errorf("%w: %s", ErrOutputDisabled, outputName)
+	}
+	return nil
+}
+
+// checkOutputLimit returns ErrOutputLimitReached when inputURL cannot feed
+// outputURL as well
+func (rm *RelayManager) checkOutputLimit(inputURL, outputURL, inputName string) error {
+	if rm.maxOutputsPerInput > 0 {
+		if count := rm.countOutputs(inputURL, outputURL); count >= rm.maxOutputsPerInput {
+			return fmt.Errorf("%w: %s already has %d of %d outputs", ErrOutputLimitReached, inputName, count, rm.maxOutputsPerInput)
+		}
+	}
+	return nil
+}
+
+// relayLocalURL returns the path and URL an input is published at on the
+// local RTSP server
+func relayLocalURL(inputName string) (string, string) {
+	relayPath := fmt.Sprintf("relay/%s", inputName)
+	return relayPath, fmt.Sprintf("%s/%s", GetRTSPServerURL(), relayPath)
+}
+
+// ffmpegOptionsMap converts FFmpegOptions to the map stored with an output relay
+func ffmpegOptionsMap(opts *FFmpegOptions) map[string]string {
+	if opts == nil {
+		return nil
+	}
+	return map[string]string{
+		"video_codec":           opts.VideoCodec,
+		"audio_codec":           opts.AudioCodec,
+		"resolution":            opts.Resolution,
+		"framerate":             opts.Framerate,
+		"bitrate":               opts.Bitrate,
+		"rotation":              opts.Rotation,
+		"reconnect":             opts.Reconnect,
+		"keyframe_interval":     opts.KeyframeInterval,
+		"audio_normalize":       opts.AudioNormalize,
+		"audio_sample_rate":     opts.AudioSampleRate,
+		"audio_channels":        opts.AudioChannels,
+		"watermark":             opts.Watermark,
+		"watermark_position":    opts.WatermarkPosition,
+		"nice":                  opts.Nice,
+		"threads":               opts.Threads,
+		"max_muxing_queue_size": opts.MaxMuxingQueueSize,
+		"audio_track":           opts.AudioTrack,
+	}
+}
+
+// FFmpegOptionsFromMap converts options keyed as in the ffmpeg_options of
+// /api/relay/start, the inverse of ffmpegOptionsMap
+func FFmpegOptionsFromMap(m map[string]string) *FFmpegOptions {
+	return &FFmpegOptions{
+		VideoCodec:         m["video_codec"],
+		AudioCodec:         m["audio_codec"],
+		Resolution:         m["resolution"],
+		Framerate:          m["framerate"],
+		Bitrate:            m["bitrate"],
+		Rotation:           m["rotation"],
+		Reconnect:          m["reconnect"],
+		KeyframeInterval:   m["keyframe_interval"],
+		AudioNormalize:     m["audio_normalize"],
+		AudioSampleRate:    m["audio_sample_rate"],
+		AudioChannels:      m["audio_channels"],
+		Watermark:          m["watermark"],
+		WatermarkPosition:  m["watermark_position"],
+		Nice:               m["nice"],
+		Threads:            m["threads"],
+		MaxMuxingQueueSize: m["max_muxing_queue_size"],
+		AudioTrack:         m["audio_track"],
+	}
+}
+
+// startDirectOutput starts an output that reads inputURL itself. The input
+// relay entry was already claimed by StartDirectInput and is released on failure.
+func (rm *RelayManager) startDirectOutput(inputURL, outputURL, inputName, outputName, localRelayURL string, opts *FFmpegOptions, preset string) ([]string, error) {
+	config := OutputRelayConfig{
+		OutputURL:      outputURL,
+		OutputName:     outputName,
+		InputURL:       inputURL,
+		LocalURL:       localRelayURL,
+		Timeout:        rm.outputTimeout,
+		PlatformPreset: preset,
+		FFmpegOptions:  ffmpegOptionsMap(opts),
+		FFmpegArgs:     rm.buildOutputRelayArgs(inputURL, outputURL, opts),
+		Direct:         true,
+	}
+	if err := rm.OutputRelays.StartOutputRelay(config); err != nil {
+		rm.Logger.Error("Failed to start direct output relay: %v", err)
+		rm.InputRelays.StopInputRelay(inputURL, outputConsumer(outputURL))
+		return nil, err
+	}
+	rm.Logger.Info("Started direct relay: %s [%s] -> %s [%s]", inputName, RedactURL(inputURL), outputName, outputURL)
+	return config.FFmpegArgs, nil
+}
+
+// inputNeedsRelay reports whether inputName has options only its input ffmpeg
+// applies, see InputConfig.needsInputRelay
+func (rm *RelayManager) inputNeedsRelay(inputName string) bool {
+	config, ok := rm.GetInputConfig(inputName)
+	return ok && config.needsInputRelay()
+}
+
+// rehomeDirectOutputs restarts the direct outputs of inputURL so they read the
+// local RTSP relay at localURL, once the input relay is publishing there. The
+// outputs keep the input reference they already hold.
+func (rm *RelayManager) rehomeDirectOutputs(inputURL, inputName, localURL string) {
+	relayPath := "relay/" + inputName
+	if rm.rtspServer != nil {
+		deadline := time.Now().Add(rm.inputTimeout)
+		for !rm.rtspServer.IsStreamPublishing(relayPath) {
+			if time.Now().After(deadline) {
+				rm.Logger.Warn("RTSP stream %s not ready, moving direct outputs of %s anyway", relayPath, RedactURL(inputURL))
+				break
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+
+	var configs []OutputRelayConfig
+	rm.OutputRelays.mu.Lock()
+	for _, out := range rm.OutputRelays.Relays {
+		out.mu.Lock()
+		// An output waiting to reconnect would otherwise come back reading the source
+		if out.InputURL == inputURL && out.Direct && (out.Status == OutputRunning || out.Status == OutputReconnecting) {
+			configs = append(configs, OutputRelayConfig{
+				OutputURL:      out.OutputURL,
+				OutputName:     out.OutputName,
+				InputURL:       out.InputURL,
+				LocalURL:       localURL,
+				Timeout:        out.Timeout,
+				PlatformPreset: out.PlatformPreset,
+				FFmpegOptions:  out.FFmpegOptions,
+				FFmpegArgs:     replaceInputArg(out.FFmpegArgs, localURL),
+			})
+		}
+		out.mu.Unlock()
+	}
+	rm.OutputRelays.mu.Unlock()
+
+	for _, config := range configs {
+		rm.Logger.Info("Moving direct output %s onto RTSP relay %s", RedactURL(config.OutputURL), localURL)
+		// Graceful stop: the output keeps its input reference for the restart
+		rm.OutputRelays.StopOutputRelay(config.OutputURL)
+		if err := rm.OutputRelays.StartOutputRelay(config); err != nil {
+			rm.Logger.Error("Failed to restart output %s on RTSP relay: %v", RedactURL(config.OutputURL), err)
+			rm.InputRelays.StopInputRelay(inputURL, outputConsumer(config.OutputURL))
+		}
+	}
+}
+
+// replaceInputArg returns a copy of args with the value of the first -i replaced
+func replaceInputArg(args []string, inputURL string) []string {
+	replaced := append([]string(nil), args...)
+	for i := 0; i+1 < len(replaced); i++ {
+		if replaced[i] == "-i" {
+			replaced[i+1] = inputURL
+			break
+		}
+	}
+	return replaced
+}
+
+// countOutputs returns how many outputs are configured for inputURL, not
+// counting outputURL itself so restarting an existing output is always allowed
+func (rm *RelayManager) countOutputs(inputURL, outputURL string) int {
+	rm.OutputRelays.mu.Lock()
+	defer rm.OutputRelays.mu.Unlock()
+	count := 0
+	for url, relay := range rm.OutputRelays.Relays {
+		if relay.InputURL == inputURL && url != outputURL {
+			count++
+		}
+	}
+	return count
+}
+
+// SetMaxOutputsPerInput caps how many outputs a single input may feed;
+// 0 means unlimited
+func (rm *RelayManager) SetMaxOutputsPerInput(max int) {
+	rm.maxOutputsPerInput = max
+}
+
+// SetThreads sets the default ffmpeg -threads for output encodes; 0 lets
+// ffmpeg pick based on the CPU count
+func (rm *RelayManager) SetThreads(n int) {
+	rm.threads = n
+}
+
+// SetMaxMuxingQueueSize sets the default -max_muxing_queue_size of outputs in
+// packets; 0 keeps ffmpeg's default
+func (rm *RelayManager) SetMaxMuxingQueueSize(n int) {
+	rm.maxMuxingQueueSize = n
+}
+
+// SetImportConcurrency sets how many relays ImportConfig starts at once
+func (rm *RelayManager) SetImportConcurrency(n int) {
+	if n < 1 {
+		n = 1
+	}
+	rm.importConcurrency = n
+}
+
+// SetShutdownOptions sets how many outputs StopAllRelays stops at once and
+// how long each ffmpeg may take to exit before it is killed. Zero values keep
+// the current settings.
+func (rm *RelayManager) SetShutdownOptions(concurrency int, stopTimeout time.Duration) {
+	if concurrency > 0 {
+		rm.shutdownConcurrency = concurrency
+	}
+	if stopTimeout > 0 {
+		rm.shutdownStopTimeout = stopTimeout
+	}
+}
+
+func (rm *RelayManager) outputPriority(outputURL string) int {
+	rm.configMu.RLock()
+	defer rm.configMu.RUnlock()
+	return rm.outputPriorities[outputURL]
+}
+
+func (rm *RelayManager) setOutputPriority(outputURL string, priority int) {
+	rm.configMu.Lock()
+	defer rm.configMu.Unlock()
+	if priority == 0 {
+		delete(rm.outputPriorities, outputURL)
+		return
+	}
+	rm.outputPriorities[outputURL] = priority
+}
+
+// SetDirectPassthrough enables relaying a live input straight to its only
+// output, without the local RTSP hop, until a second consumer needs the hub
+func (rm *RelayManager) SetDirectPassthrough(enabled bool) {
+	rm.directPassthrough = enabled
+}
+
+// buildOutputRelayArgs returns the ffmpeg args that read the local RTSP relay
+// and push it to outputURL with the given options applied.
+func (rm *RelayManager) buildOutputRelayArgs(localRelayURL, outputURL string, opts *FFmpegOptions) []string {
 	args := []string{"-hide_banner", "-loglevel", "info", "-stats", "-re", "-i", localRelayURL}
+	if opts != nil && opts.Watermark != "" {
+		// Second input; a single image is repeated by the overlay filter
+		args = append(args, "-i", rm.watermarkPath(opts.Watermark))
+	}
 	if opts != nil {
+		if opts.Watermark == "" {
+			args = append(args, audioTrackMapArgs(opts.AudioTrack)...)
+		}
 		if opts.VideoCodec != "" {
 			args = append(args, "-c:v", opts.VideoCodec)
 		}
 		if opts.AudioCodec != "" {
 			args = append(args, "-c:a", opts.AudioCodec)
 		}
-		if opts.Resolution != "" {
+		if opts.AudioSampleRate != "" {
+			args = append(args, "-ar", opts.AudioSampleRate)
+		}
+		if opts.AudioChannels != "" {
+			args = append(args, "-ac", opts.AudioChannels)
+		}
+		// With a watermark, scaling happens inside the filtergraph instead
+		if opts.Resolution != "" && opts.Watermark == "" {
 			args = append(args, "-s", opts.Resolution)
 		}
 		if opts.Framerate != "" {
@@ -184,64 +851,289 @@ func (rm *RelayManager) StartRelayWithOptions(inputURL, outputURL, inputName, ou
 		if opts.Bitrate != "" {
 			args = append(args, "-b:v", opts.Bitrate)
 		}
+		// Validated before the relay starts; a fixed GOP needs both -g and -keyint_min
+		if gop, err := opts.keyframeIntervalFrames(); err == nil && gop > 0 {
+			args = append(args, "-g", strconv.Itoa(gop), "-keyint_min", strconv.Itoa(gop))
+		}
+		// ffmpeg honours only one filtergraph per stream type, so filters from
+		// the options and from ExtraArgs are merged into a single -vf and -af
+		videoFilters, audioFilters, extraArgs := splitFilterArgs(opts.ExtraArgs)
 		if opts.Rotation != "" {
-			args = append(args, "-vf", opts.Rotation)
+			videoFilters = append([]string{opts.Rotation}, videoFilters...)
+		}
+		if opts.AudioNormalize != "" {
+			// Normalize last so loudness is measured on the final audio
+			audioFilters = append(audioFilters, fmt.Sprintf("loudnorm=I=%s:TP=-1.5:LRA=11", opts.AudioNormalize))
+		}
+		if opts.Watermark != "" {
+			// Rotate and scale the video first so the logo keeps its size and corner
+			if opts.Resolution != "" {
+				videoFilters = append(videoFilters, "scale="+strings.Replace(opts.Resolution, "x", ":", 1))
+			}
+			if len(videoFilters) == 0 {
+				videoFilters = []string{"null"}
+			}
+			graph := fmt.Sprintf("[0:v]%s[base];[base][1:v]overlay=%s[vout]", strings.Join(videoFilters, ","), watermarkOverlays[opts.watermarkPosition()])
+			audioMap := "0:a?"
+			if opts.AudioTrack != "" {
+				audioMap = "0:a:" + opts.AudioTrack
+			}
+			args = append(args, "-filter_complex", graph, "-map", "[vout]", "-map", audioMap)
+		} else if len(videoFilters) > 0 {
+			args = append(args, "-vf", strings.Join(videoFilters, ","))
 		}
-		if len(opts.ExtraArgs) > 0 {
-			args = append(args, opts.ExtraArgs...)
+		if len(audioFilters) > 0 {
+			args = append(args, "-af", strings.Join(audioFilters, ","))
 		}
+		args = append(args, extraArgs...)
+	}
+	threads := ""
+	if opts != nil && opts.Threads != "" {
+		threads = opts.Threads
+	} else if rm.threads > 0 {
+		threads = strconv.Itoa(rm.threads)
+	}
+	if threads != "" {
+		args = append(args, "-threads", threads)
 	}
-	args = append(args, "-f", "flv", outputURL)
+	// A larger queue rides out an endpoint that briefly stops reading instead
+	// of failing with "Too many packets buffered for output stream"
+	queueSize := ""
+	if opts != nil && opts.MaxMuxingQueueSize != "" {
+		queueSize = opts.MaxMuxingQueueSize
+	} else if rm.maxMuxingQueueSize > 0 {
+		queueSize = strconv.Itoa(rm.maxMuxingQueueSize)
+	}
+	if queueSize != "" {
+		args = append(args, "-max_muxing_queue_size", queueSize)
+	}
+	return append(args, "-f", "flv", outputURL)
+}
 
-	// Convert FFmpegOptions to map for storage
-	var optsMap map[string]string
-	if opts != nil {
-		optsMap = map[string]string{
-			"video_codec": opts.VideoCodec,
-			"audio_codec": opts.AudioCodec,
-			"resolution":  opts.Resolution,
-			"framerate":   opts.Framerate,
-			"bitrate":     opts.Bitrate,
-			"rotation":    opts.Rotation,
+// splitFilterArgs pulls -vf/-filter:v and -af/-filter:a filtergraphs out of
+// extra ffmpeg args so they can be combined with the generated filters
+func splitFilterArgs(extra []string) (videoFilters, audioFilters, rest []string) {
+	for i := 0; i < len(extra); i++ {
+		if i+1 < len(extra) {
+			switch extra[i] {
+			case "-vf", "-filter:v":
+				videoFilters = append(videoFilters, extra[i+1])
+				i++
+				continue
+			case "-af", "-filter:a":
+				audioFilters = append(audioFilters, extra[i+1])
+				i++
+				continue
+			}
 		}
+		rest = append(rest, extra[i])
 	}
+	return videoFilters, audioFilters, rest
+}
 
-	config := OutputRelayConfig{
-		OutputURL:      outputURL,
-		OutputName:     outputName,
-		InputURL:       inputURL,
-		LocalURL:       localRelayURL,
-		Timeout:        rm.outputTimeout,
-		PlatformPreset: preset,
-		FFmpegOptions:  optsMap,
-		FFmpegArgs:     args,
+// newCorrelationID returns a short random ID that tags the log lines of one
+// relay or recording, so its lifecycle can be grepped out of interleaved logs
+func newCorrelationID() string {
+	var b [4]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano()&0xffffffff, 16)
 	}
-	err = rm.OutputRelays.StartOutputRelay(config)
+	return hex.EncodeToString(b[:])
+}
+
+// defaultPorts are the well-known ports used when a URL has none
+var defaultPorts = map[string]string{
+	"rtsp":  "554",
+	"rtsps": "322",
+	"rtmp":  "1935",
+	"rtmps": "443",
+	"http":  "80",
+	"https": "443",
+}
+
+// checkRelayLoop rejects outputs that publish to our own RTSP server or to a
+// URL we already ingest, either of which would feed the stream back into itself
+func (rm *RelayManager) checkRelayLoop(inputURL, outputURL string) error {
+	u, err := url.Parse(outputURL)
+	if err != nil || u.Host == "" {
+		return nil // Local files and pipes cannot loop
+	}
+	scheme := strings.ToLower(u.Scheme)
+
+	serverHost, serverPort := DefaultRTSPInterface, DefaultRTSPPort
+	if rm.rtspServer != nil {
+		serverHost, serverPort = rm.rtspServer.config.Interface, rm.rtspServer.config.Port
+	}
+	port := u.Port()
+	if port == "" {
+		port = defaultPorts[scheme]
+	}
+	if (scheme == "rtsp" || scheme == "rtsps") && port == strconv.Itoa(serverPort) && isLocalHost(u.Hostname(), serverHost) {
+		return fmt.Errorf("%w: %s points at the local RTSP server", ErrRelayLoop, RedactURL(outputURL))
+	}
+
+	output := normalizeStreamURL(outputURL)
+	if output == normalizeStreamURL(inputURL) {
+		return fmt.Errorf("%w: output %s is the relay's own input", ErrRelayLoop, RedactURL(outputURL))
+	}
+	rm.InputRelays.mu.Lock()
+	defer rm.InputRelays.mu.Unlock()
+	for existing, relay := range rm.InputRelays.Relays {
+		if output == normalizeStreamURL(existing) {
+			return fmt.Errorf("%w: output %s is the input %s", ErrRelayLoop, RedactURL(outputURL), relay.InputName)
+		}
+	}
+	return nil
+}
+
+// isLocalHost reports whether host refers to this machine: a loopback or
+// unspecified address, the RTSP server's interface, or a local interface address
+func isLocalHost(host, serverHost string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") || host == strings.ToLower(serverHost) {
+		return true
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	if ip.IsLoopback() || ip.IsUnspecified() {
+		return true
+	}
+	addrs, err := net.InterfaceAddrs()
 	if err != nil {
-		rm.Logger.Error("Failed to start output relay: %v", err)
+		return false
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeStreamURL canonicalizes a stream URL for comparison: lower-case
+// scheme and host, loopback hosts as localhost, explicit default port, and no
+// credentials, query or trailing slash. Unparseable URLs are returned as is.
+func normalizeStreamURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
+	scheme := strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	if ip := net.ParseIP(host); host == "localhost" || (ip != nil && ip.IsLoopback()) {
+		host = "localhost"
+	}
+	port := u.Port()
+	if port == "" {
+		port = defaultPorts[scheme]
+	}
+	return scheme + "://" + net.JoinHostPort(host, port) + strings.TrimSuffix(u.Path, "/")
+}
+
+// CheckOutputReachable does a lightweight pre-flight check of an output: a TCP
+// dial to its host and port, or for file:// outputs a write test in the parent
+// directory. It is opt-in because some ingest servers reject bare TCP probes.
+// Schemes that cannot be probed over TCP (udp, srt) are not checked.
+func (rm *RelayManager) CheckOutputReachable(outputURL string) error {
+	u, err := url.Parse(outputURL)
+	if err != nil {
+		return fmt.Errorf("%w: invalid output URL: %v", ErrOutputUnreachable, err)
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if scheme == "file" {
+		dir := filepath.Dir(u.Path)
+		f, err := os.CreateTemp(dir, ".go-mls-probe-*")
+		if err != nil {
+			return fmt.Errorf("%w: cannot write to %s: %v", ErrOutputUnreachable, dir, err)
+		}
+		f.Close()
+		os.Remove(f.Name())
+		return nil
+	}
+	if scheme == "udp" || scheme == "srt" {
+		return nil
+	}
+	port := u.Port()
+	if port == "" {
+		port = defaultPorts[scheme]
+	}
+	if u.Hostname() == "" || port == "" {
+		return nil
+	}
+	addr := net.JoinHostPort(u.Hostname(), port)
+	conn, err := net.DialTimeout("tcp", addr, outputProbeTimeout)
+	if err != nil {
+		return fmt.Errorf("%w: cannot reach %s: %v", ErrOutputUnreachable, addr, err)
+	}
+	conn.Close()
+	return nil
+}
+
+// SetAssetsDir sets the directory watermark images are loaded from
+func (rm *RelayManager) SetAssetsDir(dir string) {
+	rm.assetsDir = dir
+}
+
+// ValidateFFmpegOptions checks opts, including that a watermark image exists
+// in the assets directory. nil options are valid.
+func (rm *RelayManager) ValidateFFmpegOptions(opts *FFmpegOptions) error {
+	if opts == nil {
+		return nil
+	}
+	if err := opts.Validate(); err != nil {
 		return err
 	}
-
-	rm.Logger.Info("Started relay: %s [%s] -> %s [%s]", inputName, inputURL, outputName, outputURL)
+	if opts.Watermark == "" {
+		return nil
+	}
+	if rm.assetsDir == "" {
+		return fmt.Errorf("watermarks are disabled: no assets directory configured")
+	}
+	// Security: only plain file names inside the assets directory
+	if strings.Contains(opts.Watermark, "..") || strings.Contains(opts.Watermark, "/") || strings.Contains(opts.Watermark, "\\") {
+		return fmt.Errorf("invalid watermark file name %q", opts.Watermark)
+	}
+	info, err := os.Stat(rm.watermarkPath(opts.Watermark))
+	if err != nil || !info.Mode().IsRegular() {
+		return fmt.Errorf("watermark image %q not found in assets directory", opts.Watermark)
+	}
 	return nil
 }
 
+func (rm *RelayManager) watermarkPath(name string) string {
+	return filepath.Join(rm.assetsDir, name)
+}
+
+// SetFFmpegCapabilities sets the detected ffmpeg capabilities used to gate
+// version-dependent ffmpeg options
+func (rm *RelayManager) SetFFmpegCapabilities(caps *FFmpegCapabilities) {
+	rm.ffmpegCaps = caps
+}
+
 // StopRelay stops a relay endpoint for an input/output URL
 func (rm *RelayManager) StopRelay(inputURL, outputURL, inputName, outputName string) error {
-	rm.Logger.Debug("StopRelay called: input=%s, output=%s, input_name=%s, output_name=%s", inputURL, outputURL, inputName, outputName)
+	rm.Logger.Debug("StopRelay called: input=%s, output=%s, input_name=%s, output_name=%s", RedactURL(inputURL), outputURL, inputName, outputName)
+	rm.setOutputPaused(outputURL, false)
 
 	// Stop the output relay first
 	rm.OutputRelays.StopOutputRelay(outputURL)
 
-	// Decrement the input relay reference count (RTSP cleanup is handled internally)
-	rm.InputRelays.StopInputRelay(inputURL)
+	// Release the output's input relay reference (RTSP cleanup is handled internally)
+	rm.InputRelays.StopInputRelay(inputURL, outputConsumer(outputURL))
 
 	return nil
 }
 
 // DeleteInput deletes an entire input relay and all its associated outputs
 func (rm *RelayManager) DeleteInput(inputURL, inputName string) error {
-	rm.Logger.Debug("DeleteInput called: input=%s, input_name=%s", inputURL, inputName)
+	rm.Logger.Debug("DeleteInput called: input=%s, input_name=%s", RedactURL(inputURL), inputName)
+
+	// Serialized with starts of the input, so a start cannot resurrect it halfway through
+	startMutex := rm.getStartMutex(inputURL)
+	startMutex.Lock()
+	defer startMutex.Unlock()
 
 	// First, find and delete all output relays associated with this input
 	rm.OutputRelays.mu.Lock()
@@ -257,153 +1149,293 @@ func (rm *RelayManager) DeleteInput(inputURL, inputName string) error {
 	for _, outputURL := range outputsToDelete {
 		err := rm.OutputRelays.DeleteOutput(outputURL)
 		if err != nil {
-			rm.Logger.Error("Failed to delete output relay %s: %v", outputURL, err)
+			rm.Logger.Error("Failed to delete output relay %s: %v", RedactURL(outputURL), err)
 		}
+		rm.setOutputPriority(outputURL, 0)
+		rm.setOutputEnabled(outputURL, true)
+		rm.setOutputPaused(outputURL, false)
 	}
 
 	// Delete the input relay
 	err := rm.InputRelays.DeleteInput(inputURL)
 	if err != nil {
-		rm.Logger.Error("Failed to delete input relay %s: %v", inputURL, err)
+		rm.Logger.Error("Failed to delete input relay %s: %v", RedactURL(inputURL), err)
 		return err
 	}
 
-	rm.Logger.Info("Deleted input relay and all associated outputs: %s [%s]", inputName, inputURL)
+	rm.Logger.Info("Deleted input relay and all associated outputs: %s [%s]", inputName, RedactURL(inputURL))
 	return nil
 }
 
 // DeleteOutput deletes a single output relay
 func (rm *RelayManager) DeleteOutput(inputURL, outputURL, inputName, outputName string) error {
-	rm.Logger.Debug("DeleteOutput called: input=%s, output=%s, input_name=%s, output_name=%s", inputURL, outputURL, inputName, outputName)
+	rm.Logger.Debug("DeleteOutput called: input=%s, output=%s, input_name=%s, output_name=%s", RedactURL(inputURL), outputURL, inputName, outputName)
+
+	startMutex := rm.getStartMutex(inputURL)
+	startMutex.Lock()
+	defer startMutex.Unlock()
 
 	// Delete the output relay (this will also clean up input relay refcount via callback)
 	err := rm.OutputRelays.DeleteOutput(outputURL)
 	if err != nil {
-		rm.Logger.Error("Failed to delete output relay %s: %v", outputURL, err)
+		rm.Logger.Error("Failed to delete output relay %s: %v", RedactURL(outputURL), err)
 		return err
 	}
+	rm.setOutputPriority(outputURL, 0)
+	rm.setOutputEnabled(outputURL, true)
+	rm.setOutputPaused(outputURL, false)
 
-	rm.Logger.Info("Deleted output relay: %s [%s] -> %s [%s]", inputName, inputURL, outputName, outputURL)
+	rm.Logger.Info("Deleted output relay: %s [%s] -> %s [%s]", inputName, RedactURL(inputURL), outputName, outputURL)
 	return nil
 }
 
+// configExportVersion is the schema version written by ExportConfig. Version 1
+// files are a bare array with the input fields inlined next to its outputs.
+const configExportVersion = 2
+
+// exportFile is the versioned export schema. Each input carries its whole
+// InputConfig, so per-input settings round-trip without schema changes.
+type exportFile struct {
+	Version int           `json:"version"`
+	Inputs  []exportInput `json:"inputs"`
+}
+
+type exportInput struct {
+	Input   InputConfig    `json:"input"`
+	Outputs []exportOutput `json:"outputs"`
+}
+
+type exportOutput struct {
+	OutputURL      string            `json:"output_url"`
+	OutputName     string            `json:"output_name"`
+	PlatformPreset string            `json:"platform_preset,omitempty"`
+	FFmpegOptions  map[string]string `json:"ffmpeg_options,omitempty"`
+	Priority       int               `json:"priority,omitempty"`
+	Enabled        *bool             `json:"enabled,omitempty"` // nil, as in older exports, is enabled
+}
+
+// enabled reports whether the output is to be started on import
+func (o exportOutput) enabled() bool {
+	return o.Enabled == nil || *o.Enabled
+}
+
+// legacyExportInput is one entry of a version 1 export
+type legacyExportInput struct {
+	InputURL        string         `json:"input_url"`
+	InputName       string         `json:"input_name"`
+	AnalyzeDuration string         `json:"analyzeduration,omitempty"`
+	ProbeSize       string         `json:"probesize,omitempty"`
+	Outputs         []exportOutput `json:"outputs"`
+}
+
 // ExportConfig saves the current relay configurations to a file (now includes names and presets)
 func (rm *RelayManager) ExportConfig(filename string) error {
 	rm.Logger.Debug("ExportConfig called: filename=%s", filename)
-	type exportConfig struct {
-		InputURL  string `json:"input_url"`
-		InputName string `json:"input_name"`
-		Outputs   []struct {
-			OutputURL      string            `json:"output_url"`
-			OutputName     string            `json:"output_name"`
-			PlatformPreset string            `json:"platform_preset,omitempty"`
-			FFmpegOptions  map[string]string `json:"ffmpeg_options,omitempty"`
-		} `json:"outputs"`
-	}
-	var configs []exportConfig
+	export := exportFile{Version: configExportVersion, Inputs: []exportInput{}}
 	rm.InputRelays.mu.Lock()
 	for _, in := range rm.InputRelays.Relays {
 		in.mu.Lock()
-		var outputs []struct {
-			OutputURL      string            `json:"output_url"`
-			OutputName     string            `json:"output_name"`
-			PlatformPreset string            `json:"platform_preset,omitempty"`
-			FFmpegOptions  map[string]string `json:"ffmpeg_options,omitempty"`
-		}
+		// Preview and recording inputs without outputs are exported too
+		outputs := []exportOutput{}
 		rm.OutputRelays.mu.Lock()
 		for _, out := range rm.OutputRelays.Relays {
 			if out.InputURL == in.InputURL {
-				outputs = append(outputs, struct {
-					OutputURL      string            `json:"output_url"`
-					OutputName     string            `json:"output_name"`
-					PlatformPreset string            `json:"platform_preset,omitempty"`
-					FFmpegOptions  map[string]string `json:"ffmpeg_options,omitempty"`
-				}{
+				enabled := rm.outputEnabled(out.OutputURL)
+				outputs = append(outputs, exportOutput{
 					OutputURL:      out.OutputURL,
 					OutputName:     out.OutputName,
 					PlatformPreset: out.PlatformPreset,
 					FFmpegOptions:  out.FFmpegOptions,
+					Priority:       rm.outputPriority(out.OutputURL),
+					Enabled:        &enabled,
 				})
 			}
 		}
 		rm.OutputRelays.mu.Unlock()
-		configs = append(configs, exportConfig{
-			InputURL:  in.InputURL,
-			InputName: in.InputName,
-			Outputs:   outputs,
-		})
+		inputCfg, exists := rm.GetInputConfig(in.InputName)
+		if !exists || inputCfg.InputURL != in.InputURL {
+			inputCfg = InputConfig{InputURL: in.InputURL, InputName: in.InputName}
+		}
+		export.Inputs = append(export.Inputs, exportInput{Input: inputCfg, Outputs: outputs})
 		in.mu.Unlock()
 	}
 	rm.InputRelays.mu.Unlock()
-	data, err := json.MarshalIndent(configs, "", "  ")
+	data, err := json.MarshalIndent(export, "", "  ")
 	if err != nil {
 		return err
 	}
 	return os.WriteFile(filename, data, 0644)
 }
 
-// ImportConfig loads relay configurations from a file (now supports names)
+// parseExport decodes an export of any supported version into the current schema
+func parseExport(data []byte) ([]exportInput, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var legacy []legacyExportInput
+		if err := json.Unmarshal(trimmed, &legacy); err != nil {
+			return nil, err
+		}
+		inputs := make([]exportInput, 0, len(legacy))
+		for _, l := range legacy {
+			inputs = append(inputs, exportInput{
+				Input: InputConfig{
+					InputURL:        l.InputURL,
+					InputName:       l.InputName,
+					AnalyzeDuration: l.AnalyzeDuration,
+					ProbeSize:       l.ProbeSize,
+				},
+				Outputs: l.Outputs,
+			})
+		}
+		return inputs, nil
+	}
+
+	var export exportFile
+	if err := json.Unmarshal(trimmed, &export); err != nil {
+		return nil, err
+	}
+	if export.Version < 2 || export.Version > configExportVersion {
+		return nil, fmt.Errorf("unsupported config version %d (this build reads up to %d)", export.Version, configExportVersion)
+	}
+	return export.Inputs, nil
+}
+
+// ImportConfig loads relay configurations from a file. Both the current
+// versioned schema and version 1 bare arrays are accepted.
 func (rm *RelayManager) ImportConfig(filename string) error {
 	rm.Logger.Debug("ImportConfig called: filename=%s", filename)
-	type importConfig struct {
-		InputURL  string `json:"input_url"`
-		InputName string `json:"input_name"`
-		Outputs   []struct {
-			OutputURL      string            `json:"output_url"`
-			OutputName     string            `json:"output_name"`
-			PlatformPreset string            `json:"platform_preset,omitempty"`
-			FFmpegOptions  map[string]string `json:"ffmpeg_options,omitempty"`
-		} `json:"outputs"`
+	configs, err := rm.readImport(filename)
+	if err != nil {
+		return err
 	}
+	return rm.importRelays(configs, filename, nil)
+}
+
+// readImport reads and decodes an import file
+func (rm *RelayManager) readImport(filename string) ([]exportInput, error) {
 	data, err := os.ReadFile(filename)
 	if err != nil {
 		rm.Logger.Error("Failed to read file %s: %v", filename, err)
-		return err
+		return nil, err
 	}
-	var configs []importConfig
-	err = json.Unmarshal(data, &configs)
+	configs, err := parseExport(data)
 	if err != nil {
 		rm.Logger.Error("Failed to unmarshal config: %v", err)
-		return err
+		return nil, err
 	}
+	return configs, nil
+}
 
-	// Start all relays in parallel for faster startup
-	var wg sync.WaitGroup
-	errorChan := make(chan error, 100) // Buffer for potential errors
+// registerImportedInput registers an input of an import with its settings,
+// dropping the invalid ones
+func (rm *RelayManager) registerImportedInput(in InputConfig) error {
+	if err := rm.RegisterInputConfig(in.InputName, in.InputURL); err != nil {
+		return err
+	}
+	if err := validateAudioTrack(in.AudioTrack, true); err != nil {
+		rm.Logger.Error("Ignoring audio track of input %s: %v", in.InputName, err)
+		in.AudioTrack = ""
+	}
+	if err := validateAudioTrack(in.HLSAudioTrack, false); err != nil {
+		rm.Logger.Error("Ignoring HLS audio track of input %s: %v", in.InputName, err)
+		in.HLSAudioTrack = ""
+	}
+	if err := validateBuffering(in.Buffering); err != nil {
+		rm.Logger.Error("Ignoring buffering of input %s: %v", in.InputName, err)
+		in.Buffering = ""
+	}
+	if err := validateInputProfile(in.InputProfile); err != nil {
+		rm.Logger.Error("Ignoring input profile of input %s: %v", in.InputName, err)
+		in.InputProfile = ""
+	}
+	if err := validateGrowingFile(in.InputURL, in.GrowingFile); err != nil {
+		rm.Logger.Error("Ignoring growing file mode of input %s: %v", in.InputName, err)
+		in.GrowingFile = false
+	}
+	if err := validateInputHeaders(in.InputURL, in.HTTPHeaders); err != nil {
+		rm.Logger.Error("Ignoring HTTP headers of input %s: %v", in.InputName, err)
+		in.HTTPHeaders = nil
+	}
+	rm.setInputConfig(in)
+	return nil
+}
 
+// importRelays registers the inputs of an import and starts its relays,
+// reporting each start to progress unless it is nil
+func (rm *RelayManager) importRelays(configs []exportInput, filename string, progress *ImportJob) error {
 	// Register all input configurations first
-	for _, relayCfg := range configs {
-		rm.RegisterInputConfig(relayCfg.InputName, relayCfg.InputURL)
+	type importJob struct {
+		inputURL, inputName, outputURL, outputName, preset string
+		ffmpegOpts                                         map[string]string
+		priority                                           int
 	}
-
+	var jobs []importJob
 	for _, relayCfg := range configs {
-		for _, out := range relayCfg.Outputs {
-			wg.Add(1)
-			go func(inputURL, inputName, outputURL, outputName, preset string, ffmpegOpts map[string]string) {
-				defer wg.Done()
-
-				var opts *FFmpegOptions
-				if ffmpegOpts != nil {
-					opts = &FFmpegOptions{
-						VideoCodec: ffmpegOpts["video_codec"],
-						AudioCodec: ffmpegOpts["audio_codec"],
-						Resolution: ffmpegOpts["resolution"],
-						Framerate:  ffmpegOpts["framerate"],
-						Bitrate:    ffmpegOpts["bitrate"],
-						Rotation:   ffmpegOpts["rotation"],
-					}
+		in := relayCfg.Input
+		if err := rm.registerImportedInput(in); err != nil {
+			// Its relays fail with the same error when started below
+			rm.Logger.Error("Failed to register input %s: %v", in.InputName, err)
+		} else {
+			enabledOutputs := 0
+			for _, out := range relayCfg.Outputs {
+				if out.enabled() {
+					enabledOutputs++
 				}
+			}
+			if enabledOutputs == 0 {
+				// Listed like any other input; started on demand by previews and recordings
+				rm.InputRelays.addIdleInput(in.InputName, in.InputURL, rm.inputTimeout)
+			}
+		}
+		for _, out := range relayCfg.Outputs {
+			rm.setOutputPriority(out.OutputURL, out.Priority)
+			rm.setOutputEnabled(out.OutputURL, out.enabled())
+			if !out.enabled() {
+				// Kept listed with its settings so it can be enabled later
+				rm.OutputRelays.addStoppedOutput(OutputRelayConfig{
+					OutputURL:      out.OutputURL,
+					OutputName:     out.OutputName,
+					InputURL:       in.InputURL,
+					PlatformPreset: out.PlatformPreset,
+					FFmpegOptions:  out.FFmpegOptions,
+				})
+				rm.Logger.Info("Not starting disabled output %s -> %s", in.InputName, out.OutputName)
+				continue
+			}
+			jobs = append(jobs, importJob{in.InputURL, in.InputName, out.OutputURL, out.OutputName, out.PlatformPreset, out.FFmpegOptions, out.Priority})
+		}
+	}
 
-				err := rm.StartRelayWithOptions(inputURL, outputURL, inputName, outputName, opts, preset)
-				if err != nil {
-					rm.Logger.Error("Failed to start relay %s -> %s: %v", inputName, outputName, err)
-					select {
-					case errorChan <- err:
-					default: // Don't block if channel is full
-					}
-				}
-			}(relayCfg.InputURL, relayCfg.InputName, out.OutputURL, out.OutputName, out.PlatformPreset, out.FFmpegOptions)
+	// Higher priorities start first; file order is kept within a priority
+	sort.SliceStable(jobs, func(i, j int) bool { return jobs[i].priority > jobs[j].priority })
+	progress.begin(len(jobs))
+
+	errorChan := make(chan error, len(jobs))
+	sem := make(chan struct{}, rm.importConcurrency)
+	var wg sync.WaitGroup
+	for i, job := range jobs {
+		// A priority level is fully started before lower ones begin
+		if i > 0 && job.priority != jobs[i-1].priority {
+			wg.Wait()
 		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(job importJob) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			var opts *FFmpegOptions
+			if job.ffmpegOpts != nil {
+				opts = FFmpegOptionsFromMap(job.ffmpegOpts)
+			}
+
+			_, err := rm.StartRelayWithOptions(job.inputURL, job.outputURL, job.inputName, job.outputName, opts, job.preset)
+			progress.record(job.inputName, job.outputName, err)
+			if err != nil {
+				rm.Logger.Error("Failed to start relay %s -> %s: %v", job.inputName, job.outputName, err)
+				errorChan <- err
+			}
+		}(job)
 	}
 
 	// Wait for all relays to start
@@ -433,58 +1465,116 @@ func (rm *RelayManager) GetEndpointConfig(inputURL, outputURL string) (string, *
 	out, exists := rm.OutputRelays.Relays[outputURL]
 	rm.OutputRelays.mu.Unlock()
 	if !exists || out.InputURL != inputURL {
-		return "", nil, fmt.Errorf("no output relay for input %s and output %s", inputURL, outputURL)
+		return "", nil, fmt.Errorf("no output relay for input %s and output %s", RedactURL(inputURL), RedactURL(outputURL))
 	}
 
 	var opts *FFmpegOptions
 	if out.FFmpegOptions != nil {
-		opts = &FFmpegOptions{
-			VideoCodec: out.FFmpegOptions["video_codec"],
-			AudioCodec: out.FFmpegOptions["audio_codec"],
-			Resolution: out.FFmpegOptions["resolution"],
-			Framerate:  out.FFmpegOptions["framerate"],
-			Bitrate:    out.FFmpegOptions["bitrate"],
-			Rotation:   out.FFmpegOptions["rotation"],
-		}
+		opts = FFmpegOptionsFromMap(out.FFmpegOptions)
 	}
 
 	return out.PlatformPreset, opts, nil
 }
 
+// SubscribeOutputLog returns the last backlog lines of the ffmpeg output of a
+// running output relay and a channel of the lines that follow, see
+// FFmpegProcess.SubscribeOutput. The output and input URLs, which ffmpeg
+// echoes, are redacted. The channel closes when this ffmpeg exits, also on a restart.
+func (rm *RelayManager) SubscribeOutputLog(outputURL string, backlog int) ([]string, <-chan string, func(), error) {
+	rm.OutputRelays.mu.Lock()
+	out, exists := rm.OutputRelays.Relays[outputURL]
+	rm.OutputRelays.mu.Unlock()
+	if !exists {
+		return nil, nil, nil, fmt.Errorf("no output relay for %s", RedactURL(outputURL))
+	}
+	out.mu.Lock()
+	proc := out.Proc
+	// A direct output reads, and echoes, the source URL itself
+	inputURL := out.InputURL
+	out.mu.Unlock()
+	if proc == nil {
+		return nil, nil, nil, fmt.Errorf("output relay %s is not running", out.OutputName)
+	}
+
+	lines, raw, cancel := proc.SubscribeOutput(backlog)
+	for i, line := range lines {
+		lines[i] = redactOutput(line, outputURL, inputURL)
+	}
+	redacted := make(chan string, subscriberBuffer)
+	go func() {
+		defer close(redacted)
+		for line := range raw {
+			select {
+			case redacted <- redactOutput(line, outputURL, inputURL):
+			default:
+			}
+		}
+	}()
+	return lines, redacted, cancel, nil
+}
+
 // RelayStatusV2 includes both input and output relay statuses for UI
 // (for responsive, accessible frontend columns)
 type RelayStatusV2 struct {
-	Input   InputRelayStatusV2    `json:"input"`
-	Outputs []OutputRelayStatusV2 `json:"outputs"`
+	Input       InputRelayStatusV2    `json:"input"`
+	Outputs     []OutputRelayStatusV2 `json:"outputs"`
+	OutputCount int                   `json:"output_count"`
+	MaxOutputs  int                   `json:"max_outputs,omitempty"` // 0 when unlimited
 }
 
 type InputRelayStatusV2 struct {
-	InputURL  string  `json:"input_url"`
-	InputName string  `json:"input_name"`
-	LocalURL  string  `json:"local_url"`
-	Status    string  `json:"status"`
-	LastError string  `json:"last_error,omitempty"`
-	CPU       float64 `json:"cpu"`
-	Mem       uint64  `json:"mem"`
-	Speed     float64 `json:"speed"`
+	ID        string   `json:"id"`
+	InputURL  string   `json:"input_url"`
+	InputName string   `json:"input_name"`
+	LocalURL  string   `json:"local_url"`
+	Status    string   `json:"status"`
+	LastError string   `json:"last_error,omitempty"`
+	Direct    bool     `json:"direct,omitempty"`
+	Consumers []string `json:"consumers"` // labels of the references keeping the input alive
+	Category  string   `json:"category"`  // InputCategoryActive or InputCategoryHistorical
+	// When the last consumer let go of a historical input, see PurgeInputHistory
+	HistoricalSince *time.Time `json:"historical_since,omitempty"`
+	Source          string     `json:"active_source,omitempty"` // "primary" or "backup", only for inputs with a backup
+	Stage           string     `json:"start_stage,omitempty"`   // see RelayStage, empty when neither starting nor running
+	CPU             float64    `json:"cpu"`
+	Mem             uint64     `json:"mem"`
+	Speed           float64    `json:"speed"`
+	Bitrate         float64    `json:"bitrate"` // kbps received from the source
+	RestartCount    int        `json:"restart_count"`
+	ErrorCount      int        `json:"error_count"`
 }
 
 type OutputRelayStatusV2 struct {
+	ID         string  `json:"id"`
 	OutputURL  string  `json:"output_url"`
 	OutputName string  `json:"output_name"`
 	InputURL   string  `json:"input_url"`
 	LocalURL   string  `json:"local_url"`
 	Status     string  `json:"status"`
 	LastError  string  `json:"last_error,omitempty"`
+	Degraded   bool    `json:"degraded,omitempty"`
+	Direct     bool    `json:"direct,omitempty"`
+	Disabled   bool    `json:"disabled,omitempty"` // see DisableOutput
 	CPU        float64 `json:"cpu"`
 	Mem        uint64  `json:"mem"`
 	Bitrate    float64 `json:"bitrate"`
+	// Since the output was added; a climbing restart count marks a flapping endpoint
+	RestartCount int `json:"restart_count"`
+	ErrorCount   int `json:"error_count"`
 }
 
 // ServerStatus represents server resource usage
 type ServerStatus struct {
 	CPU float64 `json:"cpu"`
 	Mem uint64  `json:"mem"`
+
+	// ProcessMetrics is false where CPU and memory cannot be measured (no
+	// /proc), so all cpu and mem values are 0 rather than real readings
+	ProcessMetrics bool `json:"process_metrics"`
+
+	// Totals over all relays for capacity planning, in kbps
+	InputBitrate  float64 `json:"input_bitrate"`
+	OutputBitrate float64 `json:"output_bitrate"`
 }
 
 // StatusV2Response is the new status API response with server and relay stats
@@ -497,14 +1587,43 @@ type StatusV2Response struct {
 // StatusV2 returns a struct with server stats and relay statuses for UI
 func (rm *RelayManager) StatusV2() StatusV2Response {
 	srv, _ := process.GetSelfUsage()
-	serverStatus := ServerStatus{}
+	serverStatus := ServerStatus{ProcessMetrics: process.Available()}
 	if srv != nil {
-		serverStatus = ServerStatus{CPU: srv.CPU, Mem: srv.Mem}
+		serverStatus.CPU, serverStatus.Mem = srv.CPU, srv.Mem
+	}
+	relays := rm.relayStatuses(nil)
+	for _, relay := range relays {
+		serverStatus.InputBitrate += relay.Input.Bitrate
+		for _, out := range relay.Outputs {
+			serverStatus.OutputBitrate += out.Bitrate
+		}
+	}
+	return StatusV2Response{
+		Server: serverStatus,
+		Relays: relays,
+	}
+}
+
+// StatusForInputs returns the statuses of the named inputs and their outputs,
+// in one pass over the relays. Unknown names are skipped.
+func (rm *RelayManager) StatusForInputs(names []string) []RelayStatusV2 {
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		wanted[name] = true
 	}
+	return rm.relayStatuses(wanted)
+}
+
+// relayStatuses gathers input relays with their outputs, only those named in
+// wanted unless it is nil
+func (rm *RelayManager) relayStatuses(wanted map[string]bool) []RelayStatusV2 {
 	statuses := []RelayStatusV2{}
 	// Gather input relays
 	rm.InputRelays.mu.Lock()
 	for _, in := range rm.InputRelays.Relays {
+		if wanted != nil && !wanted[in.InputName] {
+			continue
+		}
 		in.mu.Lock()
 		cpu, mem := 0.0, uint64(0)
 		// Safely access process info to avoid data race
@@ -516,18 +1635,37 @@ func (rm *RelayManager) StatusV2() StatusV2Response {
 			}
 		}
 		inputStatus := InputRelayStatusV2{
+			ID:        in.ID,
 			InputURL:  in.InputURL,
 			InputName: in.InputName,
 			LocalURL:  in.LocalURL,
 			Status:    inputRelayStatusString(in.Status),
 			LastError: in.LastError,
+			Direct:    in.Direct,
+			Consumers: in.consumerList(),
 			CPU:       cpu,
 			Mem:       mem,
+
+			RestartCount: in.RestartCount,
+			ErrorCount:   in.ErrorCount,
+		}
+		var since time.Time
+		if inputStatus.Category, since = in.categoryLocked(); !since.IsZero() {
+			inputStatus.HistoricalSince = &since
+		}
+		inputStatus.Stage = string(rm.startStage(in.InputURL, in.Status == InputRunning))
+		if cfg, ok := rm.GetInputConfig(in.InputName); ok && cfg.BackupURL != "" {
+			inputStatus.Source = "primary"
+			if in.onBackup {
+				inputStatus.Source = "backup"
+			}
 		}
 		if in.Proc != nil {
 			speed, _ := in.Proc.GetSpeed()
 			inputStatus.Speed = speed
-			rm.Logger.Debug("StatusV2: Input relay %s speed: %.2fx", in.InputURL, speed)
+			bitrate, _ := in.Proc.GetBitrate()
+			inputStatus.Bitrate = bitrate
+			rm.Logger.Debug("StatusV2: Input relay %s speed: %.2fx, bitrate: %.2f kbps", RedactURL(in.InputURL), speed, bitrate)
 		}
 		// Gather outputs for this input
 		outputs := []OutputRelayStatusV2{}
@@ -545,14 +1683,21 @@ func (rm *RelayManager) StatusV2() StatusV2Response {
 					}
 				}
 				outputStatus := OutputRelayStatusV2{
+					ID:         out.ID,
 					OutputURL:  out.OutputURL,
 					OutputName: out.OutputName,
 					InputURL:   out.InputURL,
 					LocalURL:   out.LocalURL,
-					Status:     outputRelayStatusString(out.Status),
+					Status:     rm.outputStatusString(out),
 					LastError:  out.LastError,
+					Degraded:   out.Degraded,
+					Direct:     out.Direct,
+					Disabled:   !rm.outputEnabled(out.OutputURL),
 					CPU:        cpuO,
 					Mem:        memO,
+
+					RestartCount: out.RestartCount,
+					ErrorCount:   out.ErrorCount,
 				}
 				if out.Proc != nil {
 					bitrate, _ := out.Proc.GetBitrate()
@@ -565,16 +1710,75 @@ func (rm *RelayManager) StatusV2() StatusV2Response {
 		}
 		rm.OutputRelays.mu.Unlock()
 		statuses = append(statuses, RelayStatusV2{
-			Input:   inputStatus,
-			Outputs: outputs,
+			Input:       inputStatus,
+			Outputs:     outputs,
+			OutputCount: len(outputs),
+			MaxOutputs:  rm.maxOutputsPerInput,
 		})
 		in.mu.Unlock()
 	}
 	rm.InputRelays.mu.Unlock()
-	return StatusV2Response{
-		Server: serverStatus,
-		Relays: statuses,
+	return statuses
+}
+
+// RelayHealth condenses StatusV2 for alerting: relay counts by status and the
+// relays that need attention. Healthy is false when any relay is degraded.
+type RelayHealth struct {
+	Healthy  bool            `json:"healthy"`
+	Inputs   map[string]int  `json:"inputs"`  // status -> count
+	Outputs  map[string]int  `json:"outputs"` // status -> count
+	Degraded []DegradedRelay `json:"degraded"`
+}
+
+// DegradedRelay is an input or output relay that is in Error or, for outputs,
+// flagged as too slow to keep up with real time
+type DegradedRelay struct {
+	Kind      string `json:"kind"` // "input" or "output"
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	URL       string `json:"url"`
+	Status    string `json:"status"`
+	Stalled   bool   `json:"stalled,omitempty"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// Health summarizes the relays reported by StatusV2
+func (rm *RelayManager) Health() RelayHealth {
+	health := RelayHealth{
+		Inputs:   map[string]int{},
+		Outputs:  map[string]int{},
+		Degraded: []DegradedRelay{},
+	}
+	for _, relay := range rm.StatusV2().Relays {
+		in := relay.Input
+		health.Inputs[in.Status]++
+		if in.Status == "Error" {
+			health.Degraded = append(health.Degraded, DegradedRelay{
+				Kind:      "input",
+				ID:        in.ID,
+				Name:      in.InputName,
+				URL:       RedactURL(in.InputURL),
+				Status:    in.Status,
+				LastError: in.LastError,
+			})
+		}
+		for _, out := range relay.Outputs {
+			health.Outputs[out.Status]++
+			if out.Status == "Error" || out.Degraded {
+				health.Degraded = append(health.Degraded, DegradedRelay{
+					Kind:      "output",
+					ID:        out.ID,
+					Name:      out.OutputName,
+					URL:       RedactURL(out.OutputURL),
+					Status:    out.Status,
+					Stalled:   out.Degraded,
+					LastError: out.LastError,
+				})
+			}
+		}
 	}
+	health.Healthy = len(health.Degraded) == 0
+	return health
 }
 
 func inputRelayStatusString(s InputRelayStatus) string {
@@ -585,6 +1789,10 @@ func inputRelayStatusString(s InputRelayStatus) string {
 		return "Running"
 	case InputError:
 		return "Error"
+	case InputCompleted:
+		return "Completed"
+	case InputIdle:
+		return "Idle"
 	default:
 		return "Stopped"
 	}
@@ -598,6 +1806,10 @@ func outputRelayStatusString(s OutputRelayStatus) string {
 		return "Running"
 	case OutputError:
 		return "Error"
+	case OutputCompleted:
+		return "Completed"
+	case OutputReconnecting:
+		return "Reconnecting"
 	default:
 		return "Stopped"
 	}
@@ -606,22 +1818,23 @@ func outputRelayStatusString(s OutputRelayStatus) string {
 // StopAllRelays stops all active input and output relays gracefully
 func (rm *RelayManager) StopAllRelays() {
 	rm.Logger.Info("RelayManager: Stopping all active relays...")
+	rm.stopHistoryPurge()
 
 	// Stop all output relays first by iterating directly over the map
 	// This is more efficient than using StatusV2() during shutdown
 	rm.OutputRelays.mu.Lock()
-	var outputsToStop []struct {
+	type stopJob struct {
 		inputURL, outputURL, outputName string
+		priority                        int
 	}
+	var outputsToStop []stopJob
 
 	// Collect outputs to stop while holding the lock
 	for _, output := range rm.OutputRelays.Relays {
 		output.mu.Lock()
-		// Only stop relays that are actually running or starting
-		if output.Status == OutputRunning || output.Status == OutputStarting {
-			outputsToStop = append(outputsToStop, struct {
-				inputURL, outputURL, outputName string
-			}{
+		// Only stop relays that are running, starting or waiting to reconnect
+		if output.Status.active() {
+			outputsToStop = append(outputsToStop, stopJob{
 				inputURL:   output.InputURL,
 				outputURL:  output.OutputURL,
 				outputName: output.OutputName,
@@ -634,52 +1847,73 @@ func (rm *RelayManager) StopAllRelays() {
 	}
 	rm.OutputRelays.mu.Unlock()
 
-	// Now stop the collected outputs without holding the main lock
-	for _, toStop := range outputsToStop {
-		// Look up input name for logging
-		var inputName string
-		rm.InputRelays.mu.Lock()
-		if inputRelay, exists := rm.InputRelays.Relays[toStop.inputURL]; exists {
-			inputName = inputRelay.InputName
-		} else {
-			inputName = toStop.inputURL // fallback to URL if name not found
+	// Reverse of the import order: lower priorities stop first, so the most
+	// important outputs stay live longest; outputs are sorted by name within a priority
+	for i := range outputsToStop {
+		outputsToStop[i].priority = rm.outputPriority(outputsToStop[i].outputURL)
+	}
+	sort.Slice(outputsToStop, func(i, j int) bool {
+		if outputsToStop[i].priority != outputsToStop[j].priority {
+			return outputsToStop[i].priority < outputsToStop[j].priority
 		}
-		rm.InputRelays.mu.Unlock()
+		return outputsToStop[i].outputName < outputsToStop[j].outputName
+	})
 
-		rm.Logger.Info("RelayManager: Stopping output relay %s -> %s", inputName, toStop.outputName)
-		if err := rm.StopRelay(toStop.inputURL, toStop.outputURL, inputName, toStop.outputName); err != nil {
-			rm.Logger.Error("RelayManager: Failed to stop output relay %s -> %s: %v", inputName, toStop.outputName, err)
+	// Now stop the collected outputs without holding the main lock, several
+	// at once since each may wait out the stop timeout
+	sem := make(chan struct{}, rm.shutdownConcurrency)
+	var wg sync.WaitGroup
+	for i, toStop := range outputsToStop {
+		// A priority level is fully stopped before higher ones begin
+		if i > 0 && toStop.priority != outputsToStop[i-1].priority {
+			wg.Wait()
 		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(toStop stopJob) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			// Look up input name for logging
+			var inputName string
+			rm.InputRelays.mu.Lock()
+			if inputRelay, exists := rm.InputRelays.Relays[toStop.inputURL]; exists {
+				inputName = inputRelay.InputName
+			} else {
+				inputName = toStop.inputURL // fallback to URL if name not found
+			}
+			rm.InputRelays.mu.Unlock()
+
+			rm.Logger.Info("RelayManager: Stopping output relay %s -> %s", inputName, toStop.outputName)
+			rm.OutputRelays.stopOutputRelay(toStop.outputURL, rm.shutdownStopTimeout)
+			rm.InputRelays.StopInputRelay(toStop.inputURL, outputConsumer(toStop.outputURL))
+		}(toStop)
 	}
+	wg.Wait()
 
-	// Verify that all input relays have been stopped due to reference counting
-	// If any are still active, it indicates a bug in the reference counting logic
+	// Verify that all input relays have been stopped due to reference counting.
+	// Recordings and HLS are shut down before the relays (see the lifecycle
+	// package), so an input still active here is a consumer that leaked its
+	// reference. It is reported as a bug, then force stopped as a safety net:
+	// ffmpeg runs in its own process group and would outlive the server.
 	rm.InputRelays.mu.Lock()
-	activeInputs := 0
 	var inputsToForceStop []string
 	for inputURL, inputRelay := range rm.InputRelays.Relays {
 		inputRelay.mu.Lock()
 		if inputRelay.Status == InputRunning || inputRelay.Status == InputStarting {
-			activeInputs++
-			rm.Logger.Error("RelayManager: Input relay %s [%s] is still active after stopping all outputs (refcount: %d, status: %s)",
-				inputRelay.InputName, inputURL, inputRelay.RefCount, inputRelayStatusString(inputRelay.Status))
+			rm.Logger.Error("RelayManager: BUG: input relay %s [%s] is still active after stopping all outputs (consumers: %v, status: %s)",
+				inputRelay.InputName, RedactURL(inputURL), inputRelay.consumerList(), inputRelayStatusString(inputRelay.Status))
 			inputsToForceStop = append(inputsToForceStop, inputURL)
 		}
 		inputRelay.mu.Unlock()
 	}
 	rm.InputRelays.mu.Unlock()
 
-	// Force stop any remaining active input relays
 	if len(inputsToForceStop) > 0 {
-		rm.Logger.Warn("RelayManager: Force stopping %d remaining input relays due to refcount issues", len(inputsToForceStop))
+		rm.Logger.Error("RelayManager: BUG: %d input relays still have consumers after stopping all outputs, force stopping them", len(inputsToForceStop))
 		for _, inputURL := range inputsToForceStop {
-			rm.Logger.Warn("RelayManager: Force stopping remaining input relay %s", inputURL)
+			rm.Logger.Warn("RelayManager: Force stopping remaining input relay %s", RedactURL(inputURL))
 			rm.InputRelays.ForceStopInputRelay(inputURL)
 		}
-	}
-
-	if activeInputs > 0 {
-		rm.Logger.Error("RelayManager: Found %d input relays still active after stopping all outputs - forced shutdown applied", activeInputs)
 	} else {
 		rm.Logger.Info("RelayManager: All input relays properly stopped via reference counting")
 	}
@@ -699,7 +1933,8 @@ func (rm *RelayManager) GetInputTimeout() time.Duration {
 	return rm.inputTimeout
 }
 
-// getStartMutex returns a mutex for the given input URL to serialize concurrent starts
+// getStartMutex returns a mutex for the given input URL to serialize concurrent
+// starts and deletes
 func (rm *RelayManager) getStartMutex(inputURL string) *sync.Mutex {
 	rm.startMutexesMu.Lock()
 	defer rm.startMutexesMu.Unlock()
@@ -714,16 +1949,241 @@ func (rm *RelayManager) getStartMutex(inputURL string) *sync.Mutex {
 	return mutex
 }
 
-// RegisterInputConfig stores an input configuration for later HLS access
-func (rm *RelayManager) RegisterInputConfig(inputName, inputURL string) {
+// checkInputName returns the URL inputName is registered for, and
+// ErrInputNameConflict when that is another input still in use
+func (rm *RelayManager) checkInputName(inputName, inputURL string) (string, error) {
+	rm.configMu.RLock()
+	previousURL := ""
+	if existing, exists := rm.inputConfigs[inputName]; exists {
+		previousURL = existing.InputURL
+	}
+	rm.configMu.RUnlock()
+
+	// Checked without configMu: the relay managers call back into it while locked
+	if previousURL != "" && previousURL != inputURL && rm.inputURLInUse(previousURL) {
+		return previousURL, fmt.Errorf("%w: %q already refers to %s", ErrInputNameConflict, inputName, RedactURL(previousURL))
+	}
+	return previousURL, nil
+}
+
+// RegisterInputConfig stores an input configuration for later HLS access.
+// Per-input overrides are kept as long as the input URL does not change.
+func (rm *RelayManager) RegisterInputConfig(inputName, inputURL string) error {
+	for {
+		previousURL, err := rm.checkInputName(inputName, inputURL)
+		if err != nil {
+			return err
+		}
+
+		rm.configMu.Lock()
+		existing, exists := rm.inputConfigs[inputName]
+		if exists && existing.InputURL != previousURL {
+			// Registered concurrently for another URL, check again
+			rm.configMu.Unlock()
+			continue
+		}
+		config := &InputConfig{
+			InputURL:  inputURL,
+			InputName: inputName,
+		}
+		if exists && existing.InputURL == inputURL {
+			config.AnalyzeDuration = existing.AnalyzeDuration
+			config.ProbeSize = existing.ProbeSize
+			config.BackupURL = existing.BackupURL
+			config.HLSSync = existing.HLSSync
+			config.HLSMaxHeight = existing.HLSMaxHeight
+			config.HLSMaxViewers = existing.HLSMaxViewers
+			config.AudioTrack = existing.AudioTrack
+			config.HLSAudioTrack = existing.HLSAudioTrack
+			config.Buffering = existing.Buffering
+			config.InputProfile = existing.InputProfile
+			config.GrowingFile = existing.GrowingFile
+			config.HTTPHeaders = existing.HTTPHeaders
+		} else if exists {
+			rm.Logger.Warn("Input name %s moved from unused %s to %s", inputName, RedactURL(existing.InputURL), RedactURL(inputURL))
+		}
+		rm.inputConfigs[inputName] = config
+		rm.configMu.Unlock()
+		rm.Logger.Debug("Registered input config: %s -> %s", inputName, RedactURL(inputURL))
+		return nil
+	}
+}
+
+// inputURLInUse reports whether any output endpoint or running consumer still
+// refers to inputURL
+func (rm *RelayManager) inputURLInUse(inputURL string) bool {
+	rm.OutputRelays.mu.Lock()
+	for _, out := range rm.OutputRelays.Relays {
+		if out.InputURL == inputURL {
+			rm.OutputRelays.mu.Unlock()
+			return true
+		}
+	}
+	rm.OutputRelays.mu.Unlock()
+
+	rm.InputRelays.mu.Lock()
+	defer rm.InputRelays.mu.Unlock()
+	if relay, exists := rm.InputRelays.Relays[inputURL]; exists {
+		relay.mu.Lock()
+		defer relay.mu.Unlock()
+		return relay.RefCount > 0
+	}
+	return false
+}
+
+// SetInputProbeOptions sets the per-input -analyzeduration/-probesize overrides
+// used by the input relay and HLS sessions. Empty values restore the defaults.
+func (rm *RelayManager) SetInputProbeOptions(inputName, analyzeDuration, probeSize string) error {
+	rm.configMu.Lock()
+	defer rm.configMu.Unlock()
+
+	config, exists := rm.inputConfigs[inputName]
+	if !exists {
+		return fmt.Errorf("input configuration not found for: %s", inputName)
+	}
+	config.AnalyzeDuration = analyzeDuration
+	config.ProbeSize = probeSize
+	rm.Logger.Debug("Set probe options for %s: analyzeduration=%s, probesize=%s", inputName, analyzeDuration, probeSize)
+	return nil
+}
+
+// SetInputBackupURL sets the source the input relay of inputName fails over
+// to when its primary source fails. Empty removes the backup.
+func (rm *RelayManager) SetInputBackupURL(inputName, backupURL string) error {
+	rm.configMu.Lock()
+	defer rm.configMu.Unlock()
+
+	config, exists := rm.inputConfigs[inputName]
+	if !exists {
+		return fmt.Errorf("input configuration not found for: %s", inputName)
+	}
+	config.BackupURL = backupURL
+	rm.Logger.Debug("Set backup source for %s: %s", inputName, RedactURL(backupURL))
+	return nil
+}
+
+// SetInputHLSSync sets the A/V sync flags of the HLS preview of inputName,
+// used when its next session starts. nil restores the HLS defaults.
+func (rm *RelayManager) SetInputHLSSync(inputName string, sync *HLSSyncOptions) error {
+	if sync != nil {
+		if err := sync.Validate(); err != nil {
+			return err
+		}
+	}
+	rm.configMu.Lock()
+	defer rm.configMu.Unlock()
+
+	config, exists := rm.inputConfigs[inputName]
+	if !exists {
+		return fmt.Errorf("input configuration not found for: %s", inputName)
+	}
+	config.HLSSync = sync
+	rm.Logger.Debug("Set HLS sync options for %s: %+v", inputName, sync)
+	return nil
+}
+
+// SetInputHLSMaxHeight caps the video height of the HLS preview of inputName,
+// used when its next session starts; 0 keeps the source resolution and nil
+// restores the HLS default
+func (rm *RelayManager) SetInputHLSMaxHeight(inputName string, maxHeight *int) error {
+	if maxHeight != nil && *maxHeight < 0 {
+		return fmt.Errorf("invalid max_height %d, must not be negative", *maxHeight)
+	}
+	rm.configMu.Lock()
+	defer rm.configMu.Unlock()
+
+	config, exists := rm.inputConfigs[inputName]
+	if !exists {
+		return fmt.Errorf("input configuration not found for: %s", inputName)
+	}
+	config.HLSMaxHeight = maxHeight
+	return nil
+}
+
+// SetInputHLSMaxViewers caps how many viewers the HLS preview of inputName
+// may have, checked when a viewer joins; 0 is unlimited and nil restores the
+// HLS default
+func (rm *RelayManager) SetInputHLSMaxViewers(inputName string, maxViewers *int) error {
+	if maxViewers != nil && *maxViewers < 0 {
+		return fmt.Errorf("invalid max_viewers %d, must not be negative", *maxViewers)
+	}
+	rm.configMu.Lock()
+	defer rm.configMu.Unlock()
+
+	config, exists := rm.inputConfigs[inputName]
+	if !exists {
+		return fmt.Errorf("input configuration not found for: %s", inputName)
+	}
+	config.HLSMaxViewers = maxViewers
+	return nil
+}
+
+// SetInputAudioTrack selects the audio tracks of a multi-track source the
+// input relay of inputName publishes, used when it next starts: an index, a
+// language code or "all". Empty restores ffmpeg's default track.
+func (rm *RelayManager) SetInputAudioTrack(inputName, track string) error {
+	if err := validateAudioTrack(track, true); err != nil {
+		return err
+	}
+	rm.configMu.Lock()
+	defer rm.configMu.Unlock()
+
+	config, exists := rm.inputConfigs[inputName]
+	if !exists {
+		return fmt.Errorf("input configuration not found for: %s", inputName)
+	}
+	config.AudioTrack = track
+	return nil
+}
+
+// SetInputHLSAudioTrack selects by index which of the audio tracks published
+// by the input relay of inputName its HLS preview plays, used when its next
+// session starts. Empty restores the default track.
+func (rm *RelayManager) SetInputHLSAudioTrack(inputName, track string) error {
+	if err := validateAudioTrack(track, false); err != nil {
+		return err
+	}
+	rm.configMu.Lock()
+	defer rm.configMu.Unlock()
+
+	config, exists := rm.inputConfigs[inputName]
+	if !exists {
+		return fmt.Errorf("input configuration not found for: %s", inputName)
+	}
+	config.HLSAudioTrack = track
+	return nil
+}
+
+// FailBackInput switches the input relay of inputName from its backup source
+// back to the primary one
+func (rm *RelayManager) FailBackInput(inputName string) error {
+	inputURL, exists := rm.GetInputURLByName(inputName)
+	if !exists {
+		return fmt.Errorf("input configuration not found for: %s", inputName)
+	}
+	return rm.InputRelays.FailBack(inputURL)
+}
+
+// setInputConfig replaces the per-input settings of a registered input with
+// those of config, e.g. when importing. The name and URL must match.
+func (rm *RelayManager) setInputConfig(config InputConfig) {
 	rm.configMu.Lock()
 	defer rm.configMu.Unlock()
 
-	rm.inputConfigs[inputName] = &InputConfig{
-		InputURL:  inputURL,
-		InputName: inputName,
+	if existing, exists := rm.inputConfigs[config.InputName]; exists && existing.InputURL == config.InputURL {
+		rm.inputConfigs[config.InputName] = &config
 	}
-	rm.Logger.Debug("Registered input config: %s -> %s", inputName, inputURL)
+}
+
+// GetInputConfig returns a copy of the stored configuration for an input
+func (rm *RelayManager) GetInputConfig(inputName string) (InputConfig, bool) {
+	rm.configMu.RLock()
+	defer rm.configMu.RUnlock()
+
+	if config, exists := rm.inputConfigs[inputName]; exists {
+		return *config, true
+	}
+	return InputConfig{}, false
 }
 
 // GetInputURLByName returns the input URL for a given input name
@@ -751,9 +2211,12 @@ func (rm *RelayManager) GetInputURLByName(inputName string) (string, bool) {
 	return "", false
 }
 
-// StartInputRelayForConsumer starts an input relay and marks it as having a consumer
-// This is used by HLS sessions, recordings, etc. to ensure proper lifecycle management
-func (rm *RelayManager) StartInputRelayForConsumer(inputName string) (string, error) {
+// StartInputRelayForConsumer starts an input relay and takes a reference for consumer
+// This is used by HLS sessions, recordings, etc. to ensure proper lifecycle management.
+// It returns once the input is Running; if that does not happen within the input
+// timeout or before ctx is done, the reference is released again, which stops
+// the input's ffmpeg when no one else uses it.
+func (rm *RelayManager) StartInputRelayForConsumer(ctx context.Context, inputName, consumer string) (string, error) {
 	inputURL, exists := rm.GetInputURLByName(inputName)
 	if !exists {
 		return "", fmt.Errorf("input configuration not found for: %s", inputName)
@@ -764,36 +2227,60 @@ func (rm *RelayManager) StartInputRelayForConsumer(inputName string) (string, er
 	localRelayURL := fmt.Sprintf("%s/%s", GetRTSPServerURL(), relayPath)
 
 	// Start the input relay with consumer counting
-	localURL, err := rm.InputRelays.StartInputRelay(inputName, inputURL, localRelayURL, rm.inputTimeout)
+	localURL, err := rm.InputRelays.StartInputRelay(inputName, inputURL, localRelayURL, rm.inputTimeout, consumer)
 	if err != nil {
 		return "", fmt.Errorf("failed to start input relay for %s: %v", inputName, err)
 	}
 
-	// Wait for the RTSP stream to become ready
-	if rm.rtspServer != nil {
-		rm.Logger.Info("Waiting for RTSP stream to become ready: %s", relayPath)
-		err = rm.rtspServer.WaitForStreamReady(relayPath, 30*time.Second)
-		if err != nil {
-			rm.Logger.Error("Failed to wait for RTSP stream to become ready for %s: %v", inputName, err)
-			if !rm.rtspServer.IsStreamReady(relayPath) {
-				rm.InputRelays.StopInputRelay(inputURL)
-				return "", fmt.Errorf("RTSP stream not ready: %v", err)
-			}
-			rm.Logger.Warn("Stream %s appears ready but wait failed, continuing anyway", relayPath)
-		}
+	if err := rm.WaitForInputRunning(ctx, inputURL); err != nil {
+		rm.Logger.Error("Input %s not ready for %s: %v", inputName, consumer, err)
+		rm.InputRelays.StopInputRelay(inputURL, consumer)
+		return "", err
 	}
 
 	return localURL, nil
 }
 
-// StopInputRelayForConsumer decrements the consumer count for an input relay
+// WaitForInputRunning polls the input relay for inputURL until it reports
+// Running (published and stable). It fails as soon as the relay errors out or
+// goes away, when ctx is done, or after the input timeout plus stabilization.
+func (rm *RelayManager) WaitForInputRunning(ctx context.Context, inputURL string) error {
+	ctx, cancel := context.WithTimeout(ctx, rm.inputTimeout+rm.InputRelays.stabilization)
+	defer cancel()
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		rm.InputRelays.mu.Lock()
+		relay, exists := rm.InputRelays.Relays[inputURL]
+		rm.InputRelays.mu.Unlock()
+		if !exists {
+			return fmt.Errorf("input relay for %s was removed while starting", RedactURL(inputURL))
+		}
+		relay.mu.Lock()
+		status, lastError := relay.Status, relay.LastError
+		relay.mu.Unlock()
+		switch status {
+		case InputRunning, InputCompleted:
+			return nil
+		case InputError, InputStopped:
+			return fmt.Errorf("input relay for %s failed to start: %s", RedactURL(inputURL), lastError)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("input relay for %s not running: %w", RedactURL(inputURL), ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// StopInputRelayForConsumer releases consumer's reference on an input relay
 // This is used by HLS sessions, recordings, etc. when they stop consuming
-func (rm *RelayManager) StopInputRelayForConsumer(inputName string) {
+func (rm *RelayManager) StopInputRelayForConsumer(inputName, consumer string) {
 	inputURL, exists := rm.GetInputURLByName(inputName)
 	if !exists {
 		rm.Logger.Warn("Cannot stop input relay for %s: input configuration not found", inputName)
 		return
 	}
 
-	rm.InputRelays.StopInputRelay(inputURL)
+	rm.InputRelays.StopInputRelay(inputURL, consumer)
 }
@@ -0,0 +1,239 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+)
+
+// A reload applies an updated relay config file to the running relays, unlike
+// an import, which starts everything in the file. Outputs whose settings
+// changed are restarted, new ones are started and unchanged ones are left
+// running untouched. Input settings are updated too, and take effect when the
+// input relay next starts.
+
+// ReloadMode decides what a reload does with relays missing from the file
+type ReloadMode string
+
+const (
+	ReloadMerge   ReloadMode = "merge"   // Default: keep relays the file does not list
+	ReloadReplace ReloadMode = "replace" // Delete relays the file does not list
+)
+
+// ErrInvalidReloadMode is returned for an unknown reload mode
+var ErrInvalidReloadMode = errors.New("invalid reload mode")
+
+// ValidateReloadMode checks a reload mode; empty is merge
+func ValidateReloadMode(mode ReloadMode) error {
+	switch mode {
+	case "", ReloadMerge, ReloadReplace:
+		return nil
+	}
+	return fmt.Errorf("%w %q: must be %s or %s", ErrInvalidReloadMode, mode, ReloadMerge, ReloadReplace)
+}
+
+// ReloadResult lists what a reload did, each relay as "input -> output" and
+// each deleted input by its name
+type ReloadResult struct {
+	Added     []string `json:"added"`
+	Restarted []string `json:"restarted"`
+	Updated   []string `json:"updated"` // changed while stopped, so not started
+	Unchanged []string `json:"unchanged"`
+	Removed   []string `json:"removed"`
+	Errors    []string `json:"errors,omitempty"`
+}
+
+func (r *ReloadResult) fail(label string, err error) {
+	r.Errors = append(r.Errors, fmt.Sprintf("%s: %v", label, err))
+}
+
+// RestartRelay stops an output and starts it again with opts and preset,
+// returning the ffmpeg arguments it now runs with
+func (rm *RelayManager) RestartRelay(inputURL, outputURL, inputName, outputName string, opts *FFmpegOptions, preset string) ([]string, error) {
+	rm.Logger.Info("Restarting relay %s -> %s", inputName, outputName)
+	if err := rm.ValidateFFmpegOptions(opts); err != nil {
+		return nil, err
+	}
+	if err := rm.StopRelay(inputURL, outputURL, inputName, outputName); err != nil {
+		return nil, err
+	}
+	return rm.StartRelayWithOptions(inputURL, outputURL, inputName, outputName, opts, preset)
+}
+
+// reloadedOutput is the state of an output before a reload
+type reloadedOutput struct {
+	inputURL, outputName, preset string
+	options                      map[string]string
+	running                      bool
+}
+
+// sameFFmpegOptions compares stored ffmpeg options, an unset option being
+// the same as an empty one
+func sameFFmpegOptions(a, b map[string]string) bool {
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+	for k, v := range b {
+		if a[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// ReloadConfig applies the relay config in filename to the running relays,
+// see ReloadResult. An output counts as changed when its name, preset, ffmpeg
+// options, input or enabled flag differ, see reloadOutput. With ReloadReplace
+// the inputs and outputs missing from the file are deleted.
+func (rm *RelayManager) ReloadConfig(filename string, mode ReloadMode) (ReloadResult, error) {
+	rm.Logger.Debug("ReloadConfig called: filename=%s, mode=%s", filename, mode)
+	result := ReloadResult{Added: []string{}, Restarted: []string{}, Updated: []string{}, Unchanged: []string{}, Removed: []string{}}
+	if err := ValidateReloadMode(mode); err != nil {
+		return result, err
+	}
+	configs, err := rm.readImport(filename)
+	if err != nil {
+		return result, err
+	}
+
+	inputNames := make(map[string]string) // inputURL -> inputName
+	rm.InputRelays.mu.Lock()
+	for inputURL, relay := range rm.InputRelays.Relays {
+		inputNames[inputURL] = relay.InputName // immutable
+	}
+	rm.InputRelays.mu.Unlock()
+
+	current := make(map[string]reloadedOutput)
+	rm.OutputRelays.mu.Lock()
+	for outputURL, out := range rm.OutputRelays.Relays {
+		out.mu.Lock()
+		current[outputURL] = reloadedOutput{
+			inputURL:   out.InputURL,
+			outputName: out.OutputName,
+			preset:     out.PlatformPreset,
+			options:    out.FFmpegOptions,
+			running:    out.Status == OutputRunning,
+		}
+		out.mu.Unlock()
+	}
+	rm.OutputRelays.mu.Unlock()
+
+	listedInputs := make(map[string]bool)
+	listedOutputs := make(map[string]bool)
+	for _, relayCfg := range configs {
+		in := relayCfg.Input
+		listedInputs[in.InputURL] = true
+		for _, out := range relayCfg.Outputs {
+			listedOutputs[out.OutputURL] = true
+		}
+		if err := rm.registerImportedInput(in); err != nil {
+			rm.Logger.Error("Failed to register input %s: %v", in.InputName, err)
+			result.fail(in.InputName, err)
+			continue
+		}
+		enabledOutputs := 0
+		for _, out := range relayCfg.Outputs {
+			if out.enabled() {
+				enabledOutputs++
+			}
+		}
+		if enabledOutputs == 0 {
+			rm.InputRelays.addIdleInput(in.InputName, in.InputURL, rm.inputTimeout)
+		}
+		for _, out := range relayCfg.Outputs {
+			rm.reloadOutput(in, out, current, &result)
+		}
+	}
+
+	if mode == ReloadReplace {
+		for outputURL, cur := range current {
+			if listedOutputs[outputURL] || !listedInputs[cur.inputURL] {
+				continue // kept, or deleted with its input below
+			}
+			label := inputNames[cur.inputURL] + " -> " + cur.outputName
+			if err := rm.DeleteOutput(cur.inputURL, outputURL, inputNames[cur.inputURL], cur.outputName); err != nil {
+				result.fail(label, err)
+				continue
+			}
+			result.Removed = append(result.Removed, label)
+		}
+		for inputURL, inputName := range inputNames {
+			if listedInputs[inputURL] {
+				continue
+			}
+			if err := rm.DeleteInput(inputURL, inputName); err != nil {
+				result.fail(inputName, err)
+				continue
+			}
+			result.Removed = append(result.Removed, inputName)
+		}
+	}
+
+	rm.Logger.Info("Reloaded relay config from %s: %d added, %d restarted, %d updated, %d unchanged, %d removed, %d errors",
+		filename, len(result.Added), len(result.Restarted), len(result.Updated), len(result.Unchanged), len(result.Removed), len(result.Errors))
+	if len(result.Errors) > 0 {
+		return result, fmt.Errorf("reload completed with %d errors, last: %s", len(result.Errors), result.Errors[len(result.Errors)-1])
+	}
+	return result, nil
+}
+
+// reloadOutput applies one output of a reloaded config. An enabled output is
+// (re)started when it is new, was running or was just enabled; otherwise it
+// only gets its new settings.
+func (rm *RelayManager) reloadOutput(in InputConfig, out exportOutput, current map[string]reloadedOutput, result *ReloadResult) {
+	label := in.InputName + " -> " + out.OutputName
+	var opts *FFmpegOptions
+	if out.FFmpegOptions != nil {
+		opts = FFmpegOptionsFromMap(out.FFmpegOptions)
+	}
+	cur, exists := current[out.OutputURL]
+	wasEnabled := rm.outputEnabled(out.OutputURL)
+	changed := exists && (cur.inputURL != in.InputURL || cur.outputName != out.OutputName ||
+		cur.preset != out.PlatformPreset || !sameFFmpegOptions(cur.options, out.FFmpegOptions))
+	rm.setOutputPriority(out.OutputURL, out.Priority)
+	if exists && !changed && wasEnabled == out.enabled() {
+		result.Unchanged = append(result.Unchanged, label)
+		return
+	}
+
+	run := out.enabled() && (!exists || cur.running || !wasEnabled)
+	restartInPlace := run && exists && cur.running && cur.inputURL == in.InputURL
+	if exists && !restartInPlace {
+		// Drop the old relay, releasing its input, and keep the new settings
+		if err := rm.OutputRelays.DeleteOutput(out.OutputURL); err != nil {
+			result.fail(label, err)
+			return
+		}
+	}
+	rm.setOutputEnabled(out.OutputURL, out.enabled())
+
+	var err error
+	switch {
+	case !run:
+		rm.OutputRelays.addStoppedOutput(OutputRelayConfig{
+			OutputURL:      out.OutputURL,
+			OutputName:     out.OutputName,
+			InputURL:       in.InputURL,
+			PlatformPreset: out.PlatformPreset,
+			FFmpegOptions:  out.FFmpegOptions,
+		})
+	case restartInPlace:
+		_, err = rm.RestartRelay(in.InputURL, out.OutputURL, in.InputName, out.OutputName, opts, out.PlatformPreset)
+	default:
+		_, err = rm.StartRelayWithOptions(in.InputURL, out.OutputURL, in.InputName, out.OutputName, opts, out.PlatformPreset)
+	}
+	if err != nil {
+		rm.Logger.Error("Failed to reload relay %s: %v", label, err)
+		result.fail(label, err)
+		return
+	}
+	switch {
+	case !exists:
+		result.Added = append(result.Added, label)
+	case run:
+		result.Restarted = append(result.Restarted, label)
+	default:
+		result.Updated = append(result.Updated, label)
+	}
+}
@@ -0,0 +1,96 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DefaultRestartStagger is the pause between inputs in RestartAll
+const DefaultRestartStagger = 2 * time.Second
+
+// RestartResult lists what RestartAll did, each output as "input -> output"
+type RestartResult struct {
+	Restarted []string `json:"restarted"`
+	Errors    []string `json:"errors,omitempty"`
+	Skipped   []string `json:"skipped,omitempty"` // not reached before the restart was canceled
+	Canceled  bool     `json:"canceled,omitempty"`
+}
+
+// RestartAll restarts the running outputs one input at a time, waiting
+// stagger between inputs so their ffmpeg processes do not all start at once.
+// Each output comes back with its stored preset and ffmpeg options, and an
+// input whose outputs were its only consumers restarts with them, so new
+// global settings are picked up. One output failing to start does not stop
+// the others. Canceling ctx skips the inputs not yet restarted.
+func (rm *RelayManager) RestartAll(ctx context.Context, stagger time.Duration) RestartResult {
+	result := RestartResult{Restarted: []string{}}
+	byInput := make(map[string][]pausableOutput)
+	var inputs []string
+	for _, o := range rm.outputsWhere(func(out *OutputRelay) bool {
+		return out.Status.active()
+	}) {
+		if len(byInput[o.inputURL]) == 0 {
+			inputs = append(inputs, o.inputURL)
+		}
+		byInput[o.inputURL] = append(byInput[o.inputURL], o)
+	}
+	sort.Slice(inputs, func(i, j int) bool {
+		return byInput[inputs[i]][0].inputName < byInput[inputs[j]][0].inputName
+	})
+
+	for i, inputURL := range inputs {
+		if i > 0 && stagger > 0 {
+			timer := time.NewTimer(stagger)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+			}
+		}
+		if ctx.Err() != nil {
+			for _, skipped := range inputs[i:] {
+				for _, o := range byInput[skipped] {
+					result.Skipped = append(result.Skipped, o.label())
+				}
+			}
+			result.Canceled = true
+			break
+		}
+		rm.restartInput(byInput[inputURL], &result)
+	}
+	rm.Logger.Info("Restarted %d outputs, %d errors, %d skipped", len(result.Restarted), len(result.Errors), len(result.Skipped))
+	return result
+}
+
+// restartInput stops all of one input's outputs, letting the input relay go
+// when nothing else uses it, and starts them again
+func (rm *RelayManager) restartInput(outputs []pausableOutput, result *RestartResult) {
+	type stored struct {
+		preset string
+		opts   *FFmpegOptions
+		found  bool
+	}
+	configs := make([]stored, len(outputs))
+	for i, o := range outputs {
+		preset, opts, err := rm.GetEndpointConfig(o.inputURL, o.outputURL)
+		if err != nil {
+			continue // deleted in the meantime
+		}
+		configs[i] = stored{preset, opts, true}
+		_ = rm.StopRelay(o.inputURL, o.outputURL, o.inputName, o.outputName)
+	}
+	for i, o := range outputs {
+		if !configs[i].found {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", o.label(), ErrOutputNotFound))
+			continue
+		}
+		if _, err := rm.StartRelayWithOptions(o.inputURL, o.outputURL, o.inputName, o.outputName, configs[i].opts, configs[i].preset); err != nil {
+			rm.Logger.Error("Failed to restart output %s: %v", o.label(), err)
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", o.label(), err))
+			continue
+		}
+		result.Restarted = append(result.Restarted, o.label())
+	}
+}
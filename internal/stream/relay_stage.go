@@ -0,0 +1,41 @@
+package stream
+
+// RelayStage is how far StartRelayWithOptions has got in starting a relay,
+// so a slow start can be told apart from a stuck one
+type RelayStage string
+
+const (
+	StageStartingInput  RelayStage = "starting_input"  // launching the input ffmpeg
+	StageWaitingRTSP    RelayStage = "waiting_rtsp"    // waiting for the input to publish to the local RTSP server
+	StageStartingOutput RelayStage = "starting_output" // launching the output ffmpeg
+	StageRunning        RelayStage = "running"         // no start in progress and the input is running
+)
+
+// setStartStage records the stage of the start in progress for inputURL
+func (rm *RelayManager) setStartStage(inputURL string, stage RelayStage) {
+	rm.startStagesMu.Lock()
+	rm.startStages[inputURL] = stage
+	rm.startStagesMu.Unlock()
+}
+
+// clearStartStage forgets the start of inputURL once it finished or failed
+func (rm *RelayManager) clearStartStage(inputURL string) {
+	rm.startStagesMu.Lock()
+	delete(rm.startStages, inputURL)
+	rm.startStagesMu.Unlock()
+}
+
+// startStage reports the stage of inputURL's relay start; running tells
+// whether the input relay runs, for when no start is in progress
+func (rm *RelayManager) startStage(inputURL string, running bool) RelayStage {
+	rm.startStagesMu.Lock()
+	stage, ok := rm.startStages[inputURL]
+	rm.startStagesMu.Unlock()
+	if ok {
+		return stage
+	}
+	if running {
+		return StageRunning
+	}
+	return ""
+}
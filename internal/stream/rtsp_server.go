@@ -2,7 +2,10 @@ package stream
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/rand/v2"
+	"net"
 	"strings"
 	"sync"
 	"time"
@@ -22,15 +25,40 @@ const (
 	DefaultRTSPInterface = "127.0.0.1" // Listen locally by default
 )
 
+// DefaultReadyPollInterval is how often WaitForStreamReady first checks a
+// stream when it missed the ready signal; the interval doubles up to
+// maxReadyPollInterval
+const DefaultReadyPollInterval = 100 * time.Millisecond
+
+const maxReadyPollInterval = 2 * time.Second
+
 // GetRTSPServerURL returns the base RTSP server URL
 func GetRTSPServerURL() string {
 	return fmt.Sprintf("rtsp://%s:%d", DefaultRTSPInterface, DefaultRTSPPort)
 }
 
-// RTSPServerConfig contains the configuration for the RTSP server
+// ErrRTSPPortInUse is returned by Start when a port the RTSP server needs is
+// already bound, typically by another instance
+var ErrRTSPPortInUse = errors.New("RTSP server port in use")
+
+// RTSPServerConfig contains the configuration for the RTSP server.
+// UDPRTPPort is the even UDP port for RTP, with RTCP on the port after it;
+// 0 derives them from Port, see udpPorts.
 type RTSPServerConfig struct {
-	Port      int    `json:"port"`
-	Interface string `json:"interface"`
+	Port       int    `json:"port"`
+	Interface  string `json:"interface"`
+	UDPRTPPort int    `json:"udp_rtp_port"`
+}
+
+// udpPorts returns the UDP RTP and RTCP ports: the configured pair, or the
+// first even port above the RTSP port and the one after it, so instances on
+// RTSP ports at least 4 apart never share UDP ports
+func (c RTSPServerConfig) udpPorts() (rtp, rtcp int) {
+	rtp = c.UDPRTPPort
+	if rtp == 0 {
+		rtp = c.Port + 2 - c.Port%2
+	}
+	return rtp, rtp + 1
 }
 
 // RTSPStreamInfo contains metadata about an RTSP stream
@@ -40,9 +68,26 @@ type RTSPStreamInfo struct {
 	ClientCount   int       `json:"client_count"`
 	BytesReceived int64     `json:"bytes_received"`
 	StartTime     time.Time `json:"start_time"`
+	Publishing    bool      `json:"publishing"` // set once the publisher starts sending (RECORD)
 	Stream        *gortsplib.ServerStream
 }
 
+// RTSPMediaInfo is one media of a published stream as announced in its SDP
+type RTSPMediaInfo struct {
+	Type    string           `json:"type"` // video, audio or application
+	Control string           `json:"control,omitempty"`
+	Formats []RTSPFormatInfo `json:"formats"`
+}
+
+// RTSPFormatInfo is one RTP payload format of a media
+type RTSPFormatInfo struct {
+	Codec       string            `json:"codec"`
+	PayloadType uint8             `json:"payload_type"`
+	ClockRate   int               `json:"clock_rate"`
+	RTPMap      string            `json:"rtpmap,omitempty"`
+	FMTP        map[string]string `json:"fmtp,omitempty"`
+}
+
 // RTSPServerManager manages the RTSP server instance
 type RTSPServerManager struct {
 	server       *gortsplib.Server
@@ -53,6 +98,8 @@ type RTSPServerManager struct {
 	ctx          context.Context
 	cancel       context.CancelFunc
 	streamReady  map[string]chan bool // Channel to signal when stream is ready for reading
+
+	readyPollInterval time.Duration // set before Start via SetReadyPollInterval
 }
 
 // NewRTSPServerManager creates a new RTSP server manager
@@ -69,40 +116,78 @@ func NewRTSPServerManager(l *logger.Logger) *RTSPServerManager {
 		streamReady: make(map[string]chan bool),
 		ctx:         ctx,
 		cancel:      cancel,
+
+		readyPollInterval: DefaultReadyPollInterval,
+	}
+}
+
+// SetReadyPollInterval sets the base interval WaitForStreamReady checks a
+// stream at when the ready signal went to another waiter. Set before Start.
+func (rm *RTSPServerManager) SetReadyPollInterval(d time.Duration) error {
+	if d <= 0 {
+		return fmt.Errorf("invalid RTSP ready poll interval %v: must be positive", d)
 	}
+	rm.readyPollInterval = d
+	return nil
+}
+
+// SetUDPRTPPort sets the UDP port RTP is received on, RTCP using the next
+// one; 0 derives both from the RTSP port. Set before Start.
+func (rm *RTSPServerManager) SetUDPRTPPort(port int) error {
+	if port < 0 || port > 65534 || port%2 != 0 {
+		return fmt.Errorf("invalid RTSP UDP RTP port %d: must be even and below 65535", port)
+	}
+	rm.config.UDPRTPPort = port
+	return nil
+}
+
+// checkUDPPortsFree binds and releases each UDP port, so a conflict is
+// reported with the port at fault before the server starts
+func checkUDPPortsFree(iface string, ports ...int) error {
+	for _, port := range ports {
+		conn, err := net.ListenPacket("udp", net.JoinHostPort(iface, fmt.Sprint(port)))
+		if err != nil {
+			return fmt.Errorf("%w: UDP %s:%d: %v", ErrRTSPPortInUse, iface, port, err)
+		}
+		conn.Close()
+	}
+	return nil
 }
 
 // Start starts the RTSP server
 func (rm *RTSPServerManager) Start() error {
-	rm.logger.Info("Starting RTSP server on %s:%d", rm.config.Interface, rm.config.Port)
+	rtpPort, rtcpPort := rm.config.udpPorts()
+	rm.logger.Info("Starting RTSP server on %s:%d (UDP RTP %d, RTCP %d)", rm.config.Interface, rm.config.Port, rtpPort, rtcpPort)
+	if err := checkUDPPortsFree(rm.config.Interface, rtpPort, rtcpPort); err != nil {
+		return err
+	}
 
 	// Create RTSP server instance with more permissive configuration
 	rm.server = &gortsplib.Server{
 		Handler:        rm,
 		RTSPAddress:    fmt.Sprintf("%s:%d", rm.config.Interface, rm.config.Port),
-		UDPRTPAddress:  fmt.Sprintf("%s:8000", rm.config.Interface),
-		UDPRTCPAddress: fmt.Sprintf("%s:8001", rm.config.Interface),
+		UDPRTPAddress:  fmt.Sprintf("%s:%d", rm.config.Interface, rtpPort),
+		UDPRTCPAddress: fmt.Sprintf("%s:%d", rm.config.Interface, rtcpPort),
 		ReadTimeout:    5 * time.Second, // More generous timeouts
 		WriteTimeout:   5 * time.Second,
 	}
 
 	// Start the server
-	serverReady := make(chan bool, 1)
+	serverErr := make(chan error, 1)
 	go func() {
 		err := rm.server.Start()
 		if err != nil {
 			rm.logger.Error("RTSP server error: %v", err)
-			serverReady <- false
-		} else {
-			serverReady <- true
 		}
+		serverErr <- err
 	}()
 
 	// Wait for server to be ready with timeout
 	select {
-	case ready := <-serverReady:
-		if !ready {
-			return fmt.Errorf("RTSP server failed to start")
+	case err := <-serverErr:
+		if err != nil {
+			rm.server = nil
+			return fmt.Errorf("RTSP server failed to start: %w", err)
 		}
 	case <-time.After(2 * time.Second):
 		// Give it a moment to start, but don't block indefinitely
@@ -247,6 +332,9 @@ func (rm *RTSPServerManager) OnRecord(ctx *gortsplib.ServerHandlerOnRecordCtx) (
 
 	// Signal that the stream is ready for reading after all setup is complete
 	rm.streamsMutex.Lock()
+	if ok {
+		streamInfo.Publishing = true
+	}
 	if readyChan, exists := rm.streamReady[pathName]; exists {
 		select {
 		case readyChan <- true:
@@ -282,6 +370,40 @@ func (rm *RTSPServerManager) GetStreamStats() []RTSPStreamInfo {
 	return stats
 }
 
+// GetStreamDescription returns the media a publisher announced for a stream,
+// i.e. the codecs ffmpeg produced for it. It fails if nothing was announced.
+func (rm *RTSPServerManager) GetStreamDescription(name string) ([]RTSPMediaInfo, error) {
+	rm.streamsMutex.Lock()
+	defer rm.streamsMutex.Unlock()
+
+	streamInfo, exists := rm.streams[name]
+	if !exists {
+		return nil, fmt.Errorf("RTSP stream not found: %s", name)
+	}
+	if streamInfo.Stream == nil || streamInfo.Stream.Desc == nil {
+		return nil, fmt.Errorf("RTSP stream %s has no publisher yet", name)
+	}
+	medias := make([]RTSPMediaInfo, 0, len(streamInfo.Stream.Desc.Medias))
+	for _, media := range streamInfo.Stream.Desc.Medias {
+		info := RTSPMediaInfo{
+			Type:    string(media.Type),
+			Control: media.Control,
+			Formats: make([]RTSPFormatInfo, 0, len(media.Formats)),
+		}
+		for _, f := range media.Formats {
+			info.Formats = append(info.Formats, RTSPFormatInfo{
+				Codec:       f.Codec(),
+				PayloadType: f.PayloadType(),
+				ClockRate:   f.ClockRate(),
+				RTPMap:      f.RTPMap(),
+				FMTP:        f.FMTP(),
+			})
+		}
+		medias = append(medias, info)
+	}
+	return medias, nil
+}
+
 // CreateEmptyStream creates an RTSP stream path that can be published to
 // We don't need to pre-create the stream in the latest gortsplib version,
 // as streams are created dynamically when clients publish to them
@@ -309,7 +431,11 @@ func (rm *RTSPServerManager) CreateEmptyStream(name string) (string, error) {
 	return rm.GetRTSPURL(name), nil
 }
 
-// WaitForStreamReady waits for a stream to become ready for reading (i.e., being published to)
+// WaitForStreamReady waits for a stream to become ready for reading (i.e., being published to).
+// It blocks on the signal OnRecord sends, which only one waiter receives, so
+// it also checks whether the stream is publishing at a jittered, backed-off
+// interval; concurrent starts then neither miss the stream going live nor
+// take streamsMutex in lockstep.
 func (rm *RTSPServerManager) WaitForStreamReady(name string, timeout time.Duration) error {
 	rm.streamsMutex.Lock()
 	// Create channel if it doesn't exist
@@ -319,28 +445,43 @@ func (rm *RTSPServerManager) WaitForStreamReady(name string, timeout time.Durati
 	readyChan := rm.streamReady[name]
 	rm.streamsMutex.Unlock()
 
-	// Check if already ready (for existing streams)
-	if rm.IsStreamReady(name) {
-		// For existing streams, check if they're actually recording by waiting briefly for signal
-		select {
-		case <-readyChan:
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	interval := rm.readyPollInterval
+	for {
+		if rm.IsStreamPublishing(name) {
+			// Drop a signal of this publish so it is not taken for the next one
+			select {
+			case <-readyChan:
+			default:
+			}
 			rm.logger.Debug("Stream %s is ready for reading", name)
 			return nil
-		case <-time.After(500 * time.Millisecond):
-			// If no signal after 500ms but stream exists, assume it's ready (for reused streams)
-			rm.logger.Debug("Stream %s appears to be already ready", name)
+		}
+		poll := time.NewTimer(jitter(interval))
+		select {
+		case _, ok := <-readyChan:
+			poll.Stop()
+			if !ok {
+				return fmt.Errorf("stream %s was removed while waiting for it", name)
+			}
+			rm.logger.Debug("Stream %s is ready for reading", name)
 			return nil
+		case <-poll.C:
+			interval = min(2*interval, maxReadyPollInterval)
+		case <-deadline.C:
+			poll.Stop()
+			return fmt.Errorf("timeout waiting for stream %s to become ready", name)
 		}
 	}
+}
 
-	// Wait for the stream to start recording (OnRecord signals this channel)
-	select {
-	case <-readyChan:
-		rm.logger.Debug("Stream %s is ready for reading", name)
-		return nil
-	case <-time.After(timeout):
-		return fmt.Errorf("timeout waiting for stream %s to become ready", name)
+// jitter returns d varied randomly by up to a quarter either way
+func jitter(d time.Duration) time.Duration {
+	if d < 4 {
+		return d
 	}
+	return d - d/4 + rand.N(d/2)
 }
 
 // IsStreamReady checks if a stream is ready for reading (non-blocking)
@@ -352,6 +493,16 @@ func (rm *RTSPServerManager) IsStreamReady(name string) bool {
 	return exists && streamInfo.Stream != nil
 }
 
+// IsStreamPublishing reports whether a publisher is actively sending to the
+// stream (non-blocking, and unlike WaitForStreamReady it consumes no signal)
+func (rm *RTSPServerManager) IsStreamPublishing(name string) bool {
+	rm.streamsMutex.Lock()
+	defer rm.streamsMutex.Unlock()
+
+	streamInfo, exists := rm.streams[name]
+	return exists && streamInfo.Stream != nil && streamInfo.Publishing
+}
+
 // RemoveStream removes a stream
 func (rm *RTSPServerManager) RemoveStream(name string) {
 	rm.streamsMutex.Lock()
@@ -0,0 +1,147 @@
+package stream
+
+import (
+	"bytes"
+	"errors"
+	"go-mls/internal/logger"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4"
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+	"github.com/bluenviron/gortsplib/v4/pkg/format"
+)
+
+func TestRTSPServerManager_GetStreamDescription(t *testing.T) {
+	rs := NewRTSPServerManager(logger.NewLoggerWithWriter(&bytes.Buffer{}))
+	if _, err := rs.GetStreamDescription("relay/cam"); err == nil {
+		t.Error("expected an error for an unknown stream")
+	}
+	rs.CreateEmptyStream("relay/cam")
+	if _, err := rs.GetStreamDescription("relay/cam"); err == nil {
+		t.Error("expected an error for a stream without publisher")
+	}
+
+	rs.streamsMutex.Lock()
+	rs.streams["relay/cam"].Stream = &gortsplib.ServerStream{Desc: &description.Session{
+		Medias: []*description.Media{
+			{Type: description.MediaTypeVideo, Control: "streamid=0", Formats: []format.Format{&format.H264{PayloadTyp: 96, PacketizationMode: 1}}},
+			{Type: description.MediaTypeAudio, Control: "streamid=1", Formats: []format.Format{&format.Opus{PayloadTyp: 97, ChannelCount: 2}}},
+		},
+	}}
+	rs.streamsMutex.Unlock()
+
+	medias, err := rs.GetStreamDescription("relay/cam")
+	if err != nil {
+		t.Fatalf("GetStreamDescription failed: %v", err)
+	}
+	if len(medias) != 2 {
+		t.Fatalf("expected 2 medias, got %+v", medias)
+	}
+	video, audio := medias[0], medias[1]
+	if video.Type != "video" || video.Control != "streamid=0" || len(video.Formats) != 1 {
+		t.Fatalf("unexpected video media %+v", video)
+	}
+	if f := video.Formats[0]; f.Codec != "H264" || f.PayloadType != 96 || f.ClockRate != 90000 || f.FMTP["packetization-mode"] != "1" {
+		t.Errorf("unexpected video format %+v", f)
+	}
+	if audio.Type != "audio" || len(audio.Formats) != 1 || audio.Formats[0].Codec != "Opus" || audio.Formats[0].ClockRate != 48000 {
+		t.Errorf("unexpected audio media %+v", audio)
+	}
+}
+
+func TestRTSPServerManager_UDPPorts(t *testing.T) {
+	for _, tc := range []struct {
+		cfg       RTSPServerConfig
+		rtp, rtcp int
+	}{
+		{RTSPServerConfig{Port: 8554}, 8556, 8557},
+		{RTSPServerConfig{Port: 8555}, 8556, 8557},
+		{RTSPServerConfig{Port: 8554, UDPRTPPort: 9000}, 9000, 9001},
+	} {
+		if rtp, rtcp := tc.cfg.udpPorts(); rtp != tc.rtp || rtcp != tc.rtcp {
+			t.Errorf("udpPorts(%+v) = %d, %d, want %d, %d", tc.cfg, rtp, rtcp, tc.rtp, tc.rtcp)
+		}
+	}
+
+	rs := NewRTSPServerManager(logger.NewLoggerWithWriter(&bytes.Buffer{}))
+	if err := rs.SetUDPRTPPort(9001); err == nil {
+		t.Error("expected an error for an odd RTP port")
+	}
+
+	// Hold a UDP port, then start a server whose RTP or RTCP port it is
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	held := conn.LocalAddr().(*net.UDPAddr).Port
+	if err := rs.SetUDPRTPPort(held - held%2); err != nil {
+		t.Fatalf("SetUDPRTPPort: %v", err)
+	}
+	if err := rs.Start(); !errors.Is(err, ErrRTSPPortInUse) {
+		rs.Stop()
+		t.Fatalf("Start = %v, want ErrRTSPPortInUse", err)
+	}
+}
+
+func TestRTSPServerManager_WaitForStreamReady(t *testing.T) {
+	rs := NewRTSPServerManager(logger.NewLoggerWithWriter(&bytes.Buffer{}))
+	if err := rs.SetReadyPollInterval(0); err == nil {
+		t.Error("expected an error for a zero poll interval")
+	}
+	if err := rs.SetReadyPollInterval(10 * time.Millisecond); err != nil {
+		t.Fatalf("SetReadyPollInterval: %v", err)
+	}
+	rs.CreateEmptyStream("relay/cam")
+	if err := rs.WaitForStreamReady("relay/cam", 50*time.Millisecond); err == nil {
+		t.Error("expected a timeout for a stream nobody publishes")
+	}
+
+	// Only one waiter gets the signal, the others notice by polling
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- rs.WaitForStreamReady("relay/cam", 5*time.Second)
+		}()
+	}
+	time.Sleep(30 * time.Millisecond)
+	start := time.Now()
+	rs.streamsMutex.Lock()
+	rs.streams["relay/cam"].Stream = &gortsplib.ServerStream{}
+	rs.streams["relay/cam"].Publishing = true
+	rs.streamReady["relay/cam"] <- true
+	rs.streamsMutex.Unlock()
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("WaitForStreamReady: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the waiters to see the stream within the backoff, took %v", elapsed)
+	}
+
+	// A stream that is already publishing is ready at once, without a stale signal left
+	rs.streamReady["relay/cam"] <- true
+	if err := rs.WaitForStreamReady("relay/cam", time.Millisecond); err != nil {
+		t.Errorf("expected a publishing stream to be ready, got %v", err)
+	}
+	if len(rs.streamReady["relay/cam"]) != 0 {
+		t.Error("expected the ready signal to be consumed")
+	}
+}
+
+func TestJitter(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		if d := jitter(100 * time.Millisecond); d < 75*time.Millisecond || d >= 125*time.Millisecond {
+			t.Fatalf("jitter(100ms) = %v, want within 25%%", d)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// s3PartSize is the size of multipart upload parts; S3 requires at least 5MiB
+// for all but the last part. Smaller files are sent with a single PUT.
+const s3PartSize = 16 << 20
+
+// S3Config locates a bucket on AWS S3 or any S3-compatible service
+// (MinIO, Ceph, R2...). Objects are addressed path-style under Endpoint.
+type S3Config struct {
+	Endpoint  string // e.g. "https://s3.eu-west-1.amazonaws.com"
+	Region    string // signing region, "us-east-1" when empty
+	Bucket    string
+	Prefix    string // key prefix, e.g. "recordings/"
+	AccessKey string
+	SecretKey string
+}
+
+// S3Sink uploads recordings to an S3 bucket. Large files go up as multipart
+// uploads whose finished parts are remembered, so a retried Upload of the
+// same key only sends the parts that are still missing.
+type S3Sink struct {
+	cfg      S3Config
+	endpoint *url.URL
+	client   *http.Client
+	partSize int64
+
+	mu      sync.Mutex
+	uploads map[string]*s3MultipartUpload // unfinished multipart uploads by object key
+}
+
+// s3MultipartUpload tracks an unfinished multipart upload
+type s3MultipartUpload struct {
+	id    string
+	size  int64
+	etags []string // ETag of each part, "" while not uploaded
+}
+
+// NewS3Sink validates cfg and returns a sink for it
+func NewS3Sink(cfg S3Config) (*S3Sink, error) {
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", cfg.Endpoint)
+	}
+	if cfg.Bucket == "" {
+		return nil, errors.New("S3 bucket cannot be empty")
+	}
+	if cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, errors.New("S3 credentials are required")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	endpoint.Path = strings.TrimSuffix(endpoint.Path, "/")
+	return &S3Sink{
+		cfg:      cfg,
+		endpoint: endpoint,
+		client:   &http.Client{},
+		partSize: s3PartSize,
+		uploads:  make(map[string]*s3MultipartUpload),
+	}, nil
+}
+
+// Name implements RecordingSink
+func (s *S3Sink) Name() string {
+	return "s3"
+}
+
+// Upload implements RecordingSink
+func (s *S3Sink) Upload(ctx context.Context, key, path string) (string, error) {
+	objectKey := strings.TrimPrefix(s.cfg.Prefix+key, "/")
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	if info.Size() <= s.partSize {
+		resp, err := s.do(ctx, http.MethodPut, objectKey, nil, io.NewSectionReader(f, 0, info.Size()), info.Size())
+		if err != nil {
+			return "", err
+		}
+		resp.Body.Close()
+	} else if err := s.uploadMultipart(ctx, objectKey, f, info.Size()); err != nil {
+		return "", err
+	}
+	return s.objectURL(objectKey, nil).String(), nil
+}
+
+// uploadMultipart sends f in parts, resuming the unfinished upload of
+// objectKey left by an earlier attempt if the file has not changed size
+func (s *S3Sink) uploadMultipart(ctx context.Context, objectKey string, f *os.File, size int64) error {
+	s.mu.Lock()
+	upload := s.uploads[objectKey]
+	s.mu.Unlock()
+
+	if upload == nil || upload.size != size {
+		resp, err := s.do(ctx, http.MethodPost, objectKey, url.Values{"uploads": {""}}, nil, 0)
+		if err != nil {
+			return err
+		}
+		var result struct {
+			UploadID string `xml:"UploadId"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil || result.UploadID == "" {
+			return fmt.Errorf("s3: no upload id in CreateMultipartUpload response: %v", err)
+		}
+		parts := (size + s.partSize - 1) / s.partSize
+		upload = &s3MultipartUpload{id: result.UploadID, size: size, etags: make([]string, parts)}
+		s.mu.Lock()
+		s.uploads[objectKey] = upload
+		s.mu.Unlock()
+	}
+
+	for i, etag := range upload.etags {
+		if etag != "" {
+			continue
+		}
+		offset := int64(i) * s.partSize
+		length := min(s.partSize, size-offset)
+		query := url.Values{"partNumber": {fmt.Sprint(i + 1)}, "uploadId": {upload.id}}
+		resp, err := s.do(ctx, http.MethodPut, objectKey, query, io.NewSectionReader(f, offset, length), length)
+		if err != nil {
+			s.forgetUploadOnMissing(objectKey, err)
+			return fmt.Errorf("part %d/%d: %w", i+1, len(upload.etags), err)
+		}
+		resp.Body.Close()
+		upload.etags[i] = resp.Header.Get("ETag")
+	}
+
+	var complete bytes.Buffer
+	complete.WriteString("<CompleteMultipartUpload>")
+	for i, etag := range upload.etags {
+		fmt.Fprintf(&complete, "<Part><PartNumber>%d</PartNumber><ETag>%s</ETag></Part>", i+1, etag)
+	}
+	complete.WriteString("</CompleteMultipartUpload>")
+	resp, err := s.do(ctx, http.MethodPost, objectKey, url.Values{"uploadId": {upload.id}}, bytes.NewReader(complete.Bytes()), int64(complete.Len()))
+	if err != nil {
+		s.forgetUploadOnMissing(objectKey, err)
+		return err
+	}
+	defer resp.Body.Close()
+	// CompleteMultipartUpload can fail after a 200 with an error document
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if bytes.Contains(body, []byte("<Error>")) {
+		return fmt.Errorf("s3: complete multipart upload: %s", s3ErrorCode(body))
+	}
+
+	s.mu.Lock()
+	delete(s.uploads, objectKey)
+	s.mu.Unlock()
+	return nil
+}
+
+// forgetUploadOnMissing drops the remembered upload when S3 no longer knows
+// it (aborted or expired), so the next attempt starts a new one
+func (s *S3Sink) forgetUploadOnMissing(objectKey string, err error) {
+	if strings.Contains(err.Error(), "NoSuchUpload") {
+		s.mu.Lock()
+		delete(s.uploads, objectKey)
+		s.mu.Unlock()
+	}
+}
+
+// objectURL returns the path-style URL of objectKey
+func (s *S3Sink) objectURL(objectKey string, query url.Values) *url.URL {
+	u := *s.endpoint
+	u.Path = s.endpoint.Path + "/" + s.cfg.Bucket + "/" + objectKey
+	u.RawPath = s.endpoint.Path + "/" + s3Escape(s.cfg.Bucket, false) + "/" + s3Escape(objectKey, false)
+	u.RawQuery = s3CanonicalQuery(query)
+	return &u
+}
+
+// do sends a signed request and turns non-2xx responses into errors
+func (s *S3Sink) do(ctx context.Context, method, objectKey string, query url.Values, body io.Reader, length int64) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(objectKey, query).String(), body)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = length
+	s.sign(req, time.Now())
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("s3: %s %s: %s %s", method, objectKey, resp.Status, s3ErrorCode(msg))
+	}
+	return resp, nil
+}
+
+// sign adds AWS Signature Version 4 headers to req. The payload is left
+// unsigned so files can be streamed without hashing them first.
+func (s *S3Sink) sign(req *http.Request, now time.Time) {
+	const payloadHash = "UNSIGNED-PAYLOAD"
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host + "\n" + "x-amz-content-sha256:" + payloadHash + "\n" + "x-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + s.cfg.Region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := []byte("AWS4" + s.cfg.SecretKey)
+	for _, part := range []string{date, s.cfg.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.cfg.AccessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3Escape percent-encodes s as SigV4 requires: everything but unreserved
+// characters, keeping "/" unless escapeSlash is set
+func s3Escape(s string, escapeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !escapeSlash) {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// s3CanonicalQuery encodes query sorted by key, as both sent and signed
+func s3CanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		for _, v := range query[k] {
+			pairs = append(pairs, s3Escape(k, true)+"="+s3Escape(v, true))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// s3ErrorCode extracts the <Code> of an S3 error document
+func s3ErrorCode(body []byte) string {
+	var doc struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if xml.Unmarshal(body, &doc) != nil || doc.Code == "" {
+		return ""
+	}
+	return doc.Code + ": " + doc.Message
+}
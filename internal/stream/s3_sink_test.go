@@ -0,0 +1,158 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go-mls/internal/logger"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeS3 is a minimal in-memory S3 endpoint supporting PUT and multipart uploads
+type fakeS3 struct {
+	mu        sync.Mutex
+	objects   map[string][]byte
+	parts     map[string][]byte // "<uploadId>/<partNumber>" -> data
+	partPuts  int
+	failParts map[string]int // partNumber -> failures left
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AK/") {
+		http.Error(w, "<Error><Code>AccessDenied</Code></Error>", http.StatusForbidden)
+		return
+	}
+	q := r.URL.Query()
+	body, _ := io.ReadAll(r.Body)
+	switch {
+	case r.Method == http.MethodPost && q.Has("uploads"):
+		fmt.Fprint(w, "<InitiateMultipartUploadResult><UploadId>up1</UploadId></InitiateMultipartUploadResult>")
+	case r.Method == http.MethodPut && q.Has("uploadId"):
+		part := q.Get("partNumber")
+		if f.failParts[part] > 0 {
+			f.failParts[part]--
+			http.Error(w, "<Error><Code>InternalError</Code></Error>", http.StatusInternalServerError)
+			return
+		}
+		f.partPuts++
+		f.parts[q.Get("uploadId")+"/"+part] = body
+		w.Header().Set("ETag", fmt.Sprintf("\"etag%s\"", part))
+	case r.Method == http.MethodPost && q.Has("uploadId"):
+		var data []byte
+		for i := 1; ; i++ {
+			part, ok := f.parts[fmt.Sprintf("%s/%d", q.Get("uploadId"), i)]
+			if !ok {
+				break
+			}
+			data = append(data, part...)
+		}
+		f.objects[r.URL.Path] = data
+		fmt.Fprint(w, "<CompleteMultipartUploadResult></CompleteMultipartUploadResult>")
+	case r.Method == http.MethodPut:
+		f.objects[r.URL.Path] = body
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+func newTestS3Sink(t *testing.T) (*S3Sink, *fakeS3) {
+	fake := &fakeS3{objects: map[string][]byte{}, parts: map[string][]byte{}, failParts: map[string]int{}}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	sink, err := NewS3Sink(S3Config{Endpoint: srv.URL, Bucket: "media", Prefix: "rec/", AccessKey: "AK", SecretKey: "SK"})
+	if err != nil {
+		t.Fatalf("NewS3Sink: %v", err)
+	}
+	return sink, fake
+}
+
+func TestS3Sink_Upload(t *testing.T) {
+	sink, fake := newTestS3Sink(t)
+	path := filepath.Join(t.TempDir(), "cam 1_100.mp4")
+	if err := os.WriteFile(path, []byte("small recording"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	location, err := sink.Upload(context.Background(), "cam 1_100.mp4", path)
+	if err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if !strings.HasSuffix(location, "/media/rec/cam%201_100.mp4") {
+		t.Errorf("location = %q", location)
+	}
+	if got := string(fake.objects["/media/rec/cam 1_100.mp4"]); got != "small recording" {
+		t.Errorf("stored object = %q", got)
+	}
+}
+
+func TestS3Sink_MultipartResumesAfterFailure(t *testing.T) {
+	sink, fake := newTestS3Sink(t)
+	sink.partSize = 4
+	data := []byte("0123456789abcdef!")
+	path := filepath.Join(t.TempDir(), "long.mp4")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	fake.failParts["3"] = 1
+
+	if _, err := sink.Upload(context.Background(), "long.mp4", path); err == nil || !strings.Contains(err.Error(), "part 3/5") {
+		t.Fatalf("first Upload error = %v, want failure of part 3", err)
+	}
+	if _, err := sink.Upload(context.Background(), "long.mp4", path); err != nil {
+		t.Fatalf("retried Upload: %v", err)
+	}
+	if !bytes.Equal(fake.objects["/media/rec/long.mp4"], data) {
+		t.Errorf("stored object = %q, want %q", fake.objects["/media/rec/long.mp4"], data)
+	}
+	// Parts 1 and 2 succeeded the first time and must not be sent again
+	if fake.partPuts != 5 {
+		t.Errorf("part uploads = %d, want 5", fake.partPuts)
+	}
+	if len(sink.uploads) != 0 {
+		t.Errorf("finished upload still tracked: %v", sink.uploads)
+	}
+}
+
+func TestRecordingManager_UploadsFinishedRecording(t *testing.T) {
+	dir := t.TempDir()
+	rm := NewRecordingManager(logger.NewLogger(), dir, nil)
+	defer rm.Shutdown()
+	sink, fake := newTestS3Sink(t)
+	rm.SetSink(sink, true)
+
+	path := filepath.Join(dir, "cam_100.mp4")
+	if err := os.WriteFile(path, []byte("recorded"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rm.mu.Lock()
+	rec := &Recording{ID: "r1", Name: "cam", Filename: "cam_100.mp4", FilePath: path, FileSize: 8}
+	rm.recordings["cam_100"] = rec
+	rm.finishedLocked("cam_100", rec, RecordingStopped)
+	rm.mu.Unlock()
+	rm.finishWg.Wait()
+
+	var got *Recording
+	for _, r := range rm.ListRecordings() {
+		if r.Filename == "cam_100.mp4" {
+			got = r
+		}
+	}
+	if got == nil || got.UploadStatus != UploadUploaded || !strings.HasSuffix(got.RemoteLocation, "/media/rec/cam_100.mp4") {
+		t.Fatalf("recording after upload = %+v", got)
+	}
+	if string(fake.objects["/media/rec/cam_100.mp4"]) != "recorded" {
+		t.Errorf("object not stored")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("local copy not removed after upload: %v", err)
+	}
+}
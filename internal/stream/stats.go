@@ -0,0 +1,97 @@
+package stream
+
+import (
+	"runtime"
+	"time"
+)
+
+// Stats is one snapshot of the whole server for GET /api/stats, so a client
+// polls once instead of stitching together the relay, RTSP and recording APIs
+type Stats struct {
+	TakenAt    time.Time        `json:"taken_at"`
+	Server     StatsServer      `json:"server"`
+	Relays     []RelayStatusV2  `json:"relays"`
+	RTSP       StatsRTSP        `json:"rtsp"`
+	Recordings RecordingSummary `json:"recordings"`
+}
+
+// StatsServer is the process' resource usage with the relay totals of StatusV2
+type StatsServer struct {
+	ServerStatus
+	Goroutines     int    `json:"goroutines"`
+	HeapAlloc      uint64 `json:"heap_alloc"` // bytes
+	InputsRunning  int    `json:"inputs_running"`
+	OutputsRunning int    `json:"outputs_running"`
+}
+
+// StatsRTSP lists the streams of the local RTSP server
+type StatsRTSP struct {
+	Streams    []RTSPStreamInfo `json:"streams"`
+	Publishing int              `json:"publishing"`
+	Clients    int              `json:"clients"`
+}
+
+// RecordingSummary counts the recordings the manager tracks, without the
+// per-file listing of /api/recording/list
+type RecordingSummary struct {
+	RecordingStats
+	Total     int   `json:"total"`
+	TotalSize int64 `json:"total_size"` // bytes, as last measured
+}
+
+// Summary reports the tracked recordings in one pass under the manager's lock
+func (rm *RecordingManager) Summary() RecordingSummary {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	summary := RecordingSummary{
+		RecordingStats: RecordingStats{Active: rm.activeCountLocked(), MaxConcurrent: rm.maxConcurrent},
+		Total:          len(rm.recordings),
+	}
+	for _, rec := range rm.recordings {
+		summary.TotalSize += rec.FileSize
+	}
+	return summary
+}
+
+// BuildStats gathers a Stats snapshot; rtspServer and recMgr may be nil. Each
+// manager is read once under its own lock, in the order relays, RTSP server,
+// recordings, and no lock is held while the next is taken, as the managers
+// call into each other. The server totals and counts are derived from the
+// same relay and stream lists that are returned, so they always agree with
+// them.
+func BuildStats(rm *RelayManager, rtspServer *RTSPServerManager, recMgr *RecordingManager) Stats {
+	status := rm.StatusV2()
+	stats := Stats{
+		TakenAt: time.Now(),
+		Server:  StatsServer{ServerStatus: status.Server, Goroutines: runtime.NumGoroutine()},
+		Relays:  status.Relays,
+		RTSP:    StatsRTSP{Streams: []RTSPStreamInfo{}},
+	}
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	stats.Server.HeapAlloc = memStats.HeapAlloc
+	for _, relay := range stats.Relays {
+		if relay.Input.Status == inputRelayStatusString(InputRunning) {
+			stats.Server.InputsRunning++
+		}
+		for _, out := range relay.Outputs {
+			if out.Status == outputRelayStatusString(OutputRunning) {
+				stats.Server.OutputsRunning++
+			}
+		}
+	}
+
+	if rtspServer != nil {
+		stats.RTSP.Streams = rtspServer.GetStreamStats()
+		for _, s := range stats.RTSP.Streams {
+			if s.Publishing {
+				stats.RTSP.Publishing++
+			}
+			stats.RTSP.Clients += s.ClientCount
+		}
+	}
+	if recMgr != nil {
+		stats.Recordings = recMgr.Summary()
+	}
+	return stats
+}
@@ -0,0 +1,152 @@
+package stream
+
+import "sort"
+
+// Kinds of topology nodes
+const (
+	TopologyInput     = "input"
+	TopologyOutput    = "output"
+	TopologyHLS       = "hls"
+	TopologyRecording = "recording"
+	TopologyHighlight = "highlight"
+	TopologyConsumer  = "consumer" // a reference holder none of the managers knows, i.e. a leaked ref
+)
+
+// TopologyNode is an input or one of its consumers
+type TopologyNode struct {
+	ID       string `json:"id"`
+	Kind     string `json:"kind"`
+	Name     string `json:"name"`
+	Status   string `json:"status,omitempty"`
+	RefCount int    `json:"ref_count,omitempty"` // inputs: references held on the input relay
+	Viewers  int    `json:"viewers,omitempty"`   // hls: viewers with a live heartbeat
+}
+
+// TopologyEdge links an input to a consumer. Refs is how many references the
+// consumer holds on the input relay; outputs that are not running hold none.
+type TopologyEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Refs int    `json:"refs"`
+}
+
+// Topology is the relay graph: every input with its outputs, HLS session,
+// recordings and highlight buffer
+type Topology struct {
+	Nodes []TopologyNode `json:"nodes"`
+	Edges []TopologyEdge `json:"edges"`
+}
+
+// BuildTopology gathers the relay graph from the relay manager's inputs and
+// outputs, the HLS sessions and the active recordings; hlsMgr and recMgr may
+// be nil. The edge refs come from the input relays' consumer labels, so a
+// refcount can be read off the graph, and labels no manager accounts for show
+// up as consumer nodes.
+func BuildTopology(rm *RelayManager, hlsMgr *HLSManager, recMgr *RecordingManager) Topology {
+	topo := Topology{Nodes: []TopologyNode{}, Edges: []TopologyEdge{}}
+
+	// Inputs, with a copy of who holds their references
+	inputNames := make(map[string]string) // input URL -> name
+	consumers := make(map[string]map[string]int)
+	rm.InputRelays.mu.Lock()
+	for inputURL, in := range rm.InputRelays.Relays {
+		in.mu.Lock()
+		topo.Nodes = append(topo.Nodes, TopologyNode{
+			ID:       TopologyInput + ":" + in.InputName,
+			Kind:     TopologyInput,
+			Name:     in.InputName,
+			Status:   inputRelayStatusString(in.Status),
+			RefCount: in.RefCount,
+		})
+		refs := make(map[string]int, len(in.consumers))
+		for label, n := range in.consumers {
+			refs[label] = n
+		}
+		in.mu.Unlock()
+		inputNames[inputURL] = in.InputName
+		consumers[in.InputName] = refs
+	}
+	rm.InputRelays.mu.Unlock()
+
+	// link adds the edge for a consumer, taking its label's references
+	link := func(inputName, nodeID, label string) {
+		topo.Edges = append(topo.Edges, TopologyEdge{From: TopologyInput + ":" + inputName, To: nodeID, Refs: consumers[inputName][label]})
+		delete(consumers[inputName], label)
+	}
+
+	rm.OutputRelays.mu.Lock()
+	for outputURL, out := range rm.OutputRelays.Relays {
+		out.mu.Lock()
+		status := rm.outputStatusString(out)
+		out.mu.Unlock()
+		id := TopologyOutput + ":" + outputURL
+		topo.Nodes = append(topo.Nodes, TopologyNode{ID: id, Kind: TopologyOutput, Name: out.OutputName, Status: status})
+		if inputName, ok := inputNames[out.InputURL]; ok {
+			link(inputName, id, outputConsumer(outputURL))
+		}
+	}
+	rm.OutputRelays.mu.Unlock()
+
+	if hlsMgr != nil {
+		hlsMgr.mu.Lock()
+		sessions := make(map[string]*HLSSession, len(hlsMgr.sessions))
+		viewers := make(map[string]int, len(hlsMgr.sessions))
+		for name, sess := range hlsMgr.sessions {
+			sessions[name] = sess
+			viewers[name] = len(sess.ViewerIDs)
+		}
+		hlsMgr.mu.Unlock()
+		for name, sess := range sessions {
+			status := "starting"
+			sess.ReadyMu.RLock()
+			if sess.Err != "" {
+				status = "failed"
+			} else if sess.Ready {
+				status = "ready"
+			}
+			sess.ReadyMu.RUnlock()
+			id := TopologyHLS + ":" + name
+			topo.Nodes = append(topo.Nodes, TopologyNode{ID: id, Kind: TopologyHLS, Name: name, Status: status, Viewers: viewers[name]})
+			if _, ok := consumers[name]; ok {
+				link(name, id, hlsConsumer)
+			}
+		}
+	}
+
+	if recMgr != nil {
+		for _, rec := range recMgr.ListRecordings() {
+			if !rec.Active || rec.ID == "" {
+				continue
+			}
+			id := TopologyRecording + ":" + rec.ID
+			topo.Nodes = append(topo.Nodes, TopologyNode{ID: id, Kind: TopologyRecording, Name: rec.Filename, Status: "recording"})
+			if inputName, ok := inputNames[rec.Source]; ok {
+				link(inputName, id, recordingConsumer(rec.ID))
+			}
+		}
+	}
+
+	// What is left: highlight buffers, and references nobody accounts for
+	for inputName, refs := range consumers {
+		for label, n := range refs {
+			if label == highlightConsumer {
+				id := TopologyHighlight + ":" + inputName
+				topo.Nodes = append(topo.Nodes, TopologyNode{ID: id, Kind: TopologyHighlight, Name: inputName})
+				topo.Edges = append(topo.Edges, TopologyEdge{From: TopologyInput + ":" + inputName, To: id, Refs: n})
+				continue
+			}
+			id := TopologyConsumer + ":" + inputName + ":" + label
+			topo.Nodes = append(topo.Nodes, TopologyNode{ID: id, Kind: TopologyConsumer, Name: label})
+			topo.Edges = append(topo.Edges, TopologyEdge{From: TopologyInput + ":" + inputName, To: id, Refs: n})
+		}
+	}
+
+	sort.Slice(topo.Nodes, func(i, j int) bool { return topo.Nodes[i].ID < topo.Nodes[j].ID })
+	sort.Slice(topo.Edges, func(i, j int) bool {
+		if topo.Edges[i].From != topo.Edges[j].From {
+			return topo.Edges[i].From < topo.Edges[j].From
+		}
+		return topo.Edges[i].To < topo.Edges[j].To
+	})
+	return topo
+}
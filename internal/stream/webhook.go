@@ -0,0 +1,136 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-mls/internal/logger"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	webhookQueueSize = 64
+	webhookAttempts  = 5
+	webhookTimeout   = 10 * time.Second
+)
+
+// WebhookNotifier POSTs JSON events to a URL. Events are queued and delivered
+// by one background worker, failed deliveries are retried with exponential
+// backoff, so Notify never blocks the caller on a slow or failing receiver.
+type WebhookNotifier struct {
+	url        string
+	client     *http.Client
+	logger     *logger.Logger
+	retryDelay time.Duration
+
+	queue  chan any
+	mu     sync.Mutex // guards closed against Notify racing Close
+	closed bool
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewWebhookNotifier starts a notifier delivering to url
+func NewWebhookNotifier(l *logger.Logger, url string) *WebhookNotifier {
+	ctx, cancel := context.WithCancel(context.Background())
+	n := &WebhookNotifier{
+		url:        url,
+		client:     &http.Client{Timeout: webhookTimeout},
+		logger:     l,
+		retryDelay: time.Second,
+		queue:      make(chan any, webhookQueueSize),
+		ctx:        ctx,
+		cancel:     cancel,
+		done:       make(chan struct{}),
+	}
+	go n.run()
+	return n
+}
+
+// Notify queues event, which is marshalled to JSON, for delivery. When the
+// queue is full the event is dropped and logged.
+func (n *WebhookNotifier) Notify(event any) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		return
+	}
+	select {
+	case n.queue <- event:
+	default:
+		n.logger.Warn("Webhook: queue full, dropping event for %s", RedactURL(n.url))
+	}
+}
+
+// Close stops accepting events and gives the queued ones up to grace to be
+// delivered before abandoning them
+func (n *WebhookNotifier) Close(grace time.Duration) {
+	n.mu.Lock()
+	if n.closed {
+		n.mu.Unlock()
+		return
+	}
+	n.closed = true
+	close(n.queue)
+	n.mu.Unlock()
+
+	select {
+	case <-n.done:
+	case <-time.After(grace):
+		n.cancel()
+		<-n.done
+	}
+	n.cancel()
+}
+
+// run delivers queued events in order until the queue is closed
+func (n *WebhookNotifier) run() {
+	defer close(n.done)
+	for event := range n.queue {
+		body, err := json.Marshal(event)
+		if err != nil {
+			n.logger.Error("Webhook: cannot encode event: %v", err)
+			continue
+		}
+		delay := n.retryDelay
+		for attempt := 1; ; attempt++ {
+			err = n.post(body)
+			if err == nil || n.ctx.Err() != nil {
+				break
+			}
+			n.logger.Warn("Webhook: delivery to %s failed (attempt %d/%d): %v", RedactURL(n.url), attempt, webhookAttempts, err)
+			if attempt == webhookAttempts {
+				break
+			}
+			select {
+			case <-time.After(delay):
+				delay *= 2
+			case <-n.ctx.Done():
+			}
+		}
+		if err != nil {
+			n.logger.Error("Webhook: giving up delivering event to %s: %v", RedactURL(n.url), err)
+		}
+	}
+}
+
+func (n *WebhookNotifier) post(body []byte) error {
+	req, err := http.NewRequestWithContext(n.ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
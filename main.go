@@ -3,8 +3,11 @@ package main
 import (
 	"context"
 	"embed"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"html/template"
 	"io"
 	"io/fs"
 	"net/http"
@@ -18,6 +21,7 @@ import (
 
 	"go-mls/internal/config"
 	"go-mls/internal/httputil"
+	"go-mls/internal/lifecycle"
 	"go-mls/internal/logger"
 	"go-mls/internal/stream"
 )
@@ -25,8 +29,22 @@ import (
 //go:embed web/*
 var webAssets embed.FS
 
+// embedPage is the standalone player served at /embed/{inputName}
+//
+//go:embed templates/embed.html
+var embedPageHTML string
+
+var embedPage = template.Must(template.New("embed").Parse(embedPageHTML))
+
+// hlsViewerStartWait bounds how long start-viewer waits for the HLS session to
+// settle so ffmpeg startup errors can be reported in its response.
+const hlsViewerStartWait = 12 * time.Second
+
 func apiStartRelay(relayMgr *stream.RelayManager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodPost) {
+			return
+		}
 		relayMgr.Logger.Debug("apiStartRelay called")
 		var req struct {
 			InputURL       string            `json:"input_url"`
@@ -35,6 +53,25 @@ func apiStartRelay(relayMgr *stream.RelayManager) http.HandlerFunc {
 			OutputName     string            `json:"output_name"`
 			PlatformPreset string            `json:"platform_preset"`
 			FFmpegOptions  map[string]string `json:"ffmpeg_options"`
+			// Optional probe overrides for slow-to-start inputs
+			AnalyzeDuration string `json:"analyzeduration"`
+			ProbeSize       string `json:"probesize"`
+			// Optional source to fail over to when the input fails
+			BackupURL string `json:"backup_url"`
+			// Optional audio tracks of a multi-track input to publish: an index, a language code or "all"
+			AudioTrack string `json:"audio_track"`
+			// Optional buffering of the input: "low_latency" or "stable" for jittery sources
+			Buffering stream.BufferingMode `json:"buffering"`
+			// Optional input profile: "low_latency", "balanced" or "robust"
+			InputProfile stream.InputProfile `json:"input_profile"`
+			// Optional: read a file:// input that is still being written
+			GrowingFile bool `json:"growing_file"`
+			// Optional HTTP headers sent to an http(s) input, e.g. Authorization or Referer
+			HTTPHeaders map[string]string `json:"http_headers"`
+			// Probe the output before starting ffmpeg
+			VerifyOutput bool `json:"verify_output"`
+			// Return what the start would do instead of starting anything
+			DryRun bool `json:"dry_run"`
 		}
 
 		// Use secure JSON decoding with size limits
@@ -51,19 +88,16 @@ func apiStartRelay(relayMgr *stream.RelayManager) http.HandlerFunc {
 			return
 		}
 
-		relayMgr.Logger.Debug("apiStartRelay: starting relay for input=%s, output=%s, input_name=%s, output_name=%s, preset=%s", req.InputURL, req.OutputURL, req.InputName, req.OutputName, req.PlatformPreset)
+		relayMgr.Logger.Debug("apiStartRelay: starting relay for input=%s, output=%s, input_name=%s, output_name=%s, preset=%s", stream.RedactURL(req.InputURL), req.OutputURL, req.InputName, req.OutputName, req.PlatformPreset)
 
 		// Check if preset/options are provided in request, otherwise try to get from stored config
 		platformPreset := req.PlatformPreset
 		var opts *stream.FFmpegOptions
 		if req.FFmpegOptions != nil {
-			opts = &stream.FFmpegOptions{
-				VideoCodec: req.FFmpegOptions["video_codec"],
-				AudioCodec: req.FFmpegOptions["audio_codec"],
-				Resolution: req.FFmpegOptions["resolution"],
-				Framerate:  req.FFmpegOptions["framerate"],
-				Bitrate:    req.FFmpegOptions["bitrate"],
-				Rotation:   req.FFmpegOptions["rotation"],
+			opts = stream.FFmpegOptionsFromMap(req.FFmpegOptions)
+			if err := relayMgr.ValidateFFmpegOptions(opts); err != nil {
+				httputil.WriteError(w, http.StatusBadRequest, err.Error())
+				return
 			}
 		} else if platformPreset == "" {
 			// Try to get stored configuration for this endpoint
@@ -74,18 +108,119 @@ func apiStartRelay(relayMgr *stream.RelayManager) http.HandlerFunc {
 				relayMgr.Logger.Debug("apiStartRelay: using stored config - preset=%s, options=%+v", platformPreset, opts)
 			}
 		}
-		if err := relayMgr.StartRelayWithOptions(req.InputURL, req.OutputURL, req.InputName, req.OutputName, opts, platformPreset); err != nil {
+		if opts == nil {
+			// A mistyped preset would otherwise start an unconfigured relay
+			if err := stream.ValidatePreset(platformPreset); err != nil {
+				httputil.WriteError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+		if req.VerifyOutput {
+			if err := relayMgr.CheckOutputReachable(req.OutputURL); err != nil {
+				relayMgr.Logger.Error("apiStartRelay: %v", err)
+				httputil.WriteError(w, http.StatusBadGateway, err.Error())
+				return
+			}
+		}
+		if req.DryRun {
+			overrides := &stream.InputConfig{
+				AnalyzeDuration: req.AnalyzeDuration,
+				ProbeSize:       req.ProbeSize,
+				BackupURL:       req.BackupURL,
+				AudioTrack:      req.AudioTrack,
+				Buffering:       req.Buffering,
+				InputProfile:    req.InputProfile,
+				GrowingFile:     req.GrowingFile,
+				HTTPHeaders:     req.HTTPHeaders,
+			}
+			plan, err := relayMgr.ExplainRelayStart(req.InputURL, req.OutputURL, req.InputName, req.OutputName, opts, platformPreset, overrides)
+			if err != nil {
+				status := http.StatusBadRequest
+				if errors.Is(err, stream.ErrOutputDisabled) {
+					status = http.StatusConflict
+				}
+				httputil.WriteError(w, status, err.Error())
+				return
+			}
+			httputil.WriteJSON(w, http.StatusOK, map[string]interface{}{
+				"status": "dry_run",
+				"plan":   plan,
+			})
+			return
+		}
+		if req.AnalyzeDuration != "" || req.ProbeSize != "" || req.BackupURL != "" || req.AudioTrack != "" || req.Buffering != "" || req.InputProfile != "" || req.GrowingFile || len(req.HTTPHeaders) > 0 {
+			// Register first so the overrides are in place before the input relay starts
+			if err := relayMgr.RegisterInputConfig(req.InputName, req.InputURL); err != nil {
+				httputil.WriteError(w, http.StatusConflict, err.Error())
+				return
+			}
+			if req.AnalyzeDuration != "" || req.ProbeSize != "" {
+				relayMgr.SetInputProbeOptions(req.InputName, req.AnalyzeDuration, req.ProbeSize)
+			}
+			if req.BackupURL != "" {
+				relayMgr.SetInputBackupURL(req.InputName, req.BackupURL)
+			}
+			if req.AudioTrack != "" {
+				if err := relayMgr.SetInputAudioTrack(req.InputName, req.AudioTrack); err != nil {
+					httputil.WriteError(w, http.StatusBadRequest, err.Error())
+					return
+				}
+			}
+			if req.Buffering != "" {
+				if err := relayMgr.SetInputBuffering(req.InputName, req.Buffering); err != nil {
+					httputil.WriteError(w, http.StatusBadRequest, err.Error())
+					return
+				}
+			}
+			if req.InputProfile != "" {
+				if err := relayMgr.SetInputProfile(req.InputName, req.InputProfile); err != nil {
+					httputil.WriteError(w, http.StatusBadRequest, err.Error())
+					return
+				}
+			}
+			if req.GrowingFile {
+				if err := relayMgr.SetInputGrowingFile(req.InputName, true); err != nil {
+					httputil.WriteError(w, http.StatusBadRequest, err.Error())
+					return
+				}
+			}
+			if len(req.HTTPHeaders) > 0 {
+				if err := relayMgr.SetInputHeaders(req.InputName, req.HTTPHeaders); err != nil {
+					httputil.WriteError(w, http.StatusBadRequest, err.Error())
+					return
+				}
+			}
+		}
+		args, err := relayMgr.StartRelayWithOptions(req.InputURL, req.OutputURL, req.InputName, req.OutputName, opts, platformPreset)
+		if err != nil {
 			relayMgr.Logger.Error("apiStartRelay: failed to start relay: %v", err)
-			httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+			if errors.Is(err, stream.ErrFFmpegNotFound) {
+				httputil.WriteErrorCode(w, http.StatusServiceUnavailable, "ffmpeg_not_found", stream.ErrFFmpegNotFound.Error())
+				return
+			}
+			status := http.StatusInternalServerError
+			if errors.Is(err, stream.ErrInputNameConflict) || errors.Is(err, stream.ErrOutputLimitReached) || errors.Is(err, stream.ErrOutputDisabled) {
+				status = http.StatusConflict
+			} else if errors.Is(err, stream.ErrRelayLoop) {
+				status = http.StatusBadRequest
+			}
+			httputil.WriteError(w, status, err.Error())
 			return
 		}
-		httputil.WriteJSON(w, http.StatusOK, map[string]string{"status": "started"})
+		// The command line the output runs, so it can be checked or quoted in a bug report
+		httputil.WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"status":      "started",
+			"ffmpeg_args": stream.RedactArgs(args),
+		})
 		relayMgr.Logger.Debug("apiStartRelay: relay started successfully")
 	}
 }
 
 func apiStopRelay(relayMgr *stream.RelayManager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodPost) {
+			return
+		}
 		relayMgr.Logger.Debug("apiStopRelay called")
 		var req struct {
 			InputURL   string `json:"input_url"`
@@ -105,7 +240,7 @@ func apiStopRelay(relayMgr *stream.RelayManager) http.HandlerFunc {
 			httputil.WriteError(w, http.StatusBadRequest, "Input and output names are required")
 			return
 		}
-		relayMgr.Logger.Debug("apiStopRelay: stopping relay for input=%s, output=%s, input_name=%s, output_name=%s", req.InputURL, req.OutputURL, req.InputName, req.OutputName)
+		relayMgr.Logger.Debug("apiStopRelay: stopping relay for input=%s, output=%s, input_name=%s, output_name=%s", stream.RedactURL(req.InputURL), req.OutputURL, req.InputName, req.OutputName)
 		if err := relayMgr.StopRelay(req.InputURL, req.OutputURL, req.InputName, req.OutputName); err != nil {
 			relayMgr.Logger.Error("apiStopRelay: failed to stop relay: %v", err)
 			httputil.WriteError(w, http.StatusInternalServerError, err.Error())
@@ -118,14 +253,255 @@ func apiStopRelay(relayMgr *stream.RelayManager) http.HandlerFunc {
 
 func apiRelayStatus(relayMgr *stream.RelayManager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodGet) {
+			return
+		}
 		relayMgr.Logger.Debug("apiRelayStatus called")
-		httputil.WriteJSON(w, http.StatusOK, relayMgr.StatusV2())
+		status := relayMgr.StatusV2()
+		if category := r.URL.Query().Get("category"); category != "" {
+			// e.g. ?category=active hides inputs kept only as history
+			relays := []stream.RelayStatusV2{}
+			for _, relay := range status.Relays {
+				if relay.Input.Category == category {
+					relays = append(relays, relay)
+				}
+			}
+			status.Relays = relays
+		}
+		httputil.WriteJSON(w, http.StatusOK, status)
 		relayMgr.Logger.Debug("apiRelayStatus: status returned")
 	}
 }
 
+// apiPurgeInputHistory removes historical inputs, those without consumers or
+// outputs, unused for at least older_than (default relay.input_history_max_age)
+func apiPurgeInputHistory(relayMgr *stream.RelayManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodPost) {
+			return
+		}
+		var req struct {
+			OlderThan string `json:"older_than"`
+		}
+		if r.ContentLength != 0 {
+			if err := httputil.DecodeJSON(r, &req); err != nil {
+				httputil.WriteError(w, http.StatusBadRequest, "Invalid request")
+				return
+			}
+		}
+		olderThan := relayMgr.InputHistoryMaxAge()
+		if req.OlderThan != "" {
+			d, err := time.ParseDuration(req.OlderThan)
+			if err != nil || d < 0 {
+				httputil.WriteError(w, http.StatusBadRequest, "older_than must be a non-negative duration such as \"24h\"")
+				return
+			}
+			olderThan = d
+		}
+		purged := relayMgr.PurgeInputHistory(olderThan)
+		httputil.WriteJSON(w, http.StatusOK, map[string]interface{}{"purged": purged})
+	}
+}
+
+func apiRelayStatusBatch(relayMgr *stream.RelayManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodPost) {
+			return
+		}
+		var req struct {
+			InputNames []string `json:"input_names"`
+		}
+		if err := httputil.DecodeJSON(r, &req); err != nil {
+			httputil.WriteError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+		if len(req.InputNames) == 0 {
+			httputil.WriteError(w, http.StatusBadRequest, "At least one input name is required")
+			return
+		}
+
+		relays := relayMgr.StatusForInputs(req.InputNames)
+		found := make(map[string]bool, len(relays))
+		for _, relay := range relays {
+			found[relay.Input.InputName] = true
+		}
+		notFound := []string{}
+		for _, name := range req.InputNames {
+			if !found[name] {
+				notFound = append(notFound, name)
+			}
+		}
+		httputil.WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"relays":    relays,
+			"not_found": notFound,
+		})
+	}
+}
+
+func apiFailBackInput(relayMgr *stream.RelayManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodPost) {
+			return
+		}
+		var req struct {
+			InputName string `json:"input_name"`
+		}
+		if err := httputil.DecodeJSON(r, &req); err != nil {
+			httputil.WriteError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+		if req.InputName == "" {
+			httputil.WriteError(w, http.StatusBadRequest, "Input name is required")
+			return
+		}
+		if err := relayMgr.FailBackInput(req.InputName); err != nil {
+			relayMgr.Logger.Error("apiFailBackInput: %v", err)
+			httputil.WriteError(w, http.StatusConflict, err.Error())
+			return
+		}
+		httputil.WriteJSON(w, http.StatusOK, map[string]string{"status": "primary"})
+	}
+}
+
+func apiEnableHighlight(highlightMgr *stream.HighlightManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodPost) {
+			return
+		}
+		var req struct {
+			InputName string `json:"input_name"`
+		}
+		if err := httputil.DecodeJSON(r, &req); err != nil {
+			httputil.WriteError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+		if req.InputName == "" {
+			httputil.WriteError(w, http.StatusBadRequest, "Input name is required")
+			return
+		}
+		if err := highlightMgr.Enable(r.Context(), req.InputName); err != nil {
+			httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		httputil.WriteJSON(w, http.StatusOK, map[string]string{"status": "enabled"})
+	}
+}
+
+func apiDisableHighlight(highlightMgr *stream.HighlightManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodPost) {
+			return
+		}
+		var req struct {
+			InputName string `json:"input_name"`
+		}
+		if err := httputil.DecodeJSON(r, &req); err != nil {
+			httputil.WriteError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+		if err := highlightMgr.Disable(req.InputName); err != nil {
+			httputil.WriteError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		httputil.WriteJSON(w, http.StatusOK, map[string]string{"status": "disabled"})
+	}
+}
+
+func apiHighlightStatus(highlightMgr *stream.HighlightManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodGet) {
+			return
+		}
+		httputil.WriteJSON(w, http.StatusOK, highlightMgr.Status())
+	}
+}
+
+// apiSaveHighlight saves the last duration seconds of an input's highlight
+// buffer as a recording
+func apiSaveHighlight(highlightMgr *stream.HighlightManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodPost) {
+			return
+		}
+		var req struct {
+			InputName string  `json:"input_name"`
+			Duration  float64 `json:"duration"`
+		}
+		if err := httputil.DecodeJSON(r, &req); err != nil {
+			httputil.WriteError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+		if req.InputName == "" {
+			httputil.WriteError(w, http.StatusBadRequest, "Input name is required")
+			return
+		}
+		saved, err := highlightMgr.SaveHighlight(req.InputName, time.Duration(req.Duration*float64(time.Second)))
+		switch {
+		case errors.Is(err, stream.ErrInvalidHighlightDuration):
+			httputil.WriteError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, stream.ErrHighlightNotEnabled), errors.Is(err, stream.ErrHighlightEmpty):
+			httputil.WriteError(w, http.StatusConflict, err.Error())
+		case err != nil:
+			httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+		default:
+			httputil.WriteJSON(w, http.StatusOK, saved)
+		}
+	}
+}
+
+func apiRelayHealth(relayMgr *stream.RelayManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodGet) {
+			return
+		}
+		httputil.WriteJSON(w, http.StatusOK, relayMgr.Health())
+	}
+}
+
+// readyz reports whether the server can take work: 200 when ready, otherwise
+// 503 with the reasons, e.g. an unwritable recordings directory
+func readyz(recordingMgr *stream.RecordingManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodGet) {
+			return
+		}
+		reasons := []string{}
+		if ok, reason := recordingMgr.Healthy(); !ok {
+			reasons = append(reasons, "recordings: "+reason)
+		}
+		if len(reasons) > 0 {
+			httputil.WriteJSON(w, http.StatusServiceUnavailable, map[string]interface{}{"ready": false, "reasons": reasons})
+			return
+		}
+		httputil.WriteJSON(w, http.StatusOK, map[string]interface{}{"ready": true})
+	}
+}
+
+// apiRelayTopology returns the relay graph: inputs and everything consuming them
+func apiRelayTopology(relayMgr *stream.RelayManager, hlsMgr *stream.HLSManager, recordingMgr *stream.RecordingManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodGet) {
+			return
+		}
+		httputil.WriteJSON(w, http.StatusOK, stream.BuildTopology(relayMgr, hlsMgr, recordingMgr))
+	}
+}
+
+// apiStats returns relay, RTSP, recording and server stats as one snapshot
+func apiStats(relayMgr *stream.RelayManager, rtspServer *stream.RTSPServerManager, recordingMgr *stream.RecordingManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodGet) {
+			return
+		}
+		httputil.WriteJSON(w, http.StatusOK, stream.BuildStats(relayMgr, rtspServer, recordingMgr))
+	}
+}
+
 func apiExportRelays(relayMgr *stream.RelayManager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodGet) {
+			return
+		}
 		relayMgr.Logger.Debug("apiExportRelays called")
 		if err := relayMgr.ExportConfig("relay_config.json"); err != nil {
 			relayMgr.Logger.Error("apiExportRelays: failed to export config: %v", err)
@@ -142,6 +518,9 @@ func apiExportRelays(relayMgr *stream.RelayManager) http.HandlerFunc {
 
 func apiImportRelays(relayMgr *stream.RelayManager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodPost) {
+			return
+		}
 		relayMgr.Logger.Debug("apiImportRelays called")
 		file, _, err := r.FormFile("file")
 		if err != nil {
@@ -158,6 +537,17 @@ func apiImportRelays(relayMgr *stream.RelayManager) http.HandlerFunc {
 		}
 		defer f.Close()
 		io.Copy(f, file)
+		if r.URL.Query().Get("async") != "" {
+			// Large imports can outlast the request; report progress by job instead
+			job, err := relayMgr.StartImport("relay_config.json")
+			if err != nil {
+				httputil.WriteError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			progress, _ := job.Snapshot()
+			httputil.WriteJSON(w, http.StatusAccepted, map[string]string{"status": "importing", "job_id": progress.ID})
+			return
+		}
 		if err := relayMgr.ImportConfig("relay_config.json"); err != nil {
 			relayMgr.Logger.Error("apiImportRelays: failed to import config: %v", err)
 			httputil.WriteError(w, http.StatusInternalServerError, err.Error())
@@ -168,8 +558,143 @@ func apiImportRelays(relayMgr *stream.RelayManager) http.HandlerFunc {
 	}
 }
 
+// apiReloadRelays applies relay_config.json to the running relays, restarting
+// only the relays whose settings changed. The mode defaults to relay.reload_mode.
+func apiReloadRelays(relayMgr *stream.RelayManager, defaultMode stream.ReloadMode) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodPost) {
+			return
+		}
+		var req struct {
+			Mode stream.ReloadMode `json:"mode"`
+		}
+		if r.ContentLength != 0 {
+			if err := httputil.DecodeJSON(r, &req); err != nil {
+				httputil.WriteError(w, http.StatusBadRequest, "Invalid request")
+				return
+			}
+		}
+		if req.Mode == "" {
+			req.Mode = defaultMode
+		}
+		if err := stream.ValidateReloadMode(req.Mode); err != nil {
+			httputil.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		result, err := relayMgr.ReloadConfig("relay_config.json", req.Mode)
+		if err != nil && len(result.Errors) == 0 {
+			// The file could not be read, nothing was changed
+			httputil.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		httputil.WriteJSON(w, http.StatusOK, result)
+	}
+}
+
+// apiImportProgress returns the progress of a background import
+func apiImportProgress(relayMgr *stream.RelayManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodGet) {
+			return
+		}
+		job, ok := relayMgr.ImportJob(r.URL.Query().Get("id"))
+		if !ok {
+			httputil.WriteError(w, http.StatusNotFound, "Import job not found")
+			return
+		}
+		progress, _ := job.Snapshot()
+		httputil.WriteJSON(w, http.StatusOK, progress)
+	}
+}
+
+// apiImportEvents streams the progress of a background import over SSE, one
+// event per change, until the import is done
+func apiImportEvents(relayMgr *stream.RelayManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodGet) {
+			return
+		}
+		job, ok := relayMgr.ImportJob(r.URL.Query().Get("id"))
+		if !ok {
+			httputil.WriteError(w, http.StatusNotFound, "Import job not found")
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			httputil.WriteError(w, http.StatusInternalServerError, "Streaming unsupported")
+			return
+		}
+		httputil.DisableWriteTimeout(w)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		for {
+			progress, changed := job.Snapshot()
+			data, _ := json.Marshal(progress)
+			w.Write([]byte("data: " + string(data) + "\n\n"))
+			flusher.Flush()
+			if progress.Done {
+				return
+			}
+			select {
+			case <-changed:
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}
+}
+
+// relayLogBacklog is how many earlier ffmpeg output lines a log stream starts with
+const relayLogBacklog = 50
+
+// apiRelayLogStream streams the ffmpeg output of a running output relay over a
+// WebSocket, one text message per line, closing when that ffmpeg exits
+func apiRelayLogStream(relayMgr *stream.RelayManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodGet) {
+			return
+		}
+		outputURL := r.URL.Query().Get("output_url")
+		if outputURL == "" {
+			httputil.WriteError(w, http.StatusBadRequest, "output_url is required")
+			return
+		}
+		backlog, lines, cancel, err := relayMgr.SubscribeOutputLog(outputURL, relayLogBacklog)
+		if err != nil {
+			httputil.WriteError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		defer cancel()
+		ws, err := httputil.UpgradeWebSocket(w, r)
+		if err != nil {
+			relayMgr.Logger.Debug("apiRelayLogStream: upgrade failed: %v", err)
+			return
+		}
+		defer ws.Close()
+		for _, line := range backlog {
+			if ws.WriteText(line) != nil {
+				return
+			}
+		}
+		for {
+			select {
+			case line, ok := <-lines:
+				if !ok || ws.WriteText(line) != nil {
+					return
+				}
+			case <-ws.Done():
+				return
+			}
+		}
+	}
+}
+
 func apiRTSPStatus(rtspServer *stream.RTSPServerManager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodGet) {
+			return
+		}
 		if rtspServer == nil {
 			httputil.WriteError(w, http.StatusServiceUnavailable, "RTSP server not available")
 			return
@@ -182,25 +707,99 @@ func apiRTSPStatus(rtspServer *stream.RTSPServerManager) http.HandlerFunc {
 	}
 }
 
+func apiRTSPStream(rtspServer *stream.RTSPServerManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodGet) {
+			return
+		}
+		if rtspServer == nil {
+			httputil.WriteError(w, http.StatusServiceUnavailable, "RTSP server not available")
+			return
+		}
+		path := strings.TrimPrefix(r.URL.Query().Get("path"), "/")
+		if path == "" {
+			httputil.WriteError(w, http.StatusBadRequest, "Stream path is required")
+			return
+		}
+		medias, err := rtspServer.GetStreamDescription(path)
+		if err != nil {
+			httputil.WriteError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		httputil.WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"path":   path,
+			"medias": medias,
+		})
+	}
+}
+
+// apiInputProfiles lists the input profiles and the ffmpeg options of each
+func apiInputProfiles() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodGet) {
+			return
+		}
+		httputil.WriteJSON(w, http.StatusOK, stream.InputProfiles())
+	}
+}
+
 func apiRelayPresets() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodGet) {
+			return
+		}
 		presets := make(map[string]map[string]string)
 		for name, preset := range stream.PlatformPresets {
 			presets[name] = map[string]string{
-				"video_codec": preset.Options.VideoCodec,
-				"audio_codec": preset.Options.AudioCodec,
-				"resolution":  preset.Options.Resolution,
-				"framerate":   preset.Options.Framerate,
-				"bitrate":     preset.Options.Bitrate,
-				"rotation":    preset.Options.Rotation,
+				"video_codec":       preset.Options.VideoCodec,
+				"audio_codec":       preset.Options.AudioCodec,
+				"resolution":        preset.Options.Resolution,
+				"framerate":         preset.Options.Framerate,
+				"bitrate":           preset.Options.Bitrate,
+				"rotation":          preset.Options.Rotation,
+				"keyframe_interval": preset.Options.KeyframeInterval,
+				"audio_normalize":   preset.Options.AudioNormalize,
+				"audio_sample_rate": preset.Options.AudioSampleRate,
+				"audio_channels":    preset.Options.AudioChannels,
 			}
 		}
 		httputil.WriteJSON(w, http.StatusOK, presets)
 	}
 }
 
+func apiFFmpegEncoders(caps *stream.FFmpegCapabilities) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodGet) {
+			return
+		}
+		encoders, err := caps.Encoders()
+		if err != nil {
+			httputil.WriteError(w, http.StatusServiceUnavailable, "ffmpeg not available: "+err.Error())
+			return
+		}
+		httputil.WriteJSON(w, http.StatusOK, encoders)
+	}
+}
+
+func apiFFmpegFormats(caps *stream.FFmpegCapabilities) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodGet) {
+			return
+		}
+		formats, err := caps.Formats()
+		if err != nil {
+			httputil.WriteError(w, http.StatusServiceUnavailable, "ffmpeg not available: "+err.Error())
+			return
+		}
+		httputil.WriteJSON(w, http.StatusOK, formats)
+	}
+}
+
 func apiDeleteInput(relayMgr *stream.RelayManager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodPost, http.MethodDelete) {
+			return
+		}
 		relayMgr.Logger.Debug("apiDeleteInput called")
 		var req struct {
 			InputURL  string `json:"input_url"`
@@ -218,7 +817,7 @@ func apiDeleteInput(relayMgr *stream.RelayManager) http.HandlerFunc {
 			httputil.WriteError(w, http.StatusBadRequest, "Input name is required")
 			return
 		}
-		relayMgr.Logger.Debug("apiDeleteInput: deleting input for input=%s, input_name=%s", req.InputURL, req.InputName)
+		relayMgr.Logger.Debug("apiDeleteInput: deleting input for input=%s, input_name=%s", stream.RedactURL(req.InputURL), req.InputName)
 		if err := relayMgr.DeleteInput(req.InputURL, req.InputName); err != nil {
 			relayMgr.Logger.Error("apiDeleteInput: failed to delete input: %v", err)
 			httputil.WriteError(w, http.StatusInternalServerError, err.Error())
@@ -231,6 +830,9 @@ func apiDeleteInput(relayMgr *stream.RelayManager) http.HandlerFunc {
 
 func apiDeleteOutput(relayMgr *stream.RelayManager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodPost, http.MethodDelete) {
+			return
+		}
 		relayMgr.Logger.Debug("apiDeleteOutput called")
 		var req struct {
 			InputURL   string `json:"input_url"`
@@ -250,7 +852,7 @@ func apiDeleteOutput(relayMgr *stream.RelayManager) http.HandlerFunc {
 			httputil.WriteError(w, http.StatusBadRequest, "Input and output names are required")
 			return
 		}
-		relayMgr.Logger.Debug("apiDeleteOutput: deleting output for input=%s, output=%s, input_name=%s, output_name=%s", req.InputURL, req.OutputURL, req.InputName, req.OutputName)
+		relayMgr.Logger.Debug("apiDeleteOutput: deleting output for input=%s, output=%s, input_name=%s, output_name=%s", stream.RedactURL(req.InputURL), req.OutputURL, req.InputName, req.OutputName)
 		if err := relayMgr.DeleteOutput(req.InputURL, req.OutputURL, req.InputName, req.OutputName); err != nil {
 			relayMgr.Logger.Error("apiDeleteOutput: failed to delete output: %v", err)
 			httputil.WriteError(w, http.StatusInternalServerError, err.Error())
@@ -261,9 +863,102 @@ func apiDeleteOutput(relayMgr *stream.RelayManager) http.HandlerFunc {
 	}
 }
 
+// apiSetOutputEnabled enables (and starts) or disables (and stops) an output
+func apiSetOutputEnabled(relayMgr *stream.RelayManager, enabled bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodPost) {
+			return
+		}
+		var req struct {
+			InputURL   string `json:"input_url"`
+			OutputURL  string `json:"output_url"`
+			InputName  string `json:"input_name"`
+			OutputName string `json:"output_name"`
+		}
+		if err := httputil.DecodeJSON(r, &req); err != nil {
+			httputil.WriteError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+		if req.InputName == "" || req.OutputName == "" {
+			httputil.WriteError(w, http.StatusBadRequest, "Input and output names are required")
+			return
+		}
+		setEnabled, status := relayMgr.DisableOutput, "disabled"
+		if enabled {
+			setEnabled, status = relayMgr.EnableOutput, "enabled"
+		}
+		if err := setEnabled(req.InputURL, req.OutputURL, req.InputName, req.OutputName); err != nil {
+			relayMgr.Logger.Error("apiSetOutputEnabled: %v", err)
+			code := http.StatusInternalServerError
+			if errors.Is(err, stream.ErrOutputNotFound) {
+				code = http.StatusNotFound
+			} else if errors.Is(err, stream.ErrOutputLimitReached) {
+				code = http.StatusConflict
+			}
+			httputil.WriteError(w, code, err.Error())
+			return
+		}
+		httputil.WriteJSON(w, http.StatusOK, map[string]string{"status": status})
+	}
+}
+
+// apiPauseAll pauses or resumes every output, e.g. around host maintenance
+func apiPauseAll(relayMgr *stream.RelayManager, pause bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodPost) {
+			return
+		}
+		var result stream.PauseResult
+		if pause {
+			result = relayMgr.PauseAll()
+		} else {
+			result = relayMgr.ResumeAll()
+		}
+		httputil.WriteJSON(w, http.StatusOK, result)
+	}
+}
+
+// maxRestartStagger bounds the pause between inputs of a restart-all
+const maxRestartStagger = 10 * time.Minute
+
+// apiRestartAll restarts every running output with a pause between inputs,
+// e.g. to pick up a changed global setting. A client that goes away cancels
+// the inputs not yet restarted.
+func apiRestartAll(relayMgr *stream.RelayManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodPost) {
+			return
+		}
+		var req struct {
+			Stagger string `json:"stagger"`
+		}
+		if r.ContentLength != 0 {
+			if err := httputil.DecodeJSON(r, &req); err != nil {
+				httputil.WriteError(w, http.StatusBadRequest, "Invalid request")
+				return
+			}
+		}
+		stagger := stream.DefaultRestartStagger
+		if req.Stagger != "" {
+			d, err := time.ParseDuration(req.Stagger)
+			if err != nil || d < 0 || d > maxRestartStagger {
+				httputil.WriteError(w, http.StatusBadRequest, "stagger must be a duration between 0s and 10m such as \"5s\"")
+				return
+			}
+			stagger = d
+		}
+		// Restarting many inputs can outlast the server's write timeout
+		httputil.DisableWriteTimeout(w)
+		httputil.WriteJSON(w, http.StatusOK, relayMgr.RestartAll(r.Context(), stagger))
+	}
+}
+
 // apiWatchInputHLS handles HLS playlist/segment requests for a given input relay.
 func apiWatchInputHLS(hlsMgr *stream.HLSManager, relayMgr *stream.RelayManager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodGet, http.MethodOptions) {
+			return
+		}
 		// URL: /api/relay/watch-input/hls/{inputName}/{file}
 		parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/api/relay/watch-input/hls/"), "/", 2)
 		if len(parts) != 2 {
@@ -286,8 +981,23 @@ func apiWatchInputHLS(hlsMgr *stream.HLSManager, relayMgr *stream.RelayManager)
 // apiStartHLSViewer creates a new HLS viewer session
 func apiStartHLSViewer(hlsMgr *stream.HLSManager, relayMgr *stream.RelayManager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodPost) {
+			return
+		}
 		var req struct {
 			InputName string `json:"input_name"`
+			// Optional A/V sync flags for the input's preview, applied when its session starts
+			Sync *stream.HLSSyncOptions `json:"sync"`
+			// Optional video height cap for the input's preview (0 = source), applied likewise
+			MaxHeight *int `json:"max_height"`
+			// Optional viewer cap for the input's preview (0 = unlimited), applied when the next viewer joins
+			MaxViewers *int `json:"max_viewers"`
+			// Optional index of the input's audio tracks the preview plays ("" = default), applied likewise
+			AudioTrack *string `json:"audio_track"`
+			// Optional buffering of the input ("" = default), applied when its preview or relay next starts
+			Buffering *stream.BufferingMode `json:"buffering"`
+			// Optional input profile ("" = default), applied likewise
+			InputProfile *stream.InputProfile `json:"input_profile"`
 		}
 
 		if err := httputil.DecodeJSON(r, &req); err != nil {
@@ -302,14 +1012,66 @@ func apiStartHLSViewer(hlsMgr *stream.HLSManager, relayMgr *stream.RelayManager)
 			return
 		}
 
+		if req.Sync != nil {
+			if err := relayMgr.SetInputHLSSync(req.InputName, req.Sync); err != nil {
+				httputil.WriteError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+		if req.MaxHeight != nil {
+			if err := relayMgr.SetInputHLSMaxHeight(req.InputName, req.MaxHeight); err != nil {
+				httputil.WriteError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+		if req.MaxViewers != nil {
+			if err := relayMgr.SetInputHLSMaxViewers(req.InputName, req.MaxViewers); err != nil {
+				httputil.WriteError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+		if req.AudioTrack != nil {
+			if err := relayMgr.SetInputHLSAudioTrack(req.InputName, *req.AudioTrack); err != nil {
+				httputil.WriteError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+		if req.Buffering != nil {
+			if err := relayMgr.SetInputBuffering(req.InputName, *req.Buffering); err != nil {
+				httputil.WriteError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+		if req.InputProfile != nil {
+			if err := relayMgr.SetInputProfile(req.InputName, *req.InputProfile); err != nil {
+				httputil.WriteError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+
 		// HLS manager will handle starting input relay if needed
 		viewerID, err := hlsMgr.AddViewer(req.InputName, "")
+		if errors.Is(err, stream.ErrHLSViewerLimit) {
+			relayMgr.Logger.Warn("HLS start viewer: %v", err)
+			httputil.WriteError(w, http.StatusTooManyRequests, err.Error())
+			return
+		}
 		if err != nil {
 			relayMgr.Logger.Error("HLS start viewer: failed to add viewer for input %s: %v", req.InputName, err)
 			httputil.WriteError(w, http.StatusInternalServerError, "Failed to start HLS viewer")
 			return
 		}
 
+		// Surface ffmpeg startup failures here rather than as a generic 503 on the playlist
+		waitCtx, cancel := context.WithTimeout(r.Context(), hlsViewerStartWait)
+		defer cancel()
+		if err := hlsMgr.WaitForSession(waitCtx, req.InputName); err != nil {
+			hlsMgr.RemoveViewer(req.InputName, viewerID)
+			relayMgr.Logger.Error("HLS start viewer: session for input %s failed: %v", req.InputName, err)
+			httputil.WriteError(w, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+
 		relayMgr.Logger.Info("HLS viewer started: input=%s, viewerID=%s", req.InputName, viewerID)
 		httputil.WriteJSON(w, http.StatusOK, map[string]string{
 			"viewer_id":    viewerID,
@@ -321,6 +1083,9 @@ func apiStartHLSViewer(hlsMgr *stream.HLSManager, relayMgr *stream.RelayManager)
 // apiStopHLSViewer stops an HLS viewer session
 func apiStopHLSViewer(hlsMgr *stream.HLSManager, relayMgr *stream.RelayManager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodPost) {
+			return
+		}
 		var req struct {
 			InputName string `json:"input_name"`
 			ViewerID  string `json:"viewer_id"`
@@ -347,6 +1112,9 @@ func apiStopHLSViewer(hlsMgr *stream.HLSManager, relayMgr *stream.RelayManager)
 // apiHLSViewerHeartbeat updates viewer heartbeat
 func apiHLSViewerHeartbeat(hlsMgr *stream.HLSManager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodPost) {
+			return
+		}
 		var req struct {
 			InputName string `json:"input_name"`
 			ViewerID  string `json:"viewer_id"`
@@ -367,11 +1135,46 @@ func apiHLSViewerHeartbeat(hlsMgr *stream.HLSManager) http.HandlerFunc {
 	}
 }
 
+func apiHLSSessions(hlsMgr *stream.HLSManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodGet) {
+			return
+		}
+		httputil.WriteJSON(w, http.StatusOK, hlsMgr.SessionStats())
+	}
+}
+
+// embedPlayer serves a minimal HLS player page for one input, meant to be
+// iframed elsewhere; the page manages its own viewer session
+func embedPlayer(relayMgr *stream.RelayManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !httputil.AllowMethods(w, r, http.MethodGet) {
+			return
+		}
+		// URL: /embed/{inputName}
+		inputName := strings.TrimPrefix(r.URL.Path, "/embed/")
+		if inputName == "" || strings.Contains(inputName, "..") || strings.ContainsAny(inputName, "/\\") {
+			http.NotFound(w, r)
+			return
+		}
+		if _, ok := relayMgr.GetInputURLByName(inputName); !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := embedPage.Execute(w, struct{ InputName string }{inputName}); err != nil {
+			relayMgr.Logger.Error("Embed player: failed to render page for input %s: %v", inputName, err)
+		}
+	}
+}
+
 func main() {
 	var configFile string
 	var recordingsDir string
+	var relaysConfigURL string
 	flag.StringVar(&configFile, "config", "config.json", "Configuration file path")
 	flag.StringVar(&recordingsDir, "recordings-dir", "", "Directory to store recordings (overrides config)")
+	flag.StringVar(&relaysConfigURL, "relays-config-url", "", "URL of a relay config to import at startup (overrides config)")
 	flag.Parse()
 
 	// Load configuration
@@ -385,9 +1188,20 @@ func main() {
 	if recordingsDir != "" {
 		cfg.Recording.Directory = recordingsDir
 	}
+	if relaysConfigURL != "" {
+		cfg.Relay.ConfigURL = relaysConfigURL
+		if err := cfg.Validate(); err != nil {
+			fmt.Printf("Invalid -relays-config-url: %v\n", err)
+			os.Exit(1)
+		}
+	}
 
 	logger := logger.NewLogger()
+	logger.SetTimeFormat(cfg.Logging.TimeFormat, cfg.Logging.UTC)
 	logger.Info("Starting Go-MLS Relay Manager")
+	for _, warning := range cfg.Warnings() {
+		logger.Warn("Config: %s", warning)
+	}
 
 	// Get initial goroutine count
 	initialGoroutines := runtime.NumGoroutine()
@@ -404,6 +1218,12 @@ func main() {
 	// Initialize RTSP server with configuration
 	rtspServer := stream.NewRTSPServerManager(logger)
 	// TODO: Use RTSP configuration from config file
+	if err := rtspServer.SetUDPRTPPort(cfg.Relay.RTSPServer.UDPRTPPort); err != nil {
+		logger.Fatal("Invalid RTSP server UDP port: %v", err)
+	}
+	if err := rtspServer.SetReadyPollInterval(cfg.Relay.RTSPServer.ReadyPollInterval); err != nil {
+		logger.Fatal("Invalid RTSP ready poll interval: %v", err)
+	}
 	if err := rtspServer.Start(); err != nil {
 		logger.Fatal("Failed to start RTSP server: %v", err)
 	}
@@ -412,13 +1232,118 @@ func main() {
 	relayMgr.SetRTSPServer(rtspServer)
 	// Set relay configuration timeouts
 	relayMgr.SetTimeouts(cfg.Relay.InputTimeout, cfg.Relay.OutputTimeout)
+	// ffmpeg feature detection is cached after the first successful query
+	ffmpegCaps := stream.NewFFmpegCapabilities("ffmpeg")
+	relayMgr.SetFFmpegCapabilities(ffmpegCaps)
+	relayMgr.InputRelays.SetStartupStabilization(cfg.Relay.InputStabilization)
+	relayMgr.InputRelays.SetIdleTimeout(cfg.Relay.IdleInputTimeout)
+	relayMgr.InputRelays.SetFailbackInterval(cfg.Relay.FailbackInterval)
+	relayMgr.SetInputHistoryMaxAge(cfg.Relay.InputHistoryMaxAge)
+	relayMgr.SetDirectPassthrough(cfg.Relay.DirectPassthrough)
+	relayMgr.SetMaxOutputsPerInput(cfg.Relay.MaxOutputsPerInput)
+	relayMgr.SetImportConcurrency(cfg.Relay.ImportConcurrency)
+	if err := relayMgr.SetBuffering(stream.BufferingMode(cfg.Relay.Buffering)); err != nil {
+		logger.Fatal("Invalid relay buffering: %v", err)
+	}
+	if err := relayMgr.SetDefaultInputProfile(stream.InputProfile(cfg.Relay.InputProfile)); err != nil {
+		logger.Fatal("Invalid relay input profile: %v", err)
+	}
+	relayMgr.SetShutdownOptions(cfg.Relay.ShutdownConcurrency, cfg.Relay.ShutdownStopTimeout)
+	stream.SetProcessNice(cfg.Relay.ProcessNice)
+	stream.SetProcessCPUAffinity(cfg.Relay.CPUAffinity)
+	relayMgr.SetThreads(cfg.Relay.Threads)
+	relayMgr.SetMaxMuxingQueueSize(cfg.Relay.MaxMuxingQueueSize)
+	if cfg.Assets.Directory != "" {
+		assetsDir, err := filepath.Abs(cfg.Assets.Directory)
+		if err != nil {
+			logger.Fatal("Failed to resolve assets directory: %v", err)
+		}
+		relayMgr.SetAssetsDir(assetsDir)
+	}
+	relayMgr.OutputRelays.SetSlowOutputPolicy(stream.SlowOutputPolicy(cfg.Relay.SlowOutput.Policy), cfg.Relay.SlowOutput.MinSpeed, cfg.Relay.SlowOutput.Grace)
 
 	recordingMgr := stream.NewRecordingManager(logger, absDir, relayMgr)
+	if s3 := cfg.Recording.S3; s3.Bucket != "" {
+		if s3.AccessKey == "" {
+			s3.AccessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+		}
+		if s3.SecretKey == "" {
+			s3.SecretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		}
+		sink, err := stream.NewS3Sink(stream.S3Config{
+			Endpoint:  s3.Endpoint,
+			Region:    s3.Region,
+			Bucket:    s3.Bucket,
+			Prefix:    s3.Prefix,
+			AccessKey: s3.AccessKey,
+			SecretKey: s3.SecretKey,
+		})
+		if err != nil {
+			logger.Fatal("Invalid recording upload configuration: %v", err)
+		}
+		recordingMgr.SetSink(sink, s3.DeleteLocal)
+		logger.Info("Uploading finished recordings to s3 bucket %s", s3.Bucket)
+	}
+	recordingMgr.SetMaxTotalSize(cfg.Recording.MaxTotalSize)
+	recordingMgr.SetMaxConcurrentRecordings(cfg.Recording.MaxConcurrent)
+	if err := recordingMgr.SetDateLayout(cfg.Recording.DateLayout); err != nil {
+		logger.Fatal("Invalid recording date layout: %v", err)
+	}
+	if err := recordingMgr.SetTimestampOptions(cfg.Recording.TimestampFont, cfg.Recording.TimestampPosition, cfg.Recording.TimestampFontSize); err != nil {
+		logger.Fatal("Invalid recording timestamp configuration: %v", err)
+	}
+	if err := recordingMgr.SetFIFODir(cfg.Recording.FIFODir); err != nil {
+		logger.Fatal("Invalid recording FIFO directory: %v", err)
+	}
+	if err := recordingMgr.SetFinalizeOptions(cfg.Recording.StopSignal, cfg.Recording.FinalizeTimeout); err != nil {
+		logger.Fatal("Invalid recording stop configuration: %v", err)
+	}
+	var recordingWebhook *stream.WebhookNotifier
+	if cfg.Recording.WebhookURL != "" {
+		recordingWebhook = stream.NewWebhookNotifier(logger, cfg.Recording.WebhookURL)
+		recordingMgr.SetWebhook(recordingWebhook)
+	}
+
+	highlightMgr := stream.NewHighlightManager(logger, absDir, relayMgr)
+	highlightMgr.SetWindow(cfg.Recording.HighlightWindow)
 
 	// Instantiate HLSManager (ffmpeg path, cleanup interval, session timeout)
 	hlsMgr := stream.NewHLSManager("ffmpeg", 2*time.Minute, 5*time.Minute)
 	// Connect HLS manager to relay manager for proper consumer management
 	hlsMgr.SetRelayManager(relayMgr)
+	hlsMgr.SetProbeOptions(cfg.HLS.AnalyzeDuration, cfg.HLS.ProbeSize)
+	hlsMgr.SetAudioOptions(cfg.HLS.AudioSampleRate, cfg.HLS.AudioChannels)
+	hlsMgr.SetThreads(cfg.Relay.Threads)
+	hlsMgr.SetSegmentOptions(cfg.HLS.SegmentPattern, cfg.HLS.SegmentFormat)
+	hlsMgr.SetFailedCooldown(cfg.HLS.FailedCooldown, cfg.HLS.MaxFailedCooldown)
+	hlsMgr.SetMaxHeight(cfg.HLS.MaxHeight)
+	hlsMgr.SetMaxViewers(cfg.HLS.MaxViewers)
+	recordingMgr.SetPreviewInputs(hlsMgr.ActiveInputs)
+	hlsMgr.SetSyncOptions(stream.HLSSyncOptions{
+		AudioResample: cfg.HLS.Sync.AudioResample,
+		FPSMode:       cfg.HLS.Sync.FPSMode,
+		CopyTS:        cfg.HLS.Sync.CopyTS,
+	})
+
+	// Relays of a centrally managed config, imported in the background
+	if cfg.Relay.ConfigURL != "" {
+		remote := stream.RemoteConfig{
+			URL:       cfg.Relay.ConfigURL,
+			Headers:   cfg.Relay.ConfigURLHeaders,
+			Timeout:   cfg.Relay.ConfigURLTimeout,
+			CacheFile: cfg.Relay.ConfigURLCache,
+		}
+		if _, err := relayMgr.StartImportURL(context.Background(), remote); err != nil {
+			logger.Error("Failed to import relay config from %s: %v", stream.RedactURL(cfg.Relay.ConfigURL), err)
+		}
+	}
+
+	// Inputs and recordings that were live when the server last stopped
+	if resumed, err := recordingMgr.RestoreState(context.Background(), cfg.Recording.ResumeOnStart); err != nil {
+		logger.Error("Failed to restore recording state: %v", err)
+	} else if resumed > 0 {
+		logger.Info("Resuming %d recording(s)", resumed)
+	}
 
 	// Use embedded static assets
 	staticFS, err := fs.Sub(webAssets, "web")
@@ -429,29 +1354,62 @@ func main() {
 	fs := http.FileServer(http.FS(staticFS))
 	http.Handle("/", fs)
 
-	http.HandleFunc("/api/relay/start", apiStartRelay(relayMgr))
-	http.HandleFunc("/api/relay/stop", apiStopRelay(relayMgr))
-	http.HandleFunc("/api/relay/delete-input", apiDeleteInput(relayMgr))
-	http.HandleFunc("/api/relay/delete-output", apiDeleteOutput(relayMgr))
-	http.HandleFunc("/api/relay/status", apiRelayStatus(relayMgr))
-	http.HandleFunc("/api/relay/export", apiExportRelays(relayMgr))
-	http.HandleFunc("/api/relay/import", apiImportRelays(relayMgr))
-	http.HandleFunc("/api/relay/presets", apiRelayPresets())
-	http.HandleFunc("/api/rtsp/status", apiRTSPStatus(rtspServer))
-
-	http.HandleFunc("/api/recording/start", stream.ApiStartRecording(recordingMgr))
-	http.HandleFunc("/api/recording/stop", stream.ApiStopRecording(recordingMgr))
-	http.HandleFunc("/api/recording/list", stream.ApiListRecordings(recordingMgr))
-	http.HandleFunc("/api/recording/delete", stream.ApiDeleteRecording(recordingMgr))
-	http.HandleFunc("/api/recording/download", stream.ApiDownloadRecording(recordingMgr))
-	http.HandleFunc("/api/recording/sse", stream.ApiRecordingsSSE())
-
-	http.HandleFunc("/api/input/delete", apiDeleteInput(relayMgr))
-	http.HandleFunc("/api/output/delete", apiDeleteOutput(relayMgr))
+	// JSON API endpoints, callable cross-origin when CORS origins are configured
+	handleAPI := func(pattern string, h http.HandlerFunc) {
+		http.HandleFunc(pattern, httputil.CORS(cfg.HTTP.CORSOrigins, h))
+	}
+	handleAPI("/api/relay/start", apiStartRelay(relayMgr))
+	handleAPI("/api/relay/stop", apiStopRelay(relayMgr))
+	handleAPI("/api/relay/delete-input", apiDeleteInput(relayMgr))
+	handleAPI("/api/relay/delete-output", apiDeleteOutput(relayMgr))
+	handleAPI("/api/relay/enable-output", apiSetOutputEnabled(relayMgr, true))
+	handleAPI("/api/relay/disable-output", apiSetOutputEnabled(relayMgr, false))
+	handleAPI("/api/relay/pause-all", apiPauseAll(relayMgr, true))
+	handleAPI("/api/relay/resume-all", apiPauseAll(relayMgr, false))
+	handleAPI("/api/relay/restart-all", apiRestartAll(relayMgr))
+	handleAPI("/api/relay/status", apiRelayStatus(relayMgr))
+	handleAPI("/api/relay/status/batch", apiRelayStatusBatch(relayMgr))
+	handleAPI("/api/relay/purge-history", apiPurgeInputHistory(relayMgr))
+	handleAPI("/api/relay/failback", apiFailBackInput(relayMgr))
+	handleAPI("/api/relay/health", apiRelayHealth(relayMgr))
+	handleAPI("/api/relay/topology", apiRelayTopology(relayMgr, hlsMgr, recordingMgr))
+	handleAPI("/api/relay/highlight", apiSaveHighlight(highlightMgr))
+	handleAPI("/api/relay/highlight/enable", apiEnableHighlight(highlightMgr))
+	handleAPI("/api/relay/highlight/disable", apiDisableHighlight(highlightMgr))
+	handleAPI("/api/relay/highlight/status", apiHighlightStatus(highlightMgr))
+	handleAPI("/api/relay/export", apiExportRelays(relayMgr))
+	handleAPI("/api/relay/import", apiImportRelays(relayMgr))
+	handleAPI("/api/relay/import/progress", apiImportProgress(relayMgr))
+	handleAPI("/api/relay/import/events", apiImportEvents(relayMgr))
+	// Same-origin only: browsers do not apply CORS to WebSockets
+	http.HandleFunc("/api/relay/logs/stream", apiRelayLogStream(relayMgr))
+	handleAPI("/api/relay/reload", apiReloadRelays(relayMgr, stream.ReloadMode(cfg.Relay.ReloadMode)))
+	handleAPI("/api/relay/presets", apiRelayPresets())
+	handleAPI("/api/relay/input-profiles", apiInputProfiles())
+	handleAPI("/api/stats", apiStats(relayMgr, rtspServer, recordingMgr))
+	handleAPI("/api/rtsp/status", apiRTSPStatus(rtspServer))
+	handleAPI("/api/rtsp/stream", apiRTSPStream(rtspServer))
+	handleAPI("/api/ffmpeg/encoders", apiFFmpegEncoders(ffmpegCaps))
+	handleAPI("/api/ffmpeg/formats", apiFFmpegFormats(ffmpegCaps))
+
+	handleAPI("/api/recording/start", stream.ApiStartRecording(recordingMgr))
+	handleAPI("/api/recording/stop", stream.ApiStopRecording(recordingMgr))
+	handleAPI("/api/recording/list", stream.ApiListRecordings(recordingMgr))
+	handleAPI("/api/recording/stats", stream.ApiRecordingStats(recordingMgr))
+	handleAPI("/api/recording/delete", stream.ApiDeleteRecording(recordingMgr))
+	handleAPI("/api/recording/download", stream.ApiDownloadRecording(recordingMgr))
+	stream.SetSSEMaxClients(cfg.HTTP.SSEMaxClients)
+	handleAPI("/api/recording/sse", stream.ApiRecordingsSSE())
+
+	handleAPI("/api/input/delete", apiDeleteInput(relayMgr))
+	handleAPI("/api/output/delete", apiDeleteOutput(relayMgr))
 	http.HandleFunc("/api/relay/watch-input/hls/", apiWatchInputHLS(hlsMgr, relayMgr))
-	http.HandleFunc("/api/relay/hls/start-viewer", apiStartHLSViewer(hlsMgr, relayMgr))
-	http.HandleFunc("/api/relay/hls/stop-viewer", apiStopHLSViewer(hlsMgr, relayMgr))
-	http.HandleFunc("/api/relay/hls/heartbeat", apiHLSViewerHeartbeat(hlsMgr))
+	handleAPI("/api/relay/hls/start-viewer", apiStartHLSViewer(hlsMgr, relayMgr))
+	handleAPI("/api/relay/hls/stop-viewer", apiStopHLSViewer(hlsMgr, relayMgr))
+	handleAPI("/api/relay/hls/heartbeat", apiHLSViewerHeartbeat(hlsMgr))
+	handleAPI("/api/relay/hls/sessions", apiHLSSessions(hlsMgr))
+	http.HandleFunc("/embed/", embedPlayer(relayMgr))
+	http.HandleFunc("/readyz", readyz(recordingMgr))
 
 	// Create HTTP server with proper shutdown support and timeout configuration
 	server := &http.Server{
@@ -466,11 +1424,26 @@ func main() {
 		// Maximum header size (default 1MB is usually fine)
 		MaxHeaderBytes: 1 << 20, // 1 MB
 	}
+	if cfg.Logging.AccessLog {
+		server.Handler = httputil.AccessLog(logger, http.DefaultServeMux)
+	}
 
 	// Channel to listen for interrupt signal
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
+	// SIGHUP applies an edited relay_config.json without restarting unchanged relays
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+	go func() {
+		for range hupChan {
+			logger.Info("Received SIGHUP, reloading relay_config.json")
+			if _, err := relayMgr.ReloadConfig("relay_config.json", stream.ReloadMode(cfg.Relay.ReloadMode)); err != nil {
+				logger.Error("Failed to reload relay config: %v", err)
+			}
+		}
+	}()
+
 	// Start server in a goroutine
 	go func() {
 		logger.Info("Go-MLS relay manager running at http://%s:%s ...", cfg.HTTP.Host, cfg.HTTP.Port)
@@ -480,9 +1453,39 @@ func main() {
 		}
 	}()
 
+	components := lifecycle.NewManager(logger)
+	register := func(name string, stop func(), dependsOn ...string) {
+		if err := components.Register(name, stop, dependsOn...); err != nil {
+			logger.Fatal("Failed to register component: %v", err)
+		}
+	}
+	register("rtsp-server", rtspServer.Stop)
+	register("relays", relayMgr.StopAllRelays, "rtsp-server")
+	register("hls", hlsMgr.Shutdown, "relays")
+	register("highlights", highlightMgr.Shutdown, "relays")
+	recordingDeps := []string{"relays"}
+	if recordingWebhook != nil {
+		register("recording-webhook", func() { recordingWebhook.Close(10 * time.Second) })
+		recordingDeps = append(recordingDeps, "recording-webhook")
+	}
+	register("recordings", recordingMgr.Shutdown, recordingDeps...)
+	register("http-server", func() {
+		// Allow SSE connections and long-running requests to close properly
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			logger.Error("Server shutdown error: %v", err)
+		}
+	}, "relays", "hls", "highlights", "recordings")
+	if err := components.Validate(); err != nil {
+		logger.Fatal("Invalid component dependencies: %v", err)
+	}
+
 	// Wait for interrupt signal
 	<-sigChan
 	logger.Info("Received interrupt signal, initiating graceful shutdown...")
+	signal.Stop(hupChan)
+	close(hupChan)
 
 	// Write endlist to all HLS sessions
 	logger.Info("Signalling stream end to all HLS sessions...")
@@ -490,32 +1493,9 @@ func main() {
 	// Give clients a moment to fetch the final playlist
 	time.Sleep(15 * time.Second)
 
-	// Create a context with timeout for graceful shutdown
-	// Increased timeout to allow SSE connections and long-running requests to close properly
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	// Shutdown HTTP server
-	logger.Info("Shutting down HTTP server...")
-	if err := server.Shutdown(ctx); err != nil {
-		logger.Error("Server shutdown error: %v", err)
-	}
-
-	// Shutdown HLS manager and clean up all HLS sessions/ffmpeg processes
-	logger.Info("Shutting down HLS manager...")
-	hlsMgr.Shutdown()
-
-	// Stop all recordings and shut down recording manager
-	logger.Info("Shutting down recording manager...")
-	recordingMgr.Shutdown()
-
-	// Stop all active relays
-	logger.Info("Stopping all active relays...")
-	relayMgr.StopAllRelays()
-
-	// Stop RTSP server
-	logger.Info("Stopping RTSP server...")
-	rtspServer.Stop()
+	// Stop components in dependency order: consumers of input relays (HTTP
+	// handlers, HLS, recordings) always release them before the relays stop
+	components.Shutdown()
 
 	// Give more time for cleanup of goroutines
 	logger.Info("Waiting for goroutines to clean up...")
@@ -77,11 +77,28 @@ document.addEventListener('DOMContentLoaded', function () {
             </div>
             <!-- Options Grid: Responsive columns/rows via CSS -->
             <div class="advanced-options-grid">
-                ${advancedField('videoCodec', 'Video Codec:', `<input type="text" id="videoCodec" placeholder="e.g. libx264" style="${inputStyle}">`)}
+                ${advancedField('videoCodec', 'Video Codec:', `<input type="text" id="videoCodec" list="videoEncoderList" placeholder="e.g. libx264" style="${inputStyle}"><datalist id="videoEncoderList"></datalist>`)}
                 ${advancedField('framerate', 'FPS:', `<input type="text" id="framerate" placeholder="e.g. 30" style="${inputStyle}">`)}
                 ${advancedField('resolution', 'Resolution:', `<input type="text" id="resolution" placeholder="e.g. 1280x720" style="${inputStyle}">`)}
-                ${advancedField('audioCodec', 'Audio Codec:', `<input type="text" id="audioCodec" placeholder="e.g. aac" style="${inputStyle}">`)}
+                ${advancedField('audioCodec', 'Audio Codec:', `<input type="text" id="audioCodec" list="audioEncoderList" placeholder="e.g. aac" style="${inputStyle}"><datalist id="audioEncoderList"></datalist>`)}
+                ${advancedField('audioSampleRate', 'Sample Rate:', `<input type="text" id="audioSampleRate" placeholder="e.g. 48000" style="${inputStyle}">`)}
+                ${advancedField('audioChannels', 'Channels:', `<input type="text" id="audioChannels" placeholder="e.g. 2 (1 = mono)" style="${inputStyle}">`)}
                 ${advancedField('bitrate', 'Bitrate:', `<input type="text" id="bitrate" placeholder="e.g. 2500k" style="${inputStyle}">`)}
+                ${advancedField('keyframeInterval', 'Keyframe (s):', `<input type="text" id="keyframeInterval" placeholder="e.g. 2 or 60f" style="${inputStyle}">`)}
+                ${advancedField('audioNormalize', 'Loudness (LUFS):', `<input type="text" id="audioNormalize" placeholder="e.g. -16 (normalize)" style="${inputStyle}">`)}
+                ${advancedField('watermark', 'Watermark:', `<input type="text" id="watermark" placeholder="e.g. logo.png (in assets dir)" style="${inputStyle}">`)}
+                ${advancedField('watermarkPosition', 'Logo Position:', `<select id="watermarkPosition" style="${selectStyle}">
+                    <option value="">Bottom Right</option>
+                    <option value="bottom-left">Bottom Left</option>
+                    <option value="top-right">Top Right</option>
+                    <option value="top-left">Top Left</option>
+                    <option value="center">Center</option>
+                </select>`)}
+                ${advancedField('nice', 'Nice:', `<input type="text" id="nice" placeholder="e.g. 10 (-20 to 19)" style="${inputStyle}">`)}
+                ${advancedField('threads', 'Threads:', `<input type="text" id="threads" placeholder="e.g. 2 (0 = auto)" style="${inputStyle}">`)}
+                ${advancedField('maxMuxingQueueSize', 'Muxing Queue:', `<input type="text" id="maxMuxingQueueSize" placeholder="e.g. 1024 (packets)" style="${inputStyle}">`)}
+                ${advancedField('verifyOutput', 'Check Output:', `<input type="checkbox" id="verifyOutput" title="Probe the output before starting ffmpeg">`)}
+                ${advancedField('reconnect', 'Reconnect (s):', `<input type="text" id="reconnect" placeholder="e.g. 10 (max delay)" style="${inputStyle}">`)}
                 ${advancedField('rotation', 'Rotation:', `<select id="rotation" style="${selectStyle}">
                     <option value="">None</option>
                     <option value="transpose=1">90° Clockwise</option>
@@ -96,6 +113,25 @@ document.addEventListener('DOMContentLoaded', function () {
     // Remove dynamic style injection for advanced-options-grid (CSS is now in style.css)
     advancedOptionsContainer.appendChild(advancedRow);
 
+    // Suggest only encoders the installed ffmpeg actually has ("copy" always works)
+    function populateEncoderList(listId, encoders) {
+        const list = document.getElementById(listId);
+        list.innerHTML = '<option value="copy">copy (no re-encoding)</option>';
+        (encoders || []).forEach(enc => {
+            const opt = document.createElement('option');
+            opt.value = enc.name;
+            opt.textContent = enc.description;
+            list.appendChild(opt);
+        });
+    }
+    fetch('/api/ffmpeg/encoders')
+        .then(r => r.ok ? r.json() : Promise.reject(r.status))
+        .then(data => {
+            populateEncoderList('videoEncoderList', data.video);
+            populateEncoderList('audioEncoderList', data.audio);
+        })
+        .catch(err => console.warn('Could not load ffmpeg encoders:', err));
+
     // --- Preset change handler (now uses loadedPresets) ---
     relayControls.addEventListener('change', function (e) {
         if (e.target && e.target.id === 'platformPreset') {
@@ -106,6 +142,10 @@ document.addEventListener('DOMContentLoaded', function () {
                 document.getElementById('resolution').value = loadedPresets[preset].resolution || '';
                 document.getElementById('framerate').value = loadedPresets[preset].framerate || '';
                 document.getElementById('bitrate').value = loadedPresets[preset].bitrate || '';
+                document.getElementById('keyframeInterval').value = loadedPresets[preset].keyframe_interval || '';
+                document.getElementById('audioNormalize').value = loadedPresets[preset].audio_normalize || '';
+                document.getElementById('audioSampleRate').value = loadedPresets[preset].audio_sample_rate || '';
+                document.getElementById('audioChannels').value = loadedPresets[preset].audio_channels || '';
 
                 // Set rotation dropdown based on transpose value in preset
                 let rotationValue = '';
@@ -141,6 +181,10 @@ document.addEventListener('DOMContentLoaded', function () {
                 document.getElementById('resolution').value = '';
                 document.getElementById('framerate').value = '';
                 document.getElementById('bitrate').value = '';
+                document.getElementById('keyframeInterval').value = '';
+                document.getElementById('audioNormalize').value = '';
+                document.getElementById('audioSampleRate').value = '';
+                document.getElementById('audioChannels').value = '';
                 document.getElementById('rotation').value = ''; // Clear rotation
             }
         }
@@ -197,10 +241,22 @@ document.addEventListener('DOMContentLoaded', function () {
         return '0 kbps';
     }
 
+    // Progress of a relay start, from the input's start_stage
+    const startStageLabels = {
+        starting_input: 'starting input...',
+        waiting_rtsp: 'waiting for RTSP...',
+        starting_output: 'starting output...'
+    };
+
     function getStatusBadge(status) {
         if (status === 'Running') return '<span class="badge badge-running">Running</span>';
+        if (status === 'Starting') return '<span class="badge badge-starting">Starting</span>';
         if (status === 'Stopped') return '<span class="badge badge-stopped">Stopped</span>';
         if (status === 'Error') return '<span class="badge badge-error">Error</span>';
+        if (status === 'Reconnecting') return '<span class="badge badge-starting">Reconnecting</span>';
+        if (status === 'Completed') return '<span class="badge badge-completed">Completed</span>';
+        if (status === 'Idle') return '<span class="badge badge-idle">Idle</span>';
+        if (status === 'Paused') return '<span class="badge badge-paused">Paused</span>';
         return '<span class="badge badge-unknown">Unknown</span>';
     }
 
@@ -332,6 +388,29 @@ document.addEventListener('DOMContentLoaded', function () {
                 }
             };
         });
+        // Enabling starts the output, disabling stops it and keeps it from being started
+        document.querySelectorAll('.enableOutputBtn, .disableOutputBtn').forEach(btn => {
+            btn.onclick = function () {
+                const endpoint = btn.classList.contains('enableOutputBtn') ? '/api/relay/enable-output' : '/api/relay/disable-output';
+                fetch(endpoint, {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify({
+                        input_url: btn.getAttribute('data-input'),
+                        output_url: btn.getAttribute('data-output'),
+                        input_name: btn.getAttribute('data-input-name') || '',
+                        output_name: btn.getAttribute('data-output-name') || ''
+                    })
+                }).then(response => {
+                    if (!response.ok) {
+                        response.text().then(text => {
+                            alert('Failed to update output: ' + text);
+                        });
+                    }
+                    fetchStatus();
+                });
+            };
+        });
         // Add ripple effect to all buttons
         document.querySelectorAll('button').forEach(btn => {
             btn.addEventListener('click', function (e) {
@@ -377,7 +456,17 @@ document.addEventListener('DOMContentLoaded', function () {
             resolution: document.getElementById('resolution').value.trim(),
             framerate: document.getElementById('framerate').value.trim(),
             bitrate: document.getElementById('bitrate').value.trim(),
-            rotation: document.getElementById('rotation').value.trim()
+            rotation: document.getElementById('rotation').value.trim(),
+            reconnect: document.getElementById('reconnect').value.trim(),
+            keyframe_interval: document.getElementById('keyframeInterval').value.trim(),
+            audio_normalize: document.getElementById('audioNormalize').value.trim(),
+            audio_sample_rate: document.getElementById('audioSampleRate').value.trim(),
+            audio_channels: document.getElementById('audioChannels').value.trim(),
+            watermark: document.getElementById('watermark').value.trim(),
+            watermark_position: document.getElementById('watermarkPosition').value,
+            nice: document.getElementById('nice').value.trim(),
+            threads: document.getElementById('threads').value.trim(),
+            max_muxing_queue_size: document.getElementById('maxMuxingQueueSize').value.trim()
         };
         fetch('/api/relay/start', {
             method: 'POST',
@@ -388,9 +477,17 @@ document.addEventListener('DOMContentLoaded', function () {
                 input_name: inputName,
                 output_name: outputName,
                 platform_preset: platformPreset,
-                ffmpeg_options: ffmpegOptions
+                ffmpeg_options: ffmpegOptions,
+                verify_output: document.getElementById('verifyOutput').checked
             })
-        }).then(() => { fetchStatus(); });
+        }).then(response => {
+            if (!response.ok) {
+                response.text().then(text => {
+                    alert('Failed to start relay: ' + text);
+                });
+            }
+            fetchStatus();
+        });
     };
 
     // Update table Start buttons to only send minimal info
@@ -427,15 +524,39 @@ document.addEventListener('DOMContentLoaded', function () {
         if (!file) return;
         const formData = new FormData();
         formData.append('file', file);
-        fetch('/api/relay/import', {
+        const importBtn = document.getElementById('importBtn');
+        const importLabel = importBtn.innerHTML;
+        e.target.value = '';
+        // Runs as a background job; its progress is streamed until every relay was tried
+        fetch('/api/relay/import?async=1', {
             method: 'POST',
             body: formData
-        }).then(() => { 
-            fetchStatus(); 
-            alert('Import completed successfully!');
+        }).then(r => r.ok ? r.json() : r.text().then(text => { throw new Error(text); }))
+        .then(({ job_id }) => {
+            importBtn.disabled = true;
+            const es = new EventSource('/api/relay/import/events?id=' + encodeURIComponent(job_id));
+            es.onmessage = function (ev) {
+                const p = JSON.parse(ev.data);
+                importBtn.innerHTML = `<span class="material-icons">file_upload</span>Importing ${p.started + p.failed}/${p.total}`;
+                fetchStatus();
+                if (!p.done) return;
+                es.close();
+                importBtn.disabled = false;
+                importBtn.innerHTML = importLabel;
+                const failures = p.results.filter(res => res.error).map(res => `${res.input_name} -> ${res.output_name}: ${res.error}`);
+                alert(failures.length
+                    ? `Import finished: ${p.started} of ${p.total} relays started.\n\n${failures.join('\n')}`
+                    : `Import completed: ${p.started} relays started.`);
+            };
+            es.onerror = function () {
+                es.close();
+                importBtn.disabled = false;
+                importBtn.innerHTML = importLabel;
+                fetchStatus();
+            };
         }).catch(err => {
             console.error('Import failed:', err);
-            alert('Import failed. Check console for details.');
+            alert('Import failed: ' + err.message);
         });
     };
 
@@ -445,16 +566,39 @@ document.addEventListener('DOMContentLoaded', function () {
             .then(data => updateUI(data));
     }
 
+    // Disable Start Relay when the entered input already feeds its maximum outputs
+    function updateStartRelayLimit() {
+        const btn = document.getElementById('startRelayBtn');
+        const inputName = document.getElementById('inputName').value.trim();
+        const inputUrl = document.getElementById('inputUrl').value.trim();
+        const outputUrl = document.getElementById('outputUrl').value.trim();
+        const relays = (window.latestRelayStatus && window.latestRelayStatus.relays) || [];
+        const group = relays.find(r => r.input && (r.input.input_url === inputUrl || (inputName && r.input.input_name === inputName)));
+        const full = group && group.max_outputs > 0 && group.output_count >= group.max_outputs &&
+            !(group.outputs || []).some(out => out.output_url === outputUrl);
+        btn.disabled = !!full;
+        btn.title = full ? `${group.input.input_name} already has ${group.output_count} of ${group.max_outputs} outputs` : '';
+    }
+    ['inputName', 'inputUrl', 'outputUrl'].forEach(id => {
+        document.getElementById(id).addEventListener('input', updateStartRelayLimit);
+    });
+
     function updateUI(data) {
         // Expect data: { server: {cpu, mem}, relays: [...] }
         window.latestRelayStatus = data;
         window.dispatchEvent(new Event('relayStatusUpdated'));
+        updateStartRelayLimit();
         const searchVal = document.getElementById('searchBox').value.trim();
         const filtered = filterData(data, searchVal);
         let relayGroups = 0, totalEndpoints = 0, totalCpu = 0, totalMem = 0, totalBitrate = 0, health = 'Good';
         let appCpu = '0.0%';
         let appMem = '0';
-        if (filtered && filtered.server) {
+        // Without /proc the backend reports every cpu/mem as 0; show N/A instead
+        const procMetrics = !(filtered && filtered.server && filtered.server.process_metrics === false);
+        if (!procMetrics) {
+            appCpu = 'N/A';
+            appMem = 'N/A';
+        } else if (filtered && filtered.server) {
             appCpu = typeof filtered.server.cpu === 'number' ? filtered.server.cpu.toFixed(1) + '%' : '0.0%';
             appMem = typeof filtered.server.mem === 'number' ? formatBytes(filtered.server.mem) : '0';
         }
@@ -473,7 +617,7 @@ document.addEventListener('DOMContentLoaded', function () {
                         if (typeof out.cpu === 'number') totalCpu += out.cpu;
                         if (typeof out.mem === 'number') totalMem += out.mem;
                         if (typeof out.bitrate === 'number') totalBitrate += out.bitrate;
-                        if (out.status === 'Error') health = 'Warning';
+                        if (out.status === 'Error' || out.status === 'Reconnecting' || out.degraded) health = 'Warning';
                     });
                 }
             });
@@ -481,8 +625,8 @@ document.addEventListener('DOMContentLoaded', function () {
         let healthBadge = health === 'Good'
             ? '<span class="badge badge-healthy">Good</span>'
             : '<span class="badge badge-warning">Warning</span>';
-        let totalCpuStr = (relayGroups + totalEndpoints) ? totalCpu.toFixed(1) + '%' : '0';
-        let totalMemStr = (relayGroups + totalEndpoints) ? formatBytes(totalMem) : '0';
+        let totalCpuStr = !procMetrics ? 'N/A' : (relayGroups + totalEndpoints) ? totalCpu.toFixed(1) + '%' : '0';
+        let totalMemStr = !procMetrics ? 'N/A' : (relayGroups + totalEndpoints) ? formatBytes(totalMem) : '0';
         let serverHtml = `
   <div class="stats-card">
     <div class="stats-grid stats-grid-custom">
@@ -546,7 +690,7 @@ document.addEventListener('DOMContentLoaded', function () {
         <th style="text-align:center; padding:6px 4px; width:70px;">Status</th>
         <th style="text-align:center; padding:6px 4px; width:60px;">CPU (%)</th>
         <th style="text-align:center; padding:6px 4px; width:70px;">Mem (MB)</th>
-        <th style="text-align:center; padding:6px 4px; width:65px;">Speed (x)</th>
+        <th style="text-align:center; padding:6px 4px; width:65px;" title="Speed and received bitrate">Speed (x)</th>
         <th style="text-align:center; padding:6px 4px; width:60px;">Actions</th>
         <th style="text-align:center; padding:6px 4px; width:130px;">Name</th>
         <th style="text-align:center; padding:6px 4px; width:70px;">Status</th>
@@ -561,6 +705,12 @@ document.addEventListener('DOMContentLoaded', function () {
                 const inputName = relay.input.input_name || '';
                 const inputStatus = relay.input.status || 'Stopped';
                 const inputError = relay.input.last_error || '';
+                const inputConsumers = 'Consumers: ' + ((relay.input.consumers || []).join(', ') || 'none');
+                const inputSource = relay.input.active_source === 'backup' ? `<div style="font-size:0.8em; color:#e65100;">on backup</div>` : '';
+                const stageLabel = startStageLabels[relay.input.start_stage];
+                const inputStage = stageLabel ? `<div style="font-size:0.8em; color:#666;">${stageLabel}</div>` : '';
+                const inputHistory = relay.input.category === 'historical' && relay.input.historical_since ? `<div style="font-size:0.8em; color:#999;" title="Unused since ${new Date(relay.input.historical_since).toLocaleString()}">history</div>` : '';
+                const inputBitrate = inputStatus === 'Running' && typeof relay.input.bitrate === 'number' ? `<div style="font-size:0.8em; color:#666;">${Math.round(relay.input.bitrate)} kbps</div>` : '';
                 const inputBg = relayIdx % 2 === 0 ? '#f7fafd' : '#f0f4fa';
                 
                 if (!relay.outputs || relay.outputs.length === 0) {
@@ -568,10 +718,10 @@ document.addEventListener('DOMContentLoaded', function () {
                     // For input rows (no outputs)
                     html += `<tr data-input-group="group-${relayIdx}">
                         <td class="input-group-row" data-input-group="group-${relayIdx}" title="${input}" style="word-break:break-all; color:#1976d2; font-weight:bold; padding:6px 8px; background:${inputBg}; text-align:center;">${inputName}</td>
-                        <td style="padding:6px 8px; background:${inputBg}; text-align:center;">${getStatusBadge(inputStatus)}</td>
-                        <td style="padding:6px 8px; background:${inputBg}; text-align:center;">${inputStatus === 'Running' && typeof relay.input.cpu === 'number' ? relay.input.cpu.toFixed(1) : '-'}</td>
-                        <td style="padding:6px 8px; background:${inputBg}; text-align:center;">${inputStatus === 'Running' && typeof relay.input.mem === 'number' ? Math.round(relay.input.mem / (1024 * 1024)) : '-'}</td>
-                        <td style="padding:6px 8px; background:${inputBg}; text-align:center;">${inputStatus === 'Running' && typeof relay.input.speed === 'number' ? relay.input.speed.toFixed(2) + 'x' : '-'}</td>
+                        <td title="${inputConsumers}" style="padding:6px 8px; background:${inputBg}; text-align:center;">${getStatusBadge(inputStatus)}${inputSource}${inputStage}${inputHistory}</td>
+                        <td style="padding:6px 8px; background:${inputBg}; text-align:center;">${inputStatus === 'Running' && !procMetrics ? 'N/A' : inputStatus === 'Running' && typeof relay.input.cpu === 'number' ? relay.input.cpu.toFixed(1) : '-'}</td>
+                        <td style="padding:6px 8px; background:${inputBg}; text-align:center;">${inputStatus === 'Running' && !procMetrics ? 'N/A' : inputStatus === 'Running' && typeof relay.input.mem === 'number' ? Math.round(relay.input.mem / (1024 * 1024)) : '-'}</td>
+                        <td style="padding:6px 8px; background:${inputBg}; text-align:center;">${inputStatus === 'Running' && typeof relay.input.speed === 'number' ? relay.input.speed.toFixed(2) + 'x' : '-'}${inputBitrate}</td>
                         <td style="padding:6px 8px; background:${inputBg}; text-align:center;">
         <button class="playInputBtn" data-input-name="${inputName}" data-local-url="${relay.input.local_url}" title="Play Input"><span class="material-icons">play_circle_outline</span></button>
         <button class="deleteInputBtn" data-input="${input}" data-input-name="${inputName}" title="Delete Input"><span class="material-icons">delete</span></button>
@@ -593,10 +743,10 @@ document.addEventListener('DOMContentLoaded', function () {
                         // For input rows with outputs, update the first output row to include the input actions column with rowspan
                         if (isFirstOutput) {
                             html += `<td class="input-group-row" data-input-group="group-${relayIdx}" rowspan="${relay.outputs.length}" title="${input}" style="word-break:break-all; color:#1976d2; font-weight:bold; vertical-align:middle; padding:6px 8px; background:${inputBg}; border:none; text-align:center;">${inputName}</td>`;
-                            html += `<td rowspan="${relay.outputs.length}" style="padding:6px 8px; background:${inputBg}; vertical-align:middle; text-align:center;">${getStatusBadge(inputStatus)}</td>`;
-                            html += `<td rowspan="${relay.outputs.length}" style="padding:6px 8px; background:${inputBg}; vertical-align:middle; text-align:center;">${inputStatus === 'Running' && typeof relay.input.cpu === 'number' ? relay.input.cpu.toFixed(1) : '-'}</td>`;
-                            html += `<td rowspan="${relay.outputs.length}" style="padding:6px 8px; background:${inputBg}; vertical-align:middle; text-align:center;">${inputStatus === 'Running' && typeof relay.input.mem === 'number' ? Math.round(relay.input.mem / (1024 * 1024)) : '-'}</td>`;
-                            html += `<td rowspan="${relay.outputs.length}" style="padding:6px 8px; background:${inputBg}; vertical-align:middle; text-align:center;">${inputStatus === 'Running' && typeof relay.input.speed === 'number' ? relay.input.speed.toFixed(2) + 'x' : '-'}</td>`;
+                            html += `<td rowspan="${relay.outputs.length}" title="${inputConsumers}" style="padding:6px 8px; background:${inputBg}; vertical-align:middle; text-align:center;">${getStatusBadge(inputStatus)}${inputSource}${inputStage}</td>`;
+                            html += `<td rowspan="${relay.outputs.length}" style="padding:6px 8px; background:${inputBg}; vertical-align:middle; text-align:center;">${inputStatus === 'Running' && !procMetrics ? 'N/A' : inputStatus === 'Running' && typeof relay.input.cpu === 'number' ? relay.input.cpu.toFixed(1) : '-'}</td>`;
+                            html += `<td rowspan="${relay.outputs.length}" style="padding:6px 8px; background:${inputBg}; vertical-align:middle; text-align:center;">${inputStatus === 'Running' && !procMetrics ? 'N/A' : inputStatus === 'Running' && typeof relay.input.mem === 'number' ? Math.round(relay.input.mem / (1024 * 1024)) : '-'}</td>`;
+                            html += `<td rowspan="${relay.outputs.length}" style="padding:6px 8px; background:${inputBg}; vertical-align:middle; text-align:center;">${inputStatus === 'Running' && typeof relay.input.speed === 'number' ? relay.input.speed.toFixed(2) + 'x' : '-'}${inputBitrate}</td>`;
                             html += `<td rowspan="${relay.outputs.length}" style="padding:6px 8px; background:${inputBg}; vertical-align:middle; text-align:center;">
         <button class="playInputBtn" data-input-name="${inputName}" data-local-url="${relay.input.local_url}" title="Play Input"><span class="material-icons">play_circle_outline</span></button>
         <button class="deleteInputBtn" data-input="${input}" data-input-name="${inputName}" title="Delete Input"><span class="material-icons">delete</span></button>
@@ -608,16 +758,19 @@ document.addEventListener('DOMContentLoaded', function () {
                                     <div title="${out.output_url}" style="font-weight:bold; color:#1976d2;">${out.output_name || out.output_url}</div>
                                 </div>
                             </td>
-                            <td class="output-cell">${getStatusBadge(outputStatus)}</td>
-                            <td class="output-cell">${outputStatus === 'Running' && typeof out.cpu === 'number' ? out.cpu.toFixed(1) : '-'}</td>
-                            <td class="output-cell">${outputStatus === 'Running' && typeof out.mem === 'number' ? Math.round(out.mem / (1024 * 1024)) : '-'}</td>
+                            <td class="output-cell">${out.disabled ? '<span class="badge badge-disabled">Disabled</span>' : getStatusBadge(outputStatus)}</td>
+                            <td class="output-cell">${outputStatus === 'Running' && !procMetrics ? 'N/A' : outputStatus === 'Running' && typeof out.cpu === 'number' ? out.cpu.toFixed(1) : '-'}</td>
+                            <td class="output-cell">${outputStatus === 'Running' && !procMetrics ? 'N/A' : outputStatus === 'Running' && typeof out.mem === 'number' ? Math.round(out.mem / (1024 * 1024)) : '-'}</td>
                             <td class="output-cell">${outputStatus === 'Running' && typeof out.bitrate === 'number' ? Math.round(out.bitrate) : '-'}</td>
                             <td class="output-cell">
                                 <div style="display:flex; flex-direction:row; align-items:center; justify-content:center; gap:8px; flex-wrap:nowrap;">
-                                    ${outputStatus === 'Running'
+                                    ${out.disabled
+                                    ? `<button class="enableOutputBtn relay-action-btn" data-input="${input}" data-output="${out.output_url}" data-input-name="${inputName}" data-output-name="${out.output_name || ''}" title="Enable Output"><span class="material-icons" style="font-size:16px;">toggle_on</span></button>`
+                                    : outputStatus === 'Running'
                                     ? `<button class="stopRelayBtn relay-action-btn" data-input="${input}" data-output="${out.output_url}" data-input-name="${inputName}" data-output-name="${out.output_name || ''}" title="Stop Output"><span class="material-icons" style="font-size:16px;">stop</span></button>`
                                     : `<button class="startRelayBtn relay-action-btn" data-input="${input}" data-output="${out.output_url}" data-input-name="${inputName}" data-output-name="${out.output_name || ''}" title="Start Output"><span class="material-icons" style="font-size:16px;">play_arrow</span></button>`
                                     }
+                                    ${out.disabled ? '' : `<button class="disableOutputBtn relay-action-btn" data-input="${input}" data-output="${out.output_url}" data-input-name="${inputName}" data-output-name="${out.output_name || ''}" title="Disable Output"><span class="material-icons" style="font-size:16px;">toggle_off</span></button>`}
                                     <button class="deleteOutputBtn" data-input="${input}" data-output="${out.output_url}" data-input-name="${inputName}" data-output-name="${out.output_name || ''}" title="Delete Output"><span class="material-icons" style="font-size:16px;">delete</span></button>
                                 </div>
                             </td>`;
@@ -845,7 +998,7 @@ document.addEventListener('DOMContentLoaded', function () {
                         };
                     } else {
                         console.error('Failed to start HLS viewer:', data);
-                        alert('Failed to start video player');
+                        alert(data.error ? 'Failed to start video player:\n' + data.error : 'Failed to start video player');
                     }
                 })
                 .catch(err => {
@@ -73,6 +73,13 @@ document.addEventListener('DOMContentLoaded', function () {
         }
     };
 
+    // Concurrent recording limit from /api/recording/stats, 0 for unlimited
+    let maxConcurrentRecordings = 0;
+    fetch('/api/recording/stats')
+        .then(r => r.json())
+        .then(stats => { maxConcurrentRecordings = stats.max_concurrent || 0; })
+        .catch(err => console.error('Failed to fetch recording stats:', err));
+
     function renderInputUrlsV2(relays, allRecordings) {
         // relays: [{input, outputs}]
         if (!Array.isArray(relays)) {
@@ -87,6 +94,8 @@ document.addEventListener('DOMContentLoaded', function () {
         });
         
         const search = document.getElementById('inputSearchBox').value.trim().toLowerCase();
+        const activeCount = Array.isArray(allRecordings) ? allRecordings.filter(r => r.active).length : 0;
+        const atLimit = maxConcurrentRecordings > 0 && activeCount >= maxConcurrentRecordings;
         let html = '<table style="width:100%"><thead><tr><th>Name</th><th>URL</th><th>Status</th><th>Action</th></tr></thead><tbody>';
         
         for (const relay of relays) {
@@ -104,7 +113,8 @@ document.addEventListener('DOMContentLoaded', function () {
             if (Array.isArray(allRecordings)) {
                 const matches = allRecordings.filter(r => r.name === input.input_name && r.source === input.input_url)
                     .sort((a, b) => new Date(b.started_at) - new Date(a.started_at));
-                latestActive = matches.find(r => r.active);
+                // Prefer the stream-copy recording; other profiles may run alongside it
+                latestActive = matches.find(r => r.active && !r.profile) || matches.find(r => r.active);
                 latestCompleted = matches.find(r => !r.active);
             }
             
@@ -116,7 +126,9 @@ document.addEventListener('DOMContentLoaded', function () {
             if (startingButtons.has(buttonKey)) {
                 toggleBtn = `<button class="toggleRecBtn starting" data-name="${input.input_name}" data-url="${input.input_url}" disabled><span class="material-icons">hourglass_empty</span>Starting...</button>`;
             } else if (latestActive) {
-                toggleBtn = `<button class=\"toggleRecBtn active\" data-name=\"${input.input_name}\" data-url=\"${input.input_url}\"><span class=\"rec-dot\"></span>Stop</button>`;
+                toggleBtn = `<button class=\"toggleRecBtn active\" data-name=\"${input.input_name}\" data-url=\"${input.input_url}\" data-profile=\"${latestActive.profile || ''}\"><span class=\"rec-dot\"></span>Stop</button>`;
+            } else if (atLimit) {
+                toggleBtn = `<button class=\"toggleRecBtn\" data-name=\"${input.input_name}\" data-url=\"${input.input_url}\" disabled title=\"${activeCount} of ${maxConcurrentRecordings} recordings already active\"><span class=\"material-icons\">fiber_manual_record</span>Start</button>`;
             } else {
                 toggleBtn = `<button class=\"toggleRecBtn\" data-name=\"${input.input_name}\" data-url=\"${input.input_url}\"><span class=\"material-icons\">fiber_manual_record</span>Start</button>`;
             }
@@ -189,7 +201,7 @@ document.addEventListener('DOMContentLoaded', function () {
                     fetch('/api/recording/stop', {
                         method: 'POST',
                         headers: { 'Content-Type': 'application/json' },
-                        body: JSON.stringify({ name, source: url })
+                        body: JSON.stringify({ name, source: url, profile: btn.getAttribute('data-profile') || '' })
                     }).then(response => {
                         if (!response.ok) {
                             return response.text().then(text => {
@@ -248,7 +260,7 @@ document.addEventListener('DOMContentLoaded', function () {
                             .then(recordings => {
                                 // Check if a recording with this name and source exists and is active
                                 const recordingExists = recordings.some(rec => 
-                                    rec.name === name && rec.source === url && rec.active
+                                    rec.name === name && rec.source === url && !rec.profile && rec.active
                                 );
                                 
                                 if (recordingExists) {
@@ -385,13 +397,19 @@ document.addEventListener('DOMContentLoaded', function () {
                 downloadBtn = `<button class=\"downloadRecordingBtn\" data-filename=\"${encodeURIComponent(rec.filename)}\"><span class=\"material-icons\">download</span></button>`;
                 deleteBtn = `<button class=\"deleteRecordingBtn\" data-filename=\"${encodeURIComponent(rec.filename)}\"><span class=\"material-icons\">delete</span></button>`;
             }
+            // Upload to remote storage, when configured
+            let uploadStr = '';
+            if (rec.upload_status) {
+                const uploadTitle = rec.upload_error || rec.remote_location || '';
+                uploadStr = `<br><small title="${uploadTitle}">Upload: ${rec.upload_status}</small>`;
+            }
             // Show source on hover if available
             const titleAttr = rec.source ? `title="Source: ${rec.source}"` : '';
             html += `<tr>
                 <td ${titleAttr}>${rec.filename || rec.name}</td>
                 <td>${new Date(rec.started_at).toLocaleString()}</td>
                 <td>${sizeStr}</td>
-                <td>${rec.active ? '<span style=\"color:red;\">Active</span>' : 'Stopped'}</td>
+                <td>${rec.active ? '<span style=\"color:red;\">Active</span>' : 'Stopped'}${uploadStr}</td>
                 <td>
                     ${downloadBtn}
                     ${deleteBtn}